@@ -110,12 +110,15 @@ func (s *API) Handler() http.Handler {
 		r.Post("/resetPassword", s.resetPassword)
 	})
 	r.Route("/problem", func(r chi.Router) {
+		// Editor routes additionally require the management scope, see validateProblemEditor
+		r.Use(s.withTokenScope(kilonova.ScopeReadProblems, kilonova.ScopeManageProblems))
+
 		r.Post("/get", webWrapper(s.getProblems))
 		r.Post("/search", webWrapper(s.searchProblems))
 
-		r.With(s.MustBeProposer).Post("/create", s.initProblem)
+		r.With(s.requireTokenScope(kilonova.ScopeManageProblems), s.MustBeProposer).Post("/create", s.initProblem)
 
-		r.With(s.MustBeProposer).Post("/import", s.importProblemArchive)
+		r.With(s.requireTokenScope(kilonova.ScopeManageProblems), s.MustBeProposer).Post("/import", s.importProblemArchive)
 
 		r.Route("/{problemID}", func(r chi.Router) {
 			r.Use(s.validateProblemID)
@@ -216,14 +219,19 @@ func (s *API) Handler() http.Handler {
 			r.With(s.validateBlogPostEditor).Post("/delete", webMessageWrapper("Removed blog post", s.deleteBlogPost))
 		})
 	})
+	r.Get("/events", s.eventStream)
+
 	r.Route("/submissions", func(r chi.Router) {
-		r.Get("/get", s.filterSubs())
-		r.Get("/getByID", s.getSubmissionByID())
+		r.With(s.withTokenScope(kilonova.ScopeReadSubmissions)).Get("/get", s.filterSubs())
+		r.With(s.withTokenScope(kilonova.ScopeReadSubmissions)).Get("/getByID", s.getSubmissionByID())
 
 		r.Route("/{subID}", func(r chi.Router) {
 			r.Use(s.validateSubmissionID)
 
 			r.With(s.MustBeAuthed).Post("/createPaste", s.createPaste)
+			r.With(s.MustBeAuthed).Post("/useFeedbackToken", webMessageWrapper("Used feedback token", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
+				return s.base.UseFeedbackToken(ctx, &util.SubmissionContext(ctx).Submission, util.UserBriefContext(ctx))
+			}))
 			r.With(s.MustBeAuthed).Post("/delete", webMessageWrapper("Deleted submission", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
 				// Check submission permissions
 				if !(util.UserBriefContext(ctx).Admin || util.SubmissionContext(ctx).ProblemEditor) {
@@ -242,7 +250,7 @@ func (s *API) Handler() http.Handler {
 			}))
 		})
 
-		r.With(s.MustBeAuthed).Post("/submit", s.createSubmission)
+		r.With(s.withTokenScope(kilonova.ScopeSubmit), s.MustBeAuthed).Post("/submit", s.createSubmission)
 	})
 	r.Route("/paste/{pasteID}", func(r chi.Router) {
 		r.Get("/", s.getPaste)
@@ -287,10 +295,54 @@ func (s *API) Handler() http.Handler {
 		userRouter := chi.NewMux()
 		userRouter.Get("/", func(w http.ResponseWriter, r *http.Request) { returnData(w, util.ContentUserBrief(r)) })
 		userRouter.Get("/solvedProblems", s.getSolvedProblems)
+		userRouter.Get("/ratingHistory", func(w http.ResponseWriter, r *http.Request) {
+			history, err := s.base.UserRatingHistory(r.Context(), util.ContentUserBrief(r).ID)
+			if err != nil {
+				err.WriteError(w)
+				return
+			}
+			returnData(w, history)
+		})
 		userRouter.Get("/gravatar", s.getGravatar)
 		userRouter.Get("/avatar", s.getAvatar)
 		userRouter.Get("/discordAvatar", s.getDiscordAvatar)
 		userRouter.With(s.selfOrAdmin).Post("/deauthAll", s.deauthAllSessions)
+		userRouter.With(s.selfOrAdmin).Get("/calendarToken", webWrapper(func(ctx context.Context, _ struct{}) (string, *kilonova.StatusError) {
+			return s.base.CalendarToken(ctx, util.ContentUserBriefContext(ctx).ID)
+		}))
+		userRouter.With(s.selfOrAdmin).Post("/resetCalendarToken", webWrapper(func(ctx context.Context, _ struct{}) (string, *kilonova.StatusError) {
+			return s.base.ResetCalendarToken(ctx, util.ContentUserBriefContext(ctx).ID)
+		}))
+		userRouter.With(s.selfOrAdmin).Get("/notificationPreferences", webWrapper(func(ctx context.Context, _ struct{}) (*kilonova.NotificationPreferences, *kilonova.StatusError) {
+			return s.base.NotificationPreferences(ctx, util.ContentUserBriefContext(ctx).ID)
+		}))
+		userRouter.With(s.selfOrAdmin).Post("/updateNotificationPreferences", webMessageWrapper("Updated notification preferences", func(ctx context.Context, args kilonova.NotificationPreferences) *kilonova.StatusError {
+			return s.base.UpdateNotificationPreferences(ctx, util.ContentUserBriefContext(ctx).ID, &args)
+		}))
+
+		userRouter.With(s.selfOrAdmin).Get("/apiTokens", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.APIToken, *kilonova.StatusError) {
+			return s.base.APITokens(ctx, util.ContentUserBriefContext(ctx).ID)
+		}))
+		userRouter.With(s.selfOrAdmin).Post("/revokeAPIToken", webMessageWrapper("Revoked token", func(ctx context.Context, args struct {
+			ID int `json:"id"`
+		}) *kilonova.StatusError {
+			return s.base.RevokeAPIToken(ctx, util.ContentUserBriefContext(ctx).ID, args.ID)
+		}))
+
+		userRouter.With(s.selfOrAdmin).Get("/oauthGrants", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.OAuthGrant, *kilonova.StatusError) {
+			return s.base.OAuthGrants(ctx, util.ContentUserBriefContext(ctx).ID)
+		}))
+		userRouter.With(s.selfOrAdmin).Post("/revokeOAuthGrant", webMessageWrapper("Revoked app access", func(ctx context.Context, args struct {
+			ClientID string `json:"client_id"`
+		}) *kilonova.StatusError {
+			return s.base.RevokeOAuthGrant(ctx, util.ContentUserBriefContext(ctx).ID, args.ClientID)
+		}))
+
+		userRouter.With(s.selfOrAdmin).Post("/unlinkExternalIdentity", webMessageWrapper("Unlinked account", func(ctx context.Context, args struct {
+			Provider string `json:"provider"`
+		}) *kilonova.StatusError {
+			return s.base.UnlinkExternalIdentity(ctx, util.ContentUserBriefContext(ctx).ID, args.Provider)
+		}))
 
 		userRouter.With(s.selfOrAdmin).Post("/setBio", s.setBio())
 		userRouter.With(s.selfOrAdmin).Post("/setAvatarType", s.setAvatarType())
@@ -314,6 +366,21 @@ func (s *API) Handler() http.Handler {
 		// TODO: Make this secure and maybe with email stuff
 		r.With(s.MustBeAuthed).Post("/changeEmail", s.changeEmail)
 		r.With(s.MustBeAuthed).Post("/changePassword", s.changePassword)
+
+		r.With(s.MustBeAuthed).Post("/createAPIToken", webWrapper(s.createAPIToken))
+	})
+	r.Route("/oauth", func(r chi.Router) {
+		r.Use(s.MustBeAuthed)
+		r.Post("/authorize", webWrapper(func(ctx context.Context, args kilonova.OAuthAuthorizeRequest) (string, *kilonova.StatusError) {
+			return s.base.AuthorizeOAuthRequest(ctx, util.UserBriefContext(ctx), &args)
+		}))
+		r.Get("/clients", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.OAuthClient, *kilonova.StatusError) {
+			return s.base.OAuthClients(ctx, util.UserBriefContext(ctx).ID)
+		}))
+		r.Post("/createClient", webWrapper(s.createOAuthClient))
+		r.Post("/updateClient", webMessageWrapper("Updated app", s.updateOAuthClient))
+		r.Post("/resetClientSecret", webWrapper(s.resetOAuthClientSecret))
+		r.Post("/deleteClient", webMessageWrapper("Deleted app", s.deleteOAuthClient))
 	})
 	r.Route("/problemList", func(r chi.Router) {
 		r.Get("/filter", s.problemLists)
@@ -333,10 +400,18 @@ func (s *API) Handler() http.Handler {
 	})
 
 	r.Route("/contest", func(r chi.Router) {
-		r.With(s.MustBeAuthed).Post("/create", s.createContest)
+		// Editor and tester routes additionally require the administration scope, see validateContestEditor
+		r.Use(s.withTokenScope(kilonova.ScopeContestParticipate, kilonova.ScopeContestAdmin))
+		contestAdmin := s.requireTokenScope(kilonova.ScopeContestAdmin)
+
+		r.With(contestAdmin, s.MustBeAuthed).Post("/create", s.createContest)
+		r.With(contestAdmin, s.MustBeAdmin).Post("/import", s.importContestArchive)
 
 		r.With(s.MustBeAuthed).Post("/acceptInvitation", webMessageWrapper("Registered for contest", s.acceptContestInvitation))
-		r.With(s.MustBeAuthed).Post("/updateInvitation", webMessageWrapper("Updated invitation", s.updateContestInvitation))
+		r.With(contestAdmin, s.MustBeAuthed).Post("/updateInvitation", webMessageWrapper("Updated invitation", s.updateContestInvitation))
+
+		// Mounted separately, since CLICS clients authenticate on their own and may not pass the visibility check beforehand
+		r.Mount("/{contestID}/clics", s.clicsRouter())
 
 		r.Route("/{contestID}", func(r chi.Router) {
 			r.Use(s.validateContestID)
@@ -346,11 +421,16 @@ func (s *API) Handler() http.Handler {
 			r.Get("/problems", s.getContestProblems)
 
 			r.Get("/leaderboard", s.contestLeaderboard)
+			r.Get("/events", s.eventStream)
 
 			r.Get("/questions", webWrapper(s.contestUserQuestions))
 			r.With(s.validateContestEditor).Get("/allQuestions", webWrapper(s.contestAllQuestions))
 			r.With(s.validateContestParticipant).Post("/askQuestion", s.askContestQuestion)
 			r.With(s.validateContestEditor).Post("/answerQuestion", s.answerContestQuestion)
+			r.With(s.validateContestEditor).Get("/questionQueue", webWrapper(s.contestQuestionQueue))
+			r.With(s.validateContestEditor).Post("/claimQuestion", webMessageWrapper("Claimed question", s.claimContestQuestion))
+			r.With(s.validateContestEditor).Post("/unclaimQuestion", webMessageWrapper("Unclaimed question", s.unclaimContestQuestion))
+			r.With(s.validateContestEditor).Post("/assignQuestion", webMessageWrapper("Assigned question", s.assignContestQuestion))
 
 			r.Get("/announcements", webWrapper(s.contestAnnouncements))
 			r.With(s.validateContestEditor).Post("/createAnnouncement", webMessageWrapper("Created announcement", s.createContestAnnouncement))
@@ -359,25 +439,71 @@ func (s *API) Handler() http.Handler {
 
 			r.With(s.MustBeAuthed).Post("/register", s.registerForContest)
 			r.With(s.MustBeAuthed).Post("/startRegistration", s.startContestRegistration)
+			r.With(s.MustBeAuthed).Post("/startVirtual", webMessageWrapper("Started virtual participation", s.startVirtualParticipation))
+			r.With(s.MustBeAuthed).Post("/createTeam", webWrapper(s.createContestTeam))
+			r.With(s.MustBeAuthed).Get("/team", webWrapper(s.userContestTeam))
+			r.With(s.validateContestEditor).Get("/teams", webWrapper(s.contestTeams))
+			r.With(s.validateContestEditor).Get("/problemSettings", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.ContestProblemSettings, *kilonova.StatusError) {
+				return s.base.ContestProblemSettings(ctx, util.ContestContext(ctx).ID)
+			}))
+			r.Get("/ratingChanges", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.RatingChange, *kilonova.StatusError) {
+				return s.base.ContestRatingChanges(ctx, util.ContestContext(ctx).ID)
+			}))
+			r.With(contestAdmin, s.MustBeAdmin).Post("/computeRatings", webMessageWrapper("Computed ratings", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
+				return s.base.ComputeContestRatings(ctx, util.ContestContext(ctx))
+			}))
+			r.With(s.validateContestEditor).Get("/resolver", webWrapper(func(ctx context.Context, _ struct{}) (*kilonova.ContestResolver, *kilonova.StatusError) {
+				return s.base.ContestResolver(ctx, util.ContestContext(ctx))
+			}))
 			r.With(s.validateContestEditor).Post("/runMOSS", webMessageWrapper("MOSS executed successfully", s.runMOSS))
+			r.With(s.validateContestEditor).Post("/startSystemTest", webMessageWrapper("Started system testing", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
+				return s.base.StartSystemTest(context.WithoutCancel(ctx), util.ContestContext(ctx))
+			}))
 
 			r.With(s.validateContestEditor).Get("/invitations", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.ContestInvitation, *kilonova.StatusError) {
 				return s.base.ContestInvitations(ctx, util.ContestContext(ctx).ID)
 			}))
 			r.With(s.validateContestEditor).Post("/createInvitation", webWrapper(func(ctx context.Context, args struct {
-				MaxUses int `json:"max_uses"`
+				MaxUses  int     `json:"max_uses"`
+				Category *string `json:"category"`
 			}) (string, *kilonova.StatusError) {
 				var cnt *int
 				if args.MaxUses > 0 {
 					cnt = &args.MaxUses
 				}
-				return s.base.CreateContestInvitation(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx), cnt)
+				if args.Category != nil && *args.Category == "" {
+					args.Category = nil
+				}
+				return s.base.CreateContestInvitation(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx), cnt, args.Category)
 			}))
 
 			r.With(s.MustBeAuthed).Get("/checkRegistration", webWrapper(s.checkRegistration))
+			r.With(s.MustBeAuthed).Get("/feedbackTokens", webWrapper(func(ctx context.Context, args struct {
+				ProblemID int `json:"problem_id"`
+			}) (int, *kilonova.StatusError) {
+				return s.base.RemainingFeedbackTokens(ctx, util.ContestContext(ctx), args.ProblemID, util.UserBriefContext(ctx))
+			}))
+			r.With(s.validateContestParticipant).Post("/lockProblem", webMessageWrapper("Locked problem", s.lockContestProblem))
+			r.With(s.MustBeAuthed).Get("/lockedProblems", webWrapper(s.lockedContestProblems))
+			r.With(s.validateContestParticipant).Post("/hack", webWrapper(s.createHack))
+			r.With(s.MustBeAuthed).Get("/hacks", webWrapper(s.contestHacks))
+			r.With(s.MustBeAuthed).Get("/hackInput", webWrapper(s.contestHackInput))
+			r.With(s.validateContestEditor).Post("/addHackAsTest", webWrapper(s.addHackAsTest))
 			r.With(s.validateContestEditor).Get("/registrations", s.contestRegistrations)
 			r.With(s.validateContestEditor).Post("/kickUser", s.stripContestRegistration)
-			r.With(s.MustBeAdmin).Post("/forceRegister", s.forceRegisterForContest)
+			r.With(contestAdmin, s.MustBeAdmin).Post("/forceRegister", s.forceRegisterForContest)
+			r.With(s.validateContestEditor).Get("/certificateSettings", webWrapper(func(ctx context.Context, _ struct{}) (*kilonova.CertificateSettings, *kilonova.StatusError) {
+				return s.base.CertificateSettings(ctx, util.ContestContext(ctx).ID)
+			}))
+			r.With(s.validateContestParticipant).Post("/print", webWrapper(s.createPrintJob))
+			r.With(s.MustBeAuthed).Get("/printJobs", webWrapper(s.userPrintJobs))
+			r.With(s.validateContestTester).Get("/allPrintJobs", webWrapper(s.allPrintJobs))
+			r.With(s.validateContestTester).Post("/markPrinted", webMessageWrapper("Updated print job", s.markPrintJob))
+			r.With(s.validateContestTester).Get("/balloons", webWrapper(func(ctx context.Context, _ struct{}) ([]*kilonova.BalloonEvent, *kilonova.StatusError) {
+				return s.base.BalloonEvents(ctx, util.ContestContext(ctx))
+			}))
+			r.With(s.validateContestTester).Post("/deliverBalloon", webMessageWrapper("Updated balloon", s.deliverBalloon))
+			r.With(s.validateContestEditor).Post("/clone", webWrapper(s.cloneContest))
 			r.With(s.validateContestEditor).Post("/delete", webMessageWrapper("Deleted contest", func(ctx context.Context, _ struct{}) *kilonova.StatusError {
 				return s.base.DeleteContest(ctx, util.ContestContext(ctx))
 			}))
@@ -387,6 +513,21 @@ func (s *API) Handler() http.Handler {
 
 				r.Post("/", s.updateContest)
 				r.Post("/problems", s.updateContestProblems)
+				r.Post("/problemSettings", webMessageWrapper("Updated problem settings", s.updateContestProblemSettings))
+				r.Post("/categories", s.updateContestCategories)
+				r.Post("/ipRanges", s.updateContestIPRanges)
+				r.Post("/registrationCategory", webMessageWrapper("Updated registration category", s.updateRegistrationCategory))
+				r.Post("/certificateSettings", webMessageWrapper("Updated certificate settings", func(ctx context.Context, args kilonova.CertificateSettings) *kilonova.StatusError {
+					args.ContestID = util.ContestContext(ctx).ID
+					return s.base.UpdateCertificateSettings(ctx, &args)
+				}))
+				r.Post("/issueCertificates", webWrapper(func(ctx context.Context, _ struct{}) (int, *kilonova.StatusError) {
+					certs, err := s.base.IssueContestCertificates(ctx, util.ContestContext(ctx))
+					if err != nil {
+						return -1, err
+					}
+					return len(certs), nil
+				}))
 
 				r.Post("/addEditor", s.addContestEditor)
 				r.Post("/addTester", s.addContestTester)
@@ -395,6 +536,23 @@ func (s *API) Handler() http.Handler {
 		})
 	})
 
+	r.Route("/contestSeries", func(r chi.Router) {
+		r.Get("/", webWrapper(s.contestSeriesList))
+		r.With(s.MustBeProposer).Post("/create", webWrapper(s.createContestSeries))
+
+		r.Route("/{seriesID}", func(r chi.Router) {
+			r.Use(s.validateSeriesID)
+			r.Get("/", webWrapper(func(ctx context.Context, _ struct{}) (*kilonova.ContestSeries, *kilonova.StatusError) {
+				return util.ContestSeriesContext(ctx), nil
+			}))
+			r.Get("/leaderboard", webWrapper(s.seriesLeaderboard))
+
+			r.With(s.validateSeriesEditor).Post("/update", webMessageWrapper("Updated contest series", s.updateContestSeries))
+			r.With(s.validateSeriesEditor).Post("/updateRounds", webMessageWrapper("Updated series rounds", s.updateSeriesRounds))
+			r.With(s.validateSeriesEditor).Post("/delete", webMessageWrapper("Deleted contest series", s.deleteContestSeries))
+		})
+	})
+
 	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		errorData(w, "Endpoint not found", 404)
 	})
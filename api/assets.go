@@ -1,6 +1,7 @@
 package api
 
 import (
+	"archive/zip"
 	"bufio"
 	"bytes"
 	"context"
@@ -18,6 +19,7 @@ import (
 	"time"
 
 	"github.com/KiloProjects/kilonova"
+	contestArchive "github.com/KiloProjects/kilonova/archive/contest"
 	"github.com/KiloProjects/kilonova/archive/test"
 	"github.com/KiloProjects/kilonova/internal/util"
 	"github.com/KiloProjects/kilonova/sudoapi"
@@ -80,6 +82,14 @@ func (s *Assets) AssetsRouter() http.Handler {
 	r.With(s.api.MustBeProposer).Get("/subtest/{subtestID}", s.ServeSubtest)
 
 	r.With(s.api.validateContestID).Get("/contest/{contestID}/leaderboard.csv", s.ServeContestLeaderboard)
+	r.With(s.api.validateContestID, s.api.validateContestEditor).Get("/contest/{contestID}/event-feed.ndjson", s.ServeContestEventFeed)
+	r.With(s.api.validateContestID, s.api.validateContestEditor).Get("/contest/{contestID}/archive.zip", s.ServeContestArchive)
+	r.With(s.api.validateContestID, s.api.validateContestEditor).Get("/contest/{contestID}/certificates.zip", s.ServeContestCertificates)
+	r.With(s.api.validateContestID, s.api.validateContestVisible, s.api.MustBeAuthed).Get("/contest/{contestID}/certificate.pdf", s.ServeContestCertificate)
+	r.With(s.api.validateContestID, s.api.validateContestTester).Get("/contest/{contestID}/print/{jobID}.pdf", s.ServePrintJob)
+	r.With(s.api.validateSeriesID).Get("/contestSeries/{seriesID}/leaderboard.csv", s.ServeSeriesLeaderboard)
+	r.Get("/calendar.ics", s.ServeCalendar)
+	r.Get("/calendar/{token}.ics", s.ServeCalendar)
 
 	return r
 }
@@ -205,6 +215,10 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 	if hasDisplayName {
 		header = append(header, "display_name")
 	}
+	hasCategories := len(util.Contest(r).Categories) > 0
+	if hasCategories {
+		header = append(header, "category")
+	}
 	for _, pb := range ld.ProblemOrder {
 		name, ok := ld.ProblemNames[pb]
 		if !ok {
@@ -226,9 +240,24 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	for _, entry := range ld.Entries {
-		line := []string{entry.User.Name}
-		if hasDisplayName {
-			line = append(line, entry.User.DisplayName)
+		var line []string
+		if entry.Team != nil {
+			line = []string{entry.Team.Name}
+			if hasDisplayName {
+				line = append(line, "")
+			}
+		} else {
+			line = []string{entry.User.Name}
+			if hasDisplayName {
+				line = append(line, entry.User.DisplayName)
+			}
+		}
+		if hasCategories {
+			if entry.Category != nil {
+				line = append(line, *entry.Category)
+			} else {
+				line = append(line, "")
+			}
 		}
 		if util.Contest(r).LeaderboardStyle == kilonova.LeaderboardTypeICPC {
 			for _, pb := range ld.ProblemOrder {
@@ -286,6 +315,83 @@ func (s *Assets) ServeContestLeaderboard(w http.ResponseWriter, r *http.Request)
 	http.ServeContent(w, r, "leaderboard.csv", time.Now(), bytes.NewReader(buf.Bytes()))
 }
 
+func (s *Assets) ServeSeriesLeaderboard(w http.ResponseWriter, r *http.Request) {
+	ld, err := s.base.SeriesLeaderboard(r.Context(), util.ContestSeries(r), util.UserBrief(r))
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	var buf bytes.Buffer
+	wr := csv.NewWriter(&buf)
+
+	var hasDisplayName bool
+	for _, entry := range ld.Entries {
+		if entry.User.DisplayName != "" {
+			hasDisplayName = true
+			break
+		}
+	}
+
+	header := []string{"rank", "username"}
+	if hasDisplayName {
+		header = append(header, "display_name")
+	}
+	for _, round := range ld.Rounds {
+		header = append(header, round.Name)
+	}
+	header = append(header, "total")
+	if err := wr.Write(header); err != nil {
+		zap.S().Warn(err)
+		http.Error(w, "Couldn't write CSV", 500)
+		return
+	}
+
+	for _, entry := range ld.Entries {
+		line := []string{strconv.Itoa(entry.Rank), entry.User.Name}
+		if hasDisplayName {
+			line = append(line, entry.User.DisplayName)
+		}
+		for _, round := range ld.Rounds {
+			score, ok := entry.RoundScores[round.ContestID]
+			switch {
+			case !ok:
+				line = append(line, "-")
+			case !entry.Counted[round.ContestID]:
+				// Dropped by the best-k rule
+				line = append(line, "("+score.String()+")")
+			default:
+				line = append(line, score.String())
+			}
+		}
+		line = append(line, entry.Total.String())
+
+		if err := wr.Write(line); err != nil {
+			zap.S().Warn(err)
+			http.Error(w, "Couldn't write CSV", 500)
+			return
+		}
+	}
+
+	wr.Flush()
+	if err := wr.Error(); err != nil {
+		zap.S().Warn(err)
+		http.Error(w, "Couldn't write CSV", 500)
+		return
+	}
+
+	http.ServeContent(w, r, "series_leaderboard.csv", time.Now(), bytes.NewReader(buf.Bytes()))
+}
+
+func (s *Assets) ServeContestEventFeed(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if err := s.base.WriteContestEventFeed(r.Context(), util.Contest(r), util.UserBrief(r), &buf); err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+
+	http.ServeContent(w, r, "event-feed.ndjson", time.Now(), bytes.NewReader(buf.Bytes()))
+}
+
 func (s *Assets) ServeSubtest(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(chi.URLParam(r, "subtestID"))
 	if err != nil {
@@ -408,3 +514,231 @@ func (s *Assets) ServeProblemArchive() http.HandlerFunc {
 		}
 	}
 }
+
+func (s *Assets) ServeContestArchive(w http.ResponseWriter, r *http.Request) {
+	contest := util.Contest(r)
+	// Contest archives include the archives of all problems, so they are generated one at a time
+	if !s.api.testArchiveLock.TryLock() {
+		http.Error(w, "Another archive is being processed, try again later", http.StatusTooManyRequests)
+		return
+	}
+	defer s.api.testArchiveLock.Unlock()
+
+	w.Header().Add("Content-Type", "application/zip")
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="contest-%d-%s.zip"`, contest.ID, kilonova.MakeSlug(contest.Name)))
+	w.WriteHeader(200)
+
+	wr := bufio.NewWriter(w)
+	if err := contestArchive.GenerateContestArchive(r.Context(), contest, wr, s.base); err != nil {
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
+			zap.S().Warn(err)
+		}
+		fmt.Fprint(w, err)
+	}
+	if err := wr.Flush(); err != nil && !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
+		zap.S().Warn(err)
+	}
+}
+
+// ServeContestCertificates serves the issued certificates of the contest's participants as a zip of PDFs, named after their rank
+func (s *Assets) ServeContestCertificates(w http.ResponseWriter, r *http.Request) {
+	if !s.base.PDFRenderingEnabled() {
+		http.Error(w, "PDF certificates were not configured by admins", http.StatusServiceUnavailable)
+		return
+	}
+	// Rendering goes through headless Chromium, so only one batch is generated at a time
+	if !s.api.testArchiveLock.TryLock() {
+		http.Error(w, "Another archive is being processed, try again later", http.StatusTooManyRequests)
+		return
+	}
+	defer s.api.testArchiveLock.Unlock()
+
+	contest := util.Contest(r)
+	settings, err := s.base.CertificateSettings(r.Context(), contest.ID)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	certs, err := s.base.ContestCertificates(r.Context(), contest.ID)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	if len(certs) == 0 {
+		http.Error(w, "No certificates were issued yet", http.StatusNotFound)
+		return
+	}
+
+	// Everything except the PDF conversion is prepared before the response starts, so errors can still be reported properly
+	type certFile struct {
+		name string
+		page []byte
+	}
+	files := make([]certFile, 0, len(certs))
+	for _, cert := range certs {
+		user, err := s.base.UserBrief(r.Context(), cert.UserID)
+		if err != nil {
+			http.Error(w, err.Error(), err.Code)
+			return
+		}
+		page, err := s.base.RenderCertificate(r.Context(), contest, settings, cert)
+		if err != nil {
+			http.Error(w, err.Error(), err.Code)
+			return
+		}
+		files = append(files, certFile{name: fmt.Sprintf("%03d-%s.pdf", cert.Rank, user.Name), page: page})
+	}
+	// Converting the first certificate catches a misconfigured Chromium before anything is sent
+	firstPDF, err := s.base.RenderPDF(r.Context(), files[0].page)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/zip")
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="certificates-%d-%s.zip"`, contest.ID, kilonova.MakeSlug(contest.Name)))
+	w.WriteHeader(200)
+
+	ar := zip.NewWriter(w)
+	for i, file := range files {
+		pdf := firstPDF
+		if i > 0 {
+			pdf, err = s.base.RenderPDF(r.Context(), file.page)
+			if err != nil {
+				// The response already started, so the truncated archive is the only way to signal the error
+				zap.S().Warn(err)
+				return
+			}
+		}
+		// PDFs are already compressed
+		f, err1 := ar.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Store})
+		if err1 != nil {
+			zap.S().Warn(err1)
+			return
+		}
+		if _, err := f.Write(pdf); err != nil {
+			if !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
+				zap.S().Warn(err)
+			}
+			return
+		}
+	}
+	if err := ar.Close(); err != nil && !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
+		zap.S().Warn(err)
+	}
+}
+
+// ServeContestCertificate serves a single issued certificate as PDF.
+// Editors can get the certificate of any participant, passed as the `user` query parameter.
+// Participants can get their own certificate once the editors publish the certificates
+func (s *Assets) ServeContestCertificate(w http.ResponseWriter, r *http.Request) {
+	// Rendering goes through headless Chromium, so it shares the limit of the certificate archives
+	if !s.api.testArchiveLock.TryLock() {
+		http.Error(w, "Too many certificates are being generated, try again later", http.StatusTooManyRequests)
+		return
+	}
+	defer s.api.testArchiveLock.Unlock()
+
+	contest := util.Contest(r)
+	settings, err := s.base.CertificateSettings(r.Context(), contest.ID)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+
+	user := util.UserBrief(r)
+	if name := r.FormValue("user"); name != "" {
+		if !s.base.IsContestEditor(util.UserBrief(r), contest) {
+			http.Error(w, "You can't view the certificates of other users", http.StatusForbidden)
+			return
+		}
+		user, err = s.base.UserBriefByName(r.Context(), name)
+		if err != nil {
+			http.Error(w, err.Error(), err.Code)
+			return
+		}
+	} else if !settings.Published && !s.base.IsContestEditor(user, contest) {
+		http.Error(w, "Certificates were not published yet", http.StatusForbidden)
+		return
+	}
+
+	cert, err := s.base.UserContestCertificate(r.Context(), contest.ID, user.ID)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	page, err := s.base.RenderCertificate(r.Context(), contest, settings, cert)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	pdf, err := s.base.RenderPDF(r.Context(), page)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/pdf")
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`inline; filename="certificate-%d-%s.pdf"`, contest.ID, user.Name))
+	http.ServeContent(w, r, "certificate.pdf", cert.CreatedAt, bytes.NewReader(pdf))
+}
+
+// ServePrintJob serves the print job as a PDF for the contest staff.
+// If PDF rendering is not configured, the printable HTML page is served instead, so it can be printed from the browser
+func (s *Assets) ServePrintJob(w http.ResponseWriter, r *http.Request) {
+	jobID, err1 := strconv.Atoi(chi.URLParam(r, "jobID"))
+	if err1 != nil {
+		http.Error(w, "Invalid print job ID", http.StatusBadRequest)
+		return
+	}
+	contest := util.Contest(r)
+	job, err := s.base.PrintJob(r.Context(), contest.ID, jobID)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+
+	if !s.base.PDFRenderingEnabled() {
+		page, err := s.base.RenderPrintJob(r.Context(), contest, job)
+		if err != nil {
+			http.Error(w, err.Error(), err.Code)
+			return
+		}
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+		http.ServeContent(w, r, "print.html", job.CreatedAt, bytes.NewReader(page))
+		return
+	}
+
+	pdf, err := s.base.PrintJobPDF(r.Context(), contest, job)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	w.Header().Add("Content-Type", "application/pdf")
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`inline; filename="print-%d-%d.pdf"`, contest.ID, job.ID))
+	http.ServeContent(w, r, "print.pdf", job.CreatedAt, bytes.NewReader(pdf))
+}
+
+// ServeCalendar serves the iCalendar feed of upcoming contests.
+// Calendar apps don't send cookies, so personal feeds are identified by the secret token in the URL
+func (s *Assets) ServeCalendar(w http.ResponseWriter, r *http.Request) {
+	var user *kilonova.UserBrief
+	if token := chi.URLParam(r, "token"); token != "" {
+		var err *kilonova.StatusError
+		user, err = s.base.CalendarTokenUser(r.Context(), token)
+		if err != nil {
+			http.Error(w, err.Error(), err.Code)
+			return
+		}
+	}
+
+	cal, err := s.base.ContestCalendar(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), err.Code)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(200)
+	w.Write(cal)
+}
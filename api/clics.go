@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/integrations/clics"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	ClicsEnabled      = config.GenFlag("integrations.clics.enabled", true, "Read-only CLICS Contest API for contest editors, used by ICPC tools")
+	clicsPollInterval = 5 * time.Second
+)
+
+// clicsRouter serves the CLICS Contest API of a contest, for its editors
+func (s *API) clicsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(s.clicsBasicAuth)
+	r.Use(s.withTokenScope(kilonova.ScopeContestFeed, kilonova.ScopeContestAdmin))
+	r.Use(s.validateContestID)
+	r.Use(s.contestEditorWithScopes(kilonova.ScopeContestFeed, kilonova.ScopeContestAdmin))
+
+	r.Get("/", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Contest }, false))
+	r.Get("/contests", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return []clics.Contest{data.Contest} }, false))
+	r.Get("/state", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.State }, false))
+	r.Get("/judgement-types", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.JudgementTypes }, false))
+	r.Get("/languages", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Languages }, false))
+	r.Get("/problems", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Problems }, false))
+	r.Get("/groups", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Groups }, false))
+	r.Get("/teams", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Teams }, false))
+	r.Get("/submissions", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Submissions }, false))
+	r.Get("/judgements", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Judgements }, false))
+	r.Get("/runs", s.clicsCollection(func(data *sudoapi.ClicsContest) any { return data.Runs }, true))
+	r.Get("/event-feed", s.clicsEventFeed)
+	return r
+}
+
+// clicsBasicAuth allows ICPC tools, which don't know about Kilonova sessions, to authenticate using HTTP Basic credentials.
+// The password must be a personal access token with the contest feed scope, account passwords are not accepted.
+// Requests that already have a session are passed through
+func (s *API) clicsBasicAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ClicsEnabled.Value() {
+			errorData(w, "The CLICS API is disabled", http.StatusNotFound)
+			return
+		}
+		_, password, ok := r.BasicAuth()
+		if !ok || util.UserBrief(r) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", `Basic realm="Kilonova CLICS API"`)
+		if !strings.HasPrefix(password, kilonova.APITokenPrefix) {
+			errorData(w, "The password must be a personal access token", http.StatusUnauthorized)
+			return
+		}
+		ip, _ := s.base.GetRequestInfo(r)
+		user, token, err := s.base.APITokenUser(r.Context(), password, ip)
+		if err != nil {
+			err.WriteError(w)
+			return
+		}
+		if !token.HasScope(kilonova.ScopeContestFeed) && !token.HasScope(kilonova.ScopeContestAdmin) {
+			errorData(w, "Token doesn't have the contest feed scope", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.AuthedUserKey, user)))
+	})
+}
+
+// clicsCollection serves a single endpoint of the CLICS API. Unlike the rest of the API, the objects are returned bare, as the specification requires
+func (s *API) clicsCollection(get func(*sudoapi.ClicsContest) any, withRuns bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := s.base.ClicsContest(r.Context(), util.Contest(r), util.UserBrief(r), withRuns)
+		if err != nil {
+			err.WriteError(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(get(data)); err != nil {
+			zap.S().Warn(err)
+		}
+	}
+}
+
+// clicsEventFeed streams the contest as newline delimited CLICS events.
+// The contest is polled periodically and changed objects are sent as updates, until the end of updates is reached.
+// If the stream parameter is false, only the current state is sent
+func (s *API) clicsEventFeed(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		errorData(w, "Streaming is not supported", 500)
+		return
+	}
+	stream := r.FormValue("stream") != "false"
+
+	feed := clics.NewFeedWriter(w)
+	ticker := time.NewTicker(clicsPollInterval)
+	defer ticker.Stop()
+	for started := false; ; started = true {
+		data, err := s.base.ClicsContest(r.Context(), util.Contest(r), util.UserBrief(r), true)
+		if err != nil {
+			if !started {
+				err.WriteError(w)
+			}
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+		}
+		changed, err1 := data.SyncFeed(feed)
+		if err1 != nil {
+			return
+		}
+		if !changed {
+			// Keep-alive, as defined by the specification
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+		if !stream || data.State.EndOfUpdates != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"archive/zip"
 	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/KiloProjects/kilonova"
+	contestArchive "github.com/KiloProjects/kilonova/archive/contest"
+	"github.com/KiloProjects/kilonova/archive/test"
 	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
 	"go.uber.org/zap"
 )
 
@@ -41,6 +46,22 @@ func (s *API) updateContest(w http.ResponseWriter, r *http.Request) {
 		errorData(w, "You aren't allowed to change contest type!", 400)
 		return
 	}
+	if args.FeedbackLevel != kilonova.FeedbackLevelNone && !args.FeedbackLevel.Valid() {
+		errorData(w, "Invalid feedback level", 400)
+		return
+	}
+	if args.FeedbackTokens != nil && *args.FeedbackTokens < 0 {
+		errorData(w, "Feedback token count must be non-negative", 400)
+		return
+	}
+	if (args.HackPoints != nil && *args.HackPoints < 0) || (args.HackPenalty != nil && *args.HackPenalty < 0) {
+		errorData(w, "Hack points must be non-negative", 400)
+		return
+	}
+	if args.TeamSize != nil && *args.TeamSize < 0 {
+		errorData(w, "Team size must be non-negative", 400)
+		return
+	}
 	st := util.Contest(r).StartTime
 	et := util.Contest(r).EndTime
 	if args.StartTime != nil {
@@ -91,6 +112,58 @@ func (s *API) updateContestProblems(w http.ResponseWriter, r *http.Request) {
 	returnData(w, "Updated contest problems")
 }
 
+func (s *API) updateContestProblemSettings(ctx context.Context, args kilonova.ContestProblemSettings) *kilonova.StatusError {
+	return s.base.UpdateContestProblemSettings(ctx, util.ContestContext(ctx), &args)
+}
+
+func (s *API) updateContestCategories(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Categories []string `json:"categories"`
+	}
+	if err := parseJSONBody(r, &args); err != nil {
+		err.WriteError(w)
+		return
+	}
+
+	if err := s.base.UpdateContestCategories(r.Context(), util.Contest(r).ID, args.Categories); err != nil {
+		err.WriteError(w)
+		return
+	}
+
+	returnData(w, "Updated contest categories")
+}
+
+func (s *API) updateContestIPRanges(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Ranges []string `json:"ranges"`
+	}
+	if err := parseJSONBody(r, &args); err != nil {
+		err.WriteError(w)
+		return
+	}
+
+	if err := s.base.UpdateContestIPRanges(r.Context(), util.Contest(r).ID, args.Ranges); err != nil {
+		err.WriteError(w)
+		return
+	}
+
+	returnData(w, "Updated contest IP ranges")
+}
+
+func (s *API) updateRegistrationCategory(ctx context.Context, args struct {
+	Username string  `json:"name"`
+	Category *string `json:"category"`
+}) *kilonova.StatusError {
+	user, err := s.base.UserBriefByName(ctx, args.Username)
+	if err != nil {
+		return err
+	}
+	if args.Category != nil && *args.Category == "" {
+		args.Category = nil
+	}
+	return s.base.UpdateContestRegistrationCategory(ctx, util.ContestContext(ctx), user.ID, args.Category)
+}
+
 func (s *API) getContest(ctx context.Context, _ struct{}) (*kilonova.Contest, *kilonova.StatusError) {
 	return util.ContestContext(ctx), nil
 }
@@ -108,6 +181,18 @@ type contestLeaderboardParams struct {
 	Frozen bool `json:"frozen"`
 
 	Generated *bool `json:"generated_acc"`
+
+	// Virtual requests the ghost leaderboard, with the virtual participants merged in
+	Virtual bool `json:"virtual"`
+
+	Category *string `json:"category"`
+
+	MinRating *int `json:"min_rating"`
+	MaxRating *int `json:"max_rating"`
+}
+
+func (args *contestLeaderboardParams) userFilter() kilonova.UserFilter {
+	return kilonova.UserFilter{Generated: args.Generated, Category: args.Category, MinRating: args.MinRating, MaxRating: args.MaxRating}
 }
 
 func (s *API) leaderboard(ctx context.Context, contest *kilonova.Contest, lookingUser *kilonova.UserBrief, args *contestLeaderboardParams) (*kilonova.ContestLeaderboard, *kilonova.StatusError) {
@@ -115,10 +200,14 @@ func (s *API) leaderboard(ctx context.Context, contest *kilonova.Contest, lookin
 		return nil, kilonova.Statusf(400, "Leaderboard for this contest is not available")
 	}
 
+	if args.Virtual {
+		return s.base.VirtualContestLeaderboard(ctx, contest, lookingUser, args.userFilter())
+	}
+
 	return s.base.ContestLeaderboard(
 		ctx, contest,
 		s.base.UserContestFreezeTime(lookingUser, contest, args.Frozen),
-		kilonova.UserFilter{Generated: args.Generated},
+		args.userFilter(),
 	)
 }
 
@@ -218,13 +307,19 @@ func (s *API) contestAnnouncements(ctx context.Context, _ struct{}) ([]*kilonova
 }
 
 func (s *API) createContestAnnouncement(ctx context.Context, args struct {
-	Text string `json:"text"`
+	Text      string `json:"text"`
+	ProblemID *int   `json:"problem_id"`
 }) *kilonova.StatusError {
 	if args.Text == "" {
 		return kilonova.Statusf(400, "No announcement text supplied")
 	}
+	if args.ProblemID != nil {
+		if _, err := s.base.ContestProblem(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx), *args.ProblemID); err != nil {
+			return kilonova.Statusf(400, "Problem is not in contest")
+		}
+	}
 
-	_, err := s.base.CreateContestAnnouncement(ctx, util.ContestContext(ctx).ID, args.Text)
+	_, err := s.base.CreateContestAnnouncement(ctx, util.ContestContext(ctx).ID, args.Text, args.ProblemID)
 	return err
 }
 
@@ -270,10 +365,62 @@ func (s *API) contestAllQuestions(ctx context.Context, _ struct{}) ([]*kilonova.
 	return s.base.ContestQuestions(ctx, util.ContestContext(ctx).ID)
 }
 
+func (s *API) contestQuestionQueue(ctx context.Context, _ struct{}) ([]*kilonova.ContestQuestion, *kilonova.StatusError) {
+	return s.base.ContestQuestionQueue(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx).ID)
+}
+
+// contestQuestionFromArgs returns the question with the given ID, making sure it's from the current contest
+func (s *API) contestQuestionFromArgs(ctx context.Context, id int) (*kilonova.ContestQuestion, *kilonova.StatusError) {
+	question, err := s.base.ContestQuestion(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if question.ContestID != util.ContestContext(ctx).ID {
+		return nil, kilonova.Statusf(400, "Contest question must be from contest")
+	}
+	return question, nil
+}
+
+func (s *API) claimContestQuestion(ctx context.Context, args struct {
+	ID int `json:"questionID"`
+}) *kilonova.StatusError {
+	question, err := s.contestQuestionFromArgs(ctx, args.ID)
+	if err != nil {
+		return err
+	}
+	return s.base.ClaimContestQuestion(ctx, question, util.UserBriefContext(ctx))
+}
+
+func (s *API) unclaimContestQuestion(ctx context.Context, args struct {
+	ID int `json:"questionID"`
+}) *kilonova.StatusError {
+	question, err := s.contestQuestionFromArgs(ctx, args.ID)
+	if err != nil {
+		return err
+	}
+	return s.base.UnclaimContestQuestion(ctx, question)
+}
+
+func (s *API) assignContestQuestion(ctx context.Context, args struct {
+	ID       int    `json:"questionID"`
+	Username string `json:"username"`
+}) *kilonova.StatusError {
+	question, err := s.contestQuestionFromArgs(ctx, args.ID)
+	if err != nil {
+		return err
+	}
+	judge, err := s.base.UserBriefByName(ctx, args.Username)
+	if err != nil {
+		return err
+	}
+	return s.base.AssignContestQuestion(ctx, util.ContestContext(ctx), question, judge)
+}
+
 func (s *API) askContestQuestion(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		Text string `json:"text"`
+		Text      string `json:"text"`
+		ProblemID *int   `json:"problem_id"`
 	}
 	if err := decoder.Decode(&args, r.Form); err != nil {
 		errorData(w, err, 400)
@@ -285,7 +432,7 @@ func (s *API) askContestQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := s.base.CreateContestQuestion(r.Context(), util.Contest(r), util.UserBrief(r).ID, args.Text); err != nil {
+	if _, err := s.base.CreateContestQuestion(r.Context(), util.Contest(r), util.UserBrief(r).ID, args.Text, args.ProblemID); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -296,8 +443,9 @@ func (s *API) askContestQuestion(w http.ResponseWriter, r *http.Request) {
 func (s *API) answerContestQuestion(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		ID   int    `json:"questionID"`
-		Text string `json:"text"`
+		ID     int    `json:"questionID"`
+		Text   string `json:"text"`
+		Public bool   `json:"public"`
 	}
 	if err := decoder.Decode(&args, r.Form); err != nil {
 		errorData(w, err, 400)
@@ -320,7 +468,7 @@ func (s *API) answerContestQuestion(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.base.AnswerContestQuestion(r.Context(), args.ID, args.Text); err != nil {
+	if err := s.base.AnswerContestQuestion(r.Context(), question, args.Text, util.UserBrief(r), args.Public); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -351,7 +499,7 @@ func (s *API) acceptContestInvitation(ctx context.Context, args struct {
 	if !contest.RegisterDuringContest && contest.Running() {
 		return kilonova.Statusf(400, "Cannot register while contest is running")
 	}
-	return s.base.RegisterContestUser(ctx, contest, util.UserBriefContext(ctx).ID, &inv.ID, true)
+	return s.base.RegisterContestUser(ctx, contest, util.UserBriefContext(ctx).ID, &inv.ID, nil, true)
 }
 
 func (s *API) updateContestInvitation(ctx context.Context, args struct {
@@ -373,7 +521,19 @@ func (s *API) updateContestInvitation(ctx context.Context, args struct {
 }
 
 func (s *API) registerForContest(w http.ResponseWriter, r *http.Request) {
-	if err := s.base.RegisterContestUser(r.Context(), util.Contest(r), util.UserBrief(r).ID, nil, false); err != nil {
+	r.ParseForm()
+	var args struct {
+		Category *string `json:"category"`
+	}
+	if err := decoder.Decode(&args, r.Form); err != nil {
+		errorData(w, err, 400)
+		return
+	}
+	if args.Category != nil && *args.Category == "" {
+		args.Category = nil
+	}
+
+	if err := s.base.RegisterContestUser(r.Context(), util.Contest(r), util.UserBrief(r).ID, nil, args.Category, false); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -388,15 +548,50 @@ func (s *API) startContestRegistration(w http.ResponseWriter, r *http.Request) {
 	returnData(w, "Started contest registration.")
 }
 
+func (s *API) startVirtualParticipation(ctx context.Context, _ struct{}) *kilonova.StatusError {
+	return s.base.StartVirtualParticipation(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx))
+}
+
+func (s *API) createContestTeam(ctx context.Context, args struct {
+	Name string `json:"name"`
+}) (*kilonova.ContestTeam, *kilonova.StatusError) {
+	return s.base.CreateContestTeam(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx), args.Name)
+}
+
+type contestTeamData struct {
+	*kilonova.ContestTeam
+	Invitations []*kilonova.ContestInvitation `json:"invitations"`
+}
+
+func (s *API) userContestTeam(ctx context.Context, _ struct{}) (*contestTeamData, *kilonova.StatusError) {
+	team, err := s.base.UserContestTeam(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx).ID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.base.TeamInvitations(ctx, team.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &contestTeamData{ContestTeam: team, Invitations: invitations}, nil
+}
+
+func (s *API) contestTeams(ctx context.Context, _ struct{}) ([]*kilonova.ContestTeam, *kilonova.StatusError) {
+	return s.base.ContestTeams(ctx, util.ContestContext(ctx).ID)
+}
+
 func (s *API) forceRegisterForContest(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		Username string `json:"name"`
+		Username string  `json:"name"`
+		Category *string `json:"category"`
 	}
 	if err := decoder.Decode(&args, r.Form); err != nil {
 		errorData(w, err, 400)
 		return
 	}
+	if args.Category != nil && *args.Category == "" {
+		args.Category = nil
+	}
 
 	user, err := s.base.UserBriefByName(r.Context(), args.Username)
 	if err != nil {
@@ -409,7 +604,7 @@ func (s *API) forceRegisterForContest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.base.RegisterContestUser(r.Context(), util.Contest(r), user.ID, nil, true); err != nil {
+	if err := s.base.RegisterContestUser(r.Context(), util.Contest(r), user.ID, nil, args.Category, true); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -525,3 +720,216 @@ func (s *API) runMOSS(ctx context.Context, args struct{}) *kilonova.StatusError
 	}
 	return s.base.RunMOSS(context.WithoutCancel(ctx), util.ContestContext(ctx))
 }
+
+func (s *API) lockContestProblem(ctx context.Context, args struct {
+	ProblemID int `json:"problem_id"`
+}) *kilonova.StatusError {
+	problem, err := s.base.Problem(ctx, args.ProblemID)
+	if err != nil {
+		return err
+	}
+	return s.base.LockContestProblem(ctx, util.ContestContext(ctx), problem, util.UserBriefContext(ctx))
+}
+
+func (s *API) lockedContestProblems(ctx context.Context, _ struct{}) ([]int, *kilonova.StatusError) {
+	return s.base.LockedContestProblems(ctx, util.ContestContext(ctx).ID, util.UserBriefContext(ctx).ID)
+}
+
+func (s *API) createHack(ctx context.Context, args struct {
+	SubmissionID int    `json:"submission_id"`
+	Input        string `json:"input"`
+}) (int, *kilonova.StatusError) {
+	sub, err := s.base.RawSubmission(ctx, args.SubmissionID)
+	if err != nil {
+		return -1, err
+	}
+	if sub.ContestID == nil || *sub.ContestID != util.ContestContext(ctx).ID {
+		return -1, kilonova.Statusf(400, "Submission is not from this contest")
+	}
+	return s.base.CreateHack(ctx, sub.ID, util.UserBriefContext(ctx), []byte(args.Input))
+}
+
+func (s *API) contestHacks(ctx context.Context, args struct {
+	ProblemID *int `json:"problem_id"`
+
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}) ([]*kilonova.ContestHack, *kilonova.StatusError) {
+	filter := kilonova.HackFilter{
+		ContestID: &util.ContestContext(ctx).ID,
+		ProblemID: args.ProblemID,
+		Limit:     args.Limit,
+		Offset:    args.Offset,
+	}
+	if !s.base.IsContestEditor(util.UserBriefContext(ctx), util.ContestContext(ctx)) {
+		filter.InvolvedUserID = &util.UserBriefContext(ctx).ID
+	}
+	return s.base.Hacks(ctx, filter)
+}
+
+func (s *API) contestHack(ctx context.Context, hackID int) (*kilonova.ContestHack, *kilonova.StatusError) {
+	hack, err := s.base.Hack(ctx, hackID)
+	if err != nil {
+		return nil, err
+	}
+	if hack.ContestID != util.ContestContext(ctx).ID || !s.base.CanViewHack(hack, util.ContestContext(ctx), util.UserBriefContext(ctx)) {
+		return nil, kilonova.Statusf(404, "Hack not found")
+	}
+	return hack, nil
+}
+
+func (s *API) contestHackInput(ctx context.Context, args struct {
+	HackID int `json:"hack_id"`
+}) (string, *kilonova.StatusError) {
+	hack, err := s.contestHack(ctx, args.HackID)
+	if err != nil {
+		return "", err
+	}
+	input, err := s.base.HackInput(ctx, hack.ID)
+	if err != nil {
+		return "", err
+	}
+	return string(input), nil
+}
+
+func (s *API) addHackAsTest(ctx context.Context, args struct {
+	HackID int `json:"hack_id"`
+}) (int, *kilonova.StatusError) {
+	hack, err := s.contestHack(ctx, args.HackID)
+	if err != nil {
+		return -1, err
+	}
+	problem, err := s.base.Problem(ctx, hack.ProblemID)
+	if err != nil {
+		return -1, err
+	}
+	if !s.base.IsProblemEditor(util.UserBriefContext(ctx), problem) {
+		return -1, kilonova.Statusf(403, "You must be a problem editor to add tests")
+	}
+	return s.base.AddHackAsTest(ctx, hack)
+}
+
+func (s *API) cloneContest(ctx context.Context, args struct {
+	sudoapi.ContestCloneOptions
+	CloneProblems bool `json:"clone_problems"`
+}) (int, *kilonova.StatusError) {
+	contest := util.ContestContext(ctx)
+	if args.CloneProblems {
+		settings, err := s.base.ContestProblemSettings(ctx, contest.ID)
+		if err != nil {
+			return -1, err
+		}
+		problems := make([]*kilonova.Problem, 0, len(settings))
+		for _, setting := range settings {
+			problem, err := s.base.Problem(ctx, setting.ProblemID)
+			if err != nil {
+				return -1, err
+			}
+			if !s.base.IsProblemEditor(util.UserBriefContext(ctx), problem) {
+				return -1, kilonova.Statusf(403, "You must be an editor of all contest problems to clone them")
+			}
+			problems = append(problems, problem)
+		}
+
+		// Cloning goes through the test archive code, so it shares its lock
+		s.testArchiveLock.Lock()
+		defer s.testArchiveLock.Unlock()
+		args.ProblemReplacements = make(map[int]int, len(problems))
+		clones := make([]*kilonova.Problem, 0, len(problems))
+		// Don't leave orphaned problem copies behind if cloning fails
+		removeClones := func() {
+			for _, clone := range clones {
+				if err := s.base.DeleteProblem(context.WithoutCancel(ctx), clone); err != nil {
+					zap.S().Warn("Couldn't remove problem clone: ", err)
+				}
+			}
+		}
+		for _, problem := range problems {
+			newProblem, err := test.CloneProblem(ctx, problem, s.base, util.UserFullContext(ctx))
+			if err != nil {
+				removeClones()
+				return -1, err
+			}
+			clones = append(clones, newProblem)
+			args.ProblemReplacements[problem.ID] = newProblem.ID
+		}
+		id, err := s.base.CloneContest(ctx, contest, args.ContestCloneOptions, util.UserBriefContext(ctx))
+		if err != nil {
+			removeClones()
+			return -1, err
+		}
+		return id, nil
+	}
+	return s.base.CloneContest(ctx, contest, args.ContestCloneOptions, util.UserBriefContext(ctx))
+}
+
+func (s *API) importContestArchive(w http.ResponseWriter, r *http.Request) {
+	// Importing creates problems from their test archives, so it shares their lock
+	s.testArchiveLock.Lock()
+	defer s.testArchiveLock.Unlock()
+	r.ParseMultipartForm(20 * 1024 * 1024)
+	defer cleanupMultipart(r)
+
+	file, fh, err := r.FormFile("archive")
+	if err != nil {
+		kilonova.Statusf(400, "Missing archive").WriteError(w)
+		return
+	}
+	defer file.Close()
+
+	ar, err := zip.NewReader(file, fh.Size)
+	if err != nil {
+		kilonova.WrapError(err, "Couldn't read zip archive").WriteError(w)
+		return
+	}
+
+	result, err1 := contestArchive.ImportContestArchive(context.WithoutCancel(r.Context()), ar, s.base, util.UserFull(r))
+	if err1 != nil {
+		if result != nil && result.ContestID > 0 {
+			err1 = kilonova.WrapError(err1, fmt.Sprintf("Contest #%d was only partially imported", result.ContestID))
+		}
+		err1.WriteError(w)
+		return
+	}
+	returnData(w, result)
+}
+
+func (s *API) createPrintJob(ctx context.Context, args struct {
+	Filename string `json:"filename"`
+	Language string `json:"language"`
+	Code     string `json:"code"`
+	Seat     string `json:"seat"`
+	Location string `json:"location"`
+}) (int, *kilonova.StatusError) {
+	return s.base.CreatePrintJob(ctx, util.ContestContext(ctx), util.UserBriefContext(ctx), &kilonova.PrintJob{
+		Filename: args.Filename,
+		Language: args.Language,
+		Code:     args.Code,
+		Seat:     args.Seat,
+		Location: args.Location,
+	})
+}
+
+func (s *API) userPrintJobs(ctx context.Context, _ struct{}) ([]*kilonova.PrintJob, *kilonova.StatusError) {
+	return s.base.PrintJobs(ctx, util.ContestContext(ctx).ID, &util.UserBriefContext(ctx).ID, false)
+}
+
+func (s *API) allPrintJobs(ctx context.Context, args struct {
+	PendingOnly bool `json:"pending_only"`
+}) ([]*kilonova.PrintJob, *kilonova.StatusError) {
+	return s.base.PrintJobs(ctx, util.ContestContext(ctx).ID, nil, args.PendingOnly)
+}
+
+func (s *API) markPrintJob(ctx context.Context, args struct {
+	ID      int  `json:"id"`
+	Printed bool `json:"printed"`
+}) *kilonova.StatusError {
+	return s.base.SetPrintJobPrinted(ctx, util.ContestContext(ctx).ID, args.ID, util.UserBriefContext(ctx), args.Printed)
+}
+
+func (s *API) deliverBalloon(ctx context.Context, args struct {
+	SubmissionID int  `json:"submission_id"`
+	Delivered    bool `json:"delivered"`
+}) *kilonova.StatusError {
+	return s.base.SetBalloonDelivered(ctx, util.ContestContext(ctx), args.SubmissionID, util.UserBriefContext(ctx), args.Delivered)
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/util"
+)
+
+func (s *API) contestSeriesList(ctx context.Context, args struct {
+	ContestID *int `json:"contest_id"`
+}) ([]*kilonova.ContestSeries, *kilonova.StatusError) {
+	return s.base.ContestSeriesList(ctx, kilonova.ContestSeriesFilter{
+		ContestID:   args.ContestID,
+		Look:        true,
+		LookingUser: util.UserBriefContext(ctx),
+	})
+}
+
+func (s *API) createContestSeries(ctx context.Context, args struct {
+	Name string `json:"name"`
+}) (int, *kilonova.StatusError) {
+	return s.base.CreateContestSeries(ctx, args.Name, util.UserBriefContext(ctx))
+}
+
+func (s *API) seriesLeaderboard(ctx context.Context, _ struct{}) (*kilonova.SeriesLeaderboard, *kilonova.StatusError) {
+	return s.base.SeriesLeaderboard(ctx, util.ContestSeriesContext(ctx), util.UserBriefContext(ctx))
+}
+
+func (s *API) updateContestSeries(ctx context.Context, args kilonova.ContestSeriesUpdate) *kilonova.StatusError {
+	return s.base.UpdateContestSeries(ctx, util.ContestSeriesContext(ctx).ID, args)
+}
+
+func (s *API) updateSeriesRounds(ctx context.Context, args struct {
+	Rounds []*kilonova.SeriesRound `json:"rounds"`
+}) *kilonova.StatusError {
+	return s.base.UpdateSeriesRounds(ctx, util.ContestSeriesContext(ctx), args.Rounds, util.UserBriefContext(ctx))
+}
+
+func (s *API) deleteContestSeries(ctx context.Context, _ struct{}) *kilonova.StatusError {
+	return s.base.DeleteContestSeries(ctx, util.ContestSeriesContext(ctx))
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KiloProjects/kilonova/internal/util"
+	"go.uber.org/zap"
+)
+
+const eventKeepAliveInterval = 30 * time.Second
+
+// eventStream streams the live updates the user may see as Server-Sent Events.
+// If mounted under a contest, the contest's leaderboard updates and announcements are also streamed
+func (s *API) eventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		errorData(w, "Streaming is not supported", 500)
+		return
+	}
+
+	ip, _ := s.base.GetRequestInfo(r)
+	sub, err := s.base.SubscribeEvents(util.UserBrief(r), util.Contest(r), ip)
+	if err != nil {
+		err.WriteError(w)
+		return
+	}
+	defer s.base.UnsubscribeEvents(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case ev := <-sub.Events():
+			data, err := json.Marshal(ev.Data)
+			if err != nil {
+				zap.S().Warn(err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
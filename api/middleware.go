@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/KiloProjects/kilonova/datastore"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
@@ -19,7 +21,8 @@ var FilterUserAgent = config.GenFlag[bool]("behavior.user_agent_filter", true, "
 
 func (s *API) filterUserAgent(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if FilterUserAgent.Value() && (util.UserBrief(r) == nil || !util.UserBrief(r).Admin) {
+		// Requests with personal access tokens are expected to come from scripts
+		if FilterUserAgent.Value() && util.APIToken(r) == nil && (util.UserBrief(r) == nil || !util.UserBrief(r).Admin) {
 			// If filtering is enabled and user is not admin, disallow common software for bots
 			if strings.Contains(r.Header.Get("User-Agent"), "python") {
 				errorData(w, "Request blocked", http.StatusForbidden)
@@ -74,10 +77,25 @@ func (s *API) MustBeProposer(next http.Handler) http.Handler {
 	})
 }
 
-// SetupSession adds the user with the specified user ID to context
+// SetupSession adds the user with the specified user ID to context.
+// Personal access tokens and OAuth access tokens are only added as pending, see withTokenScope
 func (s *API) SetupSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user, err := s.base.SessionUser(r.Context(), getAuthHeader(r), r)
+		r = r.WithContext(sudoapi.WithLockdownCache(r.Context()))
+		header := getAuthHeader(r)
+		if strings.HasPrefix(header, kilonova.APITokenPrefix) || strings.HasPrefix(header, kilonova.OAuthTokenPrefix) {
+			ip, _ := s.base.GetRequestInfo(r)
+			user, token, err := s.base.APITokenUser(r.Context(), header, ip)
+			if err != nil {
+				err.WriteError(w)
+				return
+			}
+			ctx := context.WithValue(r.Context(), util.APITokenKey, token)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, util.TokenUserKey, user)))
+			return
+		}
+
+		user, err := s.base.SessionUser(r.Context(), header, r)
 		if err != nil || user == nil {
 			if err != nil && !errors.Is(err, context.Canceled) {
 				zap.S().Warn(err)
@@ -89,20 +107,67 @@ func (s *API) SetupSession(next http.Handler) http.Handler {
 	})
 }
 
+// withTokenScope authenticates requests made with a personal access token as the token's owner, if the token has any of the given scopes.
+// Otherwise, as on all routes without this middleware, requests with tokens are handled as if they came from guests
+func (s *API) withTokenScope(scopes ...kilonova.APITokenScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := util.APIToken(r)
+			if token == nil || util.UserBrief(r) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			for _, scope := range scopes {
+				if token.HasScope(scope) {
+					user := r.Context().Value(util.TokenUserKey)
+					next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.AuthedUserKey, user)))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// tokenAllows returns false if the request was authenticated with a token that has none of the given scopes.
+// Requests authenticated with a session are always allowed
+func tokenAllows(r *http.Request, scopes ...kilonova.APITokenScope) bool {
+	token := util.APIToken(r)
+	if token == nil {
+		return true
+	}
+	return slices.ContainsFunc(scopes, token.HasScope)
+}
+
+// requireTokenScope rejects requests made with tokens that have none of the given scopes.
+// Together with withTokenScope on a whole router, it separates the management routes from the read and participant ones
+func (s *API) requireTokenScope(scopes ...kilonova.APITokenScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !tokenAllows(r, scopes...) {
+				errorData(w, "The token doesn't have the scope required for this action", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validateProblemEditor also requires tokens to have the problem management scope
 func (s *API) validateProblemEditor(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return s.requireTokenScope(kilonova.ScopeManageProblems)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !s.base.IsProblemEditor(util.UserBrief(r), util.Problem(r)) {
 			errorData(w, "You must be authorized to access internal problem data", http.StatusUnauthorized)
 			return
 		}
 
 		next.ServeHTTP(w, r)
-	})
+	}))
 }
 
 func (s *API) validateContestParticipant(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.base.CanSubmitInContest(util.UserBrief(r), util.Contest(r)) {
+		if !s.base.CanSubmitInContest(r.Context(), util.UserBrief(r), util.Contest(r)) {
 			errorData(w, "You must be registered and during a contest to do this", http.StatusUnauthorized)
 			return
 		}
@@ -110,15 +175,35 @@ func (s *API) validateContestParticipant(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// validateContestEditor also requires tokens to have the contest administration scope
 func (s *API) validateContestEditor(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !s.base.IsContestEditor(util.UserBrief(r), util.Contest(r)) {
+	return s.contestEditorWithScopes(kilonova.ScopeContestAdmin)(next)
+}
+
+func (s *API) contestEditorWithScopes(scopes ...kilonova.APITokenScope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return s.requireTokenScope(scopes...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !s.base.IsContestEditor(util.UserBrief(r), util.Contest(r)) {
+				errorData(w, "You must be authorized to access this contest data", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// validateContestTester also requires tokens to have the contest administration scope
+func (s *API) validateContestTester(next http.Handler) http.Handler {
+	return s.requireTokenScope(kilonova.ScopeContestAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.base.IsContestTester(util.UserBrief(r), util.Contest(r)) {
 			errorData(w, "You must be authorized to access this contest data", http.StatusUnauthorized)
 			return
 		}
 
 		next.ServeHTTP(w, r)
-	})
+	}))
 }
 func (s *API) validateContestVisible(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -136,6 +221,10 @@ func (s *API) validateProblemVisible(next http.Handler) http.Handler {
 			errorData(w, "You are not allowed to access this problem", http.StatusUnauthorized)
 			return
 		}
+		if !s.base.LockdownAllowsProblem(r.Context(), util.UserBrief(r), util.Problem(r)) {
+			errorData(w, "Only the contest's problems can be accessed during the contest", http.StatusForbidden)
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
@@ -166,6 +255,10 @@ func (s *API) validateBlogPostVisible(next http.Handler) http.Handler {
 			errorData(w, "You are not allowed to access this post", http.StatusUnauthorized)
 			return
 		}
+		if s.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+			errorData(w, "Blog posts are not available during the contest", http.StatusForbidden)
+			return
+		}
 
 		next.ServeHTTP(w, r)
 	})
@@ -421,13 +514,56 @@ func (s *API) validateContestID(next http.Handler) http.Handler {
 			errorData(w, "contest does not exist", http.StatusBadRequest)
 			return
 		}
+		if err := s.base.EnforceContestSession(r.Context(), contest, util.UserBrief(r), sessionID(r)); err != nil {
+			err.WriteError(w)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.ContestKey, contest)))
 	})
 }
 
+func (s *API) validateSeriesID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seriesID, err := strconv.Atoi(chi.URLParam(r, "seriesID"))
+		if err != nil {
+			errorData(w, "invalid contest series ID", http.StatusBadRequest)
+			return
+		}
+		series, err1 := s.base.ContestSeries(r.Context(), seriesID)
+		if err1 != nil {
+			errorData(w, "contest series does not exist", http.StatusBadRequest)
+			return
+		}
+		if !s.base.IsSeriesVisible(util.UserBrief(r), series) {
+			errorData(w, "You are not allowed to access this contest series", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.SeriesKey, series)))
+	})
+}
+
+func (s *API) validateSeriesEditor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.base.IsSeriesEditor(util.UserBrief(r), util.ContestSeries(r)) {
+			errorData(w, "You must be authorized to edit this contest series", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionID returns the ID of the session the request was made with, or an empty string for requests made using tokens
+func sessionID(r *http.Request) string {
+	if util.APIToken(r) != nil {
+		return ""
+	}
+	return getAuthHeader(r)
+}
+
 func getAuthHeader(r *http.Request) string {
-	header := r.Header.Get("Authorization")
-	if header == "guest" {
+	header := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if header == "guest" || strings.HasPrefix(header, "Basic ") {
 		header = ""
 	}
 	return header
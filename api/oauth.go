@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// RegisterOAuthRoutes adds the OAuth2 and OpenID Connect protocol endpoints, which are called directly by third-party apps.
+// They live outside of /api, since their requests and responses follow the specifications instead of the API's conventions.
+// The authorization endpoint itself is the consent page, served by the web frontend
+func (s *API) RegisterOAuthRoutes(r chi.Router) {
+	r.Get("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeOAuthJSON(w, sudoapi.OIDCDiscoveryDocument(), http.StatusOK)
+	})
+	r.Get("/oauth/jwks.json", s.oauthKeySet)
+	r.Post("/oauth/token", s.oauthToken)
+	r.Post("/oauth/revoke", s.oauthRevoke)
+
+	userInfo := r.With(s.SetupSession, s.withTokenScope(kilonova.ScopeOpenID, kilonova.ScopeProfile))
+	userInfo.Get("/oauth/userinfo", s.oauthUserInfo)
+	userInfo.Post("/oauth/userinfo", s.oauthUserInfo)
+}
+
+func writeOAuthJSON(w http.ResponseWriter, data any, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		zap.S().Warn(err)
+	}
+}
+
+// writeOAuthError sends the error in the format described in RFC 6749, section 5.2
+func writeOAuthError(w http.ResponseWriter, err *kilonova.StatusError) {
+	code := kilonova.OAuthErrorCode(err)
+	status := err.Code
+	if code == "server_error" {
+		status = http.StatusInternalServerError
+	}
+	if status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="kilonova"`)
+	}
+	writeOAuthJSON(w, map[string]string{"error": code, "error_description": err.Text}, status)
+}
+
+// oauthClientCredentials returns the client's ID and secret, sent either through HTTP Basic authentication or in the form body
+func oauthClientCredentials(r *http.Request) (string, string) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		// Credentials are form-encoded before being put in the header, see RFC 6749, section 2.3.1
+		if unescaped, err := url.QueryUnescape(id); err == nil {
+			id = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(secret); err == nil {
+			secret = unescaped
+		}
+		return id, secret
+	}
+	return r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+}
+
+func (s *API) oauthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, kilonova.OAuthError(kilonova.ErrOAuthInvalidRequest, "Invalid form body"))
+		return
+	}
+	clientID, secret := oauthClientCredentials(r)
+	if clientID == "" {
+		writeOAuthError(w, kilonova.OAuthError(kilonova.ErrOAuthInvalidClient, "Missing client ID"))
+		return
+	}
+
+	var resp *kilonova.OAuthTokenResponse
+	var err *kilonova.StatusError
+	switch r.PostFormValue("grant_type") {
+	case "authorization_code":
+		resp, err = s.base.ExchangeOAuthCode(r.Context(), clientID, secret, r.PostFormValue("code"), r.PostFormValue("redirect_uri"), r.PostFormValue("code_verifier"))
+	case "refresh_token":
+		resp, err = s.base.RefreshOAuthToken(r.Context(), clientID, secret, r.PostFormValue("refresh_token"))
+	default:
+		err = kilonova.OAuthError(kilonova.ErrOAuthUnsupportedGrantType, "Only the authorization_code and refresh_token grants are supported")
+	}
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	writeOAuthJSON(w, resp, http.StatusOK)
+}
+
+func (s *API) oauthRevoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, kilonova.OAuthError(kilonova.ErrOAuthInvalidRequest, "Invalid form body"))
+		return
+	}
+	clientID, secret := oauthClientCredentials(r)
+	if err := s.base.RevokeOAuthToken(r.Context(), clientID, secret, r.PostFormValue("token")); err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *API) oauthKeySet(w http.ResponseWriter, r *http.Request) {
+	keys, err := s.base.OIDCKeySet(r.Context())
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		zap.S().Warn(err)
+	}
+}
+
+func (s *API) oauthUserInfo(w http.ResponseWriter, r *http.Request) {
+	user := util.UserBrief(r)
+	if util.APIToken(r) == nil || !user.IsAuthed() {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeOAuthJSON(w, map[string]string{"error": "invalid_token"}, http.StatusUnauthorized)
+		return
+	}
+	writeOAuthJSON(w, sudoapi.OIDCUserClaims(user), http.StatusOK)
+}
+
+// createdOAuthClient also holds the client secret, which is shown only once
+type createdOAuthClient struct {
+	Secret string `json:"secret,omitempty"`
+	*kilonova.OAuthClient
+}
+
+func (s *API) createOAuthClient(ctx context.Context, args struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Confidential bool     `json:"confidential"`
+}) (*createdOAuthClient, *kilonova.StatusError) {
+	client := &kilonova.OAuthClient{
+		Name:         args.Name,
+		Description:  args.Description,
+		RedirectURIs: args.RedirectURIs,
+		Confidential: args.Confidential,
+	}
+	secret, err := s.base.CreateOAuthClient(ctx, util.UserBriefContext(ctx), client)
+	if err != nil {
+		return nil, err
+	}
+	return &createdOAuthClient{Secret: secret, OAuthClient: client}, nil
+}
+
+// editableOAuthClient returns the client with the given ID, if the user may manage it
+func (s *API) editableOAuthClient(ctx context.Context, id string) (*kilonova.OAuthClient, *kilonova.StatusError) {
+	client, err := s.base.OAuthClient(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.base.IsOAuthClientEditor(util.UserBriefContext(ctx), client) {
+		return nil, kilonova.Statusf(403, "You can't manage this app")
+	}
+	return client, nil
+}
+
+func (s *API) updateOAuthClient(ctx context.Context, args struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description"`
+	RedirectURIs []string `json:"redirect_uris"`
+}) *kilonova.StatusError {
+	client, err := s.editableOAuthClient(ctx, args.ID)
+	if err != nil {
+		return err
+	}
+	client.Name = args.Name
+	client.Description = args.Description
+	client.RedirectURIs = args.RedirectURIs
+	return s.base.UpdateOAuthClient(ctx, client)
+}
+
+func (s *API) resetOAuthClientSecret(ctx context.Context, args struct {
+	ID string `json:"id"`
+}) (string, *kilonova.StatusError) {
+	client, err := s.editableOAuthClient(ctx, args.ID)
+	if err != nil {
+		return "", err
+	}
+	return s.base.ResetOAuthClientSecret(ctx, client)
+}
+
+func (s *API) deleteOAuthClient(ctx context.Context, args struct {
+	ID string `json:"id"`
+}) *kilonova.StatusError {
+	client, err := s.editableOAuthClient(ctx, args.ID)
+	if err != nil {
+		return err
+	}
+	return s.base.DeleteOAuthClient(ctx, client)
+}
@@ -14,6 +14,15 @@ func (s *API) createPaste(w http.ResponseWriter, r *http.Request) {
 		errorData(w, "You can't create a paste for this submission!", 403)
 		return
 	}
+	if util.Submission(r).FeedbackLimited() {
+		// Pastes show the full evaluation, so they would bypass the contest's feedback limits
+		errorData(w, "You can't create a paste while the submission's feedback is limited!", 403)
+		return
+	}
+	if s.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+		errorData(w, "Pastes are not available during the contest", 403)
+		return
+	}
 
 	id, err := s.base.CreatePaste(r.Context(), &util.Submission(r).Submission, util.UserBrief(r))
 	if err != nil {
@@ -25,6 +34,10 @@ func (s *API) createPaste(w http.ResponseWriter, r *http.Request) {
 
 func (s *API) getPaste(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "pasteID")
+	if s.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+		errorData(w, "Pastes are not available during the contest", 403)
+		return
+	}
 
 	paste, err := s.base.SubmissionPaste(r.Context(), id)
 	if err != nil {
@@ -111,6 +111,9 @@ func (s *API) maxScoreBreakdown(w http.ResponseWriter, r *http.Request) {
 		)
 	}
 
+	// Per-test (and possibly per-subtask) results are hidden while the contest limits feedback
+	feedback := s.base.ContestFeedbackLevel(contest, util.Problem(r), util.UserBrief(r))
+
 	switch util.Problem(r).ScoringStrategy {
 	case kilonova.ScoringTypeMaxSub, kilonova.ScoringTypeICPC:
 		id, err := s.base.MaxScoreSubID(r.Context(), args.UserID, util.Problem(r).ID)
@@ -156,6 +159,13 @@ func (s *API) maxScoreBreakdown(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if feedback != kilonova.FeedbackLevelFull {
+			tests = []*kilonova.SubTest{}
+			if feedback == kilonova.FeedbackLevelTotal {
+				stks = []*kilonova.SubmissionSubTask{}
+			}
+		}
+
 		returnData(w, scoreBreakdownRet{
 			MaxScore: maxScore,
 			Problem:  util.Problem(r),
@@ -92,6 +92,22 @@ func (s *API) createSubmission(w http.ResponseWriter, r *http.Request) {
 		errorData(w, "Problem is not visible", 401)
 		return
 	}
+	if !s.base.LockdownAllowsProblem(r.Context(), util.UserBrief(r), problem) {
+		errorData(w, "Only the contest's problems can be accessed during the contest", 403)
+		return
+	}
+	if args.ContestID != nil {
+		contest, err := s.base.Contest(r.Context(), *args.ContestID)
+		if err != nil {
+			err.WriteError(w)
+			return
+		}
+		ip, _ := s.base.GetRequestInfo(r)
+		if err := s.base.CheckContestSubmitAccess(r.Context(), contest, util.UserBrief(r), sessionID(r), ip); err != nil {
+			err.WriteError(w)
+			return
+		}
+	}
 
 	lang, ok := eval.Langs[args.Lang]
 	if !ok {
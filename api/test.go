@@ -39,8 +39,9 @@ func (s *API) saveTestData(w http.ResponseWriter, r *http.Request) {
 func (s *API) updateTestInfo(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	var args struct {
-		ID    int
-		Score string
+		ID      int
+		Score   string
+		Pretest *bool
 	}
 	if err := decoder.Decode(&args, r.Form); err != nil {
 		errorData(w, err, http.StatusBadRequest)
@@ -58,7 +59,7 @@ func (s *API) updateTestInfo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.base.UpdateTest(r.Context(), util.Test(r).ID, kilonova.TestUpdate{VisibleID: &args.ID, Score: &scoreValue}); err != nil {
+	if err := s.base.UpdateTest(r.Context(), util.Test(r).ID, kilonova.TestUpdate{VisibleID: &args.ID, Score: &scoreValue, Pretest: args.Pretest}); err != nil {
 		err.WriteError(w)
 		return
 	}
@@ -527,7 +527,7 @@ func (s *API) generateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if contest != nil {
-		if err := s.base.RegisterContestUser(r.Context(), contest, user.ID, nil, true); err != nil {
+		if err := s.base.RegisterContestUser(r.Context(), contest, user.ID, nil, nil, true); err != nil {
 			err.WriteError(w)
 			return
 		}
@@ -588,3 +588,30 @@ func (s *API) generateUser(w http.ResponseWriter, r *http.Request) {
 		User:     user,
 	})
 }
+
+func (s *API) createAPIToken(ctx context.Context, args struct {
+	Name   string                   `json:"name"`
+	Scopes []kilonova.APITokenScope `json:"scopes"`
+	// ExpiresInDays is 0 for tokens that never expire
+	ExpiresInDays int `json:"expires_in_days"`
+}) (*createdAPIToken, *kilonova.StatusError) {
+	if args.ExpiresInDays < 0 {
+		return nil, kilonova.Statusf(400, "Invalid expiration")
+	}
+	var expiresAt *time.Time
+	if args.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, args.ExpiresInDays)
+		expiresAt = &t
+	}
+	secret, token, err := s.base.CreateAPIToken(ctx, util.UserBriefContext(ctx), args.Name, args.Scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &createdAPIToken{Token: secret, APIToken: token}, nil
+}
+
+// createdAPIToken also holds the token itself, which is shown only once
+type createdAPIToken struct {
+	Token string `json:"token"`
+	*kilonova.APIToken
+}
@@ -0,0 +1,53 @@
+package kilonova
+
+import (
+	"net/netip"
+	"slices"
+	"time"
+)
+
+// APITokenScope limits what a personal access token can be used for
+type APITokenScope string
+
+const (
+	ScopeReadSubmissions APITokenScope = "submissions:read"
+	ScopeSubmit          APITokenScope = "submissions:create"
+	ScopeReadProblems    APITokenScope = "problems:read"
+	ScopeManageProblems  APITokenScope = "problems:manage"
+	// ScopeContestParticipate allows reading contests and taking part in them (registering, asking questions, hacking, etc.)
+	ScopeContestParticipate APITokenScope = "contests:participate"
+	ScopeContestAdmin       APITokenScope = "contests:admin"
+	// ScopeContestFeed only allows reading the CLICS feeds of the contests the user edits
+	ScopeContestFeed APITokenScope = "contests:feed"
+)
+
+var APITokenScopes = []APITokenScope{ScopeReadSubmissions, ScopeSubmit, ScopeReadProblems, ScopeManageProblems, ScopeContestParticipate, ScopeContestAdmin, ScopeContestFeed}
+
+// APITokenPrefix is prepended to all personal access tokens, so they can be told apart from session IDs
+const APITokenPrefix = "knpat_"
+
+// APIToken is a named personal access token, sent as `Authorization: Bearer <token>`
+type APIToken struct {
+	ID        int       `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Name      string    `json:"name" db:"name"`
+
+	Scopes []APITokenScope `json:"scopes" db:"scopes"`
+	// ExpiresAt is nil for tokens that never expire
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	// LastUsedIP is the address the token was last used from, to help find leaked tokens
+	LastUsedIP *netip.Addr `json:"last_used_ip" db:"last_used_ip"`
+
+	// ClientID is set for access tokens issued to OAuth apps
+	ClientID *string `json:"client_id" db:"client_id"`
+}
+
+func (t *APIToken) HasScope(scope APITokenScope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+func (t *APIToken) Expired() bool {
+	return t.ExpiresAt != nil && t.ExpiresAt.Before(time.Now())
+}
@@ -0,0 +1,161 @@
+// Package contest exports whole contests to self-contained zip archives and imports them back, possibly on another instance.
+//
+// An archive has the following layout:
+//
+//	contest.json           contest settings, editors, testers and problem settings
+//	problems/{id}.zip      problem archive, in the format understood by the test archive importer
+//	teams.json             teams and their creators. Members are given by the team IDs of the registrations
+//	registrations.json
+//	submissions.json       submission metadata and results (subtests and subtasks included)
+//	submissions/{id}.{ext} submission source code
+//	questions.json
+//	announcements.json
+//	hacks.json             problem locks and hacks of the hacking phase
+//	hacks/{id}.in          hack input
+//	hacks/{id}.out         reference output of successful hacks that weren't added as tests yet
+//	pretest_scores.json    leaderboard scores snapshotted when system testing started
+//	leaderboard.json       final, unfrozen leaderboard. It is kept only as a record, since importing recomputes it
+//
+// Users are referenced by their username, since IDs differ between instances.
+// Other IDs (of teams, problems and submissions) are the original ones, and are remapped on import.
+// Invitations are not part of the archive.
+package contest
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/KiloProjects/kilonova"
+)
+
+// archiveVersion is bumped whenever the layout changes in an incompatible way
+const archiveVersion = 2
+
+type contestManifest struct {
+	Version int `json:"version"`
+
+	Contest *kilonova.Contest `json:"contest"`
+
+	Editors []string `json:"editors"`
+	Testers []string `json:"testers"`
+
+	Problems []*problemEntry `json:"problems"`
+}
+
+type problemEntry struct {
+	// Problem holds the details of the original problem. The other entries reference problems by its ID
+	Problem *kilonova.Problem `json:"problem"`
+
+	Settings *kilonova.ContestProblemSettings `json:"settings"`
+}
+
+func (pe *problemEntry) archivePath() string {
+	return fmt.Sprintf("problems/%d.zip", pe.Problem.ID)
+}
+
+type teamEntry struct {
+	kilonova.ContestTeam
+	Creator *string `json:"creator_name"`
+}
+
+type registrationEntry struct {
+	kilonova.ContestRegistration
+	Username string `json:"username"`
+}
+
+type questionEntry struct {
+	kilonova.ContestQuestion
+	Author     string  `json:"author"`
+	AnsweredBy *string `json:"answered_by_name"`
+}
+
+type submissionEntry struct {
+	kilonova.Submission
+	Author string `json:"author"`
+	// CodeFile is the path of the source code in the archive
+	CodeFile string `json:"code_file"`
+
+	SubTests []*kilonova.SubTest           `json:"subtests"`
+	SubTasks []*kilonova.SubmissionSubTask `json:"subtasks"`
+}
+
+type lockEntry struct {
+	kilonova.ContestProblemLock
+	Username string `json:"username"`
+}
+
+type hackEntry struct {
+	kilonova.ContestHack
+	Hacker     string `json:"hacker"`
+	TargetUser string `json:"target_user"`
+	// TestVisibleID is the visible ID of the problem test created from the hack, if any
+	TestVisibleID *int `json:"test_visible_id"`
+	// OutputFile is the path of the reference output in the archive, empty if it isn't kept
+	OutputFile string `json:"output_file"`
+}
+
+func (he *hackEntry) inputPath() string {
+	return fmt.Sprintf("hacks/%d.in", he.ID)
+}
+
+type hacksFile struct {
+	Locks []*lockEntry `json:"locks"`
+	Hacks []*hackEntry `json:"hacks"`
+}
+
+type pretestScoreEntry struct {
+	kilonova.PretestScore
+	Username string `json:"username"`
+}
+
+func writeJSON(ar *zip.Writer, name string, v any) *kilonova.StatusError {
+	f, err := ar.Create(name)
+	if err != nil {
+		return kilonova.WrapError(err, "Couldn't create archive file")
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		return kilonova.WrapError(err, "Couldn't write "+name)
+	}
+	return nil
+}
+
+func writeFile(ar *zip.Writer, name string, data []byte) *kilonova.StatusError {
+	f, err := ar.Create(name)
+	if err != nil {
+		return kilonova.WrapError(err, "Couldn't create archive file")
+	}
+	if _, err := f.Write(data); err != nil {
+		return kilonova.WrapError(err, "Couldn't write "+name)
+	}
+	return nil
+}
+
+// readJSON decodes the given archive file. Missing files are treated as empty
+func readJSON(ar *zip.Reader, name string, v any) *kilonova.StatusError {
+	f, err := ar.Open(name)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(v); err != nil {
+		return kilonova.Statusf(400, "Invalid %s file: %v", name, err)
+	}
+	return nil
+}
+
+func readFile(ar *zip.Reader, name string) ([]byte, *kilonova.StatusError) {
+	f, err := ar.Open(name)
+	if err != nil {
+		return nil, kilonova.Statusf(400, "Missing archive file %q", name)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't read archive file")
+	}
+	return data, nil
+}
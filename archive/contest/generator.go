@@ -0,0 +1,312 @@
+package contest
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/archive/test"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"go.uber.org/zap"
+)
+
+type archiveGenerator struct {
+	contest *kilonova.Contest
+	ar      *zip.Writer
+	base    *sudoapi.BaseAPI
+
+	// usernames caches the names of the users referenced in the archive
+	usernames map[int]string
+}
+
+func (ag *archiveGenerator) username(ctx context.Context, id int) string {
+	if name, ok := ag.usernames[id]; ok {
+		return name
+	}
+	user, err := ag.base.UserBrief(ctx, id)
+	if err != nil {
+		zap.S().Warn(err)
+		ag.usernames[id] = ""
+		return ""
+	}
+	ag.usernames[id] = user.Name
+	return user.Name
+}
+
+func (ag *archiveGenerator) addContest(ctx context.Context) *kilonova.StatusError {
+	manifest := &contestManifest{
+		Version: archiveVersion,
+		Contest: ag.contest,
+		Editors: []string{},
+		Testers: []string{},
+
+		Problems: []*problemEntry{},
+	}
+	for _, editor := range ag.contest.Editors {
+		manifest.Editors = append(manifest.Editors, editor.Name)
+	}
+	for _, tester := range ag.contest.Testers {
+		manifest.Testers = append(manifest.Testers, tester.Name)
+	}
+
+	settings, err := ag.base.ContestProblemSettings(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		pb, err := ag.base.Problem(ctx, setting.ProblemID)
+		if err != nil {
+			return err
+		}
+		entry := &problemEntry{Problem: pb, Settings: setting}
+		manifest.Problems = append(manifest.Problems, entry)
+
+		// Problem archives are already compressed
+		f, err1 := ag.ar.CreateHeader(&zip.FileHeader{Name: entry.archivePath(), Method: zip.Store})
+		if err1 != nil {
+			return kilonova.WrapError(err1, "Couldn't create problem archive file")
+		}
+		if err := test.GenerateArchive(ctx, pb, f, ag.base, &test.ArchiveGenOptions{
+			Tests:              true,
+			Attachments:        true,
+			PrivateAttachments: true,
+			ProblemDetails:     true,
+			Tags:               true,
+			Editors:            true,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return writeJSON(ag.ar, "contest.json", manifest)
+}
+
+func (ag *archiveGenerator) addTeams(ctx context.Context) *kilonova.StatusError {
+	teams, err := ag.base.ContestTeams(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	entries := make([]*teamEntry, 0, len(teams))
+	for _, team := range teams {
+		entry := &teamEntry{ContestTeam: *team}
+		// The registrations already say who is in the team
+		entry.Members = nil
+		if team.CreatorID != nil {
+			name := ag.username(ctx, *team.CreatorID)
+			entry.Creator = &name
+		}
+		entries = append(entries, entry)
+	}
+	return writeJSON(ag.ar, "teams.json", entries)
+}
+
+func (ag *archiveGenerator) addRegistrations(ctx context.Context) *kilonova.StatusError {
+	regs, err := ag.base.ContestRegistrations(ctx, ag.contest.ID, nil, nil, 0, 0)
+	if err != nil {
+		return err
+	}
+	entries := make([]*registrationEntry, 0, len(regs))
+	for _, reg := range regs {
+		entries = append(entries, &registrationEntry{
+			ContestRegistration: *reg,
+			Username:            ag.username(ctx, reg.UserID),
+		})
+	}
+	return writeJSON(ag.ar, "registrations.json", entries)
+}
+
+func (ag *archiveGenerator) addSubmissions(ctx context.Context) *kilonova.StatusError {
+	subs, err := ag.base.RawSubmissions(ctx, kilonova.SubmissionFilter{ContestID: &ag.contest.ID, Ascending: true})
+	if err != nil {
+		return err
+	}
+	entries := make([]*submissionEntry, 0, len(subs))
+	for _, sub := range subs {
+		entry := &submissionEntry{
+			Submission: *sub,
+			Author:     ag.username(ctx, sub.UserID),
+			CodeFile:   fmt.Sprintf("submissions/%d", sub.ID),
+		}
+		if lang, ok := eval.Langs[sub.Language]; ok {
+			entry.CodeFile += lang.Extensions[len(lang.Extensions)-1]
+		}
+
+		entry.SubTests, err = ag.base.SubTests(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		entry.SubTasks, err = ag.base.SubmissionSubTasks(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+
+		code, err := ag.base.RawSubmissionCode(ctx, sub.ID)
+		if err != nil {
+			return kilonova.WrapError(err, "Couldn't get submission code")
+		}
+		if err := writeFile(ag.ar, entry.CodeFile, code); err != nil {
+			return err
+		}
+
+		entries = append(entries, entry)
+	}
+	return writeJSON(ag.ar, "submissions.json", entries)
+}
+
+func (ag *archiveGenerator) addCommunication(ctx context.Context) *kilonova.StatusError {
+	questions, err := ag.base.ContestQuestions(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	entries := make([]*questionEntry, 0, len(questions))
+	for _, question := range questions {
+		entry := &questionEntry{
+			ContestQuestion: *question,
+			Author:          ag.username(ctx, question.AuthorID),
+		}
+		if question.AnsweredBy != nil {
+			name := ag.username(ctx, *question.AnsweredBy)
+			entry.AnsweredBy = &name
+		}
+		entries = append(entries, entry)
+	}
+	if err := writeJSON(ag.ar, "questions.json", entries); err != nil {
+		return err
+	}
+
+	announcements, err := ag.base.ContestAnnouncements(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(ag.ar, "announcements.json", announcements)
+}
+
+func (ag *archiveGenerator) addHacks(ctx context.Context) *kilonova.StatusError {
+	locks, err := ag.base.ContestProblemLocks(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	hacks, err := ag.base.Hacks(ctx, kilonova.HackFilter{ContestID: &ag.contest.ID})
+	if err != nil {
+		return err
+	}
+	file := &hacksFile{
+		Locks: make([]*lockEntry, 0, len(locks)),
+		Hacks: make([]*hackEntry, 0, len(hacks)),
+	}
+	for _, lock := range locks {
+		file.Locks = append(file.Locks, &lockEntry{ContestProblemLock: *lock, Username: ag.username(ctx, lock.UserID)})
+	}
+
+	// testVIDs maps the IDs of the tests created from hacks to their visible IDs
+	testVIDs := make(map[int]int)
+	for _, hack := range hacks {
+		entry := &hackEntry{
+			ContestHack: *hack,
+			Hacker:      ag.username(ctx, hack.HackerID),
+			TargetUser:  ag.username(ctx, hack.TargetUserID),
+		}
+		if hack.TestID != nil {
+			if _, ok := testVIDs[*hack.TestID]; !ok {
+				tests, err := ag.base.Tests(ctx, hack.ProblemID)
+				if err != nil {
+					return err
+				}
+				for _, test := range tests {
+					testVIDs[test.ID] = test.VisibleID
+				}
+			}
+			if vid, ok := testVIDs[*hack.TestID]; ok {
+				entry.TestVisibleID = &vid
+			}
+		}
+
+		input, err := ag.base.HackInput(ctx, hack.ID)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(ag.ar, entry.inputPath(), input); err != nil {
+			return err
+		}
+		// Successful hacks keep their reference output until they are added as tests
+		if hack.Verdict == kilonova.HackVerdictSuccessful && hack.TestID == nil {
+			if out, err := ag.base.TestOutput(sudoapi.HackTestID(hack.ID)); err == nil {
+				data, err := io.ReadAll(out)
+				out.Close()
+				if err != nil {
+					return kilonova.WrapError(err, "Couldn't read hack output")
+				}
+				entry.OutputFile = fmt.Sprintf("hacks/%d.out", hack.ID)
+				if err := writeFile(ag.ar, entry.OutputFile, data); err != nil {
+					return err
+				}
+			}
+		}
+
+		file.Hacks = append(file.Hacks, entry)
+	}
+	return writeJSON(ag.ar, "hacks.json", file)
+}
+
+func (ag *archiveGenerator) addPretestScores(ctx context.Context) *kilonova.StatusError {
+	scores, err := ag.base.ContestPretestScores(ctx, ag.contest.ID)
+	if err != nil {
+		return err
+	}
+	entries := make([]*pretestScoreEntry, 0, len(scores))
+	for _, score := range scores {
+		entry := &pretestScoreEntry{PretestScore: *score}
+		if score.UserID != nil {
+			entry.Username = ag.username(ctx, *score.UserID)
+		}
+		entries = append(entries, entry)
+	}
+	return writeJSON(ag.ar, "pretest_scores.json", entries)
+}
+
+func (ag *archiveGenerator) addLeaderboard(ctx context.Context) *kilonova.StatusError {
+	leaderboard, err := ag.base.ContestLeaderboard(ctx, ag.contest, nil, kilonova.UserFilter{})
+	if err != nil {
+		return err
+	}
+	return writeJSON(ag.ar, "leaderboard.json", leaderboard)
+}
+
+// GenerateContestArchive writes the whole contest (settings, problems, teams, registrations, submissions, questions, announcements,
+// hacks, pretest scores and leaderboard) as a zip archive.
+// Since the archive contains all submissions and private problem data, it should only be generated for contest editors
+func GenerateContestArchive(ctx context.Context, contest *kilonova.Contest, w io.Writer, base *sudoapi.BaseAPI) *kilonova.StatusError {
+	ag := &archiveGenerator{
+		contest:   contest,
+		ar:        zip.NewWriter(w),
+		base:      base,
+		usernames: make(map[int]string),
+	}
+	defer ag.ar.Close()
+
+	if err := ag.addContest(ctx); err != nil {
+		return err
+	}
+	if err := ag.addTeams(ctx); err != nil {
+		return err
+	}
+	if err := ag.addRegistrations(ctx); err != nil {
+		return err
+	}
+	if err := ag.addSubmissions(ctx); err != nil {
+		return err
+	}
+	if err := ag.addCommunication(ctx); err != nil {
+		return err
+	}
+	if err := ag.addHacks(ctx); err != nil {
+		return err
+	}
+	if err := ag.addPretestScores(ctx); err != nil {
+		return err
+	}
+	return ag.addLeaderboard(ctx)
+}
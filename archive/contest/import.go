@@ -0,0 +1,458 @@
+package contest
+
+import (
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/archive/test"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"go.uber.org/zap"
+)
+
+type ImportResult struct {
+	ContestID int `json:"contest_id"`
+	// SkippedUsers holds the usernames from the archive that don't exist on this instance.
+	// Their registrations, submissions and questions were not imported
+	SkippedUsers []string `json:"skipped_users"`
+}
+
+type archiveImporter struct {
+	ar        *zip.Reader
+	base      *sudoapi.BaseAPI
+	requestor *kilonova.UserFull
+
+	contest *kilonova.Contest
+
+	users map[string]*kilonova.UserBrief
+	// problems, announcements, teams and submissions map the IDs from the archive to the newly created ones
+	problems      map[int]int
+	announcements map[int]int
+	teams         map[int]int
+	submissions   map[int]int
+
+	result *ImportResult
+}
+
+// user returns the user with the given name on this instance, or nil if there is none
+func (ai *archiveImporter) user(ctx context.Context, name string) *kilonova.UserBrief {
+	if name == "" {
+		// The user was deleted before the archive was generated
+		return nil
+	}
+	if user, ok := ai.users[name]; ok {
+		return user
+	}
+	user, err := ai.base.UserBriefByName(ctx, name)
+	if err != nil {
+		user = nil
+		ai.result.SkippedUsers = append(ai.result.SkippedUsers, name)
+	}
+	ai.users[name] = user
+	return user
+}
+
+func (ai *archiveImporter) problemID(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	newID, ok := ai.problems[*id]
+	if !ok {
+		return nil
+	}
+	return &newID
+}
+
+// teamID maps the team ID from the archive, returning nil if the team wasn't imported
+func (ai *archiveImporter) teamID(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	newID, ok := ai.teams[*id]
+	if !ok {
+		return nil
+	}
+	return &newID
+}
+
+func (ai *archiveImporter) importContest(ctx context.Context, manifest *contestManifest) *kilonova.StatusError {
+	id, err := ai.base.CreateContest(ctx, manifest.Contest.Name, manifest.Contest.Type, ai.requestor.Brief())
+	if err != nil {
+		return err
+	}
+	ai.result.ContestID = id
+	if err := ai.base.CopyContestSettings(ctx, id, manifest.Contest, 0); err != nil {
+		return err
+	}
+	// Set right away, so system testing isn't started again on the imported submissions
+	if manifest.Contest.SystemTestStatus != "" {
+		if err := ai.base.ImportSystemTestStatus(ctx, id, manifest.Contest.SystemTestStatus); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range manifest.Editors {
+		if user := ai.user(ctx, name); user != nil && user.ID != ai.requestor.ID {
+			if err := ai.base.AddContestEditor(ctx, id, user.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, name := range manifest.Testers {
+		if user := ai.user(ctx, name); user != nil && user.ID != ai.requestor.ID {
+			if err := ai.base.AddContestTester(ctx, id, user.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	slices.SortFunc(manifest.Problems, func(a, b *problemEntry) int {
+		return cmp.Compare(a.Settings.Position, b.Settings.Position)
+	})
+	problemIDs := make([]int, 0, len(manifest.Problems))
+	for _, entry := range manifest.Problems {
+		data, err := readFile(ai.ar, entry.archivePath())
+		if err != nil {
+			return err
+		}
+		pbAr, err1 := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err1 != nil {
+			return kilonova.WrapError(err1, "Couldn't read problem archive")
+		}
+		pb, err := test.ImportProblem(ctx, entry.Problem, pbAr, ai.base, ai.requestor)
+		if err != nil {
+			return err
+		}
+		ai.problems[entry.Problem.ID] = pb.ID
+		problemIDs = append(problemIDs, pb.ID)
+	}
+	if err := ai.base.UpdateContestProblems(ctx, id, problemIDs); err != nil {
+		return err
+	}
+
+	ai.contest, err = ai.base.Contest(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, entry := range manifest.Problems {
+		settings := entry.Settings
+		settings.ProblemID = ai.problems[entry.Problem.ID]
+		// Languages might not be available on this instance
+		settings.Languages = slices.DeleteFunc(settings.Languages, func(lang string) bool {
+			_, ok := eval.Langs[lang]
+			return !ok
+		})
+		if err := ai.base.UpdateContestProblemSettings(ctx, ai.contest, settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importTeams(ctx context.Context) *kilonova.StatusError {
+	var entries []*teamEntry
+	if err := readJSON(ai.ar, "teams.json", &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		team := entry.ContestTeam
+		team.ContestID, team.CreatorID, team.Members = ai.contest.ID, nil, nil
+		if entry.Creator != nil {
+			if creator := ai.user(ctx, *entry.Creator); creator != nil {
+				team.CreatorID = &creator.ID
+			}
+		}
+		id, err := ai.base.ImportContestTeam(ctx, &team)
+		if err != nil {
+			return err
+		}
+		ai.teams[entry.ID] = id
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importRegistrations(ctx context.Context) *kilonova.StatusError {
+	var entries []*registrationEntry
+	if err := readJSON(ai.ar, "registrations.json", &entries); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		user := ai.user(ctx, entry.Username)
+		if user == nil {
+			continue
+		}
+		reg := entry.ContestRegistration
+		reg.ContestID, reg.UserID = ai.contest.ID, user.ID
+		// Invitations are not part of the archive
+		reg.InvitationID, reg.TeamID = nil, ai.teamID(reg.TeamID)
+		if err := ai.base.ImportContestRegistration(ctx, &reg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importCommunication(ctx context.Context) *kilonova.StatusError {
+	var announcements []*kilonova.ContestAnnouncement
+	if err := readJSON(ai.ar, "announcements.json", &announcements); err != nil {
+		return err
+	}
+	slices.SortFunc(announcements, func(a, b *kilonova.ContestAnnouncement) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, announcement := range announcements {
+		oldID := announcement.ID
+		announcement.ContestID = ai.contest.ID
+		announcement.ProblemID = ai.problemID(announcement.ProblemID)
+		id, err := ai.base.ImportContestAnnouncement(ctx, announcement)
+		if err != nil {
+			return err
+		}
+		ai.announcements[oldID] = id
+	}
+
+	var questions []*questionEntry
+	if err := readJSON(ai.ar, "questions.json", &questions); err != nil {
+		return err
+	}
+	slices.SortFunc(questions, func(a, b *questionEntry) int {
+		return a.AskedAt.Compare(b.AskedAt)
+	})
+	for _, entry := range questions {
+		author := ai.user(ctx, entry.Author)
+		if author == nil {
+			continue
+		}
+		question := entry.ContestQuestion
+		question.ContestID, question.AuthorID = ai.contest.ID, author.ID
+		question.ProblemID = ai.problemID(question.ProblemID)
+		question.ClaimedBy, question.ClaimedAt = nil, nil
+
+		question.AnsweredBy = nil
+		if entry.AnsweredBy != nil {
+			if judge := ai.user(ctx, *entry.AnsweredBy); judge != nil {
+				question.AnsweredBy = &judge.ID
+			}
+		}
+		if question.AnnouncementID != nil {
+			if id, ok := ai.announcements[*question.AnnouncementID]; ok {
+				question.AnnouncementID = &id
+			} else {
+				question.AnnouncementID = nil
+			}
+		}
+
+		if _, err := ai.base.ImportContestQuestion(ctx, &question); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importSubmissions(ctx context.Context) *kilonova.StatusError {
+	var entries []*submissionEntry
+	if err := readJSON(ai.ar, "submissions.json", &entries); err != nil {
+		return err
+	}
+	slices.SortFunc(entries, func(a, b *submissionEntry) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for _, entry := range entries {
+		author := ai.user(ctx, entry.Author)
+		if author == nil {
+			continue
+		}
+		problemID := ai.problemID(&entry.ProblemID)
+		if problemID == nil {
+			zap.S().Infof("Skipping submission of problem that isn't in the contest: %d", entry.ID)
+			continue
+		}
+		if _, ok := eval.Langs[entry.Language]; !ok {
+			zap.S().Infof("Skipping submission due to unknown language (%q): %d", entry.Language, entry.ID)
+			continue
+		}
+		code, err := readFile(ai.ar, entry.CodeFile)
+		if err != nil {
+			return err
+		}
+
+		sub := entry.Submission
+		sub.UserID, sub.ProblemID, sub.ContestID = author.ID, *problemID, &ai.contest.ID
+		sub.TeamID = ai.teamID(sub.TeamID)
+		id, err := ai.base.ImportSubmission(ctx, &sub, code, entry.SubTests, entry.SubTasks)
+		if err != nil {
+			return err
+		}
+		ai.submissions[entry.ID] = id
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importHacks(ctx context.Context) *kilonova.StatusError {
+	var file hacksFile
+	if err := readJSON(ai.ar, "hacks.json", &file); err != nil {
+		return err
+	}
+	for _, entry := range file.Locks {
+		user := ai.user(ctx, entry.Username)
+		problemID := ai.problemID(&entry.ProblemID)
+		if user == nil || problemID == nil {
+			continue
+		}
+		lock := entry.ContestProblemLock
+		lock.ContestID, lock.UserID, lock.ProblemID = ai.contest.ID, user.ID, *problemID
+		if err := ai.base.ImportContestProblemLock(ctx, &lock); err != nil {
+			return err
+		}
+	}
+
+	slices.SortFunc(file.Hacks, func(a, b *hackEntry) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for _, entry := range file.Hacks {
+		hacker, target := ai.user(ctx, entry.Hacker), ai.user(ctx, entry.TargetUser)
+		problemID := ai.problemID(&entry.ProblemID)
+		targetSubID, ok := ai.submissions[entry.TargetSubmissionID]
+		if hacker == nil || target == nil || problemID == nil || !ok {
+			zap.S().Infof("Skipping hack whose users or target submission weren't imported: %d", entry.ID)
+			continue
+		}
+		input, err := readFile(ai.ar, entry.inputPath())
+		if err != nil {
+			return err
+		}
+
+		hack := entry.ContestHack
+		hack.ContestID, hack.ProblemID = ai.contest.ID, *problemID
+		hack.HackerID, hack.TargetUserID, hack.TargetSubmissionID = hacker.ID, target.ID, targetSubID
+		hack.TestID = nil
+		if entry.TestVisibleID != nil {
+			if test, err := ai.base.Test(ctx, *problemID, *entry.TestVisibleID); err == nil {
+				hack.TestID = &test.ID
+			}
+		}
+		id, err := ai.base.ImportHack(ctx, &hack, input)
+		if err != nil {
+			return err
+		}
+
+		if entry.OutputFile != "" {
+			output, err := readFile(ai.ar, entry.OutputFile)
+			if err != nil {
+				return err
+			}
+			if err := errors.Join(
+				ai.base.SaveTestInput(sudoapi.HackTestID(id), bytes.NewReader(input)),
+				ai.base.SaveTestOutput(sudoapi.HackTestID(id), bytes.NewReader(output)),
+			); err != nil {
+				return kilonova.WrapError(err, "Couldn't save hack data")
+			}
+		}
+	}
+	return nil
+}
+
+func (ai *archiveImporter) importPretestScores(ctx context.Context) *kilonova.StatusError {
+	var entries []*pretestScoreEntry
+	if err := readJSON(ai.ar, "pretest_scores.json", &entries); err != nil {
+		return err
+	}
+	scores := make([]*kilonova.PretestScore, 0, len(entries))
+	for _, entry := range entries {
+		score := entry.PretestScore
+		problemID := ai.problemID(&score.ProblemID)
+		if problemID == nil {
+			continue
+		}
+		score.ProblemID = *problemID
+		if score.TeamID != nil {
+			if score.TeamID = ai.teamID(score.TeamID); score.TeamID == nil {
+				continue
+			}
+			score.UserID = nil
+		} else {
+			user := ai.user(ctx, entry.Username)
+			if user == nil {
+				continue
+			}
+			score.UserID = &user.ID
+		}
+		scores = append(scores, &score)
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	return ai.base.ImportPretestScores(ctx, ai.contest.ID, scores)
+}
+
+// ImportContestArchive recreates a contest from an archive made by GenerateContestArchive.
+// The contest and its problems are created as hidden, with the requestor as their author.
+// Users are matched by username, so the data of users that don't exist on this instance is skipped.
+// The imported submissions keep their original results instead of being reevaluated.
+// If an error occurs midway, the partially imported contest is kept and its ID is returned alongside the error
+func ImportContestArchive(ctx context.Context, ar *zip.Reader, base *sudoapi.BaseAPI, requestor *kilonova.UserFull) (*ImportResult, *kilonova.StatusError) {
+	if requestor == nil || !requestor.Admin {
+		return nil, kilonova.Statusf(403, "Only admins can import contest archives")
+	}
+
+	var manifest contestManifest
+	if err := readJSON(ar, "contest.json", &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Contest == nil {
+		return nil, kilonova.Statusf(400, "Archive doesn't contain a contest.json file")
+	}
+	if manifest.Version > archiveVersion {
+		return nil, kilonova.Statusf(400, "Archive was generated by a newer version of the platform")
+	}
+	for _, entry := range manifest.Problems {
+		if entry.Problem == nil || entry.Settings == nil {
+			return nil, kilonova.Statusf(400, "Invalid problem entry in contest.json")
+		}
+	}
+
+	ai := &archiveImporter{
+		ar:        ar,
+		base:      base,
+		requestor: requestor,
+
+		users:         make(map[string]*kilonova.UserBrief),
+		problems:      make(map[int]int),
+		announcements: make(map[int]int),
+		teams:         make(map[int]int),
+		submissions:   make(map[int]int),
+
+		result: &ImportResult{ContestID: -1, SkippedUsers: []string{}},
+	}
+
+	if err := ai.importContest(ctx, &manifest); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importTeams(ctx); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importRegistrations(ctx); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importCommunication(ctx); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importSubmissions(ctx); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importHacks(ctx); err != nil {
+		return ai.result, err
+	}
+	if err := ai.importPretestScores(ctx); err != nil {
+		return ai.result, err
+	}
+
+	base.LogUserAction(ctx, "Imported contest archive", slog.Any("contest", ai.contest))
+	return ai.result, nil
+}
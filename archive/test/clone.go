@@ -0,0 +1,58 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"go.uber.org/zap"
+)
+
+// CloneProblem creates a fresh, hidden copy of the problem, owned by the requestor.
+// The problem is exported to an archive (with tests, all attachments and tags) that is imported back into the new problem.
+func CloneProblem(ctx context.Context, pb *kilonova.Problem, base *sudoapi.BaseAPI, requestor *kilonova.UserFull) (*kilonova.Problem, *kilonova.StatusError) {
+	var buf bytes.Buffer
+	if err := GenerateArchive(ctx, pb, &buf, base, &ArchiveGenOptions{
+		Tests:              true,
+		Attachments:        true,
+		PrivateAttachments: true,
+		ProblemDetails:     true,
+		Tags:               true,
+	}); err != nil {
+		return nil, err
+	}
+
+	ar, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't read generated archive")
+	}
+
+	return ImportProblem(ctx, pb, ar, base, requestor)
+}
+
+// ImportProblem creates a new, hidden problem owned by the requestor from an archive generated for pb.
+// pb holds the details of the original problem, since some of them don't fit in the archive
+func ImportProblem(ctx context.Context, pb *kilonova.Problem, ar *zip.Reader, base *sudoapi.BaseAPI, requestor *kilonova.UserFull) (*kilonova.Problem, *kilonova.StatusError) {
+	newPb, err := base.CreateProblem(ctx, pb.Name, requestor.Brief(), pb.ConsoleInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ProcessZipTestArchive(ctx, newPb, ar, base, &TestProcessParams{Requestor: requestor}); err != nil {
+		return nil, err
+	}
+
+	// Some details don't fit in grader.properties
+	if err := base.UpdateProblem(ctx, newPb.ID, kilonova.ProblemUpdate{
+		TestName:     &pb.TestName,
+		ScoreScale:   &pb.ScoreScale,
+		SourceSize:   &pb.SourceSize,
+		VisibleTests: &pb.VisibleTests,
+	}, nil); err != nil {
+		zap.S().Warn(err)
+	}
+
+	return base.Problem(ctx, newPb.ID)
+}
@@ -0,0 +1,82 @@
+package kilonova
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CertificateFormat is the markup language of a contest's certificate template
+type CertificateFormat string
+
+const (
+	CertificateFormatHTML     CertificateFormat = "html"
+	CertificateFormatMarkdown CertificateFormat = "md"
+)
+
+// ContestAward is a distinction given to the participants that satisfy all of its set thresholds
+type ContestAward struct {
+	Name string `json:"name" db:"name"`
+	// MaxRank is the lowest place that still receives the award
+	MaxRank  *int             `json:"max_rank" db:"max_rank"`
+	MinScore *decimal.Decimal `json:"min_score" db:"min_score"`
+}
+
+// Matches reports whether a participant with the given rank and score receives the award
+func (a *ContestAward) Matches(rank int, score decimal.Decimal) bool {
+	if a.MaxRank == nil && a.MinScore == nil {
+		return false
+	}
+	if a.MaxRank != nil && rank > *a.MaxRank {
+		return false
+	}
+	if a.MinScore != nil && score.LessThan(*a.MinScore) {
+		return false
+	}
+	return true
+}
+
+type CertificateSettings struct {
+	ContestID int `json:"contest_id"`
+
+	// Template is rendered with the Go template syntax, see CertificateData for the available placeholders
+	Template string            `json:"template"`
+	Format   CertificateFormat `json:"format"`
+	// Published certificates can be downloaded by the participants themselves
+	Published bool `json:"published"`
+
+	// Awards are checked in order, the first matching one is given to the participant
+	Awards []*ContestAward `json:"awards"`
+}
+
+// Certificate is the record of a certificate issued to a contest participant, used to verify its authenticity
+type Certificate struct {
+	ID        string    `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+
+	Rank  int             `json:"rank" db:"rank"`
+	Score decimal.Decimal `json:"score" db:"score"`
+	// Award is nil for participation certificates
+	Award *string `json:"award" db:"award"`
+	// Team is set in team contests
+	Team *string `json:"team" db:"team"`
+}
+
+// CertificateData holds the values of the placeholders available in certificate templates
+type CertificateData struct {
+	Name        string
+	DisplayName string
+	Team        string
+
+	Rank  int
+	Score string
+	Award string
+
+	Contest string
+	// Date is the day the contest ended
+	Date string
+
+	VerificationURL string
+}
@@ -139,7 +139,9 @@ func webV1(templWeb bool, base *sudoapi.BaseAPI) *http.Server {
 		r.Use(middleware.RequestID)
 	*/
 
-	r.Mount("/api", api.New(base).Handler())
+	apiInst := api.New(base)
+	r.Mount("/api", apiInst.Handler())
+	apiInst.RegisterOAuthRoutes(r)
 	r.Mount("/assets", api.NewAssets(base).AssetsRouter())
 
 	if templWeb {
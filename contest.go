@@ -2,6 +2,8 @@ package kilonova
 
 import (
 	"log/slog"
+	"net/netip"
+	"slices"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -25,6 +27,28 @@ const (
 	LeaderboardTypeICPC    LeaderboardType = "acm-icpc"
 )
 
+// FeedbackLevel controls how much of their submissions' evaluation contestants see while the contest is running
+type FeedbackLevel string
+
+const (
+	FeedbackLevelNone     FeedbackLevel = ""
+	FeedbackLevelFull     FeedbackLevel = "full"
+	FeedbackLevelSubtasks FeedbackLevel = "subtasks"
+	FeedbackLevelTotal    FeedbackLevel = "total"
+)
+
+func (l FeedbackLevel) Valid() bool {
+	return l == FeedbackLevelFull || l == FeedbackLevelSubtasks || l == FeedbackLevelTotal
+}
+
+type SystemTestStatus string
+
+const (
+	SystemTestNone     SystemTestStatus = "none"
+	SystemTestRunning  SystemTestStatus = "running"
+	SystemTestFinished SystemTestStatus = "finished"
+)
+
 type ContestType string
 
 const (
@@ -54,10 +78,6 @@ type Contest struct {
 	// Contestants may be able to see the contest
 	Visible bool `json:"hidden"`
 
-	// Virtual indicates a virtual contest.
-	// TODO: Implement this
-	// Virtual bool `json:"virtual"`
-
 	// PublicLeaderboard controls whether the contest's leaderboard
 	// is viewable by everybody or just admins
 	PublicLeaderboard bool `json:"public_leaderboard"`
@@ -84,6 +104,49 @@ type Contest struct {
 	// that someone is allowed to send to a problem during a contest
 	// < 0 => no limit
 	MaxSubs int `json:"max_subs"`
+
+	// FeedbackLevel limits what contestants see of their submissions until the contest ends
+	FeedbackLevel FeedbackLevel `json:"feedback_level"`
+	// FeedbackTokens is the number of submissions per problem for which a contestant
+	// can reveal the full evaluation while feedback is limited
+	FeedbackTokens int `json:"feedback_tokens"`
+
+	// Pretests makes submissions sent during the contest be judged only on the problems' pretests.
+	// Once the contest ends, the submissions accepted on pretests are system tested on all tests
+	Pretests         bool             `json:"pretests"`
+	SystemTestStatus SystemTestStatus `json:"system_test_status"`
+
+	// Hacking allows contestants that locked a problem to challenge the others' accepted solutions with their own tests.
+	// HackPoints are awarded for a successful hack, HackPenalty is subtracted for an unsuccessful one
+	Hacking     bool `json:"hacking"`
+	HackPoints  int  `json:"hack_points"`
+	HackPenalty int  `json:"hack_penalty"`
+
+	// TeamSize is the maximum number of members of a team.
+	// If it's greater than 0, contestants participate in teams and the leaderboard is aggregated per team
+	TeamSize int `json:"team_size"`
+
+	// Categories are the participant categories (ex: official, unofficial) contestants can be registered under.
+	// Leaderboards can be filtered to a single category
+	Categories []string `json:"categories"`
+
+	// RatingsApplied is true once the rating changes of an ended official contest were computed
+	RatingsApplied bool `json:"ratings_applied"`
+
+	// AllowedIPRanges restricts the networks contestants can submit from. An empty list means there is no restriction
+	AllowedIPRanges []netip.Prefix `json:"allowed_ip_ranges"`
+	// SingleSession logs contestants out of their other sessions when they log in while the contest is running
+	SingleSession bool `json:"single_session"`
+	// Lockdown hides the problems outside the contest, the pastes and the blog posts from contestants while the contest is running
+	Lockdown bool `json:"lockdown"`
+
+	// OnSite enables the print queue and the balloon board used at on-site contests
+	OnSite bool `json:"on_site"`
+}
+
+// HasCategory returns whether the category is one of the contest's participant categories
+func (c *Contest) HasCategory(category string) bool {
+	return slices.Contains(c.Categories, category)
 }
 
 func (c *Contest) Started() bool {
@@ -107,6 +170,13 @@ func (c *Contest) Running() bool {
 	return c.Started() && !c.Ended()
 }
 
+func (c *Contest) TeamMode() bool {
+	if c == nil {
+		return false
+	}
+	return c.TeamSize > 0
+}
+
 func (c *Contest) LogValue() slog.Value {
 	return slog.GroupValue(slog.Int("id", c.ID), slog.String("name", c.Name))
 }
@@ -179,6 +249,57 @@ type ContestUpdate struct {
 	Type ContestType `json:"type"`
 
 	PerUserTime *int `json:"per_user_time"` // Seconds
+
+	FeedbackLevel  FeedbackLevel `json:"feedback_level"`
+	FeedbackTokens *int          `json:"feedback_tokens"`
+
+	Pretests *bool `json:"pretests"`
+
+	Hacking     *bool `json:"hacking"`
+	HackPoints  *int  `json:"hack_points"`
+	HackPenalty *int  `json:"hack_penalty"`
+
+	TeamSize *int `json:"team_size"`
+
+	SingleSession *bool `json:"single_session"`
+	Lockdown      *bool `json:"lockdown"`
+
+	OnSite *bool `json:"on_site"`
+}
+
+// ContestProblemSettings are the settings of a problem that apply only inside a contest.
+// Unset values fall back to the problem's or the contest's own settings
+type ContestProblemSettings struct {
+	ContestID int `json:"contest_id" db:"contest_id"`
+	ProblemID int `json:"problem_id" db:"problem_id"`
+	Position  int `json:"position" db:"position"`
+
+	// Label is shown instead of the problem name in the contest leaderboard.
+	// If empty, a letter based on the problem's position is used where a label is required
+	Label string `json:"label" db:"label"`
+	// Points replaces the problem's score scale on the contest leaderboard
+	Points *decimal.Decimal `json:"points" db:"points"`
+	// Languages restricts the languages allowed in the contest, on top of the problem's whitelist
+	Languages []string `json:"languages" db:"languages"`
+	// MaxSubs overrides the contest's maximum submission count for this problem
+	MaxSubs *int `json:"max_subs" db:"max_subs"`
+}
+
+// DisplayLabel returns the label of the problem, defaulting to the letter given by its position
+func (s *ContestProblemSettings) DisplayLabel() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return ProblemLabel(s.Position)
+}
+
+// ProblemLabel returns the letter label of the i-th (0-indexed) contest problem: A, B, ..., Z, AA, AB, ...
+func ProblemLabel(i int) string {
+	label := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		label = string(rune('A'+(i-1)%26)) + label
+	}
+	return label
 }
 
 type ContestQuestion struct {
@@ -188,8 +309,19 @@ type ContestQuestion struct {
 	ContestID int       `json:"contest_id"`
 	Text      string    `json:"text"`
 
+	// ProblemID is set if the question is about a specific contest problem
+	ProblemID *int `json:"problem_id"`
+
+	// ClaimedBy is the judge that is currently answering the question
+	ClaimedBy *int       `json:"claimed_by"`
+	ClaimedAt *time.Time `json:"claimed_at"`
+
 	ResponedAt *time.Time `json:"responded_at"`
 	Response   *string    `json:"response"`
+	AnsweredBy *int       `json:"answered_by"`
+
+	// AnnouncementID is set if the question was answered publicly, as a clarification visible to everyone
+	AnnouncementID *int `json:"announcement_id"`
 }
 
 type ContestAnnouncement struct {
@@ -197,6 +329,9 @@ type ContestAnnouncement struct {
 	CreatedAt time.Time `json:"created_at"`
 	ContestID int       `json:"contest_id"`
 	Text      string    `json:"text"`
+
+	// ProblemID is set for clarifications about a specific contest problem
+	ProblemID *int `json:"problem_id"`
 }
 
 type MOSSSubmission struct {
@@ -221,6 +356,22 @@ type ContestRegistration struct {
 	IndividualEndTime   *time.Time `json:"individual_end" db:"individual_end_at"`
 
 	InvitationID *string `json:"invitation_id" db:"invitation_id"`
+
+	// Virtual registrations are personal runs of the contest, started after it ended.
+	// Their window is stored in IndividualStartTime and IndividualEndTime
+	Virtual bool `json:"virtual" db:"virtual"`
+
+	TeamID *int `json:"team_id" db:"team_id"`
+
+	Category *string `json:"category" db:"category"`
+}
+
+// VirtualRunning returns whether the registration is a virtual participation that is currently running
+func (reg *ContestRegistration) VirtualRunning() bool {
+	if reg == nil || !reg.Virtual || reg.IndividualStartTime == nil || reg.IndividualEndTime == nil {
+		return false
+	}
+	return time.Now().After(*reg.IndividualStartTime) && time.Now().Before(*reg.IndividualEndTime)
 }
 
 type ContestInvitation struct {
@@ -234,6 +385,39 @@ type ContestInvitation struct {
 	MaxCount    *int `json:"max_invitation_count" db:"max_invitation_cnt"`
 
 	Expired bool `json:"expired"`
+
+	// TeamID is set for invitations that register the users as members of a team
+	TeamID *int `json:"team_id" db:"team_id"`
+
+	// Category is assigned to the users that redeem the invitation
+	Category *string `json:"category" db:"category"`
+}
+
+// RatingChange is the change in rating of a contestant after an official contest
+type RatingChange struct {
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	Rank      int `json:"rank" db:"rank"`
+	OldRating int `json:"old_rating" db:"old_rating"`
+	NewRating int `json:"new_rating" db:"new_rating"`
+
+	// ContestName and ContestEndTime are filled in when listing the rating history of an user
+	ContestName    string    `json:"contest_name" db:"contest_name"`
+	ContestEndTime time.Time `json:"contest_end_time" db:"contest_end_time"`
+}
+
+// ContestTeam is a group of contestants that participate together.
+// Their submissions count towards a single leaderboard entry
+type ContestTeam struct {
+	ID        int       `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	Name      string    `json:"name" db:"name"`
+	CreatorID *int      `json:"creator_id" db:"creator_id"`
+
+	Members []*UserBrief `json:"members" db:"-"`
 }
 
 func (ci *ContestInvitation) Invalid() bool {
@@ -243,11 +427,18 @@ func (ci *ContestInvitation) Invalid() bool {
 // TODO: Maybe it would be nicer to coalesce all problem maps in a struct?
 type LeaderboardEntry struct {
 	User *UserBrief `json:"user"`
+	// Team is set instead of User in the leaderboards of team contests
+	Team *ContestTeam `json:"team,omitempty"`
 
 	// For classic mode
 	ProblemScores map[int]decimal.Decimal `json:"scores"`
 	TotalScore    decimal.Decimal         `json:"total"`
 
+	// HackScore is the (already included in TotalScore) sum of points from the hacking phase
+	HackScore         int `json:"hack_score"`
+	SuccessfulHacks   int `json:"successful_hacks"`
+	UnsuccessfulHacks int `json:"unsuccessful_hacks"`
+
 	// For ICPC mode
 	ProblemAttempts map[int]int `json:"attempts"`
 	Penalty         int         `json:"penalty"`
@@ -257,15 +448,147 @@ type LeaderboardEntry struct {
 
 	LastTime   *time.Time `json:"last_time"`
 	FreezeTime *time.Time `json:"freeze_time"`
+
+	// Virtual is true for the entries of virtual participants in a ghost leaderboard.
+	// Their times are converted to the equivalent times of the official contest
+	Virtual bool `json:"virtual"`
+
+	Category *string `json:"category"`
 }
 
 type ContestLeaderboard struct {
-	ProblemOrder []int               `json:"problem_ordering"`
-	ProblemNames map[int]string      `json:"problem_names"`
-	Entries      []*LeaderboardEntry `json:"entries"`
+	ProblemOrder []int          `json:"problem_ordering"`
+	ProblemNames map[int]string `json:"problem_names"`
+	// ProblemLabels holds the labels explicitly set for the contest problems
+	ProblemLabels map[int]string      `json:"problem_labels"`
+	Entries       []*LeaderboardEntry `json:"entries"`
 
 	AdvancedFilter bool `json:"advanced_filter"`
 
 	FreezeTime *time.Time      `json:"freeze_time"`
 	Type       LeaderboardType `json:"type"`
+
+	// Hacking is true if the contest had a hacking phase, so hack scores should be displayed
+	Hacking bool `json:"hacking"`
+
+	// SystemTesting is true while the scores shown are the ones on pretests, from the end of the contest
+	SystemTesting bool `json:"system_testing"`
+
+	// Virtual is true for ghost leaderboards, which merge the virtual participants with the official standings.
+	// Elapsed is the contest time (in seconds) at which the standings were taken
+	Virtual bool `json:"virtual"`
+	Elapsed int  `json:"elapsed"`
+
+	// Category is set if the leaderboard only ranks the participants of a single category
+	Category *string `json:"category"`
+}
+
+// ResolverStep is a single reveal of a pending cell of the frozen ICPC standings.
+// Either UserID or TeamID is set, identifying the revealed leaderboard entry
+type ResolverStep struct {
+	UserID *int `json:"user_id,omitempty"`
+	TeamID *int `json:"team_id,omitempty"`
+
+	ProblemID int     `json:"problem_id"`
+	Solved    bool    `json:"solved"`
+	Attempts  int     `json:"attempts"`
+	Time      float64 `json:"time"`
+
+	// Ranks are 1-indexed positions in the standings, before and after the reveal
+	PrevRank int `json:"prev_rank"`
+	NewRank  int `json:"new_rank"`
+
+	NumSolved int `json:"num_solved"`
+	Penalty   int `json:"penalty"`
+}
+
+// ContestResolver holds the frozen standings of an ICPC contest and the ordered reveals that lead to the final standings
+type ContestResolver struct {
+	Frozen *ContestLeaderboard `json:"frozen"`
+	Steps  []*ResolverStep     `json:"steps"`
+}
+
+type HackStatus string
+
+const (
+	HackStatusPending  HackStatus = "pending"
+	HackStatusWorking  HackStatus = "working"
+	HackStatusFinished HackStatus = "finished"
+)
+
+type HackVerdict string
+
+const (
+	HackVerdictNone          HackVerdict = "none"
+	HackVerdictSuccessful    HackVerdict = "successful"
+	HackVerdictUnsuccessful  HackVerdict = "unsuccessful"
+	HackVerdictInvalidInput  HackVerdict = "invalid_input"
+	HackVerdictInternalError HackVerdict = "internal_error"
+)
+
+// ContestHack is a challenge of a contestant's accepted submission with a custom test, sent during a contest's hacking phase
+type ContestHack struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ContestID int       `json:"contest_id"`
+	ProblemID int       `json:"problem_id"`
+
+	HackerID           int `json:"hacker_id"`
+	TargetUserID       int `json:"target_user_id"`
+	TargetSubmissionID int `json:"target_submission_id"`
+
+	Status  HackStatus  `json:"status"`
+	Verdict HackVerdict `json:"verdict"`
+	Message string      `json:"message"`
+	Points  int         `json:"points"`
+
+	// TestID is the problem test created from this hack, if it was added to the problem
+	TestID *int `json:"test_id"`
+}
+
+type HackFilter struct {
+	ID        *int `json:"id"`
+	ContestID *int `json:"contest_id"`
+	ProblemID *int `json:"problem_id"`
+
+	HackerID           *int `json:"hacker_id"`
+	TargetSubmissionID *int `json:"target_submission_id"`
+
+	// InvolvedUserID filters the hacks in which the user is either the hacker or the target
+	InvolvedUserID *int `json:"involved_user_id"`
+
+	Status  HackStatus  `json:"status"`
+	Verdict HackVerdict `json:"verdict"`
+	// Unfinished filters the hacks that are still waiting for or being judged by the grader
+	Unfinished bool `json:"unfinished"`
+
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type HackUpdate struct {
+	Status  HackStatus
+	Verdict HackVerdict
+	Message *string
+	Points  *int
+	TestID  *int
+}
+
+// ContestProblemLock records that the user locked the problem during the hacking phase
+type ContestProblemLock struct {
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	ProblemID int       `json:"problem_id" db:"problem_id"`
+}
+
+// PretestScore is a leaderboard score snapshotted when system testing started.
+// Exactly one of UserID and TeamID is set, depending on whether the contest is played in teams
+type PretestScore struct {
+	UserID    *int            `json:"user_id" db:"user_id"`
+	TeamID    *int            `json:"team_id" db:"team_id"`
+	ProblemID int             `json:"problem_id" db:"problem_id"`
+	Frozen    bool            `json:"frozen" db:"frozen"`
+	Score     decimal.Decimal `json:"score" db:"score"`
+	MinTime   *time.Time      `json:"mintime" db:"mintime"`
 }
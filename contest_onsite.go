@@ -0,0 +1,38 @@
+package kilonova
+
+import "time"
+
+// PrintJob is a source file a contestant of an on-site contest asked to be printed
+type PrintJob struct {
+	ID        int       `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ContestID int       `json:"contest_id" db:"contest_id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	TeamID    *int      `json:"team_id" db:"team_id"`
+
+	Seat     string `json:"seat" db:"seat"`
+	Location string `json:"location" db:"location"`
+
+	Filename string `json:"filename" db:"filename"`
+	// Language is used for syntax highlighting. It may be empty for plain text files
+	Language string `json:"language" db:"language"`
+	Code     string `json:"-" db:"code"`
+
+	PrintedAt *time.Time `json:"printed_at" db:"printed_at"`
+	PrintedBy *int       `json:"printed_by" db:"printed_by"`
+}
+
+// BalloonEvent is the first accepted submission of a participant (or team) on a problem of an on-site contest
+type BalloonEvent struct {
+	SubmissionID int       `json:"submission_id" db:"submission_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UserID       int       `json:"user_id" db:"user_id"`
+	TeamID       *int      `json:"team_id" db:"team_id"`
+	ProblemID    int       `json:"problem_id" db:"problem_id"`
+
+	// FirstSolve is true for the first accepted submission on the problem in the whole contest
+	FirstSolve bool `json:"first_solve" db:"-"`
+
+	DeliveredAt *time.Time `json:"delivered_at" db:"delivered_at"`
+	DeliveredBy *int       `json:"delivered_by" db:"delivered_by"`
+}
@@ -0,0 +1,114 @@
+package kilonova
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// SeriesAggregation is the rule used to combine the round scores of a contest series
+type SeriesAggregation string
+
+const (
+	SeriesAggregationNone SeriesAggregation = ""
+	// SeriesAggregationSum adds up the scores of all rounds
+	SeriesAggregationSum SeriesAggregation = "sum"
+	// SeriesAggregationBestK adds up the best BestK round scores of every participant
+	SeriesAggregationBestK SeriesAggregation = "best_k"
+	// SeriesAggregationWeighted adds up the round scores, each multiplied by the round's weight
+	SeriesAggregationWeighted SeriesAggregation = "weighted"
+)
+
+func (a SeriesAggregation) Valid() bool {
+	return a == SeriesAggregationSum || a == SeriesAggregationBestK || a == SeriesAggregationWeighted
+}
+
+// ContestSeries groups several contests (ex: the days of an olympiad, the rounds of a league) under a combined leaderboard
+type ContestSeries struct {
+	ID          int       `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	AuthorID    int       `json:"author_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Visible     bool      `json:"visible"`
+
+	Aggregation SeriesAggregation `json:"aggregation"`
+	BestK       int               `json:"best_k"`
+
+	Rounds []*SeriesRound `json:"rounds"`
+}
+
+func (s *ContestSeries) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", s.ID), slog.String("name", s.Name))
+}
+
+// ContestIDs returns the IDs of the series' contests, in round order
+func (s *ContestSeries) ContestIDs() []int {
+	ids := make([]int, 0, len(s.Rounds))
+	for _, round := range s.Rounds {
+		ids = append(ids, round.ContestID)
+	}
+	return ids
+}
+
+type SeriesRound struct {
+	ContestID int             `json:"contest_id" db:"contest_id"`
+	Weight    decimal.Decimal `json:"weight" db:"weight"`
+}
+
+type ContestSeriesUpdate struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visible     *bool   `json:"visible"`
+
+	Aggregation SeriesAggregation `json:"aggregation"`
+	BestK       *int              `json:"best_k"`
+}
+
+type ContestSeriesFilter struct {
+	// ContestID shows the series that contain the contest as a round
+	ContestID *int `json:"contest_id"`
+
+	// Look shows only the visible series, and the ones authored by LookingUser
+	Look        bool       `json:"-"`
+	LookingUser *UserBrief `json:"-"`
+}
+
+// SeriesLeaderboard holds the combined standings of a contest series
+type SeriesLeaderboard struct {
+	Aggregation SeriesAggregation         `json:"aggregation"`
+	BestK       int                       `json:"best_k"`
+	Rounds      []*SeriesLeaderboardRound `json:"rounds"`
+	Entries     []*SeriesLeaderboardEntry `json:"entries"`
+}
+
+type SeriesLeaderboardRound struct {
+	ContestID int             `json:"contest_id"`
+	Name      string          `json:"name"`
+	Weight    decimal.Decimal `json:"weight"`
+
+	// Available is false if the round's leaderboard can't be viewed yet, so it is not included in the standings
+	Available bool `json:"available"`
+}
+
+type SeriesLeaderboardEntry struct {
+	User *UserBrief `json:"user"`
+	Rank int        `json:"rank"`
+
+	// RoundScores maps the contest IDs of the rounds the user participated in to their total score in that round
+	RoundScores map[int]decimal.Decimal `json:"round_scores"`
+	// Counted holds the contest IDs of the rounds included in the total. It differs from RoundScores only for best-k aggregation
+	Counted map[int]bool `json:"counted"`
+
+	Total decimal.Decimal `json:"total"`
+}
+
+// RoundScore returns the user's score in the round, or nil if they didn't participate in it
+func (e *SeriesLeaderboardEntry) RoundScore(contestID int) *decimal.Decimal {
+	score, ok := e.RoundScores[contestID]
+	if !ok {
+		return nil
+	}
+	return &score
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *DB) CreateAPIToken(ctx context.Context, token *kilonova.APIToken, hash string) error {
+	return s.conn.QueryRow(ctx, `INSERT INTO api_tokens (user_id, name, token_hash, scopes, expires_at, client_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		token.UserID, token.Name, hash, token.Scopes, token.ExpiresAt, token.ClientID).Scan(&token.ID, &token.CreatedAt)
+}
+
+// APITokens returns the user's personal access tokens. Tokens issued to OAuth apps are not included
+func (s *DB) APITokens(ctx context.Context, userID int) ([]*kilonova.APIToken, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT id, created_at, user_id, name, scopes, expires_at, last_used_at, last_used_ip, client_id FROM api_tokens WHERE user_id = $1 AND client_id IS NULL ORDER BY created_at DESC", userID)
+	tokens, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.APIToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.APIToken{}, nil
+	}
+	return tokens, err
+}
+
+// APITokenByHash returns the token with the given hash, or nil if it doesn't exist
+func (s *DB) APITokenByHash(ctx context.Context, hash string) (*kilonova.APIToken, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT id, created_at, user_id, name, scopes, expires_at, last_used_at, last_used_ip, client_id FROM api_tokens WHERE token_hash = $1", hash)
+	token, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.APIToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return token, err
+}
+
+// TouchAPIToken updates the token's last usage time and address.
+// To spare writes on every request, it is updated at most once a minute, unless the address changed
+func (s *DB) TouchAPIToken(ctx context.Context, id int, ip *netip.Addr) error {
+	_, err := s.conn.Exec(ctx, `UPDATE api_tokens SET last_used_at = NOW(), last_used_ip = $2 
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute' OR last_used_ip IS DISTINCT FROM $2)`, id, ip)
+	return err
+}
+
+// RemoveAPIToken deletes the user's token and returns whether it existed
+func (s *DB) RemoveAPIToken(ctx context.Context, userID, id int) (bool, error) {
+	tag, err := s.conn.Exec(ctx, "DELETE FROM api_tokens WHERE user_id = $1 AND id = $2 AND client_id IS NULL", userID, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CalendarToken returns the user's calendar feed token, or an empty string if they don't have one
+func (s *DB) CalendarToken(ctx context.Context, userID int) (string, error) {
+	var token string
+	err := s.conn.QueryRow(ctx, "SELECT id FROM calendar_tokens WHERE user_id = $1", userID).Scan(&token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return token, err
+}
+
+// CalendarTokenUser returns the ID of the user the token belongs to, or -1 if the token doesn't exist
+func (s *DB) CalendarTokenUser(ctx context.Context, token string) (int, error) {
+	var userID int
+	err := s.conn.QueryRow(ctx, "SELECT user_id FROM calendar_tokens WHERE id = $1", token).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return -1, nil
+	}
+	return userID, err
+}
+
+// SetCalendarToken replaces the user's calendar feed token
+func (s *DB) SetCalendarToken(ctx context.Context, userID int, token string) error {
+	_, err := s.conn.Exec(ctx, `INSERT INTO calendar_tokens (id, user_id) VALUES ($1, $2) 
+	ON CONFLICT (user_id) DO UPDATE SET id = EXCLUDED.id, created_at = NOW()`, token, userID)
+	return err
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+// CertificateSettings returns the contest's certificate settings. Contests without settings get an empty HTML template
+func (s *DB) CertificateSettings(ctx context.Context, contestID int) (*kilonova.CertificateSettings, error) {
+	settings := &kilonova.CertificateSettings{ContestID: contestID, Format: kilonova.CertificateFormatHTML}
+	err := s.conn.QueryRow(ctx, "SELECT template, format, published FROM contest_certificate_settings WHERE contest_id = $1", contestID).Scan(&settings.Template, &settings.Format, &settings.Published)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	rows, _ := s.conn.Query(ctx, "SELECT name, max_rank, min_score FROM contest_awards WHERE contest_id = $1 ORDER BY position ASC", contestID)
+	settings.Awards, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestAward])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if settings.Awards == nil {
+		settings.Awards = []*kilonova.ContestAward{}
+	}
+	return settings, nil
+}
+
+// UpdateCertificateSettings replaces the contest's certificate template and awards
+func (s *DB) UpdateCertificateSettings(ctx context.Context, settings *kilonova.CertificateSettings) error {
+	names := make([]string, 0, len(settings.Awards))
+	maxRanks := make([]*int, 0, len(settings.Awards))
+	minScores := make([]*decimal.Decimal, 0, len(settings.Awards))
+	for _, award := range settings.Awards {
+		names = append(names, award.Name)
+		maxRanks = append(maxRanks, award.MaxRank)
+		minScores = append(minScores, award.MinScore)
+	}
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO contest_certificate_settings (contest_id, template, format, published) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (contest_id) DO UPDATE SET template = EXCLUDED.template, format = EXCLUDED.format, published = EXCLUDED.published`,
+			settings.ContestID, settings.Template, settings.Format, settings.Published); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_awards WHERE contest_id = $1", settings.ContestID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO contest_awards (contest_id, name, max_rank, min_score, position)
+			SELECT $1, awards.name, awards.max_rank, awards.min_score, awards.position - 1 
+			FROM unnest($2::text[], $3::integer[], $4::numeric[]) WITH ORDINALITY AS awards(name, max_rank, min_score, position)`,
+			settings.ContestID, names, maxRanks, minScores)
+		return err
+	})
+}
+
+// IssueCertificates replaces the contest's certificates with the given ones. Participants that already had a certificate for the contest keep its ID,
+// so previously distributed verification URLs stay valid, while the certificates of users missing from the list are removed.
+// The IDs and creation dates of the certificates are updated in place
+func (s *DB) IssueCertificates(ctx context.Context, contestID int, certs []*kilonova.Certificate) error {
+	userIDs := make([]int, 0, len(certs))
+	for _, cert := range certs {
+		userIDs = append(userIDs, cert.UserID)
+	}
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM certificates WHERE contest_id = $1 AND NOT (user_id = ANY($2))", contestID, userIDs); err != nil {
+			return err
+		}
+		for _, cert := range certs {
+			if err := tx.QueryRow(ctx, `INSERT INTO certificates (id, contest_id, user_id, rank, score, award, team) VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (contest_id, user_id) DO UPDATE SET rank = EXCLUDED.rank, score = EXCLUDED.score, award = EXCLUDED.award, team = EXCLUDED.team
+				RETURNING id, created_at`, cert.ID, cert.ContestID, cert.UserID, cert.Rank, cert.Score, cert.Award, cert.Team).Scan(&cert.ID, &cert.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ContestCertificates returns the certificates issued for the contest, ordered by rank
+func (s *DB) ContestCertificates(ctx context.Context, contestID int) ([]*kilonova.Certificate, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM certificates WHERE contest_id = $1 ORDER BY rank ASC, user_id ASC", contestID)
+	certs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.Certificate])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.Certificate{}, nil
+	}
+	return certs, err
+}
+
+func (s *DB) UserContestCertificate(ctx context.Context, contestID, userID int) (*kilonova.Certificate, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM certificates WHERE contest_id = $1 AND user_id = $2", contestID, userID)
+	cert, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.Certificate])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return cert, err
+}
+
+func (s *DB) Certificate(ctx context.Context, id string) (*kilonova.Certificate, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM certificates WHERE id = $1", id)
+	cert, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.Certificate])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return cert, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/netip"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -43,6 +44,28 @@ type dbContest struct {
 	QuestionCooldown   int `db:"question_cooldown_ms"`
 
 	Type kilonova.ContestType `db:"type"`
+
+	FeedbackLevel  kilonova.FeedbackLevel `db:"feedback_level"`
+	FeedbackTokens int                    `db:"feedback_tokens"`
+
+	Pretests         bool                      `db:"pretests"`
+	SystemTestStatus kilonova.SystemTestStatus `db:"system_test_status"`
+
+	Hacking     bool `db:"hacking"`
+	HackPoints  int  `db:"hack_points"`
+	HackPenalty int  `db:"hack_penalty"`
+
+	TeamSize int `db:"team_size"`
+
+	Categories []string `db:"categories"`
+
+	RatingsApplied bool `db:"ratings_applied"`
+
+	AllowedIPRanges []netip.Prefix `db:"allowed_ip_ranges"`
+	SingleSession   bool           `db:"single_session"`
+	Lockdown        bool           `db:"lockdown"`
+
+	OnSite bool `db:"on_site"`
 }
 
 const createContestQuery = `INSERT INTO contests (
@@ -186,13 +209,21 @@ func (s *DB) DeleteContest(ctx context.Context, id int) error {
 
 // Contest leaderboard
 
+const leaderboardCategoryColumn = "(SELECT category FROM contest_registrations regs WHERE regs.contest_id = $1 AND regs.user_id = ranking.user_id) AS category"
+
 type databaseClassicEntry struct {
 	UserID    int             `db:"user_id"`
 	ContestID int             `db:"contest_id"`
 	Total     decimal.Decimal `db:"total_score"`
 	LastTime  *time.Time      `db:"last_time"`
 
+	HackScore         int `db:"hack_score"`
+	SuccessfulHacks   int `db:"successful_hacks"`
+	UnsuccessfulHacks int `db:"unsuccessful_hacks"`
+
 	FreezeTime *time.Time `db:"freeze_time"`
+
+	Category *string `db:"category"`
 }
 
 func (s *DB) classicToLeaderboardEntry(ctx context.Context, entry *databaseClassicEntry) (*kilonova.LeaderboardEntry, error) {
@@ -224,6 +255,10 @@ func (s *DB) classicToLeaderboardEntry(ctx context.Context, entry *databaseClass
 		TotalScore:    entry.Total,
 		ProblemScores: scores,
 
+		HackScore:         entry.HackScore,
+		SuccessfulHacks:   entry.SuccessfulHacks,
+		UnsuccessfulHacks: entry.UnsuccessfulHacks,
+
 		ProblemAttempts: make(map[int]int),
 		Penalty:         0,
 		NumSolved:       numSolved,
@@ -231,6 +266,8 @@ func (s *DB) classicToLeaderboardEntry(ctx context.Context, entry *databaseClass
 
 		LastTime:   entry.LastTime,
 		FreezeTime: entry.FreezeTime,
+
+		Category: entry.Category,
 	}, nil
 }
 
@@ -248,17 +285,24 @@ func (s *DB) ContestClassicLeaderboard(ctx context.Context, contest *kilonova.Co
 
 		FreezeTime: freezeTime,
 		Type:       kilonova.LeaderboardTypeClassic,
+
+		Hacking:       contest.Hacking,
+		SystemTesting: contest.SystemTestStatus == kilonova.SystemTestRunning,
 	}
 	for _, pb := range pbs {
 		leaderboard.ProblemNames[pb.ID] = pb.Name
 	}
+	leaderboard.ProblemLabels, err = s.contestProblemLabels(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	var topList []*databaseClassicEntry
 
-	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual)
+	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual, false)
 	userFilterQuery(filter, fb)
 
-	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_top_view($1, $2, $3) WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY total_score DESC, last_time ASC NULLS LAST, user_id", fb.Args()...)
+	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time, "+leaderboardCategoryColumn+" FROM contest_top_view($1, $2, $3, $4) ranking WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY total_score DESC, last_time ASC NULLS LAST, user_id", fb.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -279,6 +323,8 @@ type databaseICPCEntry struct {
 	NumAttempts int `db:"num_attempts"`
 
 	FreezeTime *time.Time `db:"freeze_time"`
+
+	Category *string `db:"category"`
 }
 
 func (s *DB) icpcToLeaderboardEntry(ctx context.Context, entry *databaseICPCEntry) (*kilonova.LeaderboardEntry, error) {
@@ -327,6 +373,8 @@ func (s *DB) icpcToLeaderboardEntry(ctx context.Context, entry *databaseICPCEntr
 
 		LastTime:   entry.LastTime,
 		FreezeTime: entry.FreezeTime,
+
+		Category: entry.Category,
 	}, nil
 }
 
@@ -344,17 +392,23 @@ func (s *DB) ContestICPCLeaderboard(ctx context.Context, contest *kilonova.Conte
 
 		FreezeTime: freezeTime,
 		Type:       kilonova.LeaderboardTypeICPC,
+
+		SystemTesting: contest.SystemTestStatus == kilonova.SystemTestRunning,
 	}
 	for _, pb := range pbs {
 		leaderboard.ProblemNames[pb.ID] = pb.Name
 	}
+	leaderboard.ProblemLabels, err = s.contestProblemLabels(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
 
 	var topList []*databaseICPCEntry
 
-	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual)
+	fb := newFilterBuilderFromPos(contest.ID, freezeTime, contest.Type == kilonova.ContestTypeVirtual, false)
 	userFilterQuery(filter, fb)
 
-	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_icpc_view($1, $2, $3) WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY num_solved DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, user_id", fb.Args()...)
+	err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time, "+leaderboardCategoryColumn+" FROM contest_icpc_view($1, $2, $3, $4) ranking WHERE EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+") ORDER BY num_solved DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, user_id", fb.Args()...)
 	if err != nil {
 		zap.S().Warn(err)
 		return nil, err
@@ -387,8 +441,68 @@ func (s *DB) MossSubmissions(ctx context.Context, contestID int) ([]*kilonova.MO
 
 // Contest problems
 
+// UpdateContestProblems sets the ordered list of contest problems.
+// Unlike other many-to-many relations, the rows of the problems that remain in the contest are kept, so their settings aren't lost
 func (s *DB) UpdateContestProblems(ctx context.Context, contestID int, problems []int) error {
-	return s.updateManyToMany(ctx, "contest_problems", "contest_id", "problem_id", contestID, problems, true)
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_problems WHERE contest_id = $1 AND NOT (problem_id = ANY($2))", contestID, problems); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO contest_problems (contest_id, problem_id, position) 
+			SELECT $1, pbs.problem_id, pbs.position - 1 FROM unnest($2::bigint[]) WITH ORDINALITY AS pbs(problem_id, position)
+			ON CONFLICT (contest_id, problem_id) DO UPDATE SET position = EXCLUDED.position`, contestID, problems)
+		return err
+	})
+}
+
+func (s *DB) ContestProblemSettings(ctx context.Context, contestID int) ([]*kilonova.ContestProblemSettings, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_problems WHERE contest_id = $1 ORDER BY position ASC", contestID)
+	settings, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestProblemSettings])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.ContestProblemSettings{}, nil
+	}
+	return settings, err
+}
+
+func (s *DB) ContestProblemSetting(ctx context.Context, contestID, problemID int) (*kilonova.ContestProblemSettings, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_problems WHERE contest_id = $1 AND problem_id = $2", contestID, problemID)
+	settings, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.ContestProblemSettings])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return settings, err
+}
+
+// contestProblemLabels returns the labels explicitly set for the contest problems
+func (s *DB) contestProblemLabels(ctx context.Context, contestID int) (map[int]string, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT problem_id, label FROM contest_problems WHERE contest_id = $1 AND label <> ''", contestID)
+	labels := make(map[int]string)
+	var problemID int
+	var label string
+	_, err := pgx.ForEachRow(rows, []any{&problemID, &label}, func() error {
+		labels[problemID] = label
+		return nil
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return labels, nil
+}
+
+func (s *DB) UpdateContestProblemSettings(ctx context.Context, settings *kilonova.ContestProblemSettings) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_problems SET label = $3, points = $4, languages = $5, max_subs = $6 WHERE contest_id = $1 AND problem_id = $2",
+		settings.ContestID, settings.ProblemID, settings.Label, settings.Points, settings.Languages, settings.MaxSubs)
+	return err
+}
+
+func (s *DB) UpdateContestCategories(ctx context.Context, contestID int, categories []string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contests SET categories = $2 WHERE id = $1", contestID, categories)
+	return err
+}
+
+func (s *DB) UpdateContestIPRanges(ctx context.Context, contestID int, ranges []netip.Prefix) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contests SET allowed_ip_ranges = $2 WHERE id = $1", contestID, ranges)
+	return err
 }
 
 // Access rights
@@ -465,6 +579,36 @@ func contestUpdateQuery(upd *kilonova.ContestUpdate, ub *updateBuilder) {
 	if v := upd.Type; v != kilonova.ContestTypeNone {
 		ub.AddUpdate("type = %s", v)
 	}
+	if v := upd.FeedbackLevel; v != kilonova.FeedbackLevelNone {
+		ub.AddUpdate("feedback_level = %s", v)
+	}
+	if v := upd.FeedbackTokens; v != nil {
+		ub.AddUpdate("feedback_tokens = %s", v)
+	}
+	if v := upd.Pretests; v != nil {
+		ub.AddUpdate("pretests = %s", v)
+	}
+	if v := upd.Hacking; v != nil {
+		ub.AddUpdate("hacking = %s", v)
+	}
+	if v := upd.HackPoints; v != nil {
+		ub.AddUpdate("hack_points = %s", v)
+	}
+	if v := upd.HackPenalty; v != nil {
+		ub.AddUpdate("hack_penalty = %s", v)
+	}
+	if v := upd.TeamSize; v != nil {
+		ub.AddUpdate("team_size = %s", v)
+	}
+	if v := upd.SingleSession; v != nil {
+		ub.AddUpdate("single_session = %s", v)
+	}
+	if v := upd.Lockdown; v != nil {
+		ub.AddUpdate("lockdown = %s", v)
+	}
+	if v := upd.OnSite; v != nil {
+		ub.AddUpdate("on_site = %s", v)
+	}
 }
 
 func getContestOrdering(ordering string, ascending bool) string {
@@ -530,5 +674,27 @@ func (s *DB) internalToContest(ctx context.Context, contest *dbContest) (*kilono
 
 		Visible: contest.Visible,
 		Type:    contest.Type,
+
+		FeedbackLevel:  contest.FeedbackLevel,
+		FeedbackTokens: contest.FeedbackTokens,
+
+		Pretests:         contest.Pretests,
+		SystemTestStatus: contest.SystemTestStatus,
+
+		Hacking:     contest.Hacking,
+		HackPoints:  contest.HackPoints,
+		HackPenalty: contest.HackPenalty,
+
+		TeamSize: contest.TeamSize,
+
+		Categories: contest.Categories,
+
+		RatingsApplied: contest.RatingsApplied,
+
+		AllowedIPRanges: contest.AllowedIPRanges,
+		SingleSession:   contest.SingleSession,
+		Lockdown:        contest.Lockdown,
+
+		OnSite: contest.OnSite,
 	}, nil
 }
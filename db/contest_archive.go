@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+// Imports of contest archives. Unlike the regular creation functions, these keep the original timestamps
+
+func (s *DB) ImportContestRegistration(ctx context.Context, reg *kilonova.ContestRegistration) error {
+	_, err := s.conn.Exec(ctx, `
+INSERT INTO contest_registrations (created_at, user_id, contest_id, individual_start_at, individual_end_at, virtual, category, team_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT DO NOTHING`,
+		reg.CreatedAt, reg.UserID, reg.ContestID, reg.IndividualStartTime, reg.IndividualEndTime, reg.Virtual, reg.Category, reg.TeamID)
+	return err
+}
+
+func (s *DB) ImportContestAnnouncement(ctx context.Context, ann *kilonova.ContestAnnouncement) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, `INSERT INTO contest_announcements (contest_id, announcement, problem_id, created_at) VALUES ($1, $2, $3, $4) RETURNING id`, ann.ContestID, ann.Text, ann.ProblemID, ann.CreatedAt).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (s *DB) ImportContestQuestion(ctx context.Context, q *kilonova.ContestQuestion) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, `
+INSERT INTO contest_questions (author_id, contest_id, question, problem_id, created_at, responded_at, response, answered_by, announcement_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		q.AuthorID, q.ContestID, q.Text, q.ProblemID, q.AskedAt, q.ResponedAt, q.Response, q.AnsweredBy, q.AnnouncementID).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// ImportSubmission inserts an already evaluated submission.
+// The subtest and subtask results are matched to the problem's tests and subtasks by their visible IDs.
+// If the submission wasn't finished, it is left waiting, to be evaluated by the grader
+func (s *DB) ImportSubmission(ctx context.Context, sub *kilonova.Submission, code string, subTests []*kilonova.SubTest, subTasks []*kilonova.SubmissionSubTask) (int, error) {
+	if sub.UserID <= 0 || sub.ProblemID <= 0 || sub.Language == "" || code == "" {
+		return -1, kilonova.ErrMissingRequired
+	}
+	var id int
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, `
+INSERT INTO submissions (created_at, user_id, problem_id, contest_id, language, code, pretest_only, team_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+			sub.CreatedAt, sub.UserID, sub.ProblemID, sub.ContestID, sub.Language, code, sub.PretestOnly, sub.TeamID).Scan(&id); err != nil {
+			return err
+		}
+		if err := initSubs(ctx, tx, kilonova.SubmissionFilter{ID: &id}); err != nil {
+			return err
+		}
+		if sub.Status != kilonova.StatusFinished {
+			return nil
+		}
+
+		for _, st := range subTests {
+			if _, err := tx.Exec(ctx, `
+UPDATE submission_tests SET done = $3, skipped = $4, verdict = $5, time = $6, memory = $7, percentage = $8
+	WHERE submission_id = $1 AND visible_id = $2`,
+				id, st.VisibleID, st.Done, st.Skipped, st.Verdict, st.Time, st.Memory, st.Percentage); err != nil {
+				return err
+			}
+		}
+		for _, stk := range subTasks {
+			if _, err := tx.Exec(ctx, `UPDATE submission_subtasks SET final_percentage = $3 WHERE submission_id = $1 AND visible_id = $2`, id, stk.VisibleID, stk.FinalPercentage); err != nil {
+				return err
+			}
+		}
+
+		ub := newUpdateBuilder()
+		subUpdateQuery(&kilonova.SubmissionUpdate{
+			Status: kilonova.StatusFinished,
+			Score:  &sub.Score,
+
+			CompileError:   sub.CompileError,
+			CompileMessage: sub.CompileMessage,
+			CompileTime:    sub.CompileTime,
+
+			MaxTime:   &sub.MaxTime,
+			MaxMemory: &sub.MaxMemory,
+
+			ChangeVerdict: true,
+			ICPCVerdict:   sub.ICPCVerdict,
+		}, ub)
+		fb := ub.MakeFilter()
+		fb.AddConstraint("id = %s", id)
+		_, err := tx.Exec(ctx, `UPDATE submissions SET `+fb.WithUpdate(), fb.Args()...)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (s *DB) ImportContestTeam(ctx context.Context, team *kilonova.ContestTeam) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, "INSERT INTO contest_teams (created_at, contest_id, name, creator_id) VALUES ($1, $2, $3, $4) RETURNING id", team.CreatedAt, team.ContestID, team.Name, team.CreatorID).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (s *DB) ImportContestProblemLock(ctx context.Context, lock *kilonova.ContestProblemLock) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_problem_locks (created_at, contest_id, user_id, problem_id) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", lock.CreatedAt, lock.ContestID, lock.UserID, lock.ProblemID)
+	return err
+}
+
+// ImportHack inserts a hack along with its verdict. Hacks that weren't finished are left pending, to be judged by the grader
+func (s *DB) ImportHack(ctx context.Context, hack *kilonova.ContestHack, input []byte) (int, error) {
+	status := hack.Status
+	if status != kilonova.HackStatusFinished {
+		status = kilonova.HackStatusPending
+	}
+	var id int
+	err := s.conn.QueryRow(ctx, `
+INSERT INTO contest_hacks (created_at, contest_id, problem_id, hacker_id, target_user_id, target_submission_id, input, status, verdict, message, points, test_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id`,
+		hack.CreatedAt, hack.ContestID, hack.ProblemID, hack.HackerID, hack.TargetUserID, hack.TargetSubmissionID, input,
+		status, hack.Verdict, hack.Message, hack.Points, hack.TestID).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+// ImportPretestScores replaces the contest's snapshot of the leaderboard scores before system testing
+func (s *DB) ImportPretestScores(ctx context.Context, contestID int, scores []*kilonova.PretestScore) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_pretest_scores WHERE contest_id = $1", contestID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_team_pretest_scores WHERE contest_id = $1", contestID); err != nil {
+			return err
+		}
+		for _, score := range scores {
+			var err error
+			if score.TeamID != nil {
+				_, err = tx.Exec(ctx, "INSERT INTO contest_team_pretest_scores (contest_id, team_id, problem_id, frozen, score, mintime) VALUES ($1, $2, $3, $4, $5, $6)",
+					contestID, score.TeamID, score.ProblemID, score.Frozen, score.Score, score.MinTime)
+			} else {
+				_, err = tx.Exec(ctx, "INSERT INTO contest_pretest_scores (contest_id, user_id, problem_id, frozen, score, mintime) VALUES ($1, $2, $3, $4, $5, $6)",
+					contestID, score.UserID, score.ProblemID, score.Frozen, score.Score, score.MinTime)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *DB) ImportSystemTestStatus(ctx context.Context, contestID int, status kilonova.SystemTestStatus) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contests SET system_test_status = $2 WHERE id = $1", contestID, status)
+	return err
+}
@@ -0,0 +1,261 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+// Contest hacking phase: problem locks and hacks
+
+type dbContestHack struct {
+	ID        int       `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	ContestID int       `db:"contest_id"`
+	ProblemID int       `db:"problem_id"`
+
+	HackerID           int `db:"hacker_id"`
+	TargetUserID       int `db:"target_user_id"`
+	TargetSubmissionID int `db:"target_submission_id"`
+
+	Status  kilonova.HackStatus  `db:"status"`
+	Verdict kilonova.HackVerdict `db:"verdict"`
+	Message string               `db:"message"`
+	Points  int                  `db:"points"`
+
+	TestID *int `db:"test_id"`
+}
+
+// LockContestProblem locks the problem for the user. It returns false if it was already locked
+func (s *DB) LockContestProblem(ctx context.Context, contestID, userID, problemID int) (bool, error) {
+	tag, err := s.conn.Exec(ctx, "INSERT INTO contest_problem_locks (contest_id, user_id, problem_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", contestID, userID, problemID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *DB) ContestProblemLocked(ctx context.Context, contestID, userID, problemID int) (bool, error) {
+	var exists bool
+	err := s.conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM contest_problem_locks WHERE contest_id = $1 AND user_id = $2 AND problem_id = $3)", contestID, userID, problemID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return exists, nil
+}
+
+// LockedContestProblems returns the IDs of the problems the user locked in the contest
+func (s *DB) LockedContestProblems(ctx context.Context, contestID, userID int) ([]int, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT problem_id FROM contest_problem_locks WHERE contest_id = $1 AND user_id = $2 ORDER BY problem_id", contestID, userID)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []int{}
+	}
+	return ids, nil
+}
+
+// ContestProblemLocks returns the problems locked by all users in the contest
+func (s *DB) ContestProblemLocks(ctx context.Context, contestID int) ([]*kilonova.ContestProblemLock, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT created_at, contest_id, user_id, problem_id FROM contest_problem_locks WHERE contest_id = $1 ORDER BY created_at", contestID)
+	locks, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestProblemLock])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if locks == nil {
+		locks = []*kilonova.ContestProblemLock{}
+	}
+	return locks, nil
+}
+
+func (s *DB) CreateHack(ctx context.Context, hack *kilonova.ContestHack, input []byte) error {
+	var id int
+	err := s.conn.QueryRow(ctx, `INSERT INTO contest_hacks (contest_id, problem_id, hacker_id, target_user_id, target_submission_id, input)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		hack.ContestID, hack.ProblemID, hack.HackerID, hack.TargetUserID, hack.TargetSubmissionID, input,
+	).Scan(&id)
+	if err == nil {
+		hack.ID = id
+	}
+	return err
+}
+
+func (s *DB) Hacks(ctx context.Context, filter kilonova.HackFilter) ([]*kilonova.ContestHack, error) {
+	fb := newFilterBuilder()
+	hackFilterQuery(&filter, fb)
+
+	rows, _ := s.conn.Query(
+		ctx,
+		"SELECT id, created_at, contest_id, problem_id, hacker_id, target_user_id, target_submission_id, status, verdict, message, points, test_id FROM contest_hacks WHERE "+fb.Where()+" ORDER BY id DESC "+FormatLimitOffset(filter.Limit, filter.Offset),
+		fb.Args()...,
+	)
+	hacks, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContestHack])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.ContestHack{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapper(hacks, internalToHack), nil
+}
+
+func (s *DB) Hack(ctx context.Context, id int) (*kilonova.ContestHack, error) {
+	return toSingular(ctx, kilonova.HackFilter{ID: &id, Limit: 1}, s.Hacks)
+}
+
+func (s *DB) HackCount(ctx context.Context, filter kilonova.HackFilter) (int, error) {
+	fb := newFilterBuilder()
+	hackFilterQuery(&filter, fb)
+	var cnt int
+	err := s.conn.QueryRow(ctx, "SELECT COUNT(*) FROM contest_hacks WHERE "+fb.Where(), fb.Args()...).Scan(&cnt)
+	if err != nil {
+		return -1, err
+	}
+	return cnt, nil
+}
+
+func (s *DB) HackInput(ctx context.Context, id int) ([]byte, error) {
+	var input []byte
+	err := s.conn.QueryRow(ctx, "SELECT input FROM contest_hacks WHERE id = $1", id).Scan(&input)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return input, nil
+}
+
+// ClaimPendingHacks marks up to limit pending hacks as being worked on and returns them
+func (s *DB) ClaimPendingHacks(ctx context.Context, limit int) ([]*kilonova.ContestHack, error) {
+	rows, _ := s.conn.Query(ctx, `UPDATE contest_hacks SET status = 'working'
+		WHERE id IN (SELECT id FROM contest_hacks WHERE status = 'pending' ORDER BY id ASC LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING id, created_at, contest_id, problem_id, hacker_id, target_user_id, target_submission_id, status, verdict, message, points, test_id`, limit)
+	hacks, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContestHack])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.ContestHack{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapper(hacks, internalToHack), nil
+}
+
+// ResetWorkingHacks puts back in the queue the hacks left unfinished by the grader
+func (s *DB) ResetWorkingHacks(ctx context.Context) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_hacks SET status = 'pending' WHERE status = 'working'")
+	return err
+}
+
+func (s *DB) UpdateHack(ctx context.Context, id int, upd kilonova.HackUpdate) error {
+	ub := newUpdateBuilder()
+	if v := upd.Status; v != "" {
+		ub.AddUpdate("status = %s", v)
+	}
+	if v := upd.Verdict; v != "" {
+		ub.AddUpdate("verdict = %s", v)
+	}
+	if v := upd.Message; v != nil {
+		ub.AddUpdate("message = %s", v)
+	}
+	if v := upd.Points; v != nil {
+		ub.AddUpdate("points = %s", v)
+	}
+	if v := upd.TestID; v != nil {
+		ub.AddUpdate("test_id = %s", v)
+	}
+	if ub.CheckUpdates() != nil {
+		return ub.CheckUpdates()
+	}
+	fb := ub.MakeFilter()
+	fb.AddConstraint("id = %s", id)
+	_, err := s.conn.Exec(ctx, "UPDATE contest_hacks SET "+fb.WithUpdate(), fb.Args()...)
+	return err
+}
+
+// FinishHack records the hack's verdict and points.
+// Only one successful hack per submission counts, so if the target was already hacked, the hack is finished without a verdict or points.
+// It returns false in that case.
+func (s *DB) FinishHack(ctx context.Context, hack *kilonova.ContestHack, verdict kilonova.HackVerdict, message string, points int) (bool, error) {
+	counted := true
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if verdict == kilonova.HackVerdictSuccessful {
+			// Serialize the hacks on the same target
+			if _, err := tx.Exec(ctx, "SELECT 1 FROM submissions WHERE id = $1 FOR UPDATE", hack.TargetSubmissionID); err != nil {
+				return err
+			}
+			var hacked bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM contest_hacks WHERE target_submission_id = $1 AND verdict = 'successful' AND id <> $2)", hack.TargetSubmissionID, hack.ID).Scan(&hacked); err != nil {
+				return err
+			}
+			if hacked {
+				counted = false
+				verdict, message, points = kilonova.HackVerdictNone, "Submission was already hacked", 0
+			}
+		}
+		_, err := tx.Exec(ctx, "UPDATE contest_hacks SET status = 'finished', verdict = $2, message = $3, points = $4 WHERE id = $1", hack.ID, verdict, message, points)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return counted, nil
+}
+
+func hackFilterQuery(filter *kilonova.HackFilter, fb *filterBuilder) {
+	if v := filter.ID; v != nil {
+		fb.AddConstraint("id = %s", v)
+	}
+	if v := filter.ContestID; v != nil {
+		fb.AddConstraint("contest_id = %s", v)
+	}
+	if v := filter.ProblemID; v != nil {
+		fb.AddConstraint("problem_id = %s", v)
+	}
+	if v := filter.HackerID; v != nil {
+		fb.AddConstraint("hacker_id = %s", v)
+	}
+	if v := filter.TargetSubmissionID; v != nil {
+		fb.AddConstraint("target_submission_id = %s", v)
+	}
+	if v := filter.InvolvedUserID; v != nil {
+		fb.AddConstraint("(hacker_id = %s OR target_user_id = %s)", v, v)
+	}
+	if v := filter.Status; v != "" {
+		fb.AddConstraint("status = %s", v)
+	}
+	if v := filter.Verdict; v != "" {
+		fb.AddConstraint("verdict = %s", v)
+	}
+	if filter.Unfinished {
+		fb.AddConstraint("status <> 'finished'")
+	}
+}
+
+func internalToHack(hack *dbContestHack) *kilonova.ContestHack {
+	return &kilonova.ContestHack{
+		ID:        hack.ID,
+		CreatedAt: hack.CreatedAt,
+		ContestID: hack.ContestID,
+		ProblemID: hack.ProblemID,
+
+		HackerID:           hack.HackerID,
+		TargetUserID:       hack.TargetUserID,
+		TargetSubmissionID: hack.TargetSubmissionID,
+
+		Status:  hack.Status,
+		Verdict: hack.Verdict,
+		Message: hack.Message,
+		Points:  hack.Points,
+
+		TestID: hack.TestID,
+	}
+}
@@ -18,8 +18,16 @@ type dbContestQuestion struct {
 	Question  string    `db:"question"`
 	CreatedAt time.Time `db:"created_at"`
 
+	ProblemID *int `db:"problem_id"`
+
+	ClaimedBy *int       `db:"claimed_by"`
+	ClaimedAt *time.Time `db:"claimed_at"`
+
 	RespondedAt *time.Time `db:"responded_at"`
 	Response    *string    `db:"response"`
+	AnsweredBy  *int       `db:"answered_by"`
+
+	AnnouncementID *int `db:"announcement_id"`
 }
 
 type dbContestAnnouncement struct {
@@ -27,11 +35,13 @@ type dbContestAnnouncement struct {
 	ContestID    int       `db:"contest_id"`
 	Announcement string    `db:"announcement"`
 	CreatedAt    time.Time `db:"created_at"`
+
+	ProblemID *int `db:"problem_id"`
 }
 
-func (s *DB) CreateContestQuestion(ctx context.Context, contestID, authorID int, text string) (int, error) {
+func (s *DB) CreateContestQuestion(ctx context.Context, contestID, authorID int, text string, problemID *int) (int, error) {
 	var id int
-	err := s.conn.QueryRow(ctx, `INSERT INTO contest_questions (author_id, contest_id, question) VALUES ($1, $2, $3) RETURNING id`, authorID, contestID, text).Scan(&id)
+	err := s.conn.QueryRow(ctx, `INSERT INTO contest_questions (author_id, contest_id, question, problem_id) VALUES ($1, $2, $3, $4) RETURNING id`, authorID, contestID, text, problemID).Scan(&id)
 	if err != nil {
 		return -1, err
 	}
@@ -43,6 +53,11 @@ type QuestionFilter struct {
 	ContestID *int
 	AuthorID  *int
 
+	// Unanswered only returns the questions without a response
+	Unanswered bool
+	// AvailableTo only returns the questions that are unclaimed or claimed by the given judge
+	AvailableTo *int
+
 	Limit  int
 	Offset int
 }
@@ -58,6 +73,12 @@ func (s *DB) ContestQuestions(ctx context.Context, filter QuestionFilter) ([]*ki
 	if v := filter.AuthorID; v != nil {
 		fb.AddConstraint("author_id = %s", v)
 	}
+	if filter.Unanswered {
+		fb.AddConstraint("response IS NULL")
+	}
+	if v := filter.AvailableTo; v != nil {
+		fb.AddConstraint("(claimed_by IS NULL OR claimed_by = %s)", v)
+	}
 
 	rows, _ := s.conn.Query(
 		ctx,
@@ -79,14 +100,46 @@ func (s *DB) ContestQuestion(ctx context.Context, id int) (*kilonova.ContestQues
 	return toSingular(ctx, QuestionFilter{ID: &id, Limit: 1}, s.ContestQuestions)
 }
 
-func (s *DB) AnswerContestQuestion(ctx context.Context, questionID int, response string) error {
-	_, err := s.conn.Exec(ctx, "UPDATE contest_questions SET responded_at = NOW(), response = $1 WHERE id = $2", response, questionID)
+// AnswerContestQuestion sets the response of a question and releases its claim.
+// Unanswered questions are answered only if they aren't claimed by another judge.
+// Answered questions are updated only if their response is still prevResponse, so concurrent edits don't overwrite each other.
+// Returns false if the question wasn't updated
+func (s *DB) AnswerContestQuestion(ctx context.Context, questionID int, response string, answeredBy int, prevResponse *string) (bool, error) {
+	tag, err := s.conn.Exec(ctx, `UPDATE contest_questions 
+		SET responded_at = NOW(), response = $1, answered_by = $3, claimed_by = NULL, claimed_at = NULL 
+		WHERE id = $2 AND ((response IS NULL AND (claimed_by IS NULL OR claimed_by = $3)) OR (response IS NOT NULL AND response = $4))`, response, questionID, answeredBy, prevResponse)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetContestQuestionAnnouncement links the question to the clarification it was published as
+func (s *DB) SetContestQuestionAnnouncement(ctx context.Context, questionID int, announcementID int) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_questions SET announcement_id = $2 WHERE id = $1", questionID, announcementID)
 	return err
 }
 
-func (s *DB) CreateContestAnnouncement(ctx context.Context, contestID int, text string) (int, error) {
+// ClaimContestQuestion assigns an unanswered question to a judge.
+// Unless force is set, the question is claimed only if it's not already claimed by someone else.
+// Returns false if the question couldn't be claimed
+func (s *DB) ClaimContestQuestion(ctx context.Context, questionID int, judgeID int, force bool) (bool, error) {
+	tag, err := s.conn.Exec(ctx, `UPDATE contest_questions SET claimed_by = $2, claimed_at = NOW() 
+		WHERE id = $1 AND response IS NULL AND (claimed_by IS NULL OR claimed_by = $2 OR $3)`, questionID, judgeID, force)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *DB) UnclaimContestQuestion(ctx context.Context, questionID int) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_questions SET claimed_by = NULL, claimed_at = NULL WHERE id = $1", questionID)
+	return err
+}
+
+func (s *DB) CreateContestAnnouncement(ctx context.Context, contestID int, text string, problemID *int) (int, error) {
 	var id int
-	err := s.conn.QueryRow(ctx, `INSERT INTO contest_announcements (contest_id, announcement) VALUES ($1, $2) RETURNING id`, contestID, text).Scan(&id)
+	err := s.conn.QueryRow(ctx, `INSERT INTO contest_announcements (contest_id, announcement, problem_id) VALUES ($1, $2, $3) RETURNING id`, contestID, text, problemID).Scan(&id)
 	if err != nil {
 		return -1, err
 	}
@@ -130,8 +183,14 @@ func (s *DB) internalToContestQuestion(q *dbContestQuestion) *kilonova.ContestQu
 		AskedAt:    q.CreatedAt,
 		ContestID:  q.ContestID,
 		Text:       q.Question,
+		ProblemID:  q.ProblemID,
+		ClaimedBy:  q.ClaimedBy,
+		ClaimedAt:  q.ClaimedAt,
 		ResponedAt: q.RespondedAt,
 		Response:   q.Response,
+		AnsweredBy: q.AnsweredBy,
+
+		AnnouncementID: q.AnnouncementID,
 	}
 }
 
@@ -141,5 +200,6 @@ func (s *DB) internalToContestAnnouncement(ann *dbContestAnnouncement) *kilonova
 		CreatedAt: ann.CreatedAt,
 		ContestID: ann.ContestID,
 		Text:      ann.Announcement,
+		ProblemID: ann.ProblemID,
 	}
 }
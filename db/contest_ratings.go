@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+// ContestsAwaitingRating returns the ended official contests whose ratings weren't applied yet, in the order they ended.
+// Contests with queued submissions or unfinished system tests are skipped, since their final standings aren't known yet.
+func (s *DB) ContestsAwaitingRating(ctx context.Context) ([]*kilonova.Contest, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT * FROM contests
+		WHERE type = 'official' AND ratings_applied = false AND end_time < NOW()
+			AND (pretests = false OR system_test_status = 'finished') AND NOT `+pendingContestSubsConstraint+`
+		ORDER BY end_time ASC`)
+	contests, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.Contest{}, nil
+	} else if err != nil {
+		return []*kilonova.Contest{}, err
+	}
+
+	return mapperCtx(ctx, contests, s.internalToContest), nil
+}
+
+// RatingsBeforeContest returns the ratings the users had before the contest, as given by the rated contests that ended before it.
+// Users that weren't rated before the contest are missing from the map
+func (s *DB) RatingsBeforeContest(ctx context.Context, contest *kilonova.Contest, userIDs []int) (map[int]int, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT DISTINCT ON (rc.user_id) rc.user_id, rc.new_rating
+		FROM contest_rating_changes rc INNER JOIN contests ON contests.id = rc.contest_id
+		WHERE rc.user_id = ANY($1) AND rc.contest_id <> $2 AND contests.end_time <= $3
+		ORDER BY rc.user_id, contests.end_time DESC`, userIDs, contest.ID, contest.EndTime)
+	ratings := make(map[int]int)
+	var userID, rating int
+	_, err := pgx.ForEachRow(rows, []any{&userID, &rating}, func() error {
+		ratings[userID] = rating
+		return nil
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// SetContestRatingChanges replaces the rating changes of the contest and marks its ratings as applied.
+// The current rating of every affected user is then updated to the one after their latest rated contest
+func (s *DB) SetContestRatingChanges(ctx context.Context, contestID int, changes []*kilonova.RatingChange) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		rows, _ := tx.Query(ctx, "DELETE FROM contest_rating_changes WHERE contest_id = $1 RETURNING user_id", contestID)
+		userIDs, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"contest_rating_changes"}, []string{"contest_id", "user_id", "rank", "old_rating", "new_rating"}, pgx.CopyFromSlice(len(changes), func(i int) ([]any, error) {
+			return []any{contestID, changes[i].UserID, changes[i].Rank, changes[i].OldRating, changes[i].NewRating}, nil
+		})); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			userIDs = append(userIDs, change.UserID)
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE users SET rating = (
+			SELECT rc.new_rating FROM contest_rating_changes rc INNER JOIN contests ON contests.id = rc.contest_id
+				WHERE rc.user_id = users.id ORDER BY contests.end_time DESC LIMIT 1
+		) WHERE id = ANY($1)`, userIDs); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE contests SET ratings_applied = true WHERE id = $1", contestID)
+		return err
+	})
+}
+
+func (s *DB) ContestRatingChanges(ctx context.Context, contestID int) ([]*kilonova.RatingChange, error) {
+	var changes []*kilonova.RatingChange
+	err := Select(s.conn, ctx, &changes, `SELECT rc.*, contests.name AS contest_name, contests.end_time AS contest_end_time
+		FROM contest_rating_changes rc INNER JOIN contests ON contests.id = rc.contest_id
+		WHERE rc.contest_id = $1 ORDER BY rc.rank ASC, rc.user_id ASC`, contestID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.RatingChange{}, nil
+	}
+	return changes, err
+}
+
+// UserRatingHistory returns the rating changes of the user, in the order the contests ended
+func (s *DB) UserRatingHistory(ctx context.Context, userID int) ([]*kilonova.RatingChange, error) {
+	var changes []*kilonova.RatingChange
+	err := Select(s.conn, ctx, &changes, `SELECT rc.*, contests.name AS contest_name, contests.end_time AS contest_end_time
+		FROM contest_rating_changes rc INNER JOIN contests ON contests.id = rc.contest_id
+		WHERE rc.user_id = $1 ORDER BY contests.end_time ASC`, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.RatingChange{}, nil
+	}
+	return changes, err
+}
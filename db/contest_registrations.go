@@ -53,8 +53,13 @@ func (s *DB) ContestRegistration(ctx context.Context, contestID, userID int) (*k
 	return &reg, nil
 }
 
-func (s *DB) InsertContestRegistration(ctx context.Context, contestID, userID int, invitationID *string) error {
-	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, invitation_id) VALUES ($1, $2, $3)", userID, contestID, invitationID)
+func (s *DB) InsertContestRegistration(ctx context.Context, contestID, userID int, invitationID *string, category *string) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, invitation_id, category) VALUES ($1, $2, $3, $4)", userID, contestID, invitationID, category)
+	return err
+}
+
+func (s *DB) UpdateContestRegistrationCategory(ctx context.Context, contestID, userID int, category *string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_registrations SET category = $3 WHERE contest_id = $1 AND user_id = $2", contestID, userID, category)
 	return err
 }
 
@@ -97,8 +102,8 @@ func (s *DB) UpdateContestInvitation(ctx context.Context, id string, expired boo
 	return err
 }
 
-func (s *DB) CreateContestInvitation(ctx context.Context, contestID int, creatorID *int, maxUses *int) (string, error) {
+func (s *DB) CreateContestInvitation(ctx context.Context, contestID int, creatorID *int, maxUses *int, category *string) (string, error) {
 	id := kilonova.RandomString(12)
-	_, err := s.conn.Exec(ctx, "INSERT INTO contest_invitations (id, contest_id, creator_id, max_invitation_cnt) VALUES ($1, $2, $3, $4)", id, contestID, creatorID, maxUses)
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_invitations (id, contest_id, creator_id, max_invitation_cnt, category) VALUES ($1, $2, $3, $4, $5)", id, contestID, creatorID, maxUses, category)
 	return id, err
 }
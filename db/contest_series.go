@@ -0,0 +1,146 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+)
+
+type dbContestSeries struct {
+	ID          int       `db:"id"`
+	CreatedAt   time.Time `db:"created_at"`
+	AuthorID    int       `db:"author_id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	Visible     bool      `db:"visible"`
+
+	Aggregation kilonova.SeriesAggregation `db:"aggregation"`
+	BestK       int                        `db:"best_k"`
+}
+
+func (s *DB) CreateContestSeries(ctx context.Context, name string, authorID int) (int, error) {
+	if name == "" {
+		return -1, kilonova.ErrMissingRequired
+	}
+	var id int
+	err := s.conn.QueryRow(ctx, "INSERT INTO contest_series (name, author_id) VALUES ($1, $2) RETURNING id", name, authorID).Scan(&id)
+	return id, err
+}
+
+func (s *DB) ContestSeries(ctx context.Context, id int) (*kilonova.ContestSeries, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_series WHERE id = $1", id)
+	series, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[dbContestSeries])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.internalToContestSeries(ctx, series)
+}
+
+func (s *DB) ContestSeriesList(ctx context.Context, filter kilonova.ContestSeriesFilter) ([]*kilonova.ContestSeries, error) {
+	fb := newFilterBuilder()
+	if v := filter.ContestID; v != nil {
+		fb.AddConstraint("EXISTS (SELECT 1 FROM contest_series_rounds rounds WHERE rounds.series_id = contest_series.id AND rounds.contest_id = %s)", v)
+	}
+	if filter.Look && !filter.LookingUser.IsAdmin() {
+		var id int
+		if filter.LookingUser != nil {
+			id = filter.LookingUser.ID
+		}
+		fb.AddConstraint("(visible = true OR author_id = %s)", id)
+	}
+
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_series WHERE "+fb.Where()+" ORDER BY id DESC", fb.Args()...)
+	series, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContestSeries])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.ContestSeries{}, nil
+	} else if err != nil {
+		return []*kilonova.ContestSeries{}, err
+	}
+	return mapperCtx(ctx, series, s.internalToContestSeries), nil
+}
+
+func (s *DB) UpdateContestSeries(ctx context.Context, id int, upd kilonova.ContestSeriesUpdate) error {
+	ub := newUpdateBuilder()
+	if v := upd.Name; v != nil {
+		ub.AddUpdate("name = %s", v)
+	}
+	if v := upd.Description; v != nil {
+		ub.AddUpdate("description = %s", v)
+	}
+	if v := upd.Visible; v != nil {
+		ub.AddUpdate("visible = %s", v)
+	}
+	if v := upd.Aggregation; v != kilonova.SeriesAggregationNone {
+		ub.AddUpdate("aggregation = %s", v)
+	}
+	if v := upd.BestK; v != nil {
+		ub.AddUpdate("best_k = %s", v)
+	}
+	if ub.CheckUpdates() != nil {
+		return ub.CheckUpdates()
+	}
+	fb := ub.MakeFilter()
+	fb.AddConstraint("id = %s", id)
+	_, err := s.conn.Exec(ctx, "UPDATE contest_series SET "+fb.WithUpdate(), fb.Args()...)
+	return err
+}
+
+// UpdateSeriesRounds replaces the rounds of the series. The order of the slice is the order of the rounds
+func (s *DB) UpdateSeriesRounds(ctx context.Context, seriesID int, rounds []*kilonova.SeriesRound) error {
+	contestIDs := make([]int, 0, len(rounds))
+	weights := make([]decimal.Decimal, 0, len(rounds))
+	for _, round := range rounds {
+		contestIDs = append(contestIDs, round.ContestID)
+		weights = append(weights, round.Weight)
+	}
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_series_rounds WHERE series_id = $1", seriesID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO contest_series_rounds (series_id, contest_id, weight, position)
+			SELECT $1, rounds.contest_id, rounds.weight, rounds.position - 1 FROM unnest($2::bigint[], $3::numeric[]) WITH ORDINALITY AS rounds(contest_id, weight, position)`,
+			seriesID, contestIDs, weights)
+		return err
+	})
+}
+
+func (s *DB) DeleteContestSeries(ctx context.Context, id int) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM contest_series WHERE id = $1", id)
+	return err
+}
+
+func (s *DB) seriesRounds(ctx context.Context, seriesID int) ([]*kilonova.SeriesRound, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT contest_id, weight FROM contest_series_rounds WHERE series_id = $1 ORDER BY position ASC, contest_id ASC", seriesID)
+	rounds, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.SeriesRound])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.SeriesRound{}, nil
+	}
+	return rounds, err
+}
+
+func (s *DB) internalToContestSeries(ctx context.Context, series *dbContestSeries) (*kilonova.ContestSeries, error) {
+	rounds, err := s.seriesRounds(ctx, series.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &kilonova.ContestSeries{
+		ID:          series.ID,
+		CreatedAt:   series.CreatedAt,
+		AuthorID:    series.AuthorID,
+		Name:        series.Name,
+		Description: series.Description,
+		Visible:     series.Visible,
+
+		Aggregation: series.Aggregation,
+		BestK:       series.BestK,
+
+		Rounds: rounds,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+const pendingContestSubsConstraint = "EXISTS (SELECT 1 FROM submissions subs WHERE subs.contest_id = contests.id AND subs.status IN ('creating', 'waiting', 'working', 'reevaling'))"
+
+// ContestsAwaitingSystemTest returns the ended pretest contests whose system testing didn't start yet.
+// Contests with submissions still in the queue are skipped, since their pretest results aren't known yet.
+func (s *DB) ContestsAwaitingSystemTest(ctx context.Context) ([]*kilonova.Contest, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contests WHERE pretests = true AND system_test_status = 'none' AND end_time < NOW() AND NOT "+pendingContestSubsConstraint)
+	contests, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.Contest{}, nil
+	} else if err != nil {
+		return []*kilonova.Contest{}, err
+	}
+
+	return mapperCtx(ctx, contests, s.internalToContest), nil
+}
+
+// StartSystemTest snapshots the current leaderboard scores and queues the submissions accepted on pretests for reevaluation on all tests.
+// It returns the number of queued submissions, or -1 if system testing was already started.
+func (s *DB) StartSystemTest(ctx context.Context, contest *kilonova.Contest) (int, error) {
+	var cnt int = -1
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		var status kilonova.SystemTestStatus
+		if err := tx.QueryRow(ctx, "SELECT system_test_status FROM contests WHERE id = $1 FOR UPDATE", contest.ID).Scan(&status); err != nil {
+			return err
+		}
+		if status != kilonova.SystemTestNone {
+			return nil
+		}
+
+		// The snapshot must be taken before marking system testing as running, since contest_max_scores then returns the snapshot
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_pretest_scores WHERE contest_id = $1", contest.ID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO contest_pretest_scores (contest_id, user_id, problem_id, frozen, score, mintime) 
+			SELECT $1, user_id, problem_id, false, score, mintime FROM contest_max_scores($1, NULL)`, contest.ID); err != nil {
+			return err
+		}
+		if contest.LeaderboardFreeze != nil {
+			if _, err := tx.Exec(ctx, `INSERT INTO contest_pretest_scores (contest_id, user_id, problem_id, frozen, score, mintime) 
+				SELECT $1, user_id, problem_id, true, score, mintime FROM contest_max_scores($1, $2)`, contest.ID, contest.LeaderboardFreeze); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM contest_team_pretest_scores WHERE contest_id = $1", contest.ID); err != nil {
+			return err
+		}
+		if contest.TeamMode() {
+			if _, err := tx.Exec(ctx, `INSERT INTO contest_team_pretest_scores (contest_id, team_id, problem_id, frozen, score, mintime) 
+				SELECT $1, team_id, problem_id, false, score, mintime FROM contest_team_max_scores($1, NULL)`, contest.ID); err != nil {
+				return err
+			}
+			if contest.LeaderboardFreeze != nil {
+				if _, err := tx.Exec(ctx, `INSERT INTO contest_team_pretest_scores (contest_id, team_id, problem_id, frozen, score, mintime) 
+					SELECT $1, team_id, problem_id, true, score, mintime FROM contest_team_max_scores($1, $2)`, contest.ID, contest.LeaderboardFreeze); err != nil {
+					return err
+				}
+			}
+		}
+
+		if _, err := tx.Exec(ctx, "UPDATE contests SET system_test_status = 'running' WHERE id = $1", contest.ID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `UPDATE submissions SET pretest_only = false, status = 'reevaling' 
+			WHERE contest_id = $1 AND pretest_only = true AND status = 'finished' AND compile_error IS NOT TRUE
+				AND NOT EXISTS (SELECT 1 FROM submission_tests sts WHERE sts.submission_id = submissions.id AND sts.percentage < 100)`, contest.ID)
+		if err != nil {
+			return err
+		}
+		cnt = int(tag.RowsAffected())
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+	return cnt, nil
+}
+
+// FinishSystemTests marks as finished the system tests of contests that have no more queued submissions.
+// It returns the IDs of the affected contests
+func (s *DB) FinishSystemTests(ctx context.Context) ([]int, error) {
+	rows, _ := s.conn.Query(ctx, "UPDATE contests SET system_test_status = 'finished' WHERE system_test_status = 'running' AND NOT "+pendingContestSubsConstraint+" RETURNING id")
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []int{}, nil
+		}
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ContestPretestScores returns the leaderboard scores snapshotted when system testing started, for both users and teams
+func (s *DB) ContestPretestScores(ctx context.Context, contestID int) ([]*kilonova.PretestScore, error) {
+	rows, _ := s.conn.Query(ctx, `
+SELECT user_id, NULL::bigint AS team_id, problem_id, frozen, score, mintime FROM contest_pretest_scores WHERE contest_id = $1
+UNION ALL
+SELECT NULL::bigint AS user_id, team_id, problem_id, frozen, score, mintime FROM contest_team_pretest_scores WHERE contest_id = $1`, contestID)
+	scores, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.PretestScore])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if scores == nil {
+		scores = []*kilonova.PretestScore{}
+	}
+	return scores, nil
+}
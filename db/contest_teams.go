@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Contest teams
+
+func (s *DB) CreateContestTeam(ctx context.Context, contestID int, name string, creatorID *int) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, "INSERT INTO contest_teams (contest_id, name, creator_id) VALUES ($1, $2, $3) RETURNING id", contestID, name, creatorID).Scan(&id)
+	return id, err
+}
+
+func (s *DB) ContestTeam(ctx context.Context, id int) (*kilonova.ContestTeam, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_teams WHERE id = $1 LIMIT 1", id)
+	team, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.ContestTeam])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.fillTeamMembers(ctx, team)
+}
+
+func (s *DB) ContestTeams(ctx context.Context, contestID int) ([]*kilonova.ContestTeam, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_teams WHERE contest_id = $1 ORDER BY name ASC", contestID)
+	teams, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestTeam])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []*kilonova.ContestTeam{}, nil
+		}
+		return nil, err
+	}
+	return mapperCtx(ctx, teams, s.fillTeamMembers), nil
+}
+
+func (s *DB) UpdateContestTeamName(ctx context.Context, id int, name string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE contest_teams SET name = $2 WHERE id = $1", id, name)
+	return err
+}
+
+func (s *DB) DeleteContestTeam(ctx context.Context, id int) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM contest_teams WHERE id = $1", id)
+	return err
+}
+
+// JoinContestTeam registers the user as a member of the team, unless the team already has maxSize members.
+// The team row is locked, so concurrent joins can't go over the limit. It returns false if the team is full
+func (s *DB) JoinContestTeam(ctx context.Context, contestID, userID, teamID int, invitationID *string, maxSize int) (bool, error) {
+	joined := false
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SELECT 1 FROM contest_teams WHERE id = $1 FOR UPDATE", teamID); err != nil {
+			return err
+		}
+		var cnt int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM contest_registrations WHERE team_id = $1", teamID).Scan(&cnt); err != nil {
+			return err
+		}
+		if maxSize > 0 && cnt >= maxSize {
+			return nil
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, team_id, invitation_id) VALUES ($1, $2, $3, $4)", userID, contestID, teamID, invitationID); err != nil {
+			return err
+		}
+		joined = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return joined, nil
+}
+
+func (s *DB) InsertTeamRegistration(ctx context.Context, contestID, userID, teamID int, invitationID *string) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, team_id, invitation_id) VALUES ($1, $2, $3, $4)", userID, contestID, teamID, invitationID)
+	return err
+}
+
+func (s *DB) CreateTeamInvitation(ctx context.Context, contestID int, teamID int, creatorID *int, maxUses *int) (string, error) {
+	id := kilonova.RandomString(12)
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_invitations (id, contest_id, team_id, creator_id, max_invitation_cnt) VALUES ($1, $2, $3, $4, $5)", id, contestID, teamID, creatorID, maxUses)
+	return id, err
+}
+
+func (s *DB) TeamInvitations(ctx context.Context, teamID int) ([]*kilonova.ContestInvitation, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT *, (SELECT COUNT(*) FROM contest_registrations WHERE invitation_id = inv.id) AS redeem_cnt FROM contest_invitations inv WHERE team_id = $1 ORDER BY expired ASC, created_at DESC", teamID)
+	invitations, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestInvitation])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []*kilonova.ContestInvitation{}, nil
+		}
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s *DB) fillTeamMembers(ctx context.Context, team *kilonova.ContestTeam) (*kilonova.ContestTeam, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT users.* FROM users INNER JOIN contest_registrations regs ON regs.user_id = users.id WHERE regs.team_id = $1 ORDER BY regs.created_at ASC", team.ID)
+	users, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[User])
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	team.Members = mapper(users, toUserBrief)
+	return team, nil
+}
+
+// Team leaderboard
+
+type databaseTeamClassicEntry struct {
+	TeamID    int             `db:"team_id"`
+	ContestID int             `db:"contest_id"`
+	Total     decimal.Decimal `db:"total_score"`
+	LastTime  *time.Time      `db:"last_time"`
+
+	FreezeTime *time.Time `db:"freeze_time"`
+}
+
+type databaseTeamICPCEntry struct {
+	TeamID    int        `db:"team_id"`
+	ContestID int        `db:"contest_id"`
+	LastTime  *time.Time `db:"last_time"`
+	NumSolved int        `db:"num_solved"`
+	Penalty   int        `db:"penalty"`
+
+	NumAttempts int `db:"num_attempts"`
+
+	FreezeTime *time.Time `db:"freeze_time"`
+}
+
+func (s *DB) teamClassicToLeaderboardEntry(ctx context.Context, entry *databaseTeamClassicEntry) (*kilonova.LeaderboardEntry, error) {
+	team, err := s.ContestTeam(ctx, entry.TeamID)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	rows, _ := s.conn.Query(ctx, "SELECT problem_id, score FROM contest_team_max_scores($2, $3) WHERE team_id = $1", entry.TeamID, entry.ContestID, entry.FreezeTime)
+	pbs, err := pgx.CollectRows(rows, pgx.RowToStructByName[struct {
+		ProblemID int             `db:"problem_id"`
+		Score     decimal.Decimal `db:"score"`
+	}])
+	if err != nil {
+		return nil, err
+	}
+
+	var numSolved int
+	scores := make(map[int]decimal.Decimal)
+	for _, pb := range pbs {
+		scores[pb.ProblemID] = pb.Score
+		if pb.Score.Equal(decimal.NewFromInt(100)) {
+			numSolved++
+		}
+	}
+
+	return &kilonova.LeaderboardEntry{
+		Team:          team,
+		TotalScore:    entry.Total,
+		ProblemScores: scores,
+
+		ProblemAttempts: make(map[int]int),
+		Penalty:         0,
+		NumSolved:       numSolved,
+		ProblemTimes:    make(map[int]float64),
+
+		LastTime:   entry.LastTime,
+		FreezeTime: entry.FreezeTime,
+	}, nil
+}
+
+func (s *DB) teamICPCToLeaderboardEntry(ctx context.Context, entry *databaseTeamICPCEntry) (*kilonova.LeaderboardEntry, error) {
+	team, err := s.ContestTeam(ctx, entry.TeamID)
+	if err != nil || team == nil {
+		return nil, err
+	}
+
+	rows, _ := s.conn.Query(ctx, `
+		SELECT problem_id, score, mintime, COALESCE(natts.num_atts, 0) AS num_attempts
+			FROM contest_team_max_scores($2, $3) cms,
+			LATERAL (SELECT COUNT(*) AS num_atts FROM submissions
+				WHERE contest_id = $2 AND team_id = $1 AND (status = 'finished' OR status = 'reevaling') AND problem_id = cms.problem_id AND created_at <= COALESCE($3, NOW()) AND (cms.score < 100 OR created_at < cms.mintime)) natts
+		WHERE team_id = $1
+`, entry.TeamID, entry.ContestID, entry.FreezeTime)
+	pbs, err := pgx.CollectRows(rows, pgx.RowToStructByName[struct {
+		ProblemID int             `db:"problem_id"`
+		Score     decimal.Decimal `db:"score"`
+		MinTime   *time.Time      `db:"mintime"`
+		Attempts  int             `db:"num_attempts"`
+	}])
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int]decimal.Decimal)
+	times := make(map[int]float64)
+	attempts := make(map[int]int)
+	for _, pb := range pbs {
+		scores[pb.ProblemID] = pb.Score
+		if pb.MinTime != nil && util.ContestContext(ctx) != nil {
+			times[pb.ProblemID] = pb.MinTime.Sub(util.ContestContext(ctx).StartTime).Minutes()
+		}
+		attempts[pb.ProblemID] = pb.Attempts
+	}
+
+	return &kilonova.LeaderboardEntry{
+		Team:          team,
+		TotalScore:    decimal.Zero,
+		ProblemScores: scores,
+
+		ProblemAttempts: attempts,
+		ProblemTimes:    times,
+		Penalty:         entry.Penalty,
+		NumSolved:       entry.NumSolved,
+
+		LastTime:   entry.LastTime,
+		FreezeTime: entry.FreezeTime,
+	}, nil
+}
+
+// ContestTeamLeaderboard returns the leaderboard of a team contest, with one entry per team.
+// The user filter is satisfied if any of the team's members match it
+func (s *DB) ContestTeamLeaderboard(ctx context.Context, contest *kilonova.Contest, freezeTime *time.Time, filter *kilonova.UserFilter) (*kilonova.ContestLeaderboard, error) {
+	pbs, err := s.ContestProblems(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	leaderboard := &kilonova.ContestLeaderboard{
+		ProblemOrder: mapper(pbs, func(pb *kilonova.Problem) int { return pb.ID }),
+		ProblemNames: make(map[int]string),
+
+		AdvancedFilter: contest.LeaderboardAdvancedFilter,
+
+		FreezeTime: freezeTime,
+		Type:       contest.LeaderboardStyle,
+
+		SystemTesting: contest.SystemTestStatus == kilonova.SystemTestRunning,
+	}
+	for _, pb := range pbs {
+		leaderboard.ProblemNames[pb.ID] = pb.Name
+	}
+	leaderboard.ProblemLabels, err = s.contestProblemLabels(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	fb := newFilterBuilderFromPos(contest.ID, freezeTime)
+	userFilterQuery(filter, fb)
+	memberFilter := "EXISTS (SELECT 1 FROM contest_registrations regs WHERE regs.team_id = ranking.team_id AND EXISTS (SELECT 1 FROM users WHERE regs.user_id = users.id AND " + fb.Where() + "))"
+
+	switch contest.LeaderboardStyle {
+	case kilonova.LeaderboardTypeClassic:
+		var topList []*databaseTeamClassicEntry
+		err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_team_top_view($1, $2) ranking WHERE "+memberFilter+" ORDER BY total_score DESC, last_time ASC NULLS LAST, team_id", fb.Args()...)
+		if err != nil {
+			return nil, err
+		}
+		leaderboard.Entries = mapperCtx(ctx, topList, s.teamClassicToLeaderboardEntry)
+	case kilonova.LeaderboardTypeICPC:
+		var topList []*databaseTeamICPCEntry
+		err = Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_team_icpc_view($1, $2) ranking WHERE "+memberFilter+" ORDER BY num_solved DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, team_id", fb.Args()...)
+		if err != nil {
+			zap.S().Warn(err)
+			return nil, err
+		}
+		leaderboard.Entries = mapperCtx(context.WithValue(ctx, util.ContestKey, contest), topList, s.teamICPCToLeaderboardEntry)
+	default:
+		return nil, errors.New("invalid leaderboard type")
+	}
+
+	return leaderboard, nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/jackc/pgx/v5"
+)
+
+// Virtual participation in ended contests
+
+func (s *DB) InsertVirtualRegistration(ctx context.Context, contestID, userID int, startTime time.Time, endTime time.Time) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO contest_registrations (user_id, contest_id, individual_start_at, individual_end_at, virtual) VALUES ($1, $2, $3, $4, true)", userID, contestID, startTime, endTime)
+	return err
+}
+
+func (s *DB) VirtualRegistrations(ctx context.Context, contestID int) ([]*kilonova.ContestRegistration, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM contest_registrations WHERE contest_id = $1 AND virtual = true AND individual_start_at IS NOT NULL ORDER BY created_at ASC", contestID)
+	regs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ContestRegistration])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []*kilonova.ContestRegistration{}, nil
+		}
+		return nil, err
+	}
+	return regs, nil
+}
+
+// ContestVirtualEntry returns the leaderboard entry of a virtual participant, as it was after the given time elapsed from the start of their run.
+// All times in the entry are converted to the equivalent times in the official contest. It returns nil if the user is filtered out.
+func (s *DB) ContestVirtualEntry(ctx context.Context, contest *kilonova.Contest, reg *kilonova.ContestRegistration, elapsed time.Duration, filter *kilonova.UserFilter) (*kilonova.LeaderboardEntry, error) {
+	if !reg.Virtual || reg.IndividualStartTime == nil {
+		return nil, errors.New("registration is not a started virtual participation")
+	}
+	cutoff := reg.IndividualStartTime.Add(elapsed)
+	if reg.IndividualEndTime != nil && cutoff.After(*reg.IndividualEndTime) {
+		cutoff = *reg.IndividualEndTime
+	}
+
+	fb := newFilterBuilderFromPos(contest.ID, cutoff, false, true, reg.UserID)
+	userFilterQuery(filter, fb)
+
+	var entry *kilonova.LeaderboardEntry
+	switch contest.LeaderboardStyle {
+	case kilonova.LeaderboardTypeClassic:
+		var topList []*databaseClassicEntry
+		err := Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_top_view($1, $2, $3, $4) WHERE user_id = $5 AND EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+")", fb.Args()...)
+		if err != nil || len(topList) == 0 {
+			return nil, err
+		}
+		entry, err = s.classicToLeaderboardEntry(ctx, topList[0])
+		if err != nil {
+			return nil, err
+		}
+	case kilonova.LeaderboardTypeICPC:
+		var topList []*databaseICPCEntry
+		err := Select(s.conn, ctx, &topList, "SELECT *, $2 AS freeze_time FROM contest_icpc_view($1, $2, $3, $4) WHERE user_id = $5 AND EXISTS (SELECT 1 FROM users WHERE user_id = users.id AND "+fb.Where()+")", fb.Args()...)
+		if err != nil || len(topList) == 0 {
+			return nil, err
+		}
+		entry, err = s.icpcToLeaderboardEntry(context.WithValue(ctx, util.ContestKey, contest), topList[0])
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("invalid leaderboard type")
+	}
+
+	offset := reg.IndividualStartTime.Sub(contest.StartTime)
+	if entry.LastTime != nil {
+		t := entry.LastTime.Add(-offset)
+		entry.LastTime = &t
+	}
+	for pbID, mins := range entry.ProblemTimes {
+		entry.ProblemTimes[pbID] = mins - offset.Minutes()
+	}
+	freeze := contest.StartTime.Add(cutoff.Sub(*reg.IndividualStartTime))
+	entry.FreezeTime = &freeze
+	entry.Virtual = true
+	return entry, nil
+}
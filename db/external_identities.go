@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+type ExternalLoginState struct {
+	ID        string    `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	Provider  string    `db:"provider"`
+	// UserID is set when the user is linking the identity to their account, instead of signing in
+	UserID       *int   `db:"user_id"`
+	Nonce        string `db:"nonce"`
+	CodeVerifier string `db:"code_verifier"`
+	Back         string `db:"back"`
+}
+
+const externalIdentityFields = "provider, subject, user_id, email, created_at, last_login_at"
+
+// ExternalIdentity returns the identity with the given subject at the provider, or nil if it isn't linked to any user
+func (s *DB) ExternalIdentity(ctx context.Context, provider, subject string) (*kilonova.ExternalIdentity, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT "+externalIdentityFields+" FROM external_identities WHERE provider = $1 AND subject = $2", provider, subject)
+	identity, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.ExternalIdentity])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return identity, err
+}
+
+func (s *DB) ExternalIdentities(ctx context.Context, userID int) ([]*kilonova.ExternalIdentity, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT "+externalIdentityFields+" FROM external_identities WHERE user_id = $1 ORDER BY provider", userID)
+	identities, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.ExternalIdentity])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.ExternalIdentity{}, nil
+	}
+	return identities, err
+}
+
+func (s *DB) CreateExternalIdentity(ctx context.Context, identity *kilonova.ExternalIdentity) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO external_identities (provider, subject, user_id, email, last_login_at) VALUES ($1, $2, $3, $4, NOW())",
+		identity.Provider, identity.Subject, identity.UserID, identity.Email)
+	return err
+}
+
+// TouchExternalIdentity records a sign in with the identity, also updating the email reported by the provider
+func (s *DB) TouchExternalIdentity(ctx context.Context, provider, subject string, email *string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE external_identities SET last_login_at = NOW(), email = $3 WHERE provider = $1 AND subject = $2", provider, subject, email)
+	return err
+}
+
+func (s *DB) RemoveExternalIdentity(ctx context.Context, userID int, provider string) (bool, error) {
+	tag, err := s.conn.Exec(ctx, "DELETE FROM external_identities WHERE user_id = $1 AND provider = $2", userID, provider)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *DB) CreateExternalLoginState(ctx context.Context, state *ExternalLoginState) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO external_login_states (id, provider, user_id, nonce, code_verifier, back) VALUES ($1, $2, $3, $4, $5, $6)",
+		state.ID, state.Provider, state.UserID, state.Nonce, state.CodeVerifier, state.Back)
+	return err
+}
+
+// ConsumeExternalLoginState removes the state and returns it, or nil if it doesn't exist
+func (s *DB) ConsumeExternalLoginState(ctx context.Context, id string) (*ExternalLoginState, error) {
+	rows, _ := s.conn.Query(ctx, "DELETE FROM external_login_states WHERE id = $1 RETURNING id, created_at, provider, user_id, nonce, code_verifier, back", id)
+	state, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[ExternalLoginState])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return state, err
+}
+
+// RemoveStaleExternalLoginStates cleans up the sign in attempts that were never finished
+func (s *DB) RemoveStaleExternalLoginStates(ctx context.Context, before time.Time) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM external_login_states WHERE created_at < $1", before)
+	return err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UseFeedbackToken spends one of the user's feedback tokens on the given submission.
+// It returns false if the user had no tokens left, isn't registered or if the submission was already revealed.
+// The contest registration row is locked, so concurrent requests can't spend more than maxTokens.
+func (s *DB) UseFeedbackToken(ctx context.Context, subID, userID, contestID, problemID, maxTokens int) (bool, error) {
+	var used bool
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		var cnt int
+		if err := tx.QueryRow(ctx, "SELECT 1 FROM contest_registrations WHERE contest_id = $1 AND user_id = $2 FOR UPDATE", contestID, userID).Scan(&cnt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return err
+		}
+		tag, err := tx.Exec(ctx, `INSERT INTO submission_feedback_tokens (submission_id, user_id, contest_id, problem_id) 
+			SELECT $1, $2, $3, $4 
+				WHERE (SELECT COUNT(*) FROM submission_feedback_tokens WHERE contest_id = $3 AND user_id = $2 AND problem_id = $4) < $5
+			ON CONFLICT DO NOTHING`, subID, userID, contestID, problemID, maxTokens)
+		if err != nil {
+			return err
+		}
+		used = tag.RowsAffected() > 0
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
+func (s *DB) UsedFeedbackTokens(ctx context.Context, userID, contestID, problemID int) (int, error) {
+	var cnt int
+	err := s.conn.QueryRow(ctx, "SELECT COUNT(*) FROM submission_feedback_tokens WHERE contest_id = $1 AND user_id = $2 AND problem_id = $3", contestID, userID, problemID).Scan(&cnt)
+	if err != nil {
+		return -1, err
+	}
+	return cnt, nil
+}
+
+func (s *DB) FeedbackTokenUsed(ctx context.Context, subID int) (bool, error) {
+	var exists bool
+	err := s.conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM submission_feedback_tokens WHERE submission_id = $1)", subID).Scan(&exists)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return exists, nil
+}
@@ -38,6 +38,116 @@ var migrations = []migration{
 		name:    "Discord Avatar as main",
 		handler: runFile("003.use_discord_avatar.sql"),
 	},
+	{
+		id:      4,
+		name:    "Submission internal error retries",
+		handler: runFile("004.submission_retries.sql"),
+	},
+	{
+		id:      5,
+		name:    "Contest feedback tokens",
+		handler: runFile("005.contest_feedback.sql"),
+	},
+	{
+		id:      6,
+		name:    "Contest pretests",
+		handler: runFile("006.pretests.sql"),
+	},
+	{
+		id:      7,
+		name:    "Contest hacks",
+		handler: runFile("007.contest_hacks.sql"),
+	},
+	{
+		id:      8,
+		name:    "Virtual participation",
+		handler: runFile("008.virtual_participation.sql"),
+	},
+	{
+		id:      9,
+		name:    "Contest teams",
+		handler: runFile("009.contest_teams.sql"),
+	},
+	{
+		id:      10,
+		name:    "Contest categories",
+		handler: runFile("010.contest_categories.sql"),
+	},
+	{
+		id:      11,
+		name:    "Ratings",
+		handler: runFile("011.ratings.sql"),
+	},
+	{
+		id:      12,
+		name:    "Contest problem settings",
+		handler: runFile("012.contest_problem_settings.sql"),
+	},
+	{
+		id:      13,
+		name:    "Contest clarifications",
+		handler: runFile("013.contest_clarifications.sql"),
+	},
+	{
+		id:      14,
+		name:    "Contest access control",
+		handler: runFile("014.contest_access.sql"),
+	},
+	{
+		id:      15,
+		name:    "Contest series",
+		handler: runFile("015.contest_series.sql"),
+	},
+	{
+		id:      16,
+		name:    "Calendar feed tokens",
+		handler: runFile("016.calendar_tokens.sql"),
+	},
+	{
+		id:      17,
+		name:    "Email notifications",
+		handler: runFile("017.notifications.sql"),
+	},
+	{
+		id:      18,
+		name:    "Contest certificates",
+		handler: runFile("018.certificates.sql"),
+	},
+	{
+		id:      19,
+		name:    "On-site contests",
+		handler: runFile("019.onsite.sql"),
+	},
+	{
+		id:      20,
+		name:    "API tokens",
+		handler: runFile("020.api_tokens.sql"),
+	},
+	{
+		id:      21,
+		name:    "OAuth provider",
+		handler: runFile("021.oauth.sql"),
+	},
+	{
+		id:      22,
+		name:    "External identities",
+		handler: runFile("022.external_identities.sql"),
+	},
+	{
+		id:      23,
+		name:    "Unique successful hacks",
+		handler: runFile("023.unique_successful_hacks.sql"),
+	},
+	{
+		id:      24,
+		name:    "Team pretest scores",
+		handler: runFile("024.team_pretest_scores.sql"),
+	},
+	{
+		id:      25,
+		name:    "API token usage",
+		handler: runFile("025.api_token_usage.sql"),
+	},
 }
 
 var specialMigrations = []migration{
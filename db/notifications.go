@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+// NotificationPreferences returns the user's notification preferences. Users that never changed them have all notifications disabled
+func (s *DB) NotificationPreferences(ctx context.Context, userID int) (*kilonova.NotificationPreferences, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT contest_reminders, question_answers, contest_results, reminder_hours FROM notification_preferences WHERE user_id = $1", userID)
+	prefs, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.NotificationPreferences])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &kilonova.NotificationPreferences{ReminderHours: 24}, nil
+	}
+	return prefs, err
+}
+
+func (s *DB) SetNotificationPreferences(ctx context.Context, userID int, prefs *kilonova.NotificationPreferences) error {
+	_, err := s.conn.Exec(ctx, `INSERT INTO notification_preferences (user_id, contest_reminders, question_answers, contest_results, reminder_hours) VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (user_id) DO UPDATE SET contest_reminders = EXCLUDED.contest_reminders, question_answers = EXCLUDED.question_answers, 
+		contest_results = EXCLUDED.contest_results, reminder_hours = EXCLUDED.reminder_hours`,
+		userID, prefs.ContestReminders, prefs.QuestionAnswers, prefs.ContestResults, prefs.ReminderHours)
+	return err
+}
+
+type QueuedMail struct {
+	ID        int       `db:"id"`
+	CreatedAt time.Time `db:"created_at"`
+	UserID    int       `db:"user_id"`
+
+	Email   string `db:"email"`
+	Subject string `db:"subject"`
+	Content string `db:"content"`
+
+	Attempts int `db:"attempts"`
+}
+
+// EnqueueMail adds the email to the sending queue. If an email with the same (non-empty) dedupe key was already queued, nothing happens
+func (s *DB) EnqueueMail(ctx context.Context, userID int, email, subject, content string, dedupeKey string) error {
+	_, err := s.conn.Exec(ctx, `INSERT INTO mail_queue (user_id, email, subject, content, dedupe_key) VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	ON CONFLICT (dedupe_key) DO NOTHING`, userID, email, subject, content, dedupeKey)
+	return err
+}
+
+// PendingMails returns the oldest unsent emails that didn't fail more than maxAttempts times
+func (s *DB) PendingMails(ctx context.Context, maxAttempts int, limit int) ([]*QueuedMail, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT id, created_at, user_id, email, subject, content, attempts FROM mail_queue 
+		WHERE sent_at IS NULL AND attempts < $1 ORDER BY created_at ASC, id ASC LIMIT $2`, maxAttempts, limit)
+	mails, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[QueuedMail])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*QueuedMail{}, nil
+	}
+	return mails, err
+}
+
+func (s *DB) MarkMailSent(ctx context.Context, id int) error {
+	_, err := s.conn.Exec(ctx, "UPDATE mail_queue SET sent_at = NOW(), attempts = attempts + 1 WHERE id = $1", id)
+	return err
+}
+
+func (s *DB) MarkMailFailed(ctx context.Context, id int) error {
+	_, err := s.conn.Exec(ctx, "UPDATE mail_queue SET attempts = attempts + 1 WHERE id = $1", id)
+	return err
+}
+
+// ReminderTarget is a registration that should receive a contest reminder
+type ReminderTarget struct {
+	ContestID int `db:"contest_id"`
+	UserID    int `db:"user_id"`
+}
+
+// ContestReminderTargets returns the registrations of users that opted into contest reminders, for the visible contests starting within their reminder interval.
+// Virtual registrations are not included, since they're made after the contest ended
+func (s *DB) ContestReminderTargets(ctx context.Context) ([]*ReminderTarget, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT regs.contest_id, regs.user_id 
+		FROM contest_registrations regs 
+			INNER JOIN contests ON contests.id = regs.contest_id
+			INNER JOIN notification_preferences prefs ON prefs.user_id = regs.user_id
+			INNER JOIN users ON users.id = regs.user_id
+		WHERE prefs.contest_reminders = true AND regs.virtual = false AND contests.visible = true AND users.verified_email = true
+			AND contests.start_time > NOW() AND contests.start_time <= NOW() + make_interval(hours => prefs.reminder_hours)`)
+	targets, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[ReminderTarget])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*ReminderTarget{}, nil
+	}
+	return targets, err
+}
+
+// ContestsAwaitingResultNotifications returns the visible contests that recently ended and whose final standings are public:
+// the leaderboard isn't frozen anymore, system testing finished and there are no submissions left in the queue.
+// Contests whose result emails were already queued are skipped
+func (s *DB) ContestsAwaitingResultNotifications(ctx context.Context, maxAge time.Duration) ([]*kilonova.Contest, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT * FROM contests
+		WHERE visible = true AND public_leaderboard = true AND end_time < NOW() AND end_time > $1
+			AND (leaderboard_freeze_time IS NULL OR leaderboard_freeze_time >= end_time)
+			AND (pretests = false OR system_test_status = 'finished') AND NOT `+pendingContestSubsConstraint+`
+			AND NOT EXISTS (SELECT 1 FROM contest_result_notifications notifs WHERE notifs.contest_id = contests.id)
+		ORDER BY end_time ASC`, time.Now().Add(-maxAge))
+	contests, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[dbContest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.Contest{}, nil
+	} else if err != nil {
+		return []*kilonova.Contest{}, err
+	}
+
+	return mapperCtx(ctx, contests, s.internalToContest), nil
+}
+
+// MarkContestResultsNotified records that the contest's result emails were queued.
+// It returns false if they were already marked by someone else
+func (s *DB) MarkContestResultsNotified(ctx context.Context, contestID int) (bool, error) {
+	tag, err := s.conn.Exec(ctx, "INSERT INTO contest_result_notifications (contest_id) VALUES ($1) ON CONFLICT DO NOTHING", contestID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ContestResultRecipients returns the IDs of the given users that opted into contest result notifications and have a verified email
+func (s *DB) ContestResultRecipients(ctx context.Context, userIDs []int) ([]int, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT prefs.user_id FROM notification_preferences prefs INNER JOIN users ON users.id = prefs.user_id
+		WHERE prefs.user_id = ANY($1) AND prefs.contest_results = true AND users.verified_email = true`, userIDs)
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []int{}, nil
+	}
+	return ids, err
+}
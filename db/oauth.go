@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+const oauthClientFields = "id, created_at, owner_id, name, description, redirect_uris, secret_hash IS NOT NULL AS confidential"
+
+// OAuthCode is a pending authorization code, waiting to be exchanged by the client
+type OAuthCode struct {
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+	ClientID    string    `db:"client_id"`
+	UserID      int       `db:"user_id"`
+	RedirectURI string    `db:"redirect_uri"`
+
+	Scopes        []kilonova.APITokenScope `db:"scopes"`
+	Nonce         string                   `db:"nonce"`
+	CodeChallenge string                   `db:"code_challenge"`
+}
+
+type OAuthRefreshToken struct {
+	CreatedAt time.Time                `db:"created_at"`
+	ExpiresAt time.Time                `db:"expires_at"`
+	ClientID  string                   `db:"client_id"`
+	UserID    int                      `db:"user_id"`
+	Scopes    []kilonova.APITokenScope `db:"scopes"`
+}
+
+type OAuthSigningKey struct {
+	ID         string    `db:"id"`
+	CreatedAt  time.Time `db:"created_at"`
+	PrivateKey string    `db:"private_key"`
+}
+
+func (s *DB) CreateOAuthClient(ctx context.Context, client *kilonova.OAuthClient, secretHash *string) error {
+	return s.conn.QueryRow(ctx, `INSERT INTO oauth_clients (id, owner_id, name, description, redirect_uris, secret_hash) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at`,
+		client.ID, client.OwnerID, client.Name, client.Description, client.RedirectURIs, secretHash).Scan(&client.CreatedAt)
+}
+
+// OAuthClient returns the client with the given ID, or nil if it doesn't exist
+func (s *DB) OAuthClient(ctx context.Context, id string) (*kilonova.OAuthClient, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT "+oauthClientFields+" FROM oauth_clients WHERE id = $1", id)
+	client, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.OAuthClient])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return client, err
+}
+
+func (s *DB) OAuthClients(ctx context.Context, ownerID int) ([]*kilonova.OAuthClient, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT "+oauthClientFields+" FROM oauth_clients WHERE owner_id = $1 ORDER BY created_at DESC", ownerID)
+	clients, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.OAuthClient])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.OAuthClient{}, nil
+	}
+	return clients, err
+}
+
+// OAuthClientSecretHash returns the hash of the client's secret. It is nil for public clients
+func (s *DB) OAuthClientSecretHash(ctx context.Context, id string) (*string, error) {
+	var hash *string
+	err := s.conn.QueryRow(ctx, "SELECT secret_hash FROM oauth_clients WHERE id = $1", id).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return hash, err
+}
+
+func (s *DB) UpdateOAuthClient(ctx context.Context, client *kilonova.OAuthClient) error {
+	_, err := s.conn.Exec(ctx, "UPDATE oauth_clients SET name = $2, description = $3, redirect_uris = $4 WHERE id = $1",
+		client.ID, client.Name, client.Description, client.RedirectURIs)
+	return err
+}
+
+func (s *DB) SetOAuthClientSecret(ctx context.Context, id string, secretHash string) error {
+	_, err := s.conn.Exec(ctx, "UPDATE oauth_clients SET secret_hash = $2 WHERE id = $1", id, secretHash)
+	return err
+}
+
+func (s *DB) DeleteOAuthClient(ctx context.Context, id string) error {
+	_, err := s.conn.Exec(ctx, "DELETE FROM oauth_clients WHERE id = $1", id)
+	return err
+}
+
+func (s *DB) CreateOAuthCode(ctx context.Context, hash string, code *OAuthCode) error {
+	_, err := s.conn.Exec(ctx, `INSERT INTO oauth_codes (code_hash, expires_at, client_id, user_id, redirect_uri, scopes, nonce, code_challenge) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		hash, code.ExpiresAt, code.ClientID, code.UserID, code.RedirectURI, code.Scopes, code.Nonce, code.CodeChallenge)
+	return err
+}
+
+// ConsumeOAuthCode removes the authorization code and returns it, so it can't be used twice. It returns nil if the code doesn't exist
+func (s *DB) ConsumeOAuthCode(ctx context.Context, hash string) (*OAuthCode, error) {
+	rows, _ := s.conn.Query(ctx, `DELETE FROM oauth_codes WHERE code_hash = $1 
+		RETURNING created_at, expires_at, client_id, user_id, redirect_uri, scopes, nonce, code_challenge`, hash)
+	code, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[OAuthCode])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return code, err
+}
+
+func (s *DB) CreateOAuthRefreshToken(ctx context.Context, hash string, token *OAuthRefreshToken) error {
+	_, err := s.conn.Exec(ctx, "INSERT INTO oauth_refresh_tokens (token_hash, expires_at, client_id, user_id, scopes) VALUES ($1, $2, $3, $4, $5)",
+		hash, token.ExpiresAt, token.ClientID, token.UserID, token.Scopes)
+	return err
+}
+
+// ConsumeOAuthRefreshToken removes the refresh token and returns it, or nil if it doesn't exist
+func (s *DB) ConsumeOAuthRefreshToken(ctx context.Context, hash string) (*OAuthRefreshToken, error) {
+	rows, _ := s.conn.Query(ctx, "DELETE FROM oauth_refresh_tokens WHERE token_hash = $1 RETURNING created_at, expires_at, client_id, user_id, scopes", hash)
+	token, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[OAuthRefreshToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return token, err
+}
+
+// OAuthGrants returns the latest refresh token of every app the user authorized
+func (s *DB) OAuthGrants(ctx context.Context, userID int) ([]*OAuthRefreshToken, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT DISTINCT ON (client_id) created_at, expires_at, client_id, user_id, scopes FROM oauth_refresh_tokens 
+		WHERE user_id = $1 AND expires_at > NOW() ORDER BY client_id, created_at DESC`, userID)
+	grants, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[OAuthRefreshToken])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*OAuthRefreshToken{}, nil
+	}
+	return grants, err
+}
+
+// RevokeOAuthGrant removes all refresh and access tokens the app got from the user
+func (s *DB) RevokeOAuthGrant(ctx context.Context, userID int, clientID string) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM oauth_refresh_tokens WHERE user_id = $1 AND client_id = $2", userID, clientID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM api_tokens WHERE user_id = $1 AND client_id = $2", userID, clientID)
+		return err
+	})
+}
+
+// RemoveExpiredOAuthTokens cleans up the expired authorization codes, refresh tokens and app access tokens
+func (s *DB) RemoveExpiredOAuthTokens(ctx context.Context) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM oauth_codes WHERE expires_at < NOW()"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM oauth_refresh_tokens WHERE expires_at < NOW()"); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM api_tokens WHERE client_id IS NOT NULL AND expires_at < NOW()")
+		return err
+	})
+}
+
+// OAuthSigningKey returns the newest ID token signing key, or nil if none was generated yet
+func (s *DB) OAuthSigningKey(ctx context.Context) (*OAuthSigningKey, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT id, created_at, private_key FROM oauth_signing_keys ORDER BY created_at DESC LIMIT 1")
+	key, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[OAuthSigningKey])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return key, err
+}
+
+// InitOAuthSigningKey saves the given key, unless a key was already generated, and returns the stored key.
+// The table is locked for the insert, so that instances starting together all end up using the same key
+func (s *DB) InitOAuthSigningKey(ctx context.Context, id, privateKey string) (*OAuthSigningKey, error) {
+	var key *OAuthSigningKey
+	err := pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "LOCK TABLE oauth_signing_keys IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO oauth_signing_keys (id, private_key) SELECT $1, $2
+			WHERE NOT EXISTS (SELECT 1 FROM oauth_signing_keys) ON CONFLICT DO NOTHING`, id, privateKey); err != nil {
+			return err
+		}
+		rows, _ := tx.Query(ctx, "SELECT id, created_at, private_key FROM oauth_signing_keys ORDER BY created_at DESC LIMIT 1")
+		var err error
+		key, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[OAuthSigningKey])
+		return err
+	})
+	return key, err
+}
+
+// RevokeOAuthToken removes the access or refresh token issued to the app
+func (s *DB) RevokeOAuthToken(ctx context.Context, clientID string, hash string) error {
+	return pgx.BeginFunc(ctx, s.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "DELETE FROM oauth_refresh_tokens WHERE client_id = $1 AND token_hash = $2", clientID, hash); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, "DELETE FROM api_tokens WHERE client_id = $1 AND token_hash = $2", clientID, hash)
+		return err
+	})
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *DB) CreatePrintJob(ctx context.Context, job *kilonova.PrintJob) (int, error) {
+	var id int
+	err := s.conn.QueryRow(ctx, `INSERT INTO print_jobs (contest_id, user_id, team_id, seat, location, filename, language, code) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		job.ContestID, job.UserID, job.TeamID, job.Seat, job.Location, job.Filename, job.Language, job.Code).Scan(&id)
+	return id, err
+}
+
+func (s *DB) PrintJob(ctx context.Context, contestID, id int) (*kilonova.PrintJob, error) {
+	rows, _ := s.conn.Query(ctx, "SELECT * FROM print_jobs WHERE contest_id = $1 AND id = $2", contestID, id)
+	job, err := pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByName[kilonova.PrintJob])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// PrintJobs returns the contest's print jobs, oldest first. If userID is set, only the user's jobs are returned
+func (s *DB) PrintJobs(ctx context.Context, contestID int, userID *int, pendingOnly bool) ([]*kilonova.PrintJob, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT * FROM print_jobs WHERE contest_id = $1 AND ($2::bigint IS NULL OR user_id = $2) AND (NOT $3 OR printed_at IS NULL) 
+		ORDER BY created_at ASC, id ASC`, contestID, userID, pendingOnly)
+	jobs, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.PrintJob])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.PrintJob{}, nil
+	}
+	return jobs, err
+}
+
+// SetPrintJobPrinted marks the job as printed by the given user, or as pending again if printedBy is nil
+func (s *DB) SetPrintJobPrinted(ctx context.Context, contestID, id int, printedBy *int) error {
+	_, err := s.conn.Exec(ctx, `UPDATE print_jobs SET printed_at = CASE WHEN $3::bigint IS NULL THEN NULL ELSE NOW() END, printed_by = $3 
+		WHERE contest_id = $1 AND id = $2`, contestID, id, printedBy)
+	return err
+}
+
+// BalloonEvents returns the first accepted submission of every participant (or team) on every problem, in the order they were sent.
+// Submissions of contest staff and virtual participants are ignored
+func (s *DB) BalloonEvents(ctx context.Context, contestID int) ([]*kilonova.BalloonEvent, error) {
+	rows, _ := s.conn.Query(ctx, `SELECT * FROM (
+		SELECT DISTINCT ON (subs.team_id, CASE WHEN subs.team_id IS NULL THEN subs.user_id END, subs.problem_id) 
+			subs.id AS submission_id, subs.created_at, subs.user_id, subs.team_id, subs.problem_id, deliveries.delivered_at, deliveries.delivered_by
+		FROM submissions subs
+			INNER JOIN contest_registrations regs ON regs.contest_id = subs.contest_id AND regs.user_id = subs.user_id AND regs.virtual = false
+			LEFT JOIN balloon_deliveries deliveries ON deliveries.submission_id = subs.id
+		WHERE subs.contest_id = $1 AND subs.status = 'finished' AND subs.score = 100
+			AND NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = subs.user_id AND acc.contest_id = subs.contest_id)
+		ORDER BY subs.team_id, CASE WHEN subs.team_id IS NULL THEN subs.user_id END, subs.problem_id, subs.created_at ASC, subs.id ASC
+	) events ORDER BY created_at ASC, submission_id ASC`, contestID)
+	events, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[kilonova.BalloonEvent])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return []*kilonova.BalloonEvent{}, nil
+	}
+	return events, err
+}
+
+// SetBalloonDelivered marks the balloon of the given submission as delivered by the user, or as not delivered if deliveredBy is nil
+func (s *DB) SetBalloonDelivered(ctx context.Context, contestID, submissionID int, deliveredBy *int) error {
+	if deliveredBy == nil {
+		_, err := s.conn.Exec(ctx, "DELETE FROM balloon_deliveries WHERE contest_id = $1 AND submission_id = $2", contestID, submissionID)
+		return err
+	}
+	_, err := s.conn.Exec(ctx, `INSERT INTO balloon_deliveries (submission_id, contest_id, delivered_by) VALUES ($1, $2, $3) 
+		ON CONFLICT (submission_id) DO UPDATE SET delivered_at = NOW(), delivered_by = EXCLUDED.delivered_by`, submissionID, contestID, deliveredBy)
+	return err
+}
@@ -48,6 +48,8 @@ type dbScoredProblem struct {
 
 	MaxScore  *decimal.Decimal `db:"score"`
 	UNUSEDPOZ int              `db:"unused_position"`
+
+	ContestLabel string `db:"contest_label"`
 }
 
 func (s *DB) Problem(ctx context.Context, id int) (*kilonova.Problem, error) {
@@ -119,7 +121,7 @@ func (s *DB) ContestProblems(ctx context.Context, contestID int) ([]*kilonova.Pr
 
 func (s *DB) ScoredContestProblems(ctx context.Context, contestID int, userID int, freezeTime *time.Time) ([]*kilonova.ScoredProblem, error) {
 	var pbs []*dbScoredProblem
-	err := Select(s.conn, ctx, &pbs, `SELECT pbs.*, cpbs.position AS unused_position, ms.user_id, ms.score, (editors.user_id IS NOT NULL) AS pb_editor
+	err := Select(s.conn, ctx, &pbs, `SELECT pbs.*, cpbs.position AS unused_position, cpbs.label AS contest_label, ms.user_id, ms.score, (editors.user_id IS NOT NULL) AS pb_editor
 FROM (problems pbs INNER JOIN contest_problems cpbs ON cpbs.problem_id = pbs.id) 
 	LEFT JOIN contest_max_scores($1, $3) ms ON (pbs.id = ms.problem_id AND ms.user_id = $2)
 	LEFT JOIN LATERAL (SELECT user_id FROM problem_editors editors WHERE pbs.id = editors.problem_id AND editors.user_id = $2 LIMIT 1) editors ON TRUE
@@ -379,6 +381,8 @@ func (s *DB) internalToScoredProblem(spb *dbScoredProblem, scoreUID int) (*kilon
 		ScoreUserID: uid,
 		MaxScore:    spb.MaxScore,
 		IsEditor:    spb.IsEditor,
+
+		ContestLabel: spb.ContestLabel,
 	}, nil
 }
 
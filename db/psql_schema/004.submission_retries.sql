@@ -0,0 +1,3 @@
+
+-- Number of automatic retries done after internal grader errors
+ALTER TABLE submissions ADD COLUMN internal_retries integer NOT NULL DEFAULT 0;
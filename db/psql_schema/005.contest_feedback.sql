@@ -0,0 +1,20 @@
+CREATE TYPE feedback_level AS enum (
+    'full',
+    'subtasks',
+    'total'
+);
+
+-- How much of a submission's evaluation contestants can see while the contest is running
+ALTER TABLE contests ADD COLUMN feedback_level feedback_level NOT NULL DEFAULT 'full';
+-- Number of feedback tokens each contestant has for every problem
+ALTER TABLE contests ADD COLUMN feedback_tokens integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS submission_feedback_tokens (
+    submission_id   bigint      NOT NULL PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    user_id         bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    contest_id      bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    problem_id      bigint      NOT NULL REFERENCES problems(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS feedback_tokens_user_idx ON submission_feedback_tokens (contest_id, user_id, problem_id);
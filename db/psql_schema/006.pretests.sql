@@ -0,0 +1,27 @@
+-- Tests marked as pretests are the only ones run during contests with pretests enabled
+ALTER TABLE tests ADD COLUMN pretest boolean NOT NULL DEFAULT false;
+
+-- Submissions sent while the contest was running are judged only on pretests, until system testing
+ALTER TABLE submissions ADD COLUMN pretest_only boolean NOT NULL DEFAULT false;
+
+CREATE TYPE system_test_status AS enum (
+    'none',
+    'running',
+    'finished'
+);
+
+ALTER TABLE contests ADD COLUMN pretests boolean NOT NULL DEFAULT false;
+ALTER TABLE contests ADD COLUMN system_test_status system_test_status NOT NULL DEFAULT 'none';
+
+-- Leaderboard scores at the end of the contest, shown while system testing is running.
+-- frozen marks the scores computed using the contest's leaderboard freeze time
+CREATE TABLE IF NOT EXISTS contest_pretest_scores (
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    problem_id  bigint      NOT NULL REFERENCES problems(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    frozen      boolean     NOT NULL,
+    score       decimal     NOT NULL,
+    mintime     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS contest_pretest_scores_idx ON contest_pretest_scores (contest_id, frozen);
@@ -0,0 +1,51 @@
+ALTER TABLE contests ADD COLUMN hacking boolean NOT NULL DEFAULT false;
+-- Points awarded for a successful hack and subtracted for an unsuccessful one
+ALTER TABLE contests ADD COLUMN hack_points integer NOT NULL DEFAULT 10;
+ALTER TABLE contests ADD COLUMN hack_penalty integer NOT NULL DEFAULT 5;
+
+-- Locking a problem forbids any further submissions to it, but allows viewing (and hacking) the others' accepted solutions
+CREATE TABLE IF NOT EXISTS contest_problem_locks (
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    problem_id  bigint      NOT NULL REFERENCES problems(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    UNIQUE (contest_id, user_id, problem_id)
+);
+
+CREATE TYPE hack_status AS enum (
+    'pending',
+    'working',
+    'finished'
+);
+
+CREATE TYPE hack_verdict AS enum (
+    'none',
+    'successful',
+    'unsuccessful',
+    'invalid_input',
+    'internal_error'
+);
+
+CREATE TABLE IF NOT EXISTS contest_hacks (
+    id                  bigint          GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at          timestamptz     NOT NULL DEFAULT NOW(),
+    contest_id          bigint          NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    problem_id          bigint          NOT NULL REFERENCES problems(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    hacker_id           bigint          NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    target_user_id      bigint          NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    target_submission_id bigint         NOT NULL REFERENCES submissions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    input               bytea           NOT NULL,
+
+    status              hack_status     NOT NULL DEFAULT 'pending',
+    verdict             hack_verdict    NOT NULL DEFAULT 'none',
+    message             text            NOT NULL DEFAULT '',
+    -- Leaderboard points awarded to the hacker, set when the hack is finished
+    points              integer         NOT NULL DEFAULT 0,
+
+    -- The problem test created from this hack, if any
+    test_id             bigint          REFERENCES tests(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS contest_hacks_contest_idx ON contest_hacks (contest_id, hacker_id);
+CREATE INDEX IF NOT EXISTS contest_hacks_status_idx ON contest_hacks (status);
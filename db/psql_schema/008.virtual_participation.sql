@@ -0,0 +1,3 @@
+-- Virtual participations are personal runs of an ended contest.
+-- They reuse the individual start/end times of USACO-style registrations, but are kept out of the official leaderboard
+ALTER TABLE contest_registrations ADD COLUMN virtual boolean NOT NULL DEFAULT false;
@@ -0,0 +1,21 @@
+-- Maximum number of members of a team. 0 means the contest is individual
+ALTER TABLE contests ADD COLUMN team_size integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS contest_teams (
+    id          bigint      GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    name        text        NOT NULL,
+    creator_id  bigint      REFERENCES users(id) ON DELETE SET NULL,
+    UNIQUE (contest_id, name)
+);
+
+-- The members of a team are the contest registrations with the team set
+ALTER TABLE contest_registrations ADD COLUMN team_id bigint REFERENCES contest_teams(id) ON DELETE SET NULL;
+
+-- Invitations bound to a team register the users that redeem them as members of that team
+ALTER TABLE contest_invitations ADD COLUMN team_id bigint REFERENCES contest_teams(id) ON DELETE CASCADE;
+
+-- Submissions are attributed to both the user and the team
+ALTER TABLE submissions ADD COLUMN team_id bigint REFERENCES contest_teams(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS submissions_team_idx ON submissions (team_id) WHERE team_id IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Participant categories (ex: official, official at home, unofficial) users can be registered under
+ALTER TABLE contests ADD COLUMN categories text[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE contest_registrations ADD COLUMN category text;
+
+-- Invitations can assign a category to the users that redeem them
+ALTER TABLE contest_invitations ADD COLUMN category text;
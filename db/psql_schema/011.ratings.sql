@@ -0,0 +1,19 @@
+-- Current rating of the user, NULL while unrated
+ALTER TABLE users ADD COLUMN rating integer;
+
+-- Whether the rating changes of an ended official contest were applied.
+-- Contests that existed before ratings were introduced are considered applied, so they don't get rated retroactively
+ALTER TABLE contests ADD COLUMN ratings_applied boolean NOT NULL DEFAULT true;
+ALTER TABLE contests ALTER COLUMN ratings_applied SET DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS contest_rating_changes (
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    rank        integer     NOT NULL,
+    old_rating  integer     NOT NULL,
+    new_rating  integer     NOT NULL,
+    PRIMARY KEY (contest_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS contest_rating_changes_user_idx ON contest_rating_changes (user_id);
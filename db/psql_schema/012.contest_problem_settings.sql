@@ -0,0 +1,10 @@
+-- Per-contest problem settings. NULL values fall back to the problem or contest defaults
+
+-- label is shown instead of the problem name in the contest leaderboard (A, B, C...)
+ALTER TABLE contest_problems ADD COLUMN label text NOT NULL DEFAULT '';
+-- points replaces the problem's leaderboard score scale inside the contest
+ALTER TABLE contest_problems ADD COLUMN points numeric;
+-- languages further restricts the problem's language whitelist inside the contest
+ALTER TABLE contest_problems ADD COLUMN languages text[];
+-- max_subs overrides the contest's maximum submission count for the problem
+ALTER TABLE contest_problems ADD COLUMN max_subs integer;
@@ -0,0 +1,11 @@
+-- Questions may be about a specific contest problem
+ALTER TABLE contest_questions ADD COLUMN problem_id bigint REFERENCES problems(id) ON DELETE SET NULL;
+
+-- A judge claims a question before answering it, so several judges don't answer the same question
+ALTER TABLE contest_questions ADD COLUMN claimed_by bigint REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE contest_questions ADD COLUMN claimed_at timestamptz;
+ALTER TABLE contest_questions ADD COLUMN answered_by bigint REFERENCES users(id) ON DELETE SET NULL;
+
+-- Public clarifications, made from questions answered publicly
+ALTER TABLE contest_announcements ADD COLUMN problem_id bigint REFERENCES problems(id) ON DELETE SET NULL;
+ALTER TABLE contest_questions ADD COLUMN announcement_id bigint REFERENCES contest_announcements(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- Contest access control: submissions can be restricted to some networks, contestants can be limited to a single session
+-- and a lockdown can hide everything unrelated to the contest from its participants while it runs
+ALTER TABLE contests ADD COLUMN allowed_ip_ranges cidr[] NOT NULL DEFAULT '{}';
+ALTER TABLE contests ADD COLUMN single_session boolean NOT NULL DEFAULT false;
+ALTER TABLE contests ADD COLUMN lockdown boolean NOT NULL DEFAULT false;
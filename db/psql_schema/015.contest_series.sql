@@ -0,0 +1,30 @@
+-- Contest series group several contests (rounds) under a combined leaderboard
+CREATE TYPE series_aggregation AS enum (
+    'sum',
+    'best_k',
+    'weighted'
+);
+
+CREATE TABLE IF NOT EXISTS contest_series (
+    id          bigint      GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    author_id   bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    name        text        NOT NULL,
+    description text        NOT NULL DEFAULT '',
+    visible     boolean     NOT NULL DEFAULT false,
+
+    aggregation series_aggregation NOT NULL DEFAULT 'sum',
+    -- Number of best rounds counted by the best_k aggregation
+    best_k      integer     NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS contest_series_rounds (
+    series_id   bigint  NOT NULL REFERENCES contest_series(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    contest_id  bigint  NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    position    bigint  NOT NULL DEFAULT 0,
+    -- Multiplier of the round's scores in the weighted aggregation
+    weight      numeric NOT NULL DEFAULT 1,
+    UNIQUE (series_id, contest_id)
+);
+
+CREATE INDEX IF NOT EXISTS contest_series_rounds_contest_idx ON contest_series_rounds (contest_id);
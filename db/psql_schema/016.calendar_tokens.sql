@@ -0,0 +1,6 @@
+-- Secret tokens for the personal contest calendar feeds
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    id          text        PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    user_id     bigint      NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,31 @@
+-- Opt-in email notifications. Users without a row have all notifications disabled
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id             bigint      PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    contest_reminders   boolean     NOT NULL DEFAULT false,
+    question_answers    boolean     NOT NULL DEFAULT false,
+    contest_results     boolean     NOT NULL DEFAULT false,
+    reminder_hours      integer     NOT NULL DEFAULT 24
+);
+
+-- Rendered emails waiting to be sent. dedupe_key ensures a notification is queued only once
+CREATE TABLE IF NOT EXISTS mail_queue (
+    id          bigint      GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    email       text        NOT NULL,
+    subject     text        NOT NULL,
+    content     text        NOT NULL,
+
+    dedupe_key  text        UNIQUE,
+    sent_at     timestamptz,
+    attempts    integer     NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS mail_queue_pending_index ON mail_queue (created_at) WHERE sent_at IS NULL;
+
+-- Contests whose result emails were already queued
+CREATE TABLE IF NOT EXISTS contest_result_notifications (
+    contest_id  bigint      PRIMARY KEY REFERENCES contests(id) ON DELETE CASCADE,
+    created_at  timestamptz NOT NULL DEFAULT NOW()
+);
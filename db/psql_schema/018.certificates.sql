@@ -0,0 +1,32 @@
+-- Certificate templates and the awards given based on the final leaderboard
+CREATE TABLE IF NOT EXISTS contest_certificate_settings (
+    contest_id  bigint      PRIMARY KEY REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    template    text        NOT NULL DEFAULT '',
+    format      text        NOT NULL DEFAULT 'html' CHECK (format IN ('html', 'md')),
+    published   boolean     NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS contest_awards (
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    position    bigint      NOT NULL DEFAULT 0,
+    name        text        NOT NULL,
+    max_rank    integer,
+    min_score   numeric
+);
+
+CREATE INDEX IF NOT EXISTS contest_awards_contest_idx ON contest_awards (contest_id);
+
+-- Issued certificates. The ID is the public verification code embedded in the certificate
+CREATE TABLE IF NOT EXISTS certificates (
+    id          text        PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+
+    rank        integer     NOT NULL,
+    score       numeric     NOT NULL,
+    award       text,
+    team        text,
+
+    UNIQUE (contest_id, user_id)
+);
@@ -0,0 +1,30 @@
+-- On-site contests have a print queue and a balloon board
+ALTER TABLE contests ADD COLUMN on_site boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS print_jobs (
+    id          bigint      GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT NOW(),
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    user_id     bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    team_id     bigint      REFERENCES contest_teams(id) ON DELETE SET NULL,
+
+    seat        text        NOT NULL DEFAULT '',
+    location    text        NOT NULL DEFAULT '',
+
+    filename    text        NOT NULL,
+    language    text        NOT NULL DEFAULT '',
+    code        text        NOT NULL,
+
+    printed_at  timestamptz,
+    printed_by  bigint      REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS print_jobs_contest_idx ON print_jobs (contest_id, created_at);
+
+-- Balloons are keyed by the first accepted submission of a participant on a problem
+CREATE TABLE IF NOT EXISTS balloon_deliveries (
+    submission_id   bigint      PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    contest_id      bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    delivered_at    timestamptz NOT NULL DEFAULT NOW(),
+    delivered_by    bigint      REFERENCES users(id) ON DELETE SET NULL
+);
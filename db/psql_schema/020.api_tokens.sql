@@ -0,0 +1,14 @@
+-- Personal access tokens used to authenticate API requests from scripts.
+-- Only the SHA-256 hash of the token is stored, the token itself is shown once, when it is created
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id              bigint      GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    user_id         bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            text        NOT NULL,
+    token_hash      text        NOT NULL UNIQUE,
+    scopes          text[]      NOT NULL DEFAULT '{}',
+    expires_at      timestamptz,
+    last_used_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_idx ON api_tokens (user_id);
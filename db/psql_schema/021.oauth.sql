@@ -0,0 +1,48 @@
+-- Third-party apps that sign users in through the OAuth2 authorization code flow
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    id              text        PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    owner_id        bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name            text        NOT NULL,
+    description     text        NOT NULL DEFAULT '',
+    redirect_uris   text[]      NOT NULL DEFAULT '{}',
+    -- NULL for public clients, which must use PKCE
+    secret_hash     text
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_idx ON oauth_clients (owner_id);
+
+-- Authorization codes are short lived and can be exchanged only once
+CREATE TABLE IF NOT EXISTS oauth_codes (
+    code_hash       text        PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    expires_at      timestamptz NOT NULL,
+    client_id       text        NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id         bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    redirect_uri    text        NOT NULL,
+    scopes          text[]      NOT NULL DEFAULT '{}',
+    nonce           text        NOT NULL DEFAULT '',
+    code_challenge  text        NOT NULL DEFAULT ''
+);
+
+-- Refresh tokens are rotated: using one replaces it with a new token
+CREATE TABLE IF NOT EXISTS oauth_refresh_tokens (
+    token_hash      text        PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    expires_at      timestamptz NOT NULL,
+    client_id       text        NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id         bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scopes          text[]      NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS oauth_refresh_tokens_user_idx ON oauth_refresh_tokens (user_id, client_id);
+
+-- Access tokens issued to apps are API tokens tied to the app
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS client_id text REFERENCES oauth_clients(id) ON DELETE CASCADE;
+
+-- RSA keys used to sign OpenID Connect ID tokens. The newest key is used for signing
+CREATE TABLE IF NOT EXISTS oauth_signing_keys (
+    id              text        PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    private_key     text        NOT NULL
+);
@@ -0,0 +1,24 @@
+-- Accounts of external OpenID Connect providers (such as Google Workspace or Microsoft Entra) users can sign in with.
+-- The provider is the ID given to it in the integrations.oidc.providers flag
+CREATE TABLE IF NOT EXISTS external_identities (
+    provider        text        NOT NULL,
+    subject         text        NOT NULL,
+    user_id         bigint      NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email           text,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    last_login_at   timestamptz,
+
+    PRIMARY KEY (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Pending sign in attempts. user_id is set when an existing account is being linked
+CREATE TABLE IF NOT EXISTS external_login_states (
+    id              text        PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT NOW(),
+    provider        text        NOT NULL,
+    user_id         bigint      REFERENCES users(id) ON DELETE CASCADE,
+    nonce           text        NOT NULL,
+    code_verifier   text        NOT NULL,
+    back            text        NOT NULL DEFAULT ''
+);
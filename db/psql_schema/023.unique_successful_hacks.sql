@@ -0,0 +1,5 @@
+-- A submission can only be hacked once. Later successful hacks on the same submission don't count
+UPDATE contest_hacks SET verdict = 'none', points = 0, message = 'Submission was already hacked'
+    WHERE verdict = 'successful' AND id NOT IN (SELECT MIN(id) FROM contest_hacks WHERE verdict = 'successful' GROUP BY target_submission_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS contest_hacks_successful_idx ON contest_hacks (target_submission_id) WHERE verdict = 'successful';
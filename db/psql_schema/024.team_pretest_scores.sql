@@ -0,0 +1,12 @@
+-- Team leaderboard scores at the end of the contest, shown while system testing is running.
+-- Same as contest_pretest_scores, but for contests with teams
+CREATE TABLE IF NOT EXISTS contest_team_pretest_scores (
+    contest_id  bigint      NOT NULL REFERENCES contests(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    team_id     bigint      NOT NULL REFERENCES contest_teams(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    problem_id  bigint      NOT NULL REFERENCES problems(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    frozen      boolean     NOT NULL,
+    score       decimal     NOT NULL,
+    mintime     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS contest_team_pretest_scores_idx ON contest_team_pretest_scores (contest_id, frozen);
@@ -0,0 +1,2 @@
+-- The address a token was last used from, shown next to the last usage time so leaked tokens can be spotted
+ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS last_used_ip inet;
@@ -64,7 +64,13 @@ CREATE OR REPLACE FUNCTION visible_pbs(user_id bigint) RETURNS TABLE (problem_id
         FROM contest_problems pbs, contest_registrations users, running_contests contests 
         WHERE pbs.contest_id = contests.id AND contests.id = users.contest_id 
         AND contests.per_user_time > 0 AND users.individual_start_at IS NOT NULL AND NOW() <= users.individual_end_at
-        AND users.user_id = $1); -- Contest registrants that started during the contest for USACO-style contests
+        AND users.user_id = $1) -- Contest registrants that started during the contest for USACO-style contests
+    UNION ALL
+    (SELECT pbs.problem_id as problem_id, users.user_id as user_id
+        FROM contest_problems pbs, contest_registrations users, contests
+        WHERE pbs.contest_id = contests.id AND contests.id = users.contest_id
+        AND users.virtual = true AND users.individual_start_at <= NOW() AND NOW() <= users.individual_end_at
+        AND users.user_id = $1); -- Virtual participants during their run
 $$ LANGUAGE SQL STABLE;
 
 DROP FUNCTION IF EXISTS visible_posts CASCADE;
@@ -141,59 +147,96 @@ $$ LANGUAGE SQL STABLE;
 
 DROP FUNCTION IF EXISTS contest_max_scores(bigint);
 DROP FUNCTION IF EXISTS contest_max_scores(bigint, timestamptz);
+-- The per-contest point value of a problem, if set, replaces the score scale of the submissions
+-- Submissions that were successfully hacked are worth 0 points
 CREATE OR REPLACE FUNCTION contest_max_scores(contest_id bigint, freeze_time timestamptz) RETURNS TABLE(user_id bigint, problem_id bigint, score decimal, mintime timestamptz) AS $$
-    WITH max_submission_strat AS (
-        SELECT DISTINCT user_id, problem_id, FIRST_VALUE(score * (leaderboard_score_scale / 100)) OVER w AS max_score, FIRST_VALUE(created_at) OVER w AS mintime
-            FROM submissions WHERE contest_id = $1 AND created_at <= COALESCE(freeze_time, NOW()) AND (status = 'finished' OR status = 'reevaling')
-            WINDOW w AS (PARTITION BY user_id, problem_id ORDER BY score DESC, created_at ASC)
+    WITH hacked_subs AS (
+        SELECT target_submission_id AS submission_id FROM contest_hacks hacks
+            WHERE hacks.contest_id = $1 AND hacks.verdict = 'successful' AND hacks.created_at <= COALESCE(freeze_time, NOW())
+    ), contest_subs AS (
+        SELECT subs.*, CASE WHEN hacked.submission_id IS NULL THEN subs.score ELSE 0 END AS effective_score
+            FROM submissions subs LEFT JOIN hacked_subs hacked ON hacked.submission_id = subs.id
+            WHERE subs.contest_id = $1
+    ), contest_subtasks AS (
+        SELECT stks.*, CASE WHEN hacked.submission_id IS NULL THEN stks.computed_score ELSE 0 END AS effective_score
+            FROM submission_subtasks stks LEFT JOIN hacked_subs hacked ON hacked.submission_id = stks.submission_id
+            WHERE stks.contest_id = $1
+    ), max_submission_strat AS (
+        SELECT DISTINCT subs.user_id, subs.problem_id, FIRST_VALUE(subs.effective_score * (COALESCE(cpbs.points, subs.leaderboard_score_scale) / 100)) OVER w AS max_score, FIRST_VALUE(subs.created_at) OVER w AS mintime
+            FROM contest_subs subs LEFT JOIN contest_problems cpbs ON cpbs.contest_id = subs.contest_id AND cpbs.problem_id = subs.problem_id
+            WHERE subs.created_at <= COALESCE(freeze_time, NOW()) AND (subs.status = 'finished' OR subs.status = 'reevaling')
+            WINDOW w AS (PARTITION BY subs.user_id, subs.problem_id ORDER BY subs.effective_score DESC, subs.created_at ASC)
     ), subtask_max_scores AS (
-        SELECT DISTINCT user_id, subtask_id, problem_id, FIRST_VALUE(computed_score * (leaderboard_score_scale / 100)) OVER w AS max_score, FIRST_VALUE(created_at) OVER w AS mintime
-        FROM submission_subtasks stks
-        WHERE subtask_id IS NOT NULL AND contest_id = $1
-            AND created_at <= COALESCE(freeze_time, NOW())
-            WINDOW w AS (PARTITION BY user_id, subtask_id, problem_id ORDER BY computed_score DESC, created_at ASC)
+        SELECT DISTINCT stks.user_id, stks.subtask_id, stks.problem_id, FIRST_VALUE(stks.effective_score * (COALESCE(cpbs.points, stks.leaderboard_score_scale) / 100)) OVER w AS max_score, FIRST_VALUE(stks.created_at) OVER w AS mintime
+        FROM contest_subtasks stks LEFT JOIN contest_problems cpbs ON cpbs.contest_id = stks.contest_id AND cpbs.problem_id = stks.problem_id
+        WHERE stks.subtask_id IS NOT NULL
+            AND stks.created_at <= COALESCE(freeze_time, NOW())
+            WINDOW w AS (PARTITION BY stks.user_id, stks.subtask_id, stks.problem_id ORDER BY stks.effective_score DESC, stks.created_at ASC)
     ), sum_subtasks_strat AS (
         SELECT DISTINCT user_id, problem_id, coalesce(SUM(max_score), -1) AS max_score, MAX(mintime) AS mintime FROM subtask_max_scores GROUP BY user_id, problem_id
-    ) SELECT 
-        users.user_id user_id,
-        pbs.problem_id problem_id,
-        CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.max_score, -1)
-            WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.max_score, -1)
-            ELSE -1
-        END score,
-        CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.mintime, NULL)
-            WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.mintime, NULL)
-            ELSE NULL
-        END mintime
-    FROM ((contest_problems pbs INNER JOIN contest_registrations users ON users.contest_id = pbs.contest_id AND pbs.contest_id = $1) INNER JOIN problems ON pbs.problem_id = problems.id)
-        LEFT JOIN max_submission_strat ms_sub ON (ms_sub.user_id = users.user_id AND ms_sub.problem_id = pbs.problem_id)
-        LEFT JOIN sum_subtasks_strat ms_subtask ON (ms_subtask.user_id = users.user_id AND ms_subtask.problem_id = pbs.problem_id)
+    ), live_scores AS (
+        SELECT 
+            users.user_id user_id,
+            pbs.problem_id problem_id,
+            CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.max_score, -1)
+                WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.max_score, -1)
+                ELSE -1
+            END score,
+            CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.mintime, NULL)
+                WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.mintime, NULL)
+                ELSE NULL
+            END mintime
+        FROM ((contest_problems pbs INNER JOIN contest_registrations users ON users.contest_id = pbs.contest_id AND pbs.contest_id = $1) INNER JOIN problems ON pbs.problem_id = problems.id)
+            LEFT JOIN max_submission_strat ms_sub ON (ms_sub.user_id = users.user_id AND ms_sub.problem_id = pbs.problem_id)
+            LEFT JOIN sum_subtasks_strat ms_subtask ON (ms_subtask.user_id = users.user_id AND ms_subtask.problem_id = pbs.problem_id)
+    ), system_testing AS (
+        -- While system testing is running, the scores from the end of the contest are shown
+        SELECT EXISTS (SELECT 1 FROM contests WHERE id = $1 AND system_test_status = 'running') AS running
+    )
+    SELECT live_scores.* FROM live_scores, system_testing WHERE NOT system_testing.running
+    UNION ALL
+    SELECT snap.user_id, snap.problem_id, snap.score, snap.mintime FROM contest_pretest_scores snap, system_testing 
+        WHERE system_testing.running AND snap.contest_id = $1 AND snap.frozen = (freeze_time IS NOT NULL)
 $$ LANGUAGE SQL STABLE;
 
 DROP VIEW IF EXISTS contest_top_view CASCADE;
 DROP FUNCTION IF EXISTS contest_top_view;
 -- Since we now return -1 on no attempt, we must filter it when computing the top view
 -- also, exclude contest editors/testers since they didn't get that score legit
-CREATE OR REPLACE FUNCTION contest_top_view(contest_id bigint, freeze_time timestamptz, include_editors boolean) RETURNS TABLE (user_id bigint, contest_id bigint, total_score decimal, last_time timestamptz) AS $$
+-- virtual participants are excluded unless specifically requested (ghost leaderboards)
+CREATE OR REPLACE FUNCTION contest_top_view(contest_id bigint, freeze_time timestamptz, include_editors boolean, include_virtual boolean) RETURNS TABLE (user_id bigint, contest_id bigint, total_score decimal, last_time timestamptz, hack_score integer, successful_hacks integer, unsuccessful_hacks integer) AS $$
     -- both contest_scores and legit_contestants will contain results only for that contest id, so it's safe to simply join them 
     WITH contest_scores AS (
         SELECT user_id, SUM(score) AS total_score, MAX(mintime) FILTER (WHERE score > 0) AS last_time FROM contest_max_scores($1, $2) WHERE score >= 0 GROUP BY user_id
+    ), hack_scores AS (
+        -- hacks sent after the leaderboard freeze are hidden, just like submissions
+        SELECT hacker_id AS user_id, SUM(points) AS hack_score, 
+                COUNT(*) FILTER (WHERE verdict = 'successful') AS successful_hacks, 
+                COUNT(*) FILTER (WHERE verdict = 'unsuccessful') AS unsuccessful_hacks
+            FROM contest_hacks hacks 
+            WHERE hacks.contest_id = $1 AND hacks.status = 'finished' AND ($2 IS NULL OR hacks.created_at <= $2) 
+            GROUP BY hacker_id
     ), legit_contestants AS (
         SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
+            AND (NOT regs.virtual OR $4 = true)
     )
-    SELECT users.user_id, $1 AS contest_id, COALESCE(scores.total_score, 0) AS total_score, last_time
+    SELECT users.user_id, $1 AS contest_id, COALESCE(scores.total_score, 0) + COALESCE(hacks.hack_score, 0) AS total_score, last_time,
+            COALESCE(hacks.hack_score, 0), COALESCE(hacks.successful_hacks, 0), COALESCE(hacks.unsuccessful_hacks, 0)
     FROM 
         legit_contestants users 
         LEFT JOIN contest_scores scores ON users.user_id = scores.user_id 
-        ORDER BY COALESCE(total_score, 0) DESC, last_time ASC NULLS LAST, user_id;
+        LEFT JOIN hack_scores hacks ON users.user_id = hacks.user_id
+        ORDER BY COALESCE(scores.total_score, 0) + COALESCE(hacks.hack_score, 0) DESC, last_time ASC NULLS LAST, user_id;
 $$ LANGUAGE SQL STABLE;
 
 DROP FUNCTION IF EXISTS contest_icpc_view;
 -- we exclude contest editors/testers since they didn't get that score legit
-CREATE OR REPLACE FUNCTION contest_icpc_view(contest_id bigint, freeze_time timestamptz, include_editors boolean) 
+-- virtual participants are excluded unless specifically requested (ghost leaderboards)
+CREATE OR REPLACE FUNCTION contest_icpc_view(contest_id bigint, freeze_time timestamptz, include_editors boolean, include_virtual boolean) 
 RETURNS TABLE (user_id bigint, contest_id bigint, last_time timestamptz, num_solved integer, penalty integer, num_attempts integer) AS $$
     WITH legit_contestants AS (
         SELECT regs.* FROM contest_registrations regs WHERE regs.contest_id = $1 AND (NOT EXISTS (SELECT 1 FROM contest_user_access acc WHERE acc.user_id = regs.user_id AND acc.contest_id = regs.contest_id) OR $3 = true)
+            AND (NOT regs.virtual OR $4 = true)
     ), solved_pbs AS (
         SELECT user_id, problem_id, mintime AS last_time FROM contest_max_scores($1, $2) WHERE score = 100
     ), last_times AS (
@@ -212,9 +255,10 @@ RETURNS TABLE (user_id bigint, contest_id bigint, last_time timestamptz, num_sol
             GROUP BY solved_pbs.user_id
     ), duration_sum AS (
         -- get sum of number of minutes from problems
-        SELECT user_id, 
-            SUM(FLOOR(EXTRACT(EPOCH FROM last_time - (SELECT start_time FROM contests WHERE id = $1 LIMIT 1)) / 60)) AS mins 
-        FROM solved_pbs GROUP BY user_id
+        -- virtual participants' times are relative to the start of their run
+        SELECT solved_pbs.user_id, 
+            SUM(FLOOR(EXTRACT(EPOCH FROM solved_pbs.last_time - (CASE WHEN regs.virtual THEN regs.individual_start_at ELSE (SELECT start_time FROM contests WHERE id = $1 LIMIT 1) END)) / 60)) AS mins 
+        FROM solved_pbs INNER JOIN legit_contestants regs ON regs.user_id = solved_pbs.user_id GROUP BY solved_pbs.user_id
     ), penalties AS (
         SELECT users.user_id, 
             COALESCE(atts.num_attempts, 0) * (SELECT icpc_submission_penalty FROM contests WHERE id = $1 LIMIT 1) 
@@ -231,6 +275,107 @@ RETURNS TABLE (user_id bigint, contest_id bigint, last_time timestamptz, num_sol
     ORDER BY COALESCE(num_problems, 0) DESC, penalty ASC NULLS LAST, last_time ASC NULLS LAST, user_id;
 $$ LANGUAGE SQL STABLE;
 
+DROP FUNCTION IF EXISTS contest_team_max_scores;
+-- Same as contest_max_scores, but aggregated over the submissions of each team's members
+CREATE OR REPLACE FUNCTION contest_team_max_scores(contest_id bigint, freeze_time timestamptz) RETURNS TABLE(team_id bigint, problem_id bigint, score decimal, mintime timestamptz) AS $$
+    WITH hacked_subs AS (
+        SELECT target_submission_id AS submission_id FROM contest_hacks hacks
+            WHERE hacks.contest_id = $1 AND hacks.verdict = 'successful' AND hacks.created_at <= COALESCE(freeze_time, NOW())
+    ), team_subs AS (
+        SELECT subs.*, CASE WHEN hacked.submission_id IS NULL THEN subs.score ELSE 0 END AS effective_score
+            FROM submissions subs LEFT JOIN hacked_subs hacked ON hacked.submission_id = subs.id
+            WHERE subs.contest_id = $1 AND subs.team_id IS NOT NULL
+    ), max_submission_strat AS (
+        SELECT DISTINCT subs.team_id, subs.problem_id, FIRST_VALUE(subs.effective_score * (COALESCE(cpbs.points, subs.leaderboard_score_scale) / 100)) OVER w AS max_score, FIRST_VALUE(subs.created_at) OVER w AS mintime
+            FROM team_subs subs LEFT JOIN contest_problems cpbs ON cpbs.contest_id = subs.contest_id AND cpbs.problem_id = subs.problem_id
+            WHERE subs.created_at <= COALESCE(freeze_time, NOW()) AND (subs.status = 'finished' OR subs.status = 'reevaling')
+            WINDOW w AS (PARTITION BY subs.team_id, subs.problem_id ORDER BY subs.effective_score DESC, subs.created_at ASC)
+    ), subtask_max_scores AS (
+        SELECT DISTINCT subs.team_id, stks.subtask_id, stks.problem_id, FIRST_VALUE(CASE WHEN hacked.submission_id IS NULL THEN stks.computed_score ELSE 0 END * (COALESCE(cpbs.points, stks.leaderboard_score_scale) / 100)) OVER w AS max_score, FIRST_VALUE(stks.created_at) OVER w AS mintime
+        FROM (submission_subtasks stks INNER JOIN submissions subs ON subs.id = stks.submission_id)
+            LEFT JOIN hacked_subs hacked ON hacked.submission_id = stks.submission_id
+            LEFT JOIN contest_problems cpbs ON cpbs.contest_id = stks.contest_id AND cpbs.problem_id = stks.problem_id
+        WHERE stks.subtask_id IS NOT NULL AND stks.contest_id = $1 AND subs.team_id IS NOT NULL
+            AND stks.created_at <= COALESCE(freeze_time, NOW())
+            WINDOW w AS (PARTITION BY subs.team_id, stks.subtask_id, stks.problem_id ORDER BY CASE WHEN hacked.submission_id IS NULL THEN stks.computed_score ELSE 0 END DESC, stks.created_at ASC)
+    ), sum_subtasks_strat AS (
+        SELECT DISTINCT team_id, problem_id, coalesce(SUM(max_score), -1) AS max_score, MAX(mintime) AS mintime FROM subtask_max_scores GROUP BY team_id, problem_id
+    ), live_scores AS (
+        SELECT 
+            teams.id team_id,
+            pbs.problem_id problem_id,
+            CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.max_score, -1)
+                WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.max_score, -1)
+                ELSE -1
+            END score,
+            CASE WHEN problems.scoring_strategy = 'max_submission' OR problems.scoring_strategy = 'acm-icpc' THEN COALESCE(ms_sub.mintime, NULL)
+                WHEN problems.scoring_strategy = 'sum_subtasks'   THEN COALESCE(ms_subtask.mintime, NULL)
+                ELSE NULL
+            END mintime
+        FROM ((contest_problems pbs INNER JOIN contest_teams teams ON teams.contest_id = pbs.contest_id AND pbs.contest_id = $1) INNER JOIN problems ON pbs.problem_id = problems.id)
+            LEFT JOIN max_submission_strat ms_sub ON (ms_sub.team_id = teams.id AND ms_sub.problem_id = pbs.problem_id)
+            LEFT JOIN sum_subtasks_strat ms_subtask ON (ms_subtask.team_id = teams.id AND ms_subtask.problem_id = pbs.problem_id)
+    ), system_testing AS (
+        -- While system testing is running, the scores from the end of the contest are shown
+        SELECT EXISTS (SELECT 1 FROM contests WHERE id = $1 AND system_test_status = 'running') AS running
+    )
+    SELECT live_scores.* FROM live_scores, system_testing WHERE NOT system_testing.running
+    UNION ALL
+    SELECT snap.team_id, snap.problem_id, snap.score, snap.mintime FROM contest_team_pretest_scores snap, system_testing 
+        WHERE system_testing.running AND snap.contest_id = $1 AND snap.frozen = (freeze_time IS NOT NULL)
+$$ LANGUAGE SQL STABLE;
+
+DROP FUNCTION IF EXISTS contest_team_top_view;
+-- teams with no members left are hidden
+CREATE OR REPLACE FUNCTION contest_team_top_view(contest_id bigint, freeze_time timestamptz) RETURNS TABLE (team_id bigint, contest_id bigint, total_score decimal, last_time timestamptz) AS $$
+    WITH contest_scores AS (
+        SELECT team_id, SUM(score) AS total_score, MAX(mintime) FILTER (WHERE score > 0) AS last_time FROM contest_team_max_scores($1, $2) WHERE score >= 0 GROUP BY team_id
+    ), hack_scores AS (
+        -- the hack points of the members count towards the team's score
+        SELECT regs.team_id, SUM(hacks.points) AS hack_score
+            FROM contest_hacks hacks INNER JOIN contest_registrations regs ON regs.contest_id = hacks.contest_id AND regs.user_id = hacks.hacker_id
+            WHERE hacks.contest_id = $1 AND hacks.status = 'finished' AND regs.team_id IS NOT NULL AND ($2 IS NULL OR hacks.created_at <= $2)
+            GROUP BY regs.team_id
+    ), legit_teams AS (
+        SELECT teams.* FROM contest_teams teams WHERE teams.contest_id = $1 AND EXISTS (SELECT 1 FROM contest_registrations regs WHERE regs.team_id = teams.id)
+    )
+    SELECT teams.id, $1 AS contest_id, COALESCE(scores.total_score, 0) + COALESCE(hacks.hack_score, 0) AS total_score, last_time
+    FROM legit_teams teams 
+        LEFT JOIN contest_scores scores ON teams.id = scores.team_id
+        LEFT JOIN hack_scores hacks ON teams.id = hacks.team_id
+    ORDER BY COALESCE(scores.total_score, 0) + COALESCE(hacks.hack_score, 0) DESC, last_time ASC NULLS LAST, teams.id;
+$$ LANGUAGE SQL STABLE;
+
+DROP FUNCTION IF EXISTS contest_team_icpc_view;
+CREATE OR REPLACE FUNCTION contest_team_icpc_view(contest_id bigint, freeze_time timestamptz) 
+RETURNS TABLE (team_id bigint, contest_id bigint, last_time timestamptz, num_solved integer, penalty integer, num_attempts integer) AS $$
+    WITH legit_teams AS (
+        SELECT teams.* FROM contest_teams teams WHERE teams.contest_id = $1 AND EXISTS (SELECT 1 FROM contest_registrations regs WHERE regs.team_id = teams.id)
+    ), solved_pbs AS (
+        SELECT team_id, problem_id, mintime AS last_time FROM contest_team_max_scores($1, $2) WHERE score = 100
+    ), stats AS (
+        SELECT team_id, COUNT(*) AS num_problems, MAX(last_time) AS last_time, 
+            SUM(FLOOR(EXTRACT(EPOCH FROM last_time - (SELECT start_time FROM contests WHERE id = $1 LIMIT 1)) / 60)) AS mins
+        FROM solved_pbs GROUP BY team_id
+    ), num_attempts AS (
+        -- TODO: Keep kind of in sync with left join in teamICPCToLeaderboardEntry
+        SELECT solved_pbs.team_id, COUNT(*) AS num_attempts 
+            FROM solved_pbs 
+            INNER JOIN submissions subs ON subs.contest_id = $1 
+                AND subs.team_id = solved_pbs.team_id 
+                AND subs.problem_id = solved_pbs.problem_id 
+                AND subs.created_at < solved_pbs.last_time
+                AND (subs.status = 'finished' OR subs.status = 'reevaling')
+            GROUP BY solved_pbs.team_id
+    ) SELECT teams.id, $1 AS contest_id, stats.last_time, COALESCE(stats.num_problems, 0), 
+            COALESCE(atts.num_attempts, 0) * (SELECT icpc_submission_penalty FROM contests WHERE id = $1 LIMIT 1) + GREATEST(COALESCE(stats.mins, 0), 0),
+            COALESCE(atts.num_attempts, 0)
+        FROM legit_teams teams
+        LEFT JOIN stats ON teams.id = stats.team_id
+        LEFT JOIN num_attempts atts ON teams.id = atts.team_id
+    ORDER BY COALESCE(stats.num_problems, 0) DESC, 5 ASC, stats.last_time ASC NULLS LAST, teams.id;
+$$ LANGUAGE SQL STABLE;
+
 DROP FUNCTION IF EXISTS visible_submissions;
 CREATE OR REPLACE FUNCTION visible_submissions(user_id bigint) RETURNS TABLE (sub_id bigint) AS $$
     WITH v_pbs AS (SELECT * FROM visible_pbs($1))
@@ -251,6 +396,16 @@ CREATE OR REPLACE FUNCTION visible_submissions(user_id bigint) RETURNS TABLE (su
         WHERE EXISTS (SELECT 1 FROM visible_contests($1) viz WHERE contests.id = viz.contest_id)
         AND contests.id = subs.contest_id AND v_pbs.problem_id = subs.problem_id
         AND contests.end_time <= NOW()) -- if the contest ended and the problem is visible, show the submission
+    UNION ALL
+    (SELECT subs.id as sub_id
+        FROM submissions subs, contests, contest_problem_locks locks
+        WHERE locks.user_id = $1 AND locks.contest_id = subs.contest_id AND locks.problem_id = subs.problem_id
+        AND contests.id = subs.contest_id AND contests.hacking = true
+        AND subs.status = 'finished' AND subs.score = 100) -- hacking phase: users that locked a problem can see the accepted submissions to it
+    UNION ALL
+    (SELECT subs.id as sub_id
+        FROM submissions subs, contest_registrations regs
+        WHERE regs.user_id = $1 AND regs.team_id IS NOT NULL AND subs.team_id = regs.team_id) -- team members can see their teammates' submissions
 $$ LANGUAGE SQL STABLE;
 
 DROP FUNCTION IF EXISTS visible_submissions_ex;
@@ -273,6 +428,17 @@ CREATE OR REPLACE FUNCTION visible_submissions_ex(user_id bigint, problem_id big
         WHERE EXISTS (SELECT 1 FROM visible_contests($1) viz WHERE contests.id = viz.contest_id)
         AND contests.id = subs.contest_id AND v_pbs.problem_id = subs.problem_id AND ($3 IS NULL OR subs.user_id = $3)
         AND contests.end_time <= NOW()) -- if the contest ended and the problem is visible, show the submission
+    UNION ALL
+    (SELECT subs.id as sub_id
+        FROM submissions subs, contests, contest_problem_locks locks
+        WHERE locks.user_id = $1 AND locks.contest_id = subs.contest_id AND locks.problem_id = subs.problem_id AND ($2 IS NULL OR subs.problem_id = $2)
+        AND contests.id = subs.contest_id AND contests.hacking = true
+        AND subs.status = 'finished' AND subs.score = 100 AND ($3 IS NULL OR subs.user_id = $3)) -- hacking phase: users that locked a problem can see the accepted submissions to it
+    UNION ALL
+    (SELECT subs.id as sub_id
+        FROM submissions subs, contest_registrations regs
+        WHERE regs.user_id = $1 AND regs.team_id IS NOT NULL AND subs.team_id = regs.team_id 
+        AND ($2 IS NULL OR subs.problem_id = $2) AND ($3 IS NULL OR subs.user_id = $3)) -- team members can see their teammates' submissions
 $$ LANGUAGE SQL STABLE;
 
 DROP VIEW IF EXISTS problem_list_deep_problems CASCADE;
@@ -50,6 +50,14 @@ func clearSubs(ctx context.Context, tx pgx.Tx, filter kilonova.SubmissionFilter)
 	return err
 }
 
+// pretestConstraint returns the condition for the test to be run for the submission.
+// Pretest-only submissions run only on pretests, unless the problem doesn't have any
+func pretestConstraint(testID string, sub string) string {
+	return fmt.Sprintf(`(NOT %[2]s.pretest_only 
+		OR EXISTS (SELECT 1 FROM tests pt WHERE pt.id = %[1]s AND pt.pretest) 
+		OR NOT EXISTS (SELECT 1 FROM tests pt WHERE pt.problem_id = %[2]s.problem_id AND pt.pretest))`, testID, sub)
+}
+
 func initSubs(ctx context.Context, tx pgx.Tx, filter kilonova.SubmissionFilter) error {
 	fb := newFilterBuilder()
 	subFilterQuery(&filter, fb)
@@ -73,7 +81,7 @@ func initSubs(ctx context.Context, tx pgx.Tx, filter kilonova.SubmissionFilter)
 		WITH subs_to_add AS (SELECT * FROM submissions WHERE %s)
 		SELECT subs.created_at AS created_at, subs.id AS submission_id, tests.id AS test_id, tests.visible_id, tests.score AS score 
 		FROM subs_to_add subs, tests 
-		WHERE subs.problem_id = tests.problem_id`, fb.Where()), fb.Args()...); err != nil {
+		WHERE subs.problem_id = tests.problem_id AND `+pretestConstraint("tests.id", "subs"), fb.Where()), fb.Args()...); err != nil {
 		return err
 	}
 
@@ -84,7 +92,8 @@ func initSubs(ctx context.Context, tx pgx.Tx, filter kilonova.SubmissionFilter)
 		WITH subs_to_add AS (SELECT * FROM submissions WHERE %s)
 	SELECT subs.user_id, subs.created_at AS created_at, subs.id AS submission_id, subs.contest_id, stks.id AS subtask_id, stks.problem_id AS problem_id, stks.visible_id, subs.digit_precision AS digit_precision, stks.score AS score, subs.leaderboard_score_scale AS leaderboard_score_scale
 	FROM subs_to_add subs, subtasks stks 
-	WHERE subs.problem_id = stks.problem_id`, fb.Where()), fb.Args()...); err != nil {
+	WHERE subs.problem_id = stks.problem_id 
+		AND (NOT subs.pretest_only OR EXISTS (SELECT 1 FROM subtask_tests stk_tests WHERE stk_tests.subtask_id = stks.id AND `+pretestConstraint("stk_tests.test_id", "subs")+`))`, fb.Where()), fb.Args()...); err != nil {
 		return err
 	}
 
@@ -30,6 +30,7 @@ type dbSubmission struct {
 	MaxMemory int     `db:"max_memory"`
 
 	ContestID *int `db:"contest_id"`
+	TeamID    *int `db:"team_id"`
 
 	Score          decimal.Decimal `db:"score"`
 	ScorePrecision int32           `db:"digit_precision"`
@@ -40,6 +41,10 @@ type dbSubmission struct {
 
 	SubmissionType kilonova.EvalType `db:"submission_type"`
 	ICPCVerdict    *string           `db:"icpc_verdict"`
+
+	InternalRetries int `db:"internal_retries"`
+
+	PretestOnly bool `db:"pretest_only"`
 }
 
 func (s *DB) Submission(ctx context.Context, id int) (*kilonova.Submission, error) {
@@ -124,14 +129,14 @@ func (s *DB) LastSubmissionTime(ctx context.Context, filter kilonova.SubmissionF
 	return val, nil
 }
 
-const createSubQuery = "INSERT INTO submissions (user_id, problem_id, contest_id, language, code) VALUES ($1, $2, $3, $4, $5) RETURNING id;"
+const createSubQuery = "INSERT INTO submissions (user_id, problem_id, contest_id, team_id, language, code, pretest_only) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;"
 
-func (s *DB) CreateSubmission(ctx context.Context, authorID int, problem *kilonova.Problem, language eval.Language, code string, contestID *int) (int, error) {
+func (s *DB) CreateSubmission(ctx context.Context, authorID int, problem *kilonova.Problem, language eval.Language, code string, contestID *int, teamID *int, pretestOnly bool) (int, error) {
 	if authorID <= 0 || problem == nil || language.InternalName == "" || code == "" {
 		return -1, kilonova.ErrMissingRequired
 	}
 	var id int
-	err := s.conn.QueryRow(ctx, createSubQuery, authorID, problem.ID, contestID, language.InternalName, code).Scan(&id)
+	err := s.conn.QueryRow(ctx, createSubQuery, authorID, problem.ID, contestID, teamID, language.InternalName, code, pretestOnly).Scan(&id)
 	return id, err
 }
 
@@ -304,6 +309,10 @@ func subUpdateQuery(upd *kilonova.SubmissionUpdate, b *updateBuilder) {
 	if v := upd.MaxMemory; v != nil {
 		b.AddUpdate("max_memory = %s", v)
 	}
+
+	if v := upd.InternalRetries; v != nil {
+		b.AddUpdate("internal_retries = %s", v)
+	}
 }
 
 func getSubmissionOrdering(ordering string, ascending bool) string {
@@ -344,6 +353,7 @@ func (s *DB) internalToSubmission(sub *dbSubmission) *kilonova.Submission {
 		MaxTime:        sub.MaxTime,
 		MaxMemory:      sub.MaxMemory,
 		ContestID:      sub.ContestID,
+		TeamID:         sub.TeamID,
 		Score:          sub.Score,
 		ScorePrecision: sub.ScorePrecision,
 		ScoreScale:     sub.ScoreScale,
@@ -352,5 +362,8 @@ func (s *DB) internalToSubmission(sub *dbSubmission) *kilonova.Submission {
 
 		SubmissionType: sub.SubmissionType,
 		ICPCVerdict:    sub.ICPCVerdict,
+
+		InternalRetries: sub.InternalRetries,
+		PretestOnly:     sub.PretestOnly,
 	}
 }
@@ -47,6 +47,9 @@ func (s *DB) UpdateTest(ctx context.Context, id int, upd kilonova.TestUpdate) er
 	if v := upd.VisibleID; v != nil {
 		ub.AddUpdate("visible_id = %s", v)
 	}
+	if v := upd.Pretest; v != nil {
+		ub.AddUpdate("pretest = %s", v)
+	}
 	if ub.CheckUpdates() != nil {
 		return ub.CheckUpdates()
 	}
@@ -36,6 +36,8 @@ type User struct {
 	Generated   bool `json:"generated" db:"generated"`
 
 	DisplayName string `json:"display_name" db:"display_name"`
+
+	Rating *int `json:"rating" db:"rating"`
 }
 
 func toUserBrief(user *User) *kilonova.UserBrief {
@@ -51,6 +53,8 @@ func toUserBrief(user *User) *kilonova.UserBrief {
 		DisplayName: user.DisplayName,
 
 		Generated: user.Generated,
+
+		Rating: user.Rating,
 	}
 }
 
@@ -291,6 +295,15 @@ func userFilterQuery(filter *kilonova.UserFilter, fb *filterBuilder) {
 	if v := filter.Generated; v != nil {
 		fb.AddConstraint("generated = %s", v)
 	}
+	if v := filter.Category; v != nil {
+		fb.AddConstraint("EXISTS (SELECT 1 FROM contest_registrations WHERE user_id = users.id AND contest_id = %s AND category = %s)", filter.ContestID, v)
+	}
+	if v := filter.MinRating; v != nil {
+		fb.AddConstraint("rating >= %s", v)
+	}
+	if v := filter.MaxRating; v != nil {
+		fb.AddConstraint("(rating IS NULL OR rating <= %s)", v)
+	}
 
 	if v := filter.SessionID; v != nil {
 		fb.AddConstraint("EXISTS (SELECT 1 FROM active_sessions WHERE user_id = users.id AND id = %s)", v)
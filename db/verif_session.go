@@ -190,6 +190,12 @@ func (s *DB) RemoveSessions(ctx context.Context, userID int) ([]string, error) {
 	return pgx.CollectRows(q, pgx.RowTo[string])
 }
 
+// RemoveSessionsExcept removes all of the user's sessions, other than the specified one
+func (s *DB) RemoveSessionsExcept(ctx context.Context, userID int, sid string) ([]string, error) {
+	q, _ := s.conn.Query(ctx, `DELETE FROM sessions WHERE user_id = $1 AND id != $2 RETURNING id`, userID, sid)
+	return pgx.CollectRows(q, pgx.RowTo[string])
+}
+
 func (s *DB) UpdateSessionDevice(ctx context.Context, sid string, uid int, ip *netip.Addr, userAgent *string) error {
 	_, err := s.conn.Exec(ctx, `INSERT INTO session_clients (session_id, ip_addr, user_agent, user_id) VALUES ($1, $2, $3, $4) ON CONFLICT ON CONSTRAINT unique_client_tuple DO UPDATE SET last_checked_at = NOW()`, sid, ip, userAgent, uid)
 	if err != nil && strings.Contains(err.Error(), "foreign key constraint") {
@@ -31,6 +31,11 @@ var _ Checker = &customChecker{}
 //go:embed checkerdata/testlib.h
 var testlibFile []byte
 
+// TestlibHeader returns the bundled testlib.h, for other problem helpers (such as input validators)
+func TestlibHeader() []byte {
+	return testlibFile
+}
+
 type customCheckerInput struct {
 	c *customChecker
 
@@ -74,21 +74,13 @@ func (h *Handler) LanguageVersions(ctx context.Context) map[string]string {
 }
 
 func (h *Handler) ScheduleSubmission(runner eval.BoxScheduler, sub *kilonova.Submission) error {
-	var subRunner eval.BoxScheduler
-	if sub.SubmissionType == kilonova.EvalTypeClassic {
-		r, err := runner.SubRunner(h.ctx, runner.NumConcurrent())
-		if err != nil {
-			return err
-		} else {
-			subRunner = r
-		}
-	} else {
-		r, err := runner.SubRunner(h.ctx, 1)
-		if err != nil {
-			return err
-		} else {
-			subRunner = r
-		}
+	var numBoxes int64 = 1
+	if policy := GetEvalPolicy(sub.SubmissionType); policy != nil && policy.Concurrent() {
+		numBoxes = runner.NumConcurrent()
+	}
+	subRunner, err := runner.SubRunner(h.ctx, numBoxes)
+	if err != nil {
+		return err
 	}
 	if err := h.base.UpdateSubmission(h.ctx, sub.ID, workingUpdate); err != nil {
 		return err
@@ -142,7 +134,7 @@ func (h *Handler) handle(runner eval.BoxScheduler) error {
 					rewake = true
 				}
 				for _, sub := range reevalQueue {
-					if err := h.base.ResetSubmission(h.ctx, sub.ID); err != nil {
+					if err := h.base.ReevaluateSubmission(h.ctx, sub.ID); err != nil {
 						zap.S().Warn("Couldn't reset submission: ", err)
 						continue
 					}
@@ -157,6 +149,22 @@ func (h *Handler) handle(runner eval.BoxScheduler) error {
 				}
 			}
 
+			hacks, err := h.base.ClaimPendingHacks(h.ctx, 5)
+			if err != nil {
+				zap.S().Warn(err)
+			} else if len(hacks) > 0 {
+				graderLogger.Info("Found pending hacks", slog.Int("count", len(hacks)))
+				rewake = rewake || len(hacks) == 5
+				for _, hack := range hacks {
+					if err := h.ScheduleHack(runner, hack); err != nil {
+						zap.S().Warn(err)
+						if err := h.base.RequeueHack(h.ctx, hack.ID); err != nil {
+							zap.S().Warn(err)
+						}
+					}
+				}
+			}
+
 			if rewake {
 				// Try to instantly continue working on the queue
 				h.Wake()
@@ -7,7 +7,6 @@ import (
 	"path"
 	"slices"
 	"strings"
-	"sync"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/datastore"
@@ -24,9 +23,10 @@ import (
 )
 
 var (
-	True            = true
-	skippedVerdict  = "translate:skipped"
-	acceptedVerdict = "test_verdict.accepted"
+	True                 = true
+	skippedVerdict       = "translate:skipped"
+	acceptedVerdict      = "test_verdict.accepted"
+	internalErrorVerdict = "translate:internal_error"
 )
 
 func genSubCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, pb *kilonova.Problem, settings *kilonova.ProblemEvalSettings) (*tasks.CompileRequest, *kilonova.StatusError) {
@@ -86,7 +86,18 @@ func genSubCompileRequest(ctx context.Context, base *sudoapi.BaseAPI, sub *kilon
 
 func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission) error {
 	graderLogger.Info("Executing submission", slog.Int("id", sub.ID), slog.Any("status", sub.Status))
+	var hadInternalErr bool
 	defer func() {
+		if hadInternalErr {
+			retried, err := base.RetrySubmission(ctx, sub)
+			if err != nil {
+				zap.S().Warn("Couldn't retry submission:", err)
+			}
+			if retried {
+				base.WakeGrader()
+				return
+			}
+		}
 		// In case anything ever happens, make sure it is at least marked as finished
 		if err := base.UpdateSubmission(ctx, sub.ID, kilonova.SubmissionUpdate{Status: kilonova.StatusFinished}); err != nil {
 			zap.S().Warn("Couldn't finish submission:", err)
@@ -149,23 +160,20 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 		return kilonova.WrapError(err1, "Could not fetch subtests")
 	}
 
-	// TODO: This is shit.
-	// It is basically 2 implementations for ~ the same thing. It could be merged neater
-	switch sub.SubmissionType {
-	case kilonova.EvalTypeClassic:
-		if err := handleClassicSubmission(ctx, base, runner, sub, problem, checker, subTests); err != nil {
-			zap.S().Warn(err)
-			return err
-		}
-	case kilonova.EvalTypeICPC:
-		if err := handleICPCSubmission(ctx, base, runner, sub, problem, checker, subTests); err != nil {
-			zap.S().Warn(err)
-			return err
-		}
-	default:
+	policy := GetEvalPolicy(sub.SubmissionType)
+	if policy == nil {
 		return kilonova.Statusf(500, "Invalid eval type")
 	}
 
+	failed, err := runSubmission(ctx, base, sub, problem, policy, subTests, func(subTest *kilonova.SubTest) (decimal.Decimal, string, error) {
+		return handleSubTest(ctx, base, runner, checker, sub, problem, policy.MaskSignals, subTest)
+	})
+	hadInternalErr = failed
+	if err != nil {
+		zap.S().Warn(err)
+		return err
+	}
+
 	if err := datastore.GetBucket(datastore.BucketTypeCompiles).RemoveFile(fmt.Sprintf("%d.bin", sub.ID)); err != nil {
 		zap.S().Warn("Couldn't remove compilation artifact: ", err)
 	}
@@ -176,87 +184,6 @@ func executeSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 	return nil
 }
 
-func handleClassicSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
-	var wg sync.WaitGroup
-
-	for _, subTest := range subTests {
-		subTest := subTest
-		wg.Add(1)
-
-		go func() {
-			defer wg.Done()
-			_, _, err := handleSubTest(ctx, base, runner, checker, sub, problem, subTest)
-			if err != nil {
-				zap.S().Warn("Error handling subtest:", err)
-			}
-		}()
-	}
-
-	wg.Wait()
-
-	if err := scoreTests(ctx, base, sub, problem); err != nil {
-		zap.S().Warn("Couldn't score test: ", err)
-	}
-
-	return nil
-}
-
-func handleICPCSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, checker checkers.Checker, subTests []*kilonova.SubTest) *kilonova.StatusError {
-	var failed bool
-	var upd kilonova.SubmissionUpdate
-	upd.Status = kilonova.StatusFinished
-
-	for _, subTest := range subTests {
-		if failed {
-			if err := base.UpdateSubTest(ctx, subTest.ID, kilonova.SubTestUpdate{
-				Done: &True, Skipped: &True,
-				Verdict: &skippedVerdict,
-			}); err != nil {
-				zap.S().Warn("Couldn't update skipped subtest:", err)
-			}
-			continue
-		}
-		score, verdict, err := handleSubTest(ctx, base, runner, checker, sub, problem, subTest)
-		if err != nil {
-			zap.S().Warn("Error handling subtest:", err)
-			continue
-		}
-		if !score.Equal(decimal.NewFromInt(100)) {
-			upd.Score = &problem.DefaultPoints
-			upd.ChangeVerdict = true
-
-			verdict = fmt.Sprintf("%s (test_verdict.test_x #%d)", strings.ReplaceAll(verdict, "translate:", "test_verdict."), subTest.VisibleID)
-			upd.ICPCVerdict = &verdict
-
-			failed = true
-		}
-	}
-
-	if !failed {
-		hundred := decimal.NewFromInt(100)
-		upd.Score = &hundred
-		upd.ChangeVerdict = true
-		upd.ICPCVerdict = &acceptedVerdict
-	}
-
-	subTests, err := base.SubTests(ctx, sub.ID)
-	if err != nil {
-		zap.S().Warn("Could not get subtests for max score/mem updating:", err)
-		return err
-	}
-
-	var memory int
-	var time float64
-	for _, subtest := range subTests {
-		memory = max(memory, subtest.Memory)
-		time = max(time, subtest.Time)
-	}
-	upd.MaxTime = &time
-	upd.MaxMemory = &memory
-
-	return base.UpdateSubmission(ctx, sub.ID, upd)
-}
-
 func compileSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, sub *kilonova.Submission, problem *kilonova.Problem, problemSettings *kilonova.ProblemEvalSettings) *kilonova.StatusError {
 	req, err := genSubCompileRequest(ctx, base, sub, problem, problemSettings)
 	if err != nil {
@@ -307,7 +234,7 @@ func compileSubmission(ctx context.Context, base *sudoapi.BaseAPI, runner eval.B
 	return nil
 }
 
-func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, checker checkers.Checker, sub *kilonova.Submission, problem *kilonova.Problem, subTest *kilonova.SubTest) (decimal.Decimal, string, error) {
+func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, checker checkers.Checker, sub *kilonova.Submission, problem *kilonova.Problem, maskSignals bool, subTest *kilonova.SubTest) (decimal.Decimal, string, error) {
 	if subTest.TestID == nil {
 		zap.S().Error("A subtest whose test was purged was detected.", spew.Sdump(subTest))
 		return decimal.Zero, "", kilonova.Statusf(400, "Trying to handle subtest whose test was purged. This should never happen")
@@ -342,8 +269,13 @@ func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxSc
 		resp.Comments, testScore = checker.RunChecker(ctx, subTest.ID, *subTest.TestID)
 	}
 
-	// Hide fatal signals for ICPC submissions
-	if sub.SubmissionType == kilonova.EvalTypeICPC {
+	if strings.HasPrefix(resp.Comments, internalErrorVerdict) {
+		// Either the sandbox or the checker failed, it's not the contestant's fault
+		return decimal.Zero, "", kilonova.Statusf(500, "Internal error during subtest evaluation: %s", resp.Comments)
+	}
+
+	// Hide fatal signals if the eval policy asks for it
+	if maskSignals {
 		if strings.Contains(resp.Comments, "signal 9") {
 			resp.Comments = "translate:memory_limit"
 		}
@@ -358,6 +290,16 @@ func handleSubTest(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxSc
 	return testScore, resp.Comments, nil
 }
 
+// markSubtestInternalError is used when a subtest could not be evaluated because of a grader failure
+func markSubtestInternalError(ctx context.Context, base *sudoapi.BaseAPI, subTest *kilonova.SubTest) {
+	if err := base.UpdateSubTest(ctx, subTest.ID, kilonova.SubTestUpdate{
+		Done: &True, Percentage: &decimal.Zero,
+		Verdict: &internalErrorVerdict,
+	}); err != nil {
+		zap.S().Warn("Couldn't mark subtest internal error:", err)
+	}
+}
+
 func markSubtestsDone(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission) error {
 	sts, err := base.SubTests(ctx, sub.ID)
 	if err != nil {
@@ -374,18 +316,16 @@ func markSubtestsDone(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.
 	return nil
 }
 
-func scoreTests(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, problem *kilonova.Problem) *kilonova.StatusError {
+// scoreSubtasks computes the classic score and also updates the submission subtask percentages
+func scoreSubtasks(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, problem *kilonova.Problem, _ []*subtestResult) (decimal.Decimal, *kilonova.StatusError) {
 	subtests, err1 := base.SubTests(ctx, sub.ID)
 	if err1 != nil {
-		return err1
+		return decimal.Zero, err1
 	}
 
 	subTasks, err1 := base.SubmissionSubTasks(ctx, sub.ID)
 	if err1 != nil {
-		return err1
-	}
-	if len(subTasks) == 0 {
-		subTasks = nil
+		return decimal.Zero, err1
 	}
 
 	var score = problem.DefaultPoints
@@ -423,14 +363,7 @@ func scoreTests(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submis
 		}
 	}
 
-	var memory int
-	var time float64
-	for _, subtest := range subtests {
-		memory = max(memory, subtest.Memory)
-		time = max(time, subtest.Time)
-	}
-
-	return base.UpdateSubmission(ctx, sub.ID, kilonova.SubmissionUpdate{Status: kilonova.StatusFinished, Score: &score, MaxTime: &time, MaxMemory: &memory})
+	return score, nil
 }
 
 var ForceSecureSandbox = config.GenFlag[bool]("feature.grader.force_secure_sandbox", true, "Force use of secure sandbox only. Should be always enabled in production environments")
@@ -0,0 +1,278 @@
+package grader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/datastore"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/eval/checkers"
+	"github.com/KiloProjects/kilonova/eval/tasks"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+const (
+	validatorMemoryLimit = 512 * 1024
+	validatorTimeLimit   = 10
+)
+
+// hackHelperMu guards the compilation of problem helpers (reference solutions and validators)
+var hackHelperMu sync.Mutex
+
+// hackResult is used to finish a hack from anywhere in the pipeline
+type hackResult struct {
+	verdict kilonova.HackVerdict
+	message string
+}
+
+func (h *Handler) ScheduleHack(runner eval.BoxScheduler, hack *kilonova.ContestHack) error {
+	subRunner, err := runner.SubRunner(h.ctx, 1)
+	if err != nil {
+		return err
+	}
+	go func(hack *kilonova.ContestHack, r eval.BoxScheduler) {
+		defer r.Close(h.ctx)
+		res := executeHack(h.ctx, h.base, r, hack)
+		if err := h.base.FinishHack(h.ctx, hack, res.verdict, res.message); err != nil {
+			zap.S().Warn("Couldn't finish hack: ", err)
+		}
+	}(hack, subRunner)
+	return nil
+}
+
+// executeHack validates the hack input, generates the correct output using the reference solution
+// and then judges the target submission on it, just like on a regular test.
+func executeHack(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, hack *kilonova.ContestHack) hackResult {
+	graderLogger.Info("Executing hack", slog.Int("id", hack.ID), slog.Int("target_id", hack.TargetSubmissionID))
+	internalError := func(msg string, err error) hackResult {
+		zap.S().Warnf("Hack #%d: %s: %v", hack.ID, msg, err)
+		return hackResult{kilonova.HackVerdictInternalError, msg}
+	}
+
+	testID := sudoapi.HackTestID(hack.ID)
+	targetExec := &eval.BucketFile{Bucket: datastore.BucketTypeCompiles, Filename: fmt.Sprintf("hack-%d.bin", hack.ID), Mode: 0777}
+	defer func() {
+		if err := errors.Join(
+			datastore.GetBucket(datastore.BucketTypeCompiles).RemoveFile(targetExec.Filename),
+			datastore.GetBucket(datastore.BucketTypeSubtests).RemoveFile(strconv.Itoa(testID)),
+		); err != nil {
+			zap.S().Warn("Couldn't clean up hack artifacts: ", err)
+		}
+	}()
+
+	problem, err := base.Problem(ctx, hack.ProblemID)
+	if err != nil {
+		return internalError("Couldn't get problem", err)
+	}
+	settings, err := base.ProblemSettings(ctx, hack.ProblemID)
+	if err != nil {
+		return internalError("Couldn't get problem settings", err)
+	}
+	target, err := base.RawSubmission(ctx, hack.TargetSubmissionID)
+	if err != nil {
+		return internalError("Couldn't get target submission", err)
+	}
+	if settings.SolutionName == "" {
+		return internalError("Problem has no reference solution", nil)
+	}
+
+	input, err := base.HackInput(ctx, hack.ID)
+	if err != nil {
+		return internalError("Couldn't get hack input", err)
+	}
+	if err := base.SaveTestInput(testID, bytes.NewReader(input)); err != nil {
+		return internalError("Couldn't save hack input", err)
+	}
+
+	if settings.ValidatorName != "" {
+		validator, err := prepareProblemHelper(ctx, base, runner, problem, settings.ValidatorName, "validator")
+		if err != nil {
+			return internalError("Couldn't prepare validator", err)
+		}
+		if ok, msg, err := runValidator(ctx, runner, validator, settings.ValidatorName, testID); err != nil {
+			return internalError("Couldn't run validator", err)
+		} else if !ok {
+			return hackResult{kilonova.HackVerdictInvalidInput, msg}
+		}
+	}
+
+	solution, err1 := prepareProblemHelper(ctx, base, runner, problem, settings.SolutionName, "solution")
+	if err1 != nil {
+		return internalError("Couldn't prepare reference solution", err1)
+	}
+	execRequest := &tasks.ExecRequest{
+		SubID:       target.ID,
+		SubtestID:   testID,
+		Filename:    problem.TestName,
+		MemoryLimit: problem.MemoryLimit,
+		TimeLimit:   problem.TimeLimit,
+		Lang:        eval.GetLangByFilename(settings.SolutionName),
+		TestID:      testID,
+
+		Executable: solution,
+		Output: &eval.BucketFile{
+			Bucket:   datastore.BucketTypeTests,
+			Filename: fmt.Sprintf("%d.out", testID),
+			Mode:     0644,
+		},
+	}
+	if problem.ConsoleInput {
+		execRequest.Filename = "stdin"
+	}
+	resp, err1 := tasks.ExecuteTask(ctx, runner, int64(problem.MemoryLimit), execRequest, graderLogger)
+	if err1 != nil {
+		return internalError("Couldn't run reference solution", err1)
+	}
+	if resp.Comments != "" {
+		// Most likely the input breaks the constraints in a way the validator didn't catch
+		return hackResult{kilonova.HackVerdictInvalidInput, "Reference solution failed: " + resp.Comments}
+	}
+
+	compileReq, err := genSubCompileRequest(ctx, base, target, problem, settings)
+	if err != nil {
+		return internalError("Couldn't generate target compilation request", err)
+	}
+	compileReq.Output = targetExec
+	compileResp, err1 := tasks.CompileTask(ctx, runner, compileReq, graderLogger)
+	if err1 != nil || !compileResp.Success {
+		return internalError("Couldn't compile target submission", err1)
+	}
+
+	execRequest.Lang = target.Language
+	execRequest.Executable = targetExec
+	execRequest.Output = nil
+	resp, err1 = tasks.ExecuteTask(ctx, runner, int64(problem.MemoryLimit), execRequest, graderLogger)
+	if err1 != nil {
+		return internalError("Couldn't run target submission", err1)
+	}
+	if resp.Time > problem.TimeLimit {
+		resp.Comments = "translate:timeout"
+	}
+	if strings.HasPrefix(resp.Comments, internalErrorVerdict) {
+		return internalError("Target execution failed", errors.New(resp.Comments))
+	}
+	if resp.Comments != "" {
+		return hackResult{kilonova.HackVerdictSuccessful, resp.Comments}
+	}
+
+	checker, err1 := getAppropriateChecker(ctx, base, runner, target, problem, settings)
+	if err1 != nil {
+		return internalError("Couldn't get checker", err1)
+	}
+	if _, err := checker.Prepare(ctx); err != nil {
+		return internalError("Couldn't prepare checker", err)
+	}
+	msg, percentage := checker.RunChecker(ctx, testID, testID)
+	if strings.HasPrefix(msg, internalErrorVerdict) {
+		return internalError("Checker failed", errors.New(msg))
+	}
+	if percentage.LessThan(decimal.NewFromInt(100)) {
+		return hackResult{kilonova.HackVerdictSuccessful, msg}
+	}
+	return hackResult{kilonova.HackVerdictUnsuccessful, msg}
+}
+
+// prepareProblemHelper compiles the given problem attachment into the checkers bucket, unless a fresh enough build already exists.
+// kind distinguishes the executables of the same problem
+func prepareProblemHelper(ctx context.Context, base *sudoapi.BaseAPI, runner eval.BoxScheduler, problem *kilonova.Problem, name string, kind string) (*eval.BucketFile, error) {
+	out := &eval.BucketFile{Bucket: datastore.BucketTypeCheckers, Filename: fmt.Sprintf("%s-%d.bin", kind, problem.ID), Mode: 0777}
+
+	att, err := base.ProblemAttByName(ctx, problem.ID, name)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't get helper metadata")
+	}
+
+	hackHelperMu.Lock()
+	defer hackHelperMu.Unlock()
+
+	stat, err1 := datastore.GetBucket(out.Bucket).Stat(out.Filename)
+	if err1 == nil && !stat.ModTime().Before(att.LastUpdatedAt) {
+		return out, nil
+	}
+	if err1 != nil && !errors.Is(err1, fs.ErrNotExist) {
+		zap.S().Warn("Helper stat error: ", err1)
+	}
+
+	data, err := base.ProblemAttDataByName(ctx, problem.ID, name)
+	if err != nil {
+		return nil, kilonova.WrapError(err, "Couldn't get helper code")
+	}
+	lang := eval.GetLangByFilename(name)
+	resp, err1 := tasks.CompileTask(ctx, runner, &tasks.CompileRequest{
+		ID: -problem.ID,
+		CodeFiles: map[string][]byte{
+			eval.Langs[lang].SourceName: data,
+		},
+		HeaderFiles: map[string][]byte{
+			"/box/testlib.h": checkers.TestlibHeader(),
+		},
+		Lang:   lang,
+		Output: out,
+	}, graderLogger)
+	if err1 != nil {
+		return nil, err1
+	}
+	if !resp.Success {
+		return nil, kilonova.Statusf(400, "Invalid helper code:\n%s\n%s", resp.Output, resp.Other)
+	}
+	return out, nil
+}
+
+// runValidator runs the validator with the test as standard input. The input is valid if it exits successfully
+func runValidator(ctx context.Context, runner eval.BoxScheduler, validator *eval.BucketFile, name string, testID int) (bool, string, error) {
+	lang := eval.Langs[eval.GetLangByFilename(name)]
+	req := &eval.Box2Request{
+		InputBucketFiles: map[string]*eval.BucketFile{
+			"/box/input.txt": {
+				Bucket:   datastore.BucketTypeTests,
+				Filename: fmt.Sprintf("%d.in", testID),
+				Mode:     0666,
+			},
+			lang.CompiledName: validator,
+		},
+		Command: slices.Clone(lang.RunCommand),
+		RunConfig: &eval.RunConfig{
+			EnvToSet:      maps.Clone(lang.RunEnv),
+			InputPath:     "/box/input.txt",
+			StderrPath:    "/box/validator.err",
+			MemoryLimit:   validatorMemoryLimit,
+			TimeLimit:     validatorTimeLimit,
+			WallTimeLimit: 2 * validatorTimeLimit,
+		},
+		OutputByteFiles: []string{"/box/validator.err"},
+	}
+	if !lang.Compiled {
+		req.RunConfig.Directories = slices.Clone(lang.Mounts)
+	}
+
+	resp, err := runner.RunBox2(ctx, req, validatorMemoryLimit)
+	if err != nil {
+		return false, "", err
+	}
+	if resp == nil {
+		return false, "", errors.New("no validator response")
+	}
+	switch resp.Stats.Status {
+	case "XX":
+		return false, "", fmt.Errorf("sandbox error: %s", resp.Stats.Message)
+	case "TO", "RE", "SG":
+		msg := strings.TrimSpace(string(resp.ByteFiles["/box/validator.err"]))
+		if msg == "" {
+			msg = resp.Stats.Message
+		}
+		return false, msg, nil
+	}
+	return true, "", nil
+}
@@ -0,0 +1,174 @@
+package grader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/sudoapi"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// EvalPolicy describes how the subtests of a submission are evaluated and how the final result is computed.
+// Every evaluation type is just a different configuration of the same pipeline (see runSubmission).
+type EvalPolicy struct {
+	// Parallel runs all subtests at once, using all the available boxes.
+	// It is ignored if StopOnFailure is set, since tests must then be run in order.
+	Parallel bool
+
+	// StopOnFailure marks all remaining subtests as skipped after the first one that isn't fully correct.
+	StopOnFailure bool
+
+	// MaskSignals hides fatal signal details behind generic memory limit/runtime error verdicts.
+	MaskSignals bool
+
+	// Verdict, if not nil, computes the submission-wide verdict.
+	// failed is the first subtest (in visible order) that isn't fully correct, or nil if everything passed.
+	Verdict func(failed *subtestResult) string
+
+	// Score computes the final submission score from the subtest results.
+	Score func(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, problem *kilonova.Problem, results []*subtestResult) (decimal.Decimal, *kilonova.StatusError)
+}
+
+// Concurrent returns whether the policy allows running multiple subtests at the same time
+func (p *EvalPolicy) Concurrent() bool {
+	return p.Parallel && !p.StopOnFailure
+}
+
+type subtestResult struct {
+	subTest *kilonova.SubTest
+
+	score   decimal.Decimal
+	verdict string
+
+	skipped     bool
+	internalErr bool
+}
+
+func (r *subtestResult) passed() bool {
+	return !r.skipped && !r.internalErr && r.score.Equal(decimal.NewFromInt(100))
+}
+
+var evalPolicies = map[kilonova.EvalType]*EvalPolicy{
+	kilonova.EvalTypeClassic: {
+		Parallel: true,
+		Score:    scoreSubtasks,
+	},
+	kilonova.EvalTypeICPC: {
+		StopOnFailure: true,
+		MaskSignals:   true,
+		Verdict:       icpcVerdict,
+		Score:         scoreAllOrNothing,
+	},
+}
+
+// GetEvalPolicy returns the evaluation policy for the given eval type, or nil if there is none
+func GetEvalPolicy(evalType kilonova.EvalType) *EvalPolicy {
+	return evalPolicies[evalType]
+}
+
+func scoreAllOrNothing(_ context.Context, _ *sudoapi.BaseAPI, _ *kilonova.Submission, problem *kilonova.Problem, results []*subtestResult) (decimal.Decimal, *kilonova.StatusError) {
+	for _, res := range results {
+		if !res.passed() {
+			return problem.DefaultPoints, nil
+		}
+	}
+	return decimal.NewFromInt(100), nil
+}
+
+func icpcVerdict(failed *subtestResult) string {
+	if failed == nil {
+		return acceptedVerdict
+	}
+	return fmt.Sprintf("%s (test_verdict.test_x #%d)", strings.ReplaceAll(failed.verdict, "translate:", "test_verdict."), failed.subTest.VisibleID)
+}
+
+// runSubmission runs the subtests according to the policy and finalizes the submission.
+// It returns true if any of the subtests failed because of an internal error
+func runSubmission(ctx context.Context, base *sudoapi.BaseAPI, sub *kilonova.Submission, problem *kilonova.Problem, policy *EvalPolicy, subTests []*kilonova.SubTest, runTest func(*kilonova.SubTest) (decimal.Decimal, string, error)) (bool, *kilonova.StatusError) {
+	results := make([]*subtestResult, len(subTests))
+	run := func(i int) {
+		score, verdict, err := runTest(subTests[i])
+		results[i] = &subtestResult{subTest: subTests[i], score: score, verdict: verdict}
+		if err != nil {
+			zap.S().Warn("Error handling subtest:", err)
+			markSubtestInternalError(ctx, base, subTests[i])
+			results[i].score, results[i].verdict, results[i].internalErr = decimal.Zero, internalErrorVerdict, true
+		}
+	}
+
+	if policy.Concurrent() {
+		var wg sync.WaitGroup
+		for i := range subTests {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		var failed bool
+		for i, subTest := range subTests {
+			if failed && policy.StopOnFailure {
+				results[i] = &subtestResult{subTest: subTest, score: decimal.Zero, verdict: skippedVerdict, skipped: true}
+				if err := base.UpdateSubTest(ctx, subTest.ID, kilonova.SubTestUpdate{
+					Done: &True, Skipped: &True,
+					Verdict: &skippedVerdict,
+				}); err != nil {
+					zap.S().Warn("Couldn't update skipped subtest:", err)
+				}
+				continue
+			}
+			run(i)
+			failed = failed || !results[i].passed()
+		}
+	}
+
+	var internalErr bool
+	var firstFailed *subtestResult
+	for _, res := range results {
+		internalErr = internalErr || res.internalErr
+		if firstFailed == nil && !res.skipped && !res.passed() {
+			firstFailed = res
+		}
+	}
+
+	upd := kilonova.SubmissionUpdate{Status: kilonova.StatusFinished}
+
+	score, err := policy.Score(ctx, base, sub, problem, results)
+	if err != nil {
+		zap.S().Warn("Couldn't score submission: ", err)
+	} else {
+		upd.Score = &score
+	}
+
+	if policy.Verdict != nil {
+		verdict := policy.Verdict(firstFailed)
+		upd.ChangeVerdict, upd.ICPCVerdict = true, &verdict
+	} else if internalErr {
+		verdict := "test_verdict.internal_error"
+		upd.ChangeVerdict, upd.ICPCVerdict = true, &verdict
+	}
+
+	// Refetch, since results don't contain time/memory statistics
+	subTests, err = base.SubTests(ctx, sub.ID)
+	if err != nil {
+		zap.S().Warn("Could not get subtests for max score/mem updating:", err)
+		return internalErr, err
+	}
+
+	var memory int
+	var time float64
+	for _, subtest := range subTests {
+		memory = max(memory, subtest.Memory)
+		time = max(time, subtest.Time)
+	}
+	upd.MaxTime = &time
+	upd.MaxMemory = &memory
+
+	return internalErr, base.UpdateSubmission(ctx, sub.ID, upd)
+}
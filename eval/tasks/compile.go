@@ -22,6 +22,9 @@ type CompileRequest struct {
 	CodeFiles   map[string][]byte
 	HeaderFiles map[string][]byte
 	Lang        string
+
+	// Output, if not nil, overrides the location of the compiled executable, which is otherwise decided from the ID
+	Output *eval.BucketFile
 }
 
 type CompileResponse struct {
@@ -53,6 +56,9 @@ func CompileTask(ctx context.Context, mgr eval.BoxScheduler, req *CompileRequest
 	}
 
 	bucket, outName := bucketFromIDExec(req.ID)
+	if req.Output != nil {
+		bucket, outName = req.Output.Bucket, req.Output.Filename
+	}
 	resp.Success = true
 
 	// If the language is interpreted, just save the code and leave
@@ -25,6 +25,11 @@ type ExecRequest struct {
 
 	Lang   string
 	TestID int
+
+	// Executable and Output, if not nil, override the locations of the executable (otherwise decided from SubID)
+	// and of the program output (otherwise saved as the subtest's output)
+	Executable *eval.BucketFile
+	Output     *eval.BucketFile
 }
 
 type ExecResponse struct {
@@ -38,9 +43,20 @@ func ExecuteTask(ctx context.Context, mgr eval.BoxScheduler, memQuota int64, req
 	logger.Info("Executing subtest", slog.Int("subtest_id", req.SubtestID), slog.Int("sub_id", req.SubID))
 
 	bucket, fileName := bucketFromIDExec(req.SubID)
+	if req.Executable != nil {
+		bucket, fileName = req.Executable.Bucket, req.Executable.Filename
+	}
 	lang := eval.Langs[req.Lang]
 
 	boxOut := fmt.Sprintf("/box/%s.out", req.Filename)
+	output := &eval.BucketFile{
+		Bucket:   datastore.BucketTypeSubtests,
+		Filename: strconv.Itoa(req.SubtestID),
+		Mode:     0644,
+	}
+	if req.Output != nil {
+		output = req.Output
+	}
 
 	bReq := &eval.Box2Request{
 		InputBucketFiles: map[string]*eval.BucketFile{
@@ -66,11 +82,7 @@ func ExecuteTask(ctx context.Context, mgr eval.BoxScheduler, memQuota int64, req
 		},
 
 		OutputBucketFiles: map[string]*eval.BucketFile{
-			boxOut: {
-				Bucket:   datastore.BucketTypeSubtests,
-				Filename: strconv.Itoa(req.SubtestID),
-				Mode:     0644,
-			},
+			boxOut: output,
 		},
 
 		Command: slices.Clone(lang.RunCommand),
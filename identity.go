@@ -0,0 +1,15 @@
+package kilonova
+
+import "time"
+
+// ExternalIdentity is an account of an external OpenID Connect provider, linked to a user
+type ExternalIdentity struct {
+	Provider string `json:"provider" db:"provider"`
+	// Subject is the provider's identifier for the account
+	Subject string  `json:"subject" db:"subject"`
+	UserID  int     `json:"user_id" db:"user_id"`
+	Email   *string `json:"email" db:"email"`
+
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+}
@@ -0,0 +1,174 @@
+// Package clics implements the objects of the CLICS Contest API (https://ccs-specs.icpc.io/2020-03/contest_api),
+// used to exchange contest data with ICPC tools such as the ICPC Resolver.
+package clics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Time formats the timestamp as a CLICS TIME value
+func Time(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// RelTime formats the duration as a CLICS RELTIME value (h:mm:ss.uuu)
+func RelTime(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%s%d:%02d:%02d.%03d", sign, ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+type Contest struct {
+	ID                       string  `json:"id"`
+	Name                     string  `json:"name"`
+	FormalName               string  `json:"formal_name"`
+	StartTime                *string `json:"start_time"`
+	Duration                 string  `json:"duration"`
+	ScoreboardFreezeDuration *string `json:"scoreboard_freeze_duration"`
+	ScoreboardType           string  `json:"scoreboard_type"`
+	PenaltyTime              int     `json:"penalty_time"`
+}
+
+type JudgementType struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Penalty bool   `json:"penalty"`
+	Solved  bool   `json:"solved"`
+}
+
+type Language struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Extensions []string `json:"extensions"`
+}
+
+type Problem struct {
+	ID          string  `json:"id"`
+	Label       string  `json:"label"`
+	Name        string  `json:"name"`
+	Ordinal     int     `json:"ordinal"`
+	TimeLimit   float64 `json:"time_limit"`
+	TestDataCnt int     `json:"test_data_count"`
+}
+
+type Group struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Hidden bool   `json:"hidden"`
+}
+
+type Team struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name,omitempty"`
+	GroupIDs    []string `json:"group_ids"`
+}
+
+type Submission struct {
+	ID          string `json:"id"`
+	LanguageID  string `json:"language_id"`
+	ProblemID   string `json:"problem_id"`
+	TeamID      string `json:"team_id"`
+	Time        string `json:"time"`
+	ContestTime string `json:"contest_time"`
+}
+
+type Judgement struct {
+	ID               string   `json:"id"`
+	SubmissionID     string   `json:"submission_id"`
+	JudgementTypeID  *string  `json:"judgement_type_id"`
+	StartTime        string   `json:"start_time"`
+	StartContestTime string   `json:"start_contest_time"`
+	EndTime          *string  `json:"end_time"`
+	EndContestTime   *string  `json:"end_contest_time"`
+	MaxRunTime       *float64 `json:"max_run_time,omitempty"`
+}
+
+type Run struct {
+	ID              string  `json:"id"`
+	JudgementID     string  `json:"judgement_id"`
+	Ordinal         int     `json:"ordinal"`
+	JudgementTypeID string  `json:"judgement_type_id"`
+	Time            string  `json:"time"`
+	ContestTime     string  `json:"contest_time"`
+	RunTime         float64 `json:"run_time"`
+}
+
+type State struct {
+	Started      *string `json:"started"`
+	Frozen       *string `json:"frozen"`
+	Ended        *string `json:"ended"`
+	Thawed       *string `json:"thawed"`
+	Finalized    *string `json:"finalized"`
+	EndOfUpdates *string `json:"end_of_updates"`
+}
+
+// Event is a single notification of the event feed
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Op   string `json:"op"`
+	Data any    `json:"data"`
+}
+
+// FeedWriter writes an event feed as newline delimited JSON
+type FeedWriter struct {
+	w     io.Writer
+	count int
+
+	// sent holds the last version of every object written using Sync, by type and ID
+	sent map[string]map[string][]byte
+}
+
+func NewFeedWriter(w io.Writer) *FeedWriter {
+	return &FeedWriter{w: w, sent: make(map[string]map[string][]byte)}
+}
+
+func (fw *FeedWriter) write(typ, op string, data json.RawMessage) error {
+	fw.count++
+	ev, err := json.Marshal(Event{ID: fmt.Sprintf("kn%d", fw.count), Type: typ, Op: op, Data: data})
+	if err != nil {
+		return err
+	}
+	_, err = fw.w.Write(append(ev, '\n'))
+	return err
+}
+
+// Create writes a "create" event for the object of the given type
+func (fw *FeedWriter) Create(typ string, data any) error {
+	val, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return fw.write(typ, "create", val)
+}
+
+// Sync writes a "create" event the first time the object with the given ID is seen, and an "update" event whenever it changes afterwards.
+// It returns whether anything was written. Singleton objects (the contest, the state) should use an empty ID
+func (fw *FeedWriter) Sync(typ, id string, data any) (bool, error) {
+	val, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := fw.sent[typ]; !ok {
+		fw.sent[typ] = make(map[string][]byte)
+	}
+	last, ok := fw.sent[typ][id]
+	if ok && bytes.Equal(last, val) {
+		return false, nil
+	}
+	fw.sent[typ][id] = val
+	op := "create"
+	if ok {
+		op = "update"
+	}
+	return true, fw.write(typ, op, val)
+}
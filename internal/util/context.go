@@ -37,12 +37,19 @@ const (
 	TagKey = KNContextType("tag")
 	// ContestKey is the key to be used for adding contests to context
 	ContestKey = KNContextType("contest")
+	// SeriesKey is the key to be used for adding contest series to context
+	SeriesKey = KNContextType("contestSeries")
 	// LangKey is the key to be used for adding the user language to context
 	LangKey = KNContextType("language")
 	// BucketKey is the key to be used for adding the requested bucket to context
 	BucketKey = KNContextType("bucket")
 	// ThemeKey is the key to be used for adding the user's preferred theme to context
 	ThemeKey = KNContextType("theme")
+	// APITokenKey is the key to be used for adding the personal access token that authenticated the request to context
+	APITokenKey = KNContextType("apiToken")
+	// TokenUserKey is the key to be used for adding the owner of the request's personal access token to context.
+	// The owner is only promoted to AuthedUserKey on the routes the token's scopes grant access to
+	TokenUserKey = KNContextType("tokenUser")
 )
 
 func userBrief(ctx context.Context, key KNContextType) *kilonova.UserBrief {
@@ -108,6 +115,14 @@ func Submission(r *http.Request) *kilonova.FullSubmission {
 	return SubmissionContext(r.Context())
 }
 
+func APITokenContext(ctx context.Context) *kilonova.APIToken {
+	return getValueContext[kilonova.APIToken](ctx, APITokenKey)
+}
+
+func APIToken(r *http.Request) *kilonova.APIToken {
+	return APITokenContext(r.Context())
+}
+
 func ContestContext(ctx context.Context) *kilonova.Contest {
 	return getValueContext[kilonova.Contest](ctx, ContestKey)
 }
@@ -116,6 +131,14 @@ func Contest(r *http.Request) *kilonova.Contest {
 	return ContestContext(r.Context())
 }
 
+func ContestSeriesContext(ctx context.Context) *kilonova.ContestSeries {
+	return getValueContext[kilonova.ContestSeries](ctx, SeriesKey)
+}
+
+func ContestSeries(r *http.Request) *kilonova.ContestSeries {
+	return ContestSeriesContext(r.Context())
+}
+
 func Paste(r *http.Request) *kilonova.SubmissionPaste {
 	return getValueContext[kilonova.SubmissionPaste](r.Context(), PasteKey)
 }
@@ -0,0 +1,106 @@
+package kilonova
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// ScopeOpenID and ScopeProfile grant access to the OpenID Connect userinfo endpoint
+	ScopeOpenID  APITokenScope = "openid"
+	ScopeProfile APITokenScope = "profile"
+)
+
+// OAuthScopes are the scopes third-party apps may request. Apart from the OpenID Connect ones, they map onto the API token scopes
+var OAuthScopes = append([]APITokenScope{ScopeOpenID, ScopeProfile}, APITokenScopes...)
+
+// OAuthTokenPrefix is prepended to access tokens issued to OAuth apps
+const OAuthTokenPrefix = "knoat_"
+
+// Errors of the OAuth token endpoint, as defined in RFC 6749, section 5.2
+var (
+	ErrOAuthInvalidRequest       = Statusf(400, "invalid_request")
+	ErrOAuthInvalidClient        = Statusf(401, "invalid_client")
+	ErrOAuthInvalidGrant         = Statusf(400, "invalid_grant")
+	ErrOAuthUnsupportedGrantType = Statusf(400, "unsupported_grant_type")
+	ErrOAuthInvalidScope         = Statusf(400, "invalid_scope")
+
+	ErrOAuthUnsupportedResponseType = Statusf(400, "unsupported_response_type")
+)
+
+// OAuthError returns an error of the given kind with a human readable description
+func OAuthError(kind *StatusError, description string) *StatusError {
+	return &StatusError{Code: kind.Code, Text: description, WrappedError: kind}
+}
+
+var oauthErrorKinds = []*StatusError{
+	ErrOAuthInvalidRequest, ErrOAuthInvalidClient, ErrOAuthInvalidGrant,
+	ErrOAuthUnsupportedGrantType, ErrOAuthInvalidScope, ErrOAuthUnsupportedResponseType,
+}
+
+// OAuthErrorCode returns the error code sent to apps for the given error. Errors not created with OAuthError are reported as server_error
+func OAuthErrorCode(err *StatusError) string {
+	for _, kind := range oauthErrorKinds {
+		if errors.Is(err, kind) {
+			return kind.Text
+		}
+	}
+	return "server_error"
+}
+
+// OAuthClient is a third-party app that can sign users in and use the API on their behalf
+type OAuthClient struct {
+	ID          string    `json:"id" db:"id"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	OwnerID     int       `json:"owner_id" db:"owner_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+
+	RedirectURIs []string `json:"redirect_uris" db:"redirect_uris"`
+	// Confidential clients authenticate with a secret. Public clients (such as mobile or desktop apps) must use PKCE instead
+	Confidential bool `json:"confidential" db:"confidential"`
+}
+
+// OAuthAuthorizeRequest holds the parameters of an authorization request of the authorization code flow
+type OAuthAuthorizeRequest struct {
+	ClientID     string `json:"client_id"`
+	RedirectURI  string `json:"redirect_uri"`
+	ResponseType string `json:"response_type"`
+	Scope        string `json:"scope"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// Scopes returns the requested scopes. Apps that don't ask for anything get access to the user's profile
+func (r *OAuthAuthorizeRequest) Scopes() []APITokenScope {
+	fields := strings.Fields(r.Scope)
+	if len(fields) == 0 {
+		return []APITokenScope{ScopeProfile}
+	}
+	scopes := make([]APITokenScope, 0, len(fields))
+	for _, field := range fields {
+		scopes = append(scopes, APITokenScope(field))
+	}
+	return scopes
+}
+
+// OAuthTokenResponse is the response of the token endpoint
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+// OAuthGrant is an app the user authorized, shown so that the authorization can be revoked
+type OAuthGrant struct {
+	Client    *OAuthClient    `json:"client"`
+	Scopes    []APITokenScope `json:"scopes"`
+	GrantedAt time.Time       `json:"granted_at"`
+}
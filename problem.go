@@ -40,7 +40,7 @@ type Problem struct {
 
 	SourceCredits string `json:"source_credits"`
 
-	// Used only for leaderboard scoring right now
+	// Used only for leaderboard scoring right now. Contests can override it with per-problem point values
 	ScoreScale decimal.Decimal `json:"score_scale"`
 
 	// Eval stuff
@@ -79,6 +79,9 @@ type ScoredProblem struct {
 	MaxScore *decimal.Decimal `json:"max_score"`
 	// For showing the published/unpublished label on front page
 	IsEditor bool `json:"is_editor"`
+
+	// ContestLabel is the label set for the problem in the contest it was listed from, if any
+	ContestLabel string `json:"contest_label,omitempty"`
 }
 
 // ProblemFilter is the struct with all filterable fields on the problem
@@ -203,6 +206,10 @@ type ProblemEvalSettings struct {
 	// If problem has custom checker that is marked as legacy
 	LegacyChecker bool `json:"legacy_checker"`
 
+	// Reference solution and input validator (using testlib), used to judge hacks. They are empty if not present
+	SolutionName  string `json:"solution_name"`
+	ValidatorName string `json:"validator_name"`
+
 	// Stores the list of languages that are allowed to be submitted based on existing attachments
 	LanguageWhitelist []string `json:"lang_whitelist"`
 }
@@ -41,6 +41,8 @@ type Submission struct {
 	CompileMessage *string `json:"compile_message,omitempty"`
 
 	ContestID *int `json:"contest_id"`
+	// TeamID is the team of the author, for submissions sent in team contests
+	TeamID *int `json:"team_id"`
 
 	MaxTime   float64 `json:"max_time"`
 	MaxMemory int     `json:"max_memory"`
@@ -54,6 +56,22 @@ type Submission struct {
 
 	SubmissionType EvalType `json:"submission_type"`
 	ICPCVerdict    *string  `json:"icpc_verdict"`
+
+	// InternalRetries is the number of times the submission was automatically
+	// reevaluated because of an internal grader error
+	InternalRetries int `json:"internal_retries"`
+
+	// PretestOnly is true if the submission was judged only on pretests and is awaiting system testing
+	PretestOnly bool `json:"pretest_only"`
+
+	// FeedbackLevel is the level of detail the looking user may see of the evaluation.
+	// It is only set when the submission is fetched on behalf of a user
+	FeedbackLevel FeedbackLevel `json:"feedback_level,omitempty"`
+}
+
+// FeedbackLimited returns whether the submission's evaluation details were hidden from the looking user
+func (s *Submission) FeedbackLimited() bool {
+	return s.FeedbackLevel != FeedbackLevelNone && s.FeedbackLevel != FeedbackLevelFull
 }
 
 type SubmissionUpdate struct {
@@ -69,6 +87,8 @@ type SubmissionUpdate struct {
 
 	ChangeVerdict bool
 	ICPCVerdict   *string
+
+	InternalRetries *int
 }
 
 type SubmissionFilter struct {
@@ -50,17 +50,38 @@ func (s *BaseAPI) ResetWaitingSubmissions(ctx context.Context) *StatusError {
 	for _, sub := range subs {
 		ids = append(ids, sub.ID)
 	}
+	var noRetries int
+	if err := s.db.BulkUpdateSubmissions(ctx, kilonova.SubmissionFilter{IDs: ids}, kilonova.SubmissionUpdate{InternalRetries: &noRetries}); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't reset submissions")
+	}
 	if err := s.db.ResetSubmissions(ctx, kilonova.SubmissionFilter{IDs: ids}); err != nil {
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't reset submissions")
 	}
+	if err := s.db.ResetWorkingHacks(ctx); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't reset hacks")
+	}
 
 	// Wake grader to start processing immediately
 	s.WakeGrader()
 	return nil
 }
 
+// ResetSubmission reevaluates the submission on request, giving it a new set of automatic retries
 func (s *BaseAPI) ResetSubmission(ctx context.Context, id int) *StatusError {
+	var noRetries int
+	if err := s.db.UpdateSubmission(ctx, id, kilonova.SubmissionUpdate{InternalRetries: &noRetries}); err != nil {
+		zap.S().Warn("Couldn't reset submission: ", err)
+		return Statusf(500, "Couldn't reset submission")
+	}
+	return s.ReevaluateSubmission(ctx, id)
+}
+
+// ReevaluateSubmission clears the results of a submission marked for reevaluation, so it can be graded again.
+// Unlike ResetSubmission, it keeps the internal retry counter, since it also runs for automatic retries
+func (s *BaseAPI) ReevaluateSubmission(ctx context.Context, id int) *StatusError {
 	if err := s.db.ResetSubmissions(ctx, kilonova.SubmissionFilter{ID: &id}); err != nil {
 		zap.S().Warn("Couldn't reset submission: ", err)
 		return Statusf(500, "Couldn't reset submission")
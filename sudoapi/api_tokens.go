@@ -0,0 +1,120 @@
+package sudoapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	MaxAPITokens = config.GenFlag[int]("behavior.api_tokens.max_per_user", 20, "Maximum number of personal access tokens a user can have")
+)
+
+func hashAPIToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateAPIToken creates a new personal access token for the user.
+// The token itself is only returned here, since just its hash is stored
+func (s *BaseAPI) CreateAPIToken(ctx context.Context, user *kilonova.UserBrief, name string, scopes []kilonova.APITokenScope, expiresAt *time.Time) (string, *kilonova.APIToken, *StatusError) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return "", nil, Statusf(400, "Invalid token name")
+	}
+	if len(scopes) == 0 {
+		return "", nil, Statusf(400, "Tokens must have at least one scope")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(kilonova.APITokenScopes, scope) {
+			return "", nil, Statusf(400, "Invalid token scope %q", scope)
+		}
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	if expiresAt != nil && expiresAt.Before(time.Now()) {
+		return "", nil, Statusf(400, "Expiration date must be in the future")
+	}
+
+	tokens, err := s.APITokens(ctx, user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(tokens) >= MaxAPITokens.Value() {
+		return "", nil, Statusf(400, "You have too many tokens, revoke some of them first")
+	}
+
+	secret := kilonova.APITokenPrefix + kilonova.RandomString(40)
+	token := &kilonova.APIToken{
+		UserID:    user.ID,
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.CreateAPIToken(ctx, token, hashAPIToken(secret)); err != nil {
+		zap.S().Warn(err)
+		return "", nil, WrapError(err, "Couldn't create token")
+	}
+
+	s.LogUserAction(ctx, "Created API token", slog.Int("token_id", token.ID), slog.String("name", token.Name), slog.Any("scopes", token.Scopes))
+	return secret, token, nil
+}
+
+func (s *BaseAPI) APITokens(ctx context.Context, userID int) ([]*kilonova.APIToken, *StatusError) {
+	tokens, err := s.db.APITokens(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get tokens")
+	}
+	return tokens, nil
+}
+
+func (s *BaseAPI) RevokeAPIToken(ctx context.Context, userID, id int) *StatusError {
+	removed, err := s.db.RemoveAPIToken(ctx, userID, id)
+	if err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't revoke token")
+	}
+	if !removed {
+		return WrapError(ErrNotFound, "Token not found")
+	}
+
+	s.LogUserAction(ctx, "Revoked API token", slog.Int("token_id", id), slog.Int("user_id", userID))
+	return nil
+}
+
+// APITokenUser returns the owner of the personal access token, along with the token's details.
+// Expired tokens and tokens of users that can't log in are rejected. The usage is recorded along with the request's address
+func (s *BaseAPI) APITokenUser(ctx context.Context, secret string, ip *netip.Addr) (*kilonova.UserFull, *kilonova.APIToken, *StatusError) {
+	token, err := s.db.APITokenByHash(ctx, hashAPIToken(secret))
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, nil, WrapError(err, "Couldn't get token")
+	}
+	if token == nil || token.Expired() {
+		return nil, nil, Statusf(401, "Invalid or expired token")
+	}
+	user, err1 := s.UserFull(ctx, token.UserID)
+	if err1 != nil {
+		return nil, nil, err1
+	}
+	if user.LockedLogin {
+		return nil, nil, Statusf(401, "Invalid or expired token")
+	}
+
+	go func(id int) {
+		if err := s.db.TouchAPIToken(context.Background(), id, ip); err != nil {
+			zap.S().Warn(err)
+		}
+	}(token.ID)
+	return user, token, nil
+}
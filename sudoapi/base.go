@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"path"
+	"sync"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -50,8 +51,17 @@ type BaseAPI struct {
 
 	logChan chan *logEntry
 
+	events    *eventHub
+	eventChan chan *liveEvent
+
 	dSess *discordgo.Session
 
+	oidcKeyMu sync.Mutex
+	oidcKey   *oidcSigningKey
+
+	externalOIDCMu sync.Mutex
+	externalOIDC   map[string]*oidcProviderMetadata
+
 	evictionLogger        *slog.Logger
 	testBucket            *datastore.Bucket
 	attachmentCacheBucket *datastore.Bucket
@@ -67,6 +77,11 @@ func (s *BaseAPI) Start(ctx context.Context) {
 	go s.cleanupBucketsJob(ctx, 30*time.Minute)
 	go s.refreshProblemStatsJob(ctx, 5*time.Minute)
 	go s.refreshHotProblemsJob(ctx, 4*time.Hour)
+	go s.systemTestJob(ctx, 1*time.Minute)
+	go s.ratingJob(ctx, 5*time.Minute)
+	go s.notificationJob(ctx, 1*time.Minute)
+	go s.oauthCleanupJob(ctx, 1*time.Hour)
+	go s.dispatchEvents(ctx)
 }
 
 func (s *BaseAPI) Close() *StatusError {
@@ -94,6 +109,9 @@ func GetBaseAPI(db *db.DB, mailer kilonova.Mailer) (*BaseAPI, *StatusError) {
 		grader:  nil,
 		logChan: make(chan *logEntry, 50),
 
+		events:    &eventHub{subs: make(map[*EventSubscription]struct{})},
+		eventChan: make(chan *liveEvent, 200),
+
 		testBucket:            datastore.GetBucket(datastore.BucketTypeTests),
 		attachmentCacheBucket: datastore.GetBucket(datastore.BucketTypeAttachments),
 		subtestBucket:         datastore.GetBucket(datastore.BucketTypeSubtests),
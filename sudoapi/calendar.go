@@ -0,0 +1,141 @@
+package sudoapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+// CalendarToken returns the secret token of the user's personal calendar feed, creating it if it doesn't exist
+func (s *BaseAPI) CalendarToken(ctx context.Context, userID int) (string, *StatusError) {
+	token, err := s.db.CalendarToken(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't get calendar token")
+	}
+	if token != "" {
+		return token, nil
+	}
+	return s.ResetCalendarToken(ctx, userID)
+}
+
+// ResetCalendarToken replaces the user's calendar feed token, so the old feed URL stops working
+func (s *BaseAPI) ResetCalendarToken(ctx context.Context, userID int) (string, *StatusError) {
+	token := kilonova.RandomString(32)
+	if err := s.db.SetCalendarToken(ctx, userID, token); err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't update calendar token")
+	}
+	return token, nil
+}
+
+func (s *BaseAPI) CalendarTokenUser(ctx context.Context, token string) (*kilonova.UserBrief, *StatusError) {
+	userID, err := s.db.CalendarTokenUser(ctx, token)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get calendar token")
+	}
+	if userID <= 0 {
+		return nil, WrapError(ErrNotFound, "Calendar feed not found")
+	}
+	return s.UserBrief(ctx, userID)
+}
+
+// ContestCalendar generates an iCalendar (RFC 5545) feed of the future and running contests visible to the user.
+// If the user is nil, only the public contests are included.
+// For logged in users, the feed also includes their individual windows in USACO-style contests.
+func (s *BaseAPI) ContestCalendar(ctx context.Context, user *kilonova.UserBrief) ([]byte, *StatusError) {
+	running, err := s.VisibleRunningContests(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	future, err := s.VisibleFutureContests(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	host := "kilonova"
+	if u, err := url.Parse(config.Common.HostPrefix); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	now := time.Now()
+
+	cal := &icsWriter{}
+	cal.line("BEGIN", "VCALENDAR")
+	cal.line("VERSION", "2.0")
+	cal.line("PRODID", "-//Kilonova//Contests//EN")
+	cal.line("CALSCALE", "GREGORIAN")
+	cal.line("METHOD", "PUBLISH")
+	cal.text("X-WR-CALNAME", "Kilonova")
+
+	for _, contest := range append(running, future...) {
+		link := config.Common.HostPrefix + fmt.Sprintf("/contests/%d", contest.ID)
+		cal.event(fmt.Sprintf("contest-%d@%s", contest.ID, host), now, contest.StartTime, contest.EndTime, contest.Name, link)
+
+		if user == nil || contest.PerUserTime == 0 {
+			continue
+		}
+		reg, err := s.db.ContestRegistration(ctx, contest.ID, user.ID)
+		if err != nil {
+			zap.S().Warn(err)
+			continue
+		}
+		if reg == nil || reg.IndividualStartTime == nil || reg.IndividualEndTime == nil || reg.IndividualEndTime.Before(now) {
+			continue
+		}
+		cal.event(
+			fmt.Sprintf("contest-%d-user-%d@%s", contest.ID, user.ID, host), now,
+			*reg.IndividualStartTime, *reg.IndividualEndTime,
+			contest.Name+" (individual window)", link,
+		)
+	}
+
+	cal.line("END", "VCALENDAR")
+	return cal.buf.Bytes(), nil
+}
+
+const icsTimeFormat = "20060102T150405Z"
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folding it so that no line is longer than 75 octets
+func (w *icsWriter) line(name, value string) {
+	line := name + ":" + value
+	for len(line) > 75 {
+		// Don't split UTF-8 sequences
+		cut := 75
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.buf.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+	w.buf.WriteString(line + "\r\n")
+}
+
+func (w *icsWriter) text(name, value string) {
+	w.line(name, icsEscaper.Replace(value))
+}
+
+func (w *icsWriter) event(uid string, stamp, start, end time.Time, summary, link string) {
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", uid)
+	w.line("DTSTAMP", stamp.UTC().Format(icsTimeFormat))
+	w.line("DTSTART", start.UTC().Format(icsTimeFormat))
+	w.line("DTEND", end.UTC().Format(icsTimeFormat))
+	w.text("SUMMARY", summary)
+	w.text("DESCRIPTION", link)
+	w.line("URL", link)
+	w.line("END", "VEVENT")
+}
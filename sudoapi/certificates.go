@@ -0,0 +1,280 @@
+package sudoapi
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// certificateWrapper is the page that holds the rendered certificates
+const certificateWrapper = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+@page { size: A4 landscape; margin: 0; }
+body { font-family: serif; margin: 0; padding: 2cm; text-align: center; }
+</style>
+</head>
+<body>
+%s
+</body>
+</html>`
+
+// certificatePolicy is the allowlist of the tags, attributes and styles certificates may use.
+// Scripts, style sheets, links and external resources are removed, images can only be embedded as data URIs
+var certificatePolicy = func() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("div", "span", "p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"b", "strong", "i", "em", "u", "s", "small", "sub", "sup", "blockquote",
+		"ul", "ol", "li", "table", "thead", "tbody", "tfoot", "tr", "th", "td", "caption")
+	p.AllowAttrs("colspan", "rowspan").Matching(bluemonday.Integer).OnElements("td", "th")
+	p.AllowAttrs("width", "height").Matching(bluemonday.NumberOrPercent).OnElements("img", "table", "td", "th")
+	p.AllowAttrs("alt").Matching(bluemonday.Paragraph).OnElements("img")
+	p.AllowAttrs("src").OnElements("img")
+	p.AllowDataURIImages()
+	p.AllowAttrs("style").Globally()
+	p.AllowStyles(
+		"color", "background-color", "opacity",
+		"font-family", "font-size", "font-style", "font-weight", "font-variant",
+		"text-align", "text-decoration", "text-transform", "text-indent", "letter-spacing", "word-spacing", "line-height", "white-space", "vertical-align",
+		"margin", "margin-top", "margin-right", "margin-bottom", "margin-left",
+		"padding", "padding-top", "padding-right", "padding-bottom", "padding-left",
+		"border", "border-top", "border-right", "border-bottom", "border-left", "border-color", "border-style", "border-width", "border-radius", "border-collapse",
+		"width", "height", "min-width", "min-height", "max-width", "max-height",
+		"display", "position", "top", "right", "bottom", "left", "float", "clear",
+		"page-break-before", "page-break-after", "page-break-inside",
+	).Globally()
+	return p
+}()
+
+var certificateFooterTempl = template.Must(template.New("footer").Parse(`<p style="position: fixed; bottom: 0.5cm; left: 0; right: 0; text-align: center; font-size: 9pt; color: #555;">{{.VerificationURL}}</p>`))
+
+func (s *BaseAPI) CertificateSettings(ctx context.Context, contestID int) (*kilonova.CertificateSettings, *StatusError) {
+	settings, err := s.db.CertificateSettings(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get certificate settings")
+	}
+	return settings, nil
+}
+
+func (s *BaseAPI) UpdateCertificateSettings(ctx context.Context, settings *kilonova.CertificateSettings) *StatusError {
+	if settings.Format != kilonova.CertificateFormatHTML && settings.Format != kilonova.CertificateFormatMarkdown {
+		return Statusf(400, "Invalid certificate template format")
+	}
+	for _, award := range settings.Awards {
+		award.Name = strings.TrimSpace(award.Name)
+		if award.Name == "" {
+			return Statusf(400, "Awards must have a name")
+		}
+		if award.MaxRank == nil && award.MinScore == nil {
+			return Statusf(400, "Award %q must have a rank or score threshold", award.Name)
+		}
+		if award.MaxRank != nil && *award.MaxRank < 1 {
+			return Statusf(400, "Invalid rank threshold for award %q", award.Name)
+		}
+	}
+	templ, err := s.certificateTemplate(settings)
+	if err != nil {
+		return err
+	}
+	// Catch references to missing placeholders before the certificates are generated
+	if err := templ.Execute(io.Discard, &kilonova.CertificateData{}); err != nil {
+		return Statusf(400, "Invalid certificate template: %v", err)
+	}
+
+	if err := s.db.UpdateCertificateSettings(ctx, settings); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update certificate settings")
+	}
+	return nil
+}
+
+// certificateTemplate compiles the body of the certificate. Markdown templates are converted to HTML first
+func (s *BaseAPI) certificateTemplate(settings *kilonova.CertificateSettings) (*template.Template, *StatusError) {
+	src := settings.Template
+	if settings.Format == kilonova.CertificateFormatMarkdown {
+		out, err := s.RenderMarkdown([]byte(src), nil)
+		if err != nil {
+			return nil, err
+		}
+		src = string(out)
+	}
+
+	templ, err := template.New("certificate").Parse(src)
+	if err != nil {
+		return nil, Statusf(400, "Invalid certificate template: %v", err)
+	}
+	return templ, nil
+}
+
+// IssueContestCertificates issues certificates to all participants from the contest's final leaderboard and returns them in leaderboard order.
+// Virtual participants and participants without any submission don't get certificates.
+// Issuing again updates the certificates after leaderboard changes: existing ones keep their IDs, and the ones of users that no longer qualify are removed
+func (s *BaseAPI) IssueContestCertificates(ctx context.Context, contest *kilonova.Contest) ([]*kilonova.Certificate, *StatusError) {
+	if !contest.Ended() {
+		return nil, Statusf(400, "Certificates can only be issued after the contest ended")
+	}
+	if contest.Pretests && contest.SystemTestStatus != kilonova.SystemTestFinished {
+		return nil, Statusf(400, "Certificates can only be issued after system testing finished")
+	}
+	settings, err := s.CertificateSettings(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	leaderboard, err := s.ContestLeaderboard(ctx, contest, nil, kilonova.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var certs []*kilonova.Certificate
+	var prev *kilonova.LeaderboardEntry
+	place, prevPlace := 0, 0
+	for _, entry := range leaderboard.Entries {
+		if !leaderboardParticipated(entry) {
+			continue
+		}
+		place++
+		rank := place
+		if prev != nil && leaderboardTied(leaderboard, prev, entry) {
+			rank = prevPlace
+		}
+		prev, prevPlace = entry, rank
+
+		score := entry.TotalScore
+		if leaderboard.Type == kilonova.LeaderboardTypeICPC {
+			score = decimal.NewFromInt(int64(entry.NumSolved))
+		}
+		var award *string
+		for _, aw := range settings.Awards {
+			if aw.Matches(rank, score) {
+				award = &aw.Name
+				break
+			}
+		}
+
+		var team *string
+		var users []*kilonova.UserBrief
+		if entry.Team != nil {
+			team = &entry.Team.Name
+			users = entry.Team.Members
+		} else if entry.User != nil {
+			users = []*kilonova.UserBrief{entry.User}
+		}
+		for _, user := range users {
+			certs = append(certs, &kilonova.Certificate{
+				ID:        kilonova.RandomString(16),
+				ContestID: contest.ID,
+				UserID:    user.ID,
+				Rank:      rank,
+				Score:     score,
+				Award:     award,
+				Team:      team,
+			})
+		}
+	}
+
+	if err := s.db.IssueCertificates(ctx, contest.ID, certs); err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't issue certificates")
+	}
+	s.LogUserAction(ctx, "Issued contest certificates", slog.Any("contest", contest), slog.Int("count", len(certs)))
+	return certs, nil
+}
+
+// ContestCertificates returns the certificates issued for the contest, in leaderboard order
+func (s *BaseAPI) ContestCertificates(ctx context.Context, contestID int) ([]*kilonova.Certificate, *StatusError) {
+	certs, err := s.db.ContestCertificates(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get certificates")
+	}
+	return certs, nil
+}
+
+// UserContestCertificate returns the certificate issued to the given user, if any
+func (s *BaseAPI) UserContestCertificate(ctx context.Context, contestID int, userID int) (*kilonova.Certificate, *StatusError) {
+	cert, err := s.db.UserContestCertificate(ctx, contestID, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get certificate")
+	}
+	if cert == nil {
+		return nil, WrapError(ErrNotFound, "No certificate was issued to this user")
+	}
+	return cert, nil
+}
+
+func (s *BaseAPI) Certificate(ctx context.Context, id string) (*kilonova.Certificate, *StatusError) {
+	cert, err := s.db.Certificate(ctx, id)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get certificate")
+	}
+	if cert == nil {
+		return nil, WrapError(ErrNotFound, "Certificate not found")
+	}
+	return cert, nil
+}
+
+func CertificateVerificationURL(cert *kilonova.Certificate) string {
+	return config.Common.HostPrefix + "/certificates/" + cert.ID
+}
+
+// RenderCertificate fills the contest's certificate template with the certificate's details.
+// The result is sanitized and placed on an A4 landscape page. If the template doesn't use the verification URL, it is added at the bottom of the page
+func (s *BaseAPI) RenderCertificate(ctx context.Context, contest *kilonova.Contest, settings *kilonova.CertificateSettings, cert *kilonova.Certificate) ([]byte, *StatusError) {
+	templ, err := s.certificateTemplate(settings)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.UserBrief(ctx, cert.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &kilonova.CertificateData{
+		Name:        user.Name,
+		DisplayName: user.DisplayName,
+		Rank:        cert.Rank,
+		Score:       cert.Score.String(),
+		Contest:     contest.Name,
+		Date:        contest.EndTime.Format(time.DateOnly),
+
+		VerificationURL: CertificateVerificationURL(cert),
+	}
+	if data.DisplayName == "" {
+		data.DisplayName = user.Name
+	}
+	if cert.Award != nil {
+		data.Award = *cert.Award
+	}
+	if cert.Team != nil {
+		data.Team = *cert.Team
+	}
+
+	var buf bytes.Buffer
+	if err := templ.Execute(&buf, data); err != nil {
+		return nil, Statusf(400, "Couldn't render certificate: %v", err)
+	}
+	body := certificatePolicy.SanitizeBytes(buf.Bytes())
+	if !strings.Contains(settings.Template, ".VerificationURL") {
+		var footer bytes.Buffer
+		if err := certificateFooterTempl.Execute(&footer, data); err != nil {
+			return nil, Statusf(500, "Couldn't render certificate: %v", err)
+		}
+		body = append(body, footer.Bytes()...)
+	}
+	return []byte(strings.Replace(certificateWrapper, "%s", string(body), 1)), nil
+}
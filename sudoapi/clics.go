@@ -0,0 +1,372 @@
+package sudoapi
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/eval"
+	"github.com/KiloProjects/kilonova/integrations/clics"
+	"github.com/shopspring/decimal"
+)
+
+// Kilonova counts every evaluated submission as an attempt, so all rejections are penalized
+var clicsJudgementTypes = []clics.JudgementType{
+	{ID: "AC", Name: "Accepted", Solved: true},
+	{ID: "WA", Name: "Wrong Answer", Penalty: true},
+	{ID: "TLE", Name: "Time Limit Exceeded", Penalty: true},
+	{ID: "MLE", Name: "Memory Limit Exceeded", Penalty: true},
+	{ID: "RTE", Name: "Run-Time Error", Penalty: true},
+	{ID: "CE", Name: "Compile Error", Penalty: true},
+	{ID: "JE", Name: "Judging Error", Penalty: true},
+}
+
+var clicsVerdicts = map[string]string{
+	"accepted":       "AC",
+	"success":        "AC",
+	"wrong":          "WA",
+	"timeout":        "TLE",
+	"walltimeout":    "TLE",
+	"memory_limit":   "MLE",
+	"runtime_error":  "RTE",
+	"compile_error":  "CE",
+	"internal_error": "JE",
+}
+
+// clicsVerdict maps a test or ICPC verdict (such as "translate:timeout" or "test_verdict.wrong (test_verdict.test_x #3)") to a judgement type
+func clicsVerdict(verdict string) (string, bool) {
+	verdict, _, _ = strings.Cut(verdict, " ")
+	verdict = strings.TrimPrefix(strings.TrimPrefix(verdict, "translate:"), "test_verdict.")
+	jt, ok := clicsVerdicts[verdict]
+	return jt, ok
+}
+
+func clicsSubmissionVerdict(sub *kilonova.Submission) string {
+	if sub.CompileError != nil && *sub.CompileError {
+		return "CE"
+	}
+	if sub.Score.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return "AC"
+	}
+	if sub.ICPCVerdict != nil {
+		if jt, ok := clicsVerdict(*sub.ICPCVerdict); ok && jt != "AC" {
+			return jt
+		}
+	}
+	return "WA"
+}
+
+func clicsRunVerdict(stest *kilonova.SubTest) string {
+	if stest.Percentage.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+		return "AC"
+	}
+	if jt, ok := clicsVerdict(stest.Verdict); ok && jt != "AC" {
+		return jt
+	}
+	return "WA"
+}
+
+// ClicsContest holds the CLICS Contest API objects describing a contest, as seen at the moment it was built
+type ClicsContest struct {
+	Contest        clics.Contest
+	State          clics.State
+	JudgementTypes []clics.JudgementType
+	Languages      []clics.Language
+	Problems       []clics.Problem
+	Groups         []clics.Group
+	Teams          []clics.Team
+	Submissions    []clics.Submission
+	Judgements     []clics.Judgement
+	Runs           []clics.Run
+}
+
+// ClicsContest builds the CLICS representation of the contest from its registrations (or teams) and submissions.
+// Virtual participations are left out. Runs are built from the submissions' subtests, so they are only included if requested
+func (s *BaseAPI) ClicsContest(ctx context.Context, contest *kilonova.Contest, lookingUser *kilonova.UserBrief, withRuns bool) (*ClicsContest, *StatusError) {
+	data := &ClicsContest{JudgementTypes: clicsJudgementTypes}
+
+	startTime, endTime := clics.Time(contest.StartTime), clics.Time(contest.EndTime)
+	data.Contest = clics.Contest{
+		ID:             strconv.Itoa(contest.ID),
+		Name:           contest.Name,
+		FormalName:     contest.Name,
+		StartTime:      &startTime,
+		Duration:       clics.RelTime(contest.EndTime.Sub(contest.StartTime)),
+		ScoreboardType: "score",
+	}
+	if contest.LeaderboardStyle == kilonova.LeaderboardTypeICPC {
+		data.Contest.ScoreboardType = "pass-fail"
+		data.Contest.PenaltyTime = contest.ICPCSubmissionPenalty
+	}
+	frozen := contest.LeaderboardFreeze != nil && contest.LeaderboardFreeze.Before(contest.EndTime)
+	if frozen {
+		freezeDuration := clics.RelTime(contest.EndTime.Sub(*contest.LeaderboardFreeze))
+		data.Contest.ScoreboardFreezeDuration = &freezeDuration
+	}
+
+	langNames := make([]string, 0, len(eval.Langs))
+	for name, lang := range eval.Langs {
+		if !lang.Disabled {
+			langNames = append(langNames, name)
+		}
+	}
+	slices.Sort(langNames)
+	for _, name := range langNames {
+		lang := eval.Langs[name]
+		exts := make([]string, 0, len(lang.Extensions))
+		for _, ext := range lang.Extensions {
+			exts = append(exts, strings.TrimPrefix(ext, "."))
+		}
+		data.Languages = append(data.Languages, clics.Language{ID: name, Name: lang.PrintableName, Extensions: exts})
+	}
+
+	problems, err := s.ContestProblems(ctx, contest, lookingUser)
+	if err != nil {
+		return nil, err
+	}
+	pbSettings, err := s.ContestProblemSettings(ctx, contest.ID)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[int]string, len(pbSettings))
+	for _, settings := range pbSettings {
+		labels[settings.ProblemID] = settings.DisplayLabel()
+	}
+	for i, pb := range problems {
+		tests, err := s.Tests(ctx, pb.ID)
+		if err != nil {
+			return nil, err
+		}
+		data.Problems = append(data.Problems, clics.Problem{
+			ID:          strconv.Itoa(pb.ID),
+			Label:       labels[pb.ID],
+			Name:        pb.Name,
+			Ordinal:     i,
+			TimeLimit:   pb.TimeLimit,
+			TestDataCnt: len(tests),
+		})
+	}
+
+	for _, category := range contest.Categories {
+		data.Groups = append(data.Groups, clics.Group{ID: category, Name: category})
+	}
+
+	regs, err := s.ContestRegistrations(ctx, contest.ID, nil, nil, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.UsersBrief(ctx, kilonova.UserFilter{ContestID: &contest.ID})
+	if err != nil {
+		return nil, err
+	}
+	userNames := make(map[int]*kilonova.UserBrief, len(users))
+	for _, user := range users {
+		userNames[user.ID] = user
+	}
+
+	teams := make(map[int]*clics.Team)
+	if contest.TeamMode() {
+		cteams, err := s.ContestTeams(ctx, contest.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, team := range cteams {
+			teams[team.ID] = &clics.Team{ID: strconv.Itoa(team.ID), Name: team.Name, GroupIDs: []string{}}
+		}
+	}
+	for _, reg := range regs {
+		if reg.Virtual {
+			continue
+		}
+		var team *clics.Team
+		if contest.TeamMode() {
+			if reg.TeamID == nil || teams[*reg.TeamID] == nil {
+				continue
+			}
+			team = teams[*reg.TeamID]
+		} else {
+			user, ok := userNames[reg.UserID]
+			if !ok {
+				continue
+			}
+			team = &clics.Team{ID: strconv.Itoa(user.ID), Name: user.Name, DisplayName: user.DisplayName, GroupIDs: []string{}}
+			teams[user.ID] = team
+		}
+		if reg.Category != nil && !slices.Contains(team.GroupIDs, *reg.Category) {
+			team.GroupIDs = append(team.GroupIDs, *reg.Category)
+		}
+	}
+	teamIDs := make([]int, 0, len(teams))
+	for id := range teams {
+		teamIDs = append(teamIDs, id)
+	}
+	slices.Sort(teamIDs)
+	for _, id := range teamIDs {
+		data.Teams = append(data.Teams, *teams[id])
+	}
+
+	subs, err := s.RawSubmissions(ctx, kilonova.SubmissionFilter{ContestID: &contest.ID, Ascending: true, Ordering: "id"})
+	if err != nil {
+		return nil, err
+	}
+	var pending bool
+	for _, sub := range subs {
+		teamID := sub.UserID
+		if contest.TeamMode() {
+			if sub.TeamID == nil {
+				continue
+			}
+			teamID = *sub.TeamID
+		}
+		if _, ok := teams[teamID]; !ok || sub.CreatedAt.Before(contest.StartTime) || sub.CreatedAt.After(contest.EndTime) {
+			continue
+		}
+
+		subID := strconv.Itoa(sub.ID)
+		subTime, contestTime := clics.Time(sub.CreatedAt), clics.RelTime(sub.CreatedAt.Sub(contest.StartTime))
+		data.Submissions = append(data.Submissions, clics.Submission{
+			ID:          subID,
+			LanguageID:  sub.Language,
+			ProblemID:   strconv.Itoa(sub.ProblemID),
+			TeamID:      strconv.Itoa(teamID),
+			Time:        subTime,
+			ContestTime: contestTime,
+		})
+
+		// Kilonova doesn't record when judging finished, so judgements are considered instant
+		judgement := clics.Judgement{
+			ID:               subID,
+			SubmissionID:     subID,
+			StartTime:        subTime,
+			StartContestTime: contestTime,
+		}
+		if sub.Status != kilonova.StatusFinished {
+			pending = true
+			data.Judgements = append(data.Judgements, judgement)
+			continue
+		}
+		verdict := clicsSubmissionVerdict(sub)
+		judgement.JudgementTypeID, judgement.EndTime, judgement.EndContestTime = &verdict, &subTime, &contestTime
+		if sub.MaxTime >= 0 {
+			judgement.MaxRunTime = &sub.MaxTime
+		}
+		data.Judgements = append(data.Judgements, judgement)
+
+		if !withRuns || verdict == "CE" {
+			continue
+		}
+		stests, err := s.SubTests(ctx, sub.ID)
+		if err != nil {
+			return nil, err
+		}
+		ordinal := 0
+		for _, stest := range stests {
+			if !stest.Done || stest.Skipped {
+				continue
+			}
+			ordinal++
+			data.Runs = append(data.Runs, clics.Run{
+				ID:              strconv.Itoa(stest.ID),
+				JudgementID:     subID,
+				Ordinal:         ordinal,
+				JudgementTypeID: clicsRunVerdict(stest),
+				Time:            subTime,
+				ContestTime:     contestTime,
+				RunTime:         stest.Time,
+			})
+		}
+	}
+
+	now := time.Now()
+	if now.After(contest.StartTime) {
+		data.State.Started = &startTime
+	}
+	if frozen && now.After(*contest.LeaderboardFreeze) {
+		freezeTime := clics.Time(*contest.LeaderboardFreeze)
+		data.State.Frozen = &freezeTime
+	}
+	if contest.Ended() {
+		data.State.Ended = &endTime
+		if !pending {
+			data.State.Finalized, data.State.EndOfUpdates = &endTime, &endTime
+		}
+	}
+
+	return data, nil
+}
+
+// SyncFeed writes the events for all objects that changed since the last sync on the feed writer.
+// It returns whether any event was written
+func (data *ClicsContest) SyncFeed(feed *clics.FeedWriter) (bool, error) {
+	var changed bool
+	sync := func(typ, id string, obj any) error {
+		ok, err := feed.Sync(typ, id, obj)
+		changed = changed || ok
+		return err
+	}
+
+	if err := sync("contests", "", data.Contest); err != nil {
+		return changed, err
+	}
+	for _, jt := range data.JudgementTypes {
+		if err := sync("judgement-types", jt.ID, jt); err != nil {
+			return changed, err
+		}
+	}
+	for _, lang := range data.Languages {
+		if err := sync("languages", lang.ID, lang); err != nil {
+			return changed, err
+		}
+	}
+	for _, pb := range data.Problems {
+		if err := sync("problems", pb.ID, pb); err != nil {
+			return changed, err
+		}
+	}
+	for _, group := range data.Groups {
+		if err := sync("groups", group.ID, group); err != nil {
+			return changed, err
+		}
+	}
+	for _, team := range data.Teams {
+		if err := sync("teams", team.ID, team); err != nil {
+			return changed, err
+		}
+	}
+	for _, sub := range data.Submissions {
+		if err := sync("submissions", sub.ID, sub); err != nil {
+			return changed, err
+		}
+	}
+	for _, judgement := range data.Judgements {
+		if err := sync("judgements", judgement.ID, judgement); err != nil {
+			return changed, err
+		}
+	}
+	for _, run := range data.Runs {
+		if err := sync("runs", run.ID, run); err != nil {
+			return changed, err
+		}
+	}
+	// The state is always last, so that the end of updates is announced after everything else
+	if err := sync("state", "", data.State); err != nil {
+		return changed, err
+	}
+	return changed, nil
+}
+
+// WriteContestEventFeed writes the current contest data as a CLICS event feed, which can be loaded in the ICPC Resolver
+func (s *BaseAPI) WriteContestEventFeed(ctx context.Context, contest *kilonova.Contest, lookingUser *kilonova.UserBrief, w io.Writer) *StatusError {
+	data, err := s.ClicsContest(ctx, contest, lookingUser, false)
+	if err != nil {
+		return err
+	}
+	if _, err := data.SyncFeed(clics.NewFeedWriter(w)); err != nil {
+		return WrapError(err, "Couldn't write event feed")
+	}
+	return nil
+}
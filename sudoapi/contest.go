@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
@@ -62,6 +64,16 @@ func (s *BaseAPI) CreateContest(ctx context.Context, name string, cType kilonova
 }
 
 func (s *BaseAPI) UpdateContest(ctx context.Context, id int, upd kilonova.ContestUpdate) *kilonova.StatusError {
+	if upd.LeaderboardStyle == kilonova.LeaderboardTypeICPC {
+		// ICPC leaderboards count a problem as solved only at full score, which custom point values would change
+		settings, err := s.ContestProblemSettings(ctx, id)
+		if err != nil {
+			return err
+		}
+		if slices.ContainsFunc(settings, func(setting *kilonova.ContestProblemSettings) bool { return setting.Points != nil }) {
+			return Statusf(400, "Remove the problems' custom point values before switching to an ICPC-style leaderboard")
+		}
+	}
 	if err := s.db.UpdateContest(ctx, id, upd); err != nil {
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't update contest")
@@ -77,6 +89,96 @@ func (s *BaseAPI) UpdateContestProblems(ctx context.Context, id int, list []int)
 	return nil
 }
 
+func (s *BaseAPI) ContestProblemSettings(ctx context.Context, contestID int) ([]*kilonova.ContestProblemSettings, *StatusError) {
+	settings, err := s.db.ContestProblemSettings(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get contest problem settings")
+	}
+	return settings, nil
+}
+
+func (s *BaseAPI) ContestProblemSetting(ctx context.Context, contestID, problemID int) (*kilonova.ContestProblemSettings, *StatusError) {
+	settings, err := s.db.ContestProblemSetting(ctx, contestID, problemID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get contest problem settings")
+	}
+	if settings == nil {
+		return nil, WrapError(ErrNotFound, "Problem isn't in contest")
+	}
+	return settings, nil
+}
+
+// UpdateContestProblemSettings replaces the per-contest settings of a contest problem
+func (s *BaseAPI) UpdateContestProblemSettings(ctx context.Context, contest *kilonova.Contest, settings *kilonova.ContestProblemSettings) *StatusError {
+	others, err := s.ContestProblemSettings(ctx, contest.ID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(others, func(other *kilonova.ContestProblemSettings) bool { return other.ProblemID == settings.ProblemID }) {
+		return WrapError(ErrNotFound, "Problem isn't in contest")
+	}
+
+	settings.ContestID = contest.ID
+	settings.Label = strings.TrimSpace(settings.Label)
+	if len(settings.Label) > 16 {
+		return Statusf(400, "Problem labels can have at most 16 characters")
+	}
+	if settings.Label != "" && slices.ContainsFunc(others, func(other *kilonova.ContestProblemSettings) bool {
+		return other.ProblemID != settings.ProblemID && other.Label == settings.Label
+	}) {
+		return Statusf(400, "Label %q is already used by another problem", settings.Label)
+	}
+
+	if settings.Points != nil {
+		if contest.LeaderboardStyle == kilonova.LeaderboardTypeICPC {
+			return Statusf(400, "Point values aren't used by ICPC-style leaderboards")
+		}
+		if settings.Points.IsNegative() {
+			return Statusf(400, "Point values can't be negative")
+		}
+	}
+
+	var langs []string
+	for _, lang := range settings.Languages {
+		if _, ok := eval.Langs[lang]; !ok {
+			return Statusf(400, "Unknown language %q", lang)
+		}
+		if !slices.Contains(langs, lang) {
+			langs = append(langs, lang)
+		}
+	}
+	// An empty list means there is no restriction besides the problem's own whitelist
+	settings.Languages = langs
+
+	if err := s.db.UpdateContestProblemSettings(ctx, settings); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update contest problem settings")
+	}
+	return nil
+}
+
+// UpdateContestCategories replaces the contest's participant categories.
+// Registrations under removed categories are left as they are, but they can no longer be filtered in the leaderboard
+func (s *BaseAPI) UpdateContestCategories(ctx context.Context, id int, categories []string) *StatusError {
+	list := make([]string, 0, len(categories))
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if category == "" || len(category) > 64 {
+			return Statusf(400, "Category names must have between 1 and 64 characters")
+		}
+		if slices.Contains(list, category) {
+			return Statusf(400, "Duplicate category %q", category)
+		}
+		list = append(list, category)
+	}
+	if err := s.db.UpdateContestCategories(ctx, id, list); err != nil {
+		return WrapError(err, "Couldn't update contest categories")
+	}
+	return nil
+}
+
 func (s *BaseAPI) DeleteContest(ctx context.Context, contest *kilonova.Contest) *StatusError {
 	if contest == nil {
 		return Statusf(400, "Invalid contest")
@@ -153,23 +255,54 @@ func (s *BaseAPI) ProblemRunningContests(ctx context.Context, problemID int) ([]
 	})
 }
 
+// ContestLeaderboard generates the contest's standings. If the filter has a category set, only the participants in that category are ranked
 func (s *BaseAPI) ContestLeaderboard(ctx context.Context, contest *kilonova.Contest, freezeTime *time.Time, filter kilonova.UserFilter) (*kilonova.ContestLeaderboard, *StatusError) {
-	switch contest.LeaderboardStyle {
-	case kilonova.LeaderboardTypeClassic:
-		leaderboard, err := s.db.ContestClassicLeaderboard(ctx, contest, freezeTime, &filter)
-		if err != nil {
-			return nil, WrapError(err, "Couldn't generate leaderboard")
-		}
-		return leaderboard, nil
-	case kilonova.LeaderboardTypeICPC:
-		leaderboard, err := s.db.ContestICPCLeaderboard(ctx, contest, freezeTime, &filter)
-		if err != nil {
-			return nil, WrapError(err, "Couldn't generate leaderboard")
+	if filter.Category != nil {
+		if !contest.HasCategory(*filter.Category) {
+			return nil, Statusf(400, "Invalid participant category")
 		}
-		return leaderboard, nil
+		filter.ContestID = &contest.ID
+	}
+
+	var leaderboard *kilonova.ContestLeaderboard
+	var err error
+	switch {
+	case contest.TeamMode():
+		leaderboard, err = s.db.ContestTeamLeaderboard(ctx, contest, freezeTime, &filter)
+	case contest.LeaderboardStyle == kilonova.LeaderboardTypeClassic:
+		leaderboard, err = s.db.ContestClassicLeaderboard(ctx, contest, freezeTime, &filter)
+	case contest.LeaderboardStyle == kilonova.LeaderboardTypeICPC:
+		leaderboard, err = s.db.ContestICPCLeaderboard(ctx, contest, freezeTime, &filter)
 	default:
 		return nil, Statusf(400, "Invalid contest leaderboard type")
 	}
+	if err != nil {
+		return nil, WrapError(err, "Couldn't generate leaderboard")
+	}
+	leaderboard.Category = filter.Category
+	return leaderboard, nil
+}
+
+// leaderboardTied returns whether the two entries share the same place. Ties are broken the same way the leaderboard is sorted
+func leaderboardTied(leaderboard *kilonova.ContestLeaderboard, a, b *kilonova.LeaderboardEntry) bool {
+	if leaderboard.Type == kilonova.LeaderboardTypeICPC {
+		return a.NumSolved == b.NumSolved && a.Penalty == b.Penalty
+	}
+	return a.TotalScore.Equal(b.TotalScore)
+}
+
+// leaderboardParticipated returns whether the entry belongs to an official contestant that submitted at least once.
+// Problems without any submission have a negative score
+func leaderboardParticipated(entry *kilonova.LeaderboardEntry) bool {
+	if entry.Virtual {
+		return false
+	}
+	for _, score := range entry.ProblemScores {
+		if !score.IsNegative() {
+			return true
+		}
+	}
+	return false
 }
 
 func (s *BaseAPI) CanJoinContest(c *kilonova.Contest) bool {
@@ -183,11 +316,19 @@ func (s *BaseAPI) CanJoinContest(c *kilonova.Contest) bool {
 }
 
 // CanSubmitInContest checks if the user is either a contestant and the contest is running, or a tester/editor/admin.
-// Ended contests cannot have submissions created by anyone
+// Ended contests cannot have submissions created by anyone, except for virtual participants during their run
 // Also, USACO-style contests are fun to handle...
-func (s *BaseAPI) CanSubmitInContest(user *kilonova.UserBrief, c *kilonova.Contest) bool {
+func (s *BaseAPI) CanSubmitInContest(ctx context.Context, user *kilonova.UserBrief, c *kilonova.Contest) bool {
 	if c.Ended() {
-		return false
+		if !user.IsAuthed() {
+			return false
+		}
+		reg, err := s.db.ContestRegistration(ctx, c.ID, user.ID)
+		if err != nil {
+			zap.S().Warn(err)
+			return false
+		}
+		return reg.VirtualRunning()
 	}
 	if s.IsContestTester(user, c) {
 		return true
@@ -198,7 +339,7 @@ func (s *BaseAPI) CanSubmitInContest(user *kilonova.UserBrief, c *kilonova.Conte
 	if !c.Running() {
 		return false
 	}
-	reg, err := s.db.ContestRegistration(context.Background(), c.ID, user.ID)
+	reg, err := s.db.ContestRegistration(ctx, c.ID, user.ID)
 	if err != nil {
 		zap.S().Warn(err)
 		return false
@@ -238,7 +379,7 @@ func (s *BaseAPI) CanViewContestProblems(ctx context.Context, user *kilonova.Use
 		// Problems can be seen by anyone only on visible, non-USACO contests that disallow registering during contest
 		return true
 	}
-	return s.CanSubmitInContest(user, contest)
+	return s.CanSubmitInContest(ctx, user, contest)
 }
 
 func (s *BaseAPI) CanViewContestLeaderboard(user *kilonova.UserBrief, contest *kilonova.Contest) bool {
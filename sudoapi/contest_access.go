@@ -0,0 +1,217 @@
+package sudoapi
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+// UpdateContestIPRanges replaces the networks contestants can submit from.
+// Both CIDR ranges (ex: 10.0.0.0/24) and single addresses are accepted
+func (s *BaseAPI) UpdateContestIPRanges(ctx context.Context, id int, ranges []string) *StatusError {
+	list := make([]netip.Prefix, 0, len(ranges))
+	for _, val := range ranges {
+		val = strings.TrimSpace(val)
+		if val == "" {
+			continue
+		}
+		var prefix netip.Prefix
+		if strings.Contains(val, "/") {
+			p, err := netip.ParsePrefix(val)
+			if err != nil {
+				return Statusf(400, "Invalid IP range %q", val)
+			}
+			prefix = p.Masked()
+		} else {
+			addr, err := netip.ParseAddr(val)
+			if err != nil {
+				return Statusf(400, "Invalid IP address %q", val)
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		if !slices.Contains(list, prefix) {
+			list = append(list, prefix)
+		}
+	}
+	if err := s.db.UpdateContestIPRanges(ctx, id, list); err != nil {
+		return WrapError(err, "Couldn't update contest IP ranges")
+	}
+	return nil
+}
+
+// IPAllowedInContest returns whether contestants can submit from the given address
+func IPAllowedInContest(contest *kilonova.Contest, ip *netip.Addr) bool {
+	if len(contest.AllowedIPRanges) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	addr := ip.Unmap()
+	for _, prefix := range contest.AllowedIPRanges {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// runningParticipations returns the running contests in which the user competes.
+// Testers and editors are not considered contestants, even if they are registered
+func (s *BaseAPI) runningParticipations(ctx context.Context, user *kilonova.UserBrief) []*kilonova.Contest {
+	if !user.IsAuthed() {
+		return nil
+	}
+	contests, err := s.db.Contests(ctx, kilonova.ContestFilter{ContestantID: &user.ID, Running: true})
+	if err != nil {
+		zap.S().Warn(err)
+		return nil
+	}
+	return slices.DeleteFunc(contests, func(contest *kilonova.Contest) bool {
+		return s.IsContestTester(user, contest)
+	})
+}
+
+type lockdownCacheKey struct{}
+
+// lockdownCache holds the lockdown state of the request's user, since it's checked multiple times for every request
+type lockdownCache struct {
+	mu       sync.Mutex
+	userID   int
+	contests []*kilonova.Contest
+	problems map[int]bool
+}
+
+// WithLockdownCache returns a context in which the lockdown checks of the user are done only once.
+// It should wrap the context of every request
+func WithLockdownCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockdownCacheKey{}, &lockdownCache{userID: -1})
+}
+
+// LockdownContests returns the running contests with lockdown in which the user competes
+func (s *BaseAPI) LockdownContests(ctx context.Context, user *kilonova.UserBrief) []*kilonova.Contest {
+	if !user.IsAuthed() {
+		return nil
+	}
+	cache, ok := ctx.Value(lockdownCacheKey{}).(*lockdownCache)
+	if ok {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if cache.userID == user.ID {
+			return slices.Clone(cache.contests)
+		}
+	}
+	contests := slices.DeleteFunc(s.runningParticipations(ctx, user), func(contest *kilonova.Contest) bool {
+		return !contest.Lockdown
+	})
+	if ok {
+		cache.userID, cache.contests, cache.problems = user.ID, contests, make(map[int]bool)
+	}
+	return slices.Clone(contests)
+}
+
+// IsLockedDown returns whether the user is currently competing in a contest with lockdown.
+// Locked down users cannot access pastes or blog posts
+func (s *BaseAPI) IsLockedDown(ctx context.Context, user *kilonova.UserBrief) bool {
+	return len(s.LockdownContests(ctx, user)) > 0
+}
+
+// LockdownAllowsProblem returns whether the problem can be accessed by the user, given the lockdowns they are under.
+// While locked down, only the problems of the contests with lockdown can be accessed
+func (s *BaseAPI) LockdownAllowsProblem(ctx context.Context, user *kilonova.UserBrief, problem *kilonova.Problem) bool {
+	contests := s.LockdownContests(ctx, user)
+	if len(contests) == 0 {
+		return true
+	}
+	if problem == nil {
+		return false
+	}
+	cache, ok := ctx.Value(lockdownCacheKey{}).(*lockdownCache)
+	if ok {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if allowed, ok := cache.problems[problem.ID]; ok && cache.userID == user.ID {
+			return allowed
+		}
+	}
+	ids := make([]int, 0, len(contests))
+	for _, contest := range contests {
+		ids = append(ids, contest.ID)
+	}
+	cnt, err := s.db.ContestCount(ctx, kilonova.ContestFilter{IDs: ids, ProblemID: &problem.ID})
+	if err != nil {
+		zap.S().Warn(err)
+		return false
+	}
+	if ok && cache.userID == user.ID {
+		cache.problems[problem.ID] = cnt > 0
+	}
+	return cnt > 0
+}
+
+// CheckContestSubmitAccess makes sure the contestant is allowed to submit in the contest from the current session and address.
+// Testers and editors are exempt from access restrictions
+func (s *BaseAPI) CheckContestSubmitAccess(ctx context.Context, contest *kilonova.Contest, user *kilonova.UserBrief, sid string, ip *netip.Addr) *StatusError {
+	if contest == nil || s.IsContestTester(user, contest) {
+		return nil
+	}
+	if !IPAllowedInContest(contest, ip) {
+		return Statusf(403, "Submissions to this contest are not allowed from your network")
+	}
+	return s.EnforceContestSession(ctx, contest, user, sid)
+}
+
+// EnforceContestSession keeps contestants logged in only in the session they are using to access a running contest that allows a single session.
+// It must be called whenever a contest is accessed, since sessions created before the contest started are not removed on login.
+// The sid is empty for requests that weren't made using a session (ex: API tokens), which are rejected
+func (s *BaseAPI) EnforceContestSession(ctx context.Context, contest *kilonova.Contest, user *kilonova.UserBrief, sid string) *StatusError {
+	if contest == nil || !contest.SingleSession || !contest.Running() || !user.IsAuthed() || s.IsContestTester(user, contest) {
+		return nil
+	}
+	reg, err := s.db.ContestRegistration(ctx, contest.ID, user.ID)
+	if err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't check registration")
+	}
+	if reg == nil {
+		return nil
+	}
+	if sid == "" {
+		return Statusf(403, "Only one session is allowed during this contest. Please log in")
+	}
+	removed, err := s.db.RemoveSessionsExcept(ctx, user.ID, sid)
+	if err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't remove other sessions")
+	}
+	for _, sess := range removed {
+		s.sessionUserCache.Delete(sess)
+	}
+	return nil
+}
+
+// enforceSingleSession removes the user's other sessions if they compete in a running contest that allows a single session
+func (s *BaseAPI) enforceSingleSession(ctx context.Context, uid int, sid string) {
+	user, err := s.UserBrief(ctx, uid)
+	if err != nil {
+		return
+	}
+	contests := s.runningParticipations(ctx, user)
+	if !slices.ContainsFunc(contests, func(contest *kilonova.Contest) bool { return contest.SingleSession }) {
+		return
+	}
+	removed, err1 := s.db.RemoveSessionsExcept(ctx, uid, sid)
+	if err1 != nil {
+		zap.S().Warn("Failed to remove other sessions: ", err1)
+		return
+	}
+	for _, sess := range removed {
+		s.sessionUserCache.Delete(sess)
+	}
+}
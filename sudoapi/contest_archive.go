@@ -0,0 +1,97 @@
+package sudoapi
+
+import (
+	"context"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+// Lower-level functions used when importing contest archives.
+// They keep the original timestamps and results, so they should only be used by admins
+
+func (s *BaseAPI) ImportContestRegistration(ctx context.Context, reg *kilonova.ContestRegistration) *StatusError {
+	if err := s.db.ImportContestRegistration(ctx, reg); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't import registration")
+	}
+	return nil
+}
+
+func (s *BaseAPI) ImportContestAnnouncement(ctx context.Context, announcement *kilonova.ContestAnnouncement) (int, *StatusError) {
+	id, err := s.db.ImportContestAnnouncement(ctx, announcement)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't import announcement")
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) ImportContestQuestion(ctx context.Context, question *kilonova.ContestQuestion) (int, *StatusError) {
+	id, err := s.db.ImportContestQuestion(ctx, question)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't import question")
+	}
+	return id, nil
+}
+
+// ImportSubmission inserts an already evaluated submission, along with its subtest and subtask results.
+// Unfinished submissions are sent to the grader instead
+func (s *BaseAPI) ImportSubmission(ctx context.Context, sub *kilonova.Submission, code []byte, subTests []*kilonova.SubTest, subTasks []*kilonova.SubmissionSubTask) (int, *StatusError) {
+	id, err := s.db.ImportSubmission(ctx, sub, string(code), subTests, subTasks)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't import submission")
+	}
+	if sub.Status != kilonova.StatusFinished {
+		s.WakeGrader()
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) ImportContestTeam(ctx context.Context, team *kilonova.ContestTeam) (int, *StatusError) {
+	id, err := s.db.ImportContestTeam(ctx, team)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't import team")
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) ImportContestProblemLock(ctx context.Context, lock *kilonova.ContestProblemLock) *StatusError {
+	if err := s.db.ImportContestProblemLock(ctx, lock); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't import problem lock")
+	}
+	return nil
+}
+
+// ImportHack inserts a judged hack, keeping its verdict and points. Unfinished hacks are sent to the grader instead
+func (s *BaseAPI) ImportHack(ctx context.Context, hack *kilonova.ContestHack, input []byte) (int, *StatusError) {
+	id, err := s.db.ImportHack(ctx, hack, input)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't import hack")
+	}
+	if hack.Status != kilonova.HackStatusFinished {
+		s.WakeGrader()
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) ImportPretestScores(ctx context.Context, contestID int, scores []*kilonova.PretestScore) *StatusError {
+	if err := s.db.ImportPretestScores(ctx, contestID, scores); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't import pretest scores")
+	}
+	return nil
+}
+
+func (s *BaseAPI) ImportSystemTestStatus(ctx context.Context, contestID int, status kilonova.SystemTestStatus) *StatusError {
+	if err := s.db.ImportSystemTestStatus(ctx, contestID, status); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't import system test status")
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package sudoapi
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+type ContestCloneOptions struct {
+	Name string `json:"name"`
+
+	// Offset is the number of seconds the start, end and freeze times of the clone are shifted by
+	Offset int64 `json:"offset"`
+
+	Announcements bool `json:"announcements"`
+
+	// ProblemReplacements maps the original problem IDs to the IDs of their copies, if the problems were cloned as well.
+	// Problems that aren't in the map are reused as they are
+	ProblemReplacements map[int]int `json:"-"`
+}
+
+// CloneContest creates a new contest with the settings, editors, testers and problems of the original.
+// Registrations, submissions, questions and invitations are not copied, and the clone is always hidden.
+// If copying fails midway, the partially created contest is removed.
+func (s *BaseAPI) CloneContest(ctx context.Context, contest *kilonova.Contest, opts ContestCloneOptions, author *kilonova.UserBrief) (int, *StatusError) {
+	name := strings.TrimSpace(opts.Name)
+	if name == "" {
+		name = contest.Name
+	}
+
+	id, err := s.CreateContest(ctx, name, contest.Type, author)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := s.copyContestContents(ctx, id, contest, opts, author); err != nil {
+		// The request's context might be the reason copying failed, so don't use it for the cleanup
+		if err1 := s.db.DeleteContest(context.WithoutCancel(ctx), id); err1 != nil {
+			zap.S().Warn("Couldn't remove partially cloned contest: ", err1)
+		}
+		return -1, err
+	}
+
+	s.LogUserAction(ctx, "Cloned contest", slog.Any("contest", contest), slog.Int("clone_id", id))
+	return id, nil
+}
+
+func (s *BaseAPI) copyContestContents(ctx context.Context, id int, contest *kilonova.Contest, opts ContestCloneOptions, author *kilonova.UserBrief) *StatusError {
+	if err := s.CopyContestSettings(ctx, id, contest, time.Duration(opts.Offset)*time.Second); err != nil {
+		return err
+	}
+
+	for _, editor := range contest.Editors {
+		if editor.ID == author.ID {
+			continue
+		}
+		if err := s.AddContestEditor(ctx, id, editor.ID); err != nil {
+			return err
+		}
+	}
+	for _, tester := range contest.Testers {
+		if tester.ID == author.ID {
+			continue
+		}
+		if err := s.AddContestTester(ctx, id, tester.ID); err != nil {
+			return err
+		}
+	}
+
+	replaceProblem := func(problemID int) int {
+		if newID, ok := opts.ProblemReplacements[problemID]; ok {
+			return newID
+		}
+		return problemID
+	}
+
+	settings, err := s.ContestProblemSettings(ctx, contest.ID)
+	if err != nil {
+		return err
+	}
+	problemIDs := make([]int, 0, len(settings))
+	for _, setting := range settings {
+		problemIDs = append(problemIDs, replaceProblem(setting.ProblemID))
+	}
+	if err := s.UpdateContestProblems(ctx, id, problemIDs); err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		setting.ContestID = id
+		setting.ProblemID = replaceProblem(setting.ProblemID)
+		if err := s.db.UpdateContestProblemSettings(ctx, setting); err != nil {
+			zap.S().Warn(err)
+			return WrapError(err, "Couldn't copy contest problem settings")
+		}
+	}
+
+	if opts.Announcements {
+		announcements, err := s.ContestAnnouncements(ctx, contest.ID)
+		if err != nil {
+			return err
+		}
+		// Keep the original order of the announcements
+		slices.SortFunc(announcements, func(a, b *kilonova.ContestAnnouncement) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+		for _, announcement := range announcements {
+			var problemID *int
+			if announcement.ProblemID != nil {
+				newID := replaceProblem(*announcement.ProblemID)
+				problemID = &newID
+			}
+			if _, err := s.db.CreateContestAnnouncement(ctx, id, announcement.Text, problemID); err != nil {
+				return WrapError(err, "Couldn't copy announcement")
+			}
+		}
+	}
+
+	return nil
+}
+
+// CopyContestSettings applies the settings of the given contest (except for its name, type and visibility) to the contest with the specified ID.
+// The start, end and freeze times are shifted by offset. The target contest is left hidden
+func (s *BaseAPI) CopyContestSettings(ctx context.Context, id int, contest *kilonova.Contest, offset time.Duration) *StatusError {
+	startTime, endTime := contest.StartTime.Add(offset), contest.EndTime.Add(offset)
+	var freezeTime *time.Time
+	if contest.LeaderboardFreeze != nil {
+		t := contest.LeaderboardFreeze.Add(offset)
+		freezeTime = &t
+	}
+	visible := false
+	submissionCooldown, questionCooldown := int(contest.SubmissionCooldown.Milliseconds()), int(contest.QuestionCooldown.Milliseconds())
+	if err := s.UpdateContest(ctx, id, kilonova.ContestUpdate{
+		PublicJoin:  &contest.PublicJoin,
+		Visible:     &visible,
+		Description: &contest.Description,
+
+		StartTime: &startTime,
+		EndTime:   &endTime,
+
+		MaxSubs: &contest.MaxSubs,
+
+		RegisterDuringContest: &contest.RegisterDuringContest,
+
+		PublicLeaderboard:     &contest.PublicLeaderboard,
+		LeaderboardStyle:      contest.LeaderboardStyle,
+		ICPCSubmissionPenalty: &contest.ICPCSubmissionPenalty,
+
+		LeaderboardAdvancedFilter: &contest.LeaderboardAdvancedFilter,
+
+		ChangeLeaderboardFreeze: true,
+		LeaderboardFreeze:       freezeTime,
+
+		SubmissionCooldown: &submissionCooldown,
+		QuestionCooldown:   &questionCooldown,
+
+		PerUserTime: &contest.PerUserTime,
+
+		FeedbackLevel:  contest.FeedbackLevel,
+		FeedbackTokens: &contest.FeedbackTokens,
+
+		Pretests: &contest.Pretests,
+
+		Hacking:     &contest.Hacking,
+		HackPoints:  &contest.HackPoints,
+		HackPenalty: &contest.HackPenalty,
+
+		TeamSize: &contest.TeamSize,
+
+		SingleSession: &contest.SingleSession,
+		Lockdown:      &contest.Lockdown,
+
+		OnSite: &contest.OnSite,
+	}); err != nil {
+		return err
+	}
+	if err := s.UpdateContestCategories(ctx, id, contest.Categories); err != nil {
+		return err
+	}
+	if err := s.db.UpdateContestIPRanges(ctx, id, contest.AllowedIPRanges); err != nil {
+		return WrapError(err, "Couldn't copy contest IP ranges")
+	}
+	return nil
+}
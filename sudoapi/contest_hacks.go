@@ -0,0 +1,304 @@
+package sudoapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+var (
+	HackInputSizeLimit = config.GenFlag[int]("behavior.contests.hack_input_max_size", 1024*1024, "Maximum size (in bytes) of a hack's test input")
+	MaxPendingHacks    = config.GenFlag[int]("behavior.contests.max_pending_hacks", 3, "Maximum number of a user's hacks waiting to be judged at once. Set to 0 to disable the limit")
+)
+
+// HackTestID returns the (negative, so it can't clash with actual tests) test ID under which the hack's input
+// and reference output are stored in the tests bucket.
+func HackTestID(hackID int) int {
+	return -hackID
+}
+
+// LockContestProblem forbids the user from submitting again to the problem,
+// in exchange for access to the others' accepted solutions during the hacking phase.
+func (s *BaseAPI) LockContestProblem(ctx context.Context, contest *kilonova.Contest, problem *kilonova.Problem, user *kilonova.UserBrief) *StatusError {
+	if contest == nil || problem == nil || !user.IsAuthed() {
+		return Statusf(400, "Invalid lock parameters")
+	}
+	if !contest.Hacking {
+		return Statusf(400, "Contest doesn't have a hacking phase")
+	}
+	if !contest.Running() {
+		return Statusf(400, "Problems can only be locked while the contest is running")
+	}
+	if !s.CanSubmitInContest(ctx, user, contest) {
+		return Statusf(403, "You can't participate in this contest")
+	}
+	if pb, err := s.ContestProblem(ctx, contest, user, problem.ID); err != nil || pb == nil {
+		return Statusf(400, "Problem is not in contest")
+	}
+
+	accepted := decimal.NewFromInt(100)
+	cnt, err := s.db.SubmissionCount(ctx, kilonova.SubmissionFilter{
+		ContestID: &contest.ID,
+		ProblemID: &problem.ID,
+		UserID:    &user.ID,
+		Status:    kilonova.StatusFinished,
+		Score:     &accepted,
+	}, -1)
+	if err != nil {
+		return WrapError(err, "Couldn't check accepted submissions")
+	}
+	if cnt == 0 {
+		return Statusf(400, "You can only lock problems you have solved")
+	}
+
+	if _, err := s.db.LockContestProblem(ctx, contest.ID, user.ID, problem.ID); err != nil {
+		return WrapError(err, "Couldn't lock problem")
+	}
+	return nil
+}
+
+func (s *BaseAPI) ContestProblemLocked(ctx context.Context, contestID, userID, problemID int) (bool, *StatusError) {
+	locked, err := s.db.ContestProblemLocked(ctx, contestID, userID, problemID)
+	if err != nil {
+		return false, WrapError(err, "Couldn't check problem lock")
+	}
+	return locked, nil
+}
+
+func (s *BaseAPI) LockedContestProblems(ctx context.Context, contestID, userID int) ([]int, *StatusError) {
+	ids, err := s.db.LockedContestProblems(ctx, contestID, userID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get locked problems")
+	}
+	return ids, nil
+}
+
+// CanHackSubmission returns whether the user may see and challenge the submission during the hacking phase
+func (s *BaseAPI) CanHackSubmission(ctx context.Context, sub *kilonova.Submission, user *kilonova.UserBrief) bool {
+	if sub == nil || sub.ContestID == nil || !user.IsAuthed() || sub.UserID == user.ID {
+		return false
+	}
+	if sub.Status != kilonova.StatusFinished || !sub.Score.Equal(decimal.NewFromInt(100)) {
+		return false
+	}
+	contest, err := s.Contest(ctx, *sub.ContestID)
+	if err != nil || !contest.Hacking {
+		return false
+	}
+	locked, err := s.ContestProblemLocked(ctx, contest.ID, user.ID, sub.ProblemID)
+	if err != nil {
+		return false
+	}
+	return locked
+}
+
+// CreateHack challenges the target submission with the given test input.
+// The hack is judged asynchronously by the grader.
+func (s *BaseAPI) CreateHack(ctx context.Context, targetID int, hacker *kilonova.UserBrief, input []byte) (int, *StatusError) {
+	target, err := s.RawSubmission(ctx, targetID)
+	if err != nil {
+		return -1, err
+	}
+	if target.ContestID == nil {
+		return -1, Statusf(400, "Only contest submissions can be hacked")
+	}
+	contest, err := s.Contest(ctx, *target.ContestID)
+	if err != nil {
+		return -1, err
+	}
+	if !contest.Hacking || !contest.Running() {
+		return -1, Statusf(400, "Hacks can only be sent during the contest's hacking phase")
+	}
+	if !s.CanHackSubmission(ctx, target, hacker) {
+		return -1, Statusf(403, "You can't hack this submission. Make sure you locked the problem")
+	}
+	if len(input) == 0 {
+		return -1, Statusf(400, "Empty hack input")
+	}
+	if HackInputSizeLimit.Value() > 0 && len(input) > HackInputSizeLimit.Value() {
+		return -1, Statusf(400, "Hack input exceeds %d bytes", HackInputSizeLimit.Value())
+	}
+
+	cnt, err1 := s.db.HackCount(ctx, kilonova.HackFilter{TargetSubmissionID: &target.ID, Verdict: kilonova.HackVerdictSuccessful})
+	if err1 != nil {
+		return -1, WrapError(err1, "Couldn't check previous hacks")
+	}
+	if cnt > 0 {
+		return -1, Statusf(400, "Submission was already hacked")
+	}
+
+	// Each hack runs the validator, the reference solution and the target, so the grader work a user can queue is limited
+	if MaxPendingHacks.Value() > 0 {
+		pending, err := s.db.HackCount(ctx, kilonova.HackFilter{ContestID: &contest.ID, HackerID: &hacker.ID, Unfinished: true})
+		if err != nil {
+			return -1, WrapError(err, "Couldn't check previous hacks")
+		}
+		if pending >= MaxPendingHacks.Value() {
+			return -1, Statusf(429, "You already have %d hacks waiting to be judged. Wait for them to finish before sending another", pending)
+		}
+	}
+
+	settings, err := s.ProblemSettings(ctx, target.ProblemID)
+	if err != nil {
+		return -1, err
+	}
+	if settings.SolutionName == "" {
+		return -1, Statusf(400, "Problem doesn't have a reference solution, so it can't be hacked")
+	}
+
+	hack := &kilonova.ContestHack{
+		ContestID: contest.ID,
+		ProblemID: target.ProblemID,
+
+		HackerID:           hacker.ID,
+		TargetUserID:       target.UserID,
+		TargetSubmissionID: target.ID,
+	}
+	if err := s.db.CreateHack(ctx, hack, input); err != nil {
+		return -1, WrapError(err, "Couldn't create hack")
+	}
+
+	s.WakeGrader()
+	return hack.ID, nil
+}
+
+func (s *BaseAPI) Hack(ctx context.Context, id int) (*kilonova.ContestHack, *StatusError) {
+	hack, err := s.db.Hack(ctx, id)
+	if err != nil || hack == nil {
+		return nil, WrapError(ErrNotFound, "Hack not found")
+	}
+	return hack, nil
+}
+
+func (s *BaseAPI) Hacks(ctx context.Context, filter kilonova.HackFilter) ([]*kilonova.ContestHack, *StatusError) {
+	hacks, err := s.db.Hacks(ctx, filter)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get hacks")
+	}
+	return hacks, nil
+}
+
+// CanViewHack returns whether the user may see the hack and its input.
+// Contest editors see all hacks, contestants only those they are involved in.
+func (s *BaseAPI) CanViewHack(hack *kilonova.ContestHack, contest *kilonova.Contest, user *kilonova.UserBrief) bool {
+	if hack == nil || !user.IsAuthed() {
+		return false
+	}
+	if s.IsContestEditor(user, contest) {
+		return true
+	}
+	return hack.HackerID == user.ID || hack.TargetUserID == user.ID
+}
+
+func (s *BaseAPI) HackInput(ctx context.Context, id int) ([]byte, *StatusError) {
+	input, err := s.db.HackInput(ctx, id)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get hack input")
+	}
+	if input == nil {
+		return nil, WrapError(ErrNotFound, "Hack not found")
+	}
+	return input, nil
+}
+
+// ClaimPendingHacks should only be used by the grader
+func (s *BaseAPI) ClaimPendingHacks(ctx context.Context, limit int) ([]*kilonova.ContestHack, *StatusError) {
+	hacks, err := s.db.ClaimPendingHacks(ctx, limit)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get pending hacks")
+	}
+	return hacks, nil
+}
+
+// RequeueHack puts back a claimed hack that could not be started
+func (s *BaseAPI) RequeueHack(ctx context.Context, id int) *StatusError {
+	if err := s.db.UpdateHack(ctx, id, kilonova.HackUpdate{Status: kilonova.HackStatusPending}); err != nil {
+		return WrapError(err, "Couldn't requeue hack")
+	}
+	return nil
+}
+
+// FinishHack records the verdict of the hack and awards (or subtracts) the hacker's points.
+// Hacks with invalid input or that failed because of the grader don't affect the score.
+// A successful hack zeroes the target submission's score on the leaderboard. Only the first successful hack on a submission counts.
+func (s *BaseAPI) FinishHack(ctx context.Context, hack *kilonova.ContestHack, verdict kilonova.HackVerdict, message string) *StatusError {
+	var points int
+	contest, err := s.Contest(ctx, hack.ContestID)
+	if err != nil {
+		zap.S().Warn("Couldn't get hack contest: ", err)
+	} else {
+		switch verdict {
+		case kilonova.HackVerdictSuccessful:
+			points = contest.HackPoints
+		case kilonova.HackVerdictUnsuccessful:
+			points = -contest.HackPenalty
+		}
+	}
+	counted, err1 := s.db.FinishHack(ctx, hack, verdict, message, points)
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return WrapError(err1, "Couldn't finish hack")
+	}
+	if verdict != kilonova.HackVerdictSuccessful || !counted {
+		// Only successful hacks may become tests, so the rest of the data can go
+		if err := s.PurgeTestData(HackTestID(hack.ID)); err != nil {
+			zap.S().Warn(err)
+		}
+	}
+	return nil
+}
+
+// AddHackAsTest creates a new problem test from the input and reference output of a successful hack.
+// The test has no score and isn't added to any subtask, that is left to the problem editors.
+func (s *BaseAPI) AddHackAsTest(ctx context.Context, hack *kilonova.ContestHack) (int, *StatusError) {
+	if hack.Verdict != kilonova.HackVerdictSuccessful {
+		return -1, Statusf(400, "Only successful hacks can be added as tests")
+	}
+	if hack.TestID != nil {
+		return -1, Statusf(400, "Hack was already added as a test")
+	}
+
+	in, err := s.TestInput(HackTestID(hack.ID))
+	if err != nil {
+		return -1, WrapError(err, "Couldn't get hack input")
+	}
+	defer in.Close()
+	out, err := s.TestOutput(HackTestID(hack.ID))
+	if err != nil {
+		return -1, WrapError(err, "Couldn't get hack reference output")
+	}
+	defer out.Close()
+
+	test := &kilonova.Test{
+		ProblemID: hack.ProblemID,
+		VisibleID: s.NextVID(ctx, hack.ProblemID),
+		Score:     decimal.Zero,
+	}
+	if err := s.CreateTest(ctx, test); err != nil {
+		return -1, err
+	}
+	if err := errors.Join(s.SaveTestInput(test.ID, in), s.SaveTestOutput(test.ID, out)); err != nil {
+		return -1, WrapError(err, "Couldn't save test data")
+	}
+	if err := s.db.UpdateHack(ctx, hack.ID, kilonova.HackUpdate{TestID: &test.ID}); err != nil {
+		return -1, WrapError(err, "Couldn't update hack")
+	}
+
+	s.LogUserAction(ctx, "Added hack as test", slog.Int("hack_id", hack.ID), slog.Int("problem_id", hack.ProblemID), slog.Int("test_id", test.ID))
+	return test.ID, nil
+}
+
+// ContestProblemLocks returns the problems locked by all users in the contest
+func (s *BaseAPI) ContestProblemLocks(ctx context.Context, contestID int) ([]*kilonova.ContestProblemLock, *StatusError) {
+	locks, err := s.db.ContestProblemLocks(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get problem locks")
+	}
+	return locks, nil
+}
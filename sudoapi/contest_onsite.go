@@ -0,0 +1,229 @@
+package sudoapi
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	chtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"go.uber.org/zap"
+)
+
+var (
+	PrintMaxSize    = config.GenFlag[int]("behavior.contests.print_max_size", 64*1024, "Maximum size (in bytes) of a file sent for printing at on-site contests")
+	PrintMaxPending = config.GenFlag[int]("behavior.contests.print_max_pending", 5, "Maximum number of not yet printed jobs a contestant may have at on-site contests")
+)
+
+var printJobTempl = template.Must(template.New("print_job").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+@page { size: A4; margin: 1.5cm 1cm; }
+body { font-family: sans-serif; margin: 0; }
+header { border-bottom: 1px solid #000; margin-bottom: 0.4cm; padding-bottom: 0.2cm; font-size: 11pt; }
+header h1 { font-size: 16pt; margin: 0 0 0.1cm 0; }
+pre { font-size: 9pt; white-space: pre-wrap; word-break: break-all; margin: 0; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{if .Team}}{{.Team}}{{else}}{{.User}}{{end}}{{if .Seat}} &mdash; {{.Seat}}{{end}}</h1>
+<div>{{if .Team}}{{.User}} &middot; {{end}}{{if .Location}}{{.Location}} &middot; {{end}}{{.Contest}}</div>
+<div>#{{.Job.ID}} &middot; {{.Job.Filename}} &middot; {{.Job.CreatedAt.Format "2006-01-02 15:04:05"}}</div>
+</header>
+{{.Code}}
+</body>
+</html>`))
+
+// CreatePrintJob queues the source file for printing by the staff of the on-site contest
+func (s *BaseAPI) CreatePrintJob(ctx context.Context, contest *kilonova.Contest, user *kilonova.UserBrief, job *kilonova.PrintJob) (int, *StatusError) {
+	if !contest.OnSite {
+		return -1, Statusf(400, "Printing is only available at on-site contests")
+	}
+	if !user.IsAuthed() || !s.CanSubmitInContest(ctx, user, contest) {
+		return -1, Statusf(403, "You can't print files in this contest")
+	}
+
+	job.Filename = path.Base(strings.TrimSpace(job.Filename))
+	if job.Filename == "" || job.Filename == "." || job.Filename == "/" {
+		return -1, Statusf(400, "Invalid file name")
+	}
+	job.Seat, job.Location = strings.TrimSpace(job.Seat), strings.TrimSpace(job.Location)
+	if len(job.Code) == 0 {
+		return -1, Statusf(400, "Empty file")
+	}
+	if len(job.Code) > PrintMaxSize.Value() {
+		return -1, Statusf(400, "File is too large to be printed")
+	}
+	if !utf8.ValidString(job.Code) {
+		return -1, Statusf(400, "Only text files can be printed")
+	}
+
+	pending, err := s.db.PrintJobs(ctx, contest.ID, &user.ID, true)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't get print jobs")
+	}
+	if len(pending) >= PrintMaxPending.Value() && !s.IsContestTester(user, contest) {
+		return -1, Statusf(400, "You have too many files waiting to be printed")
+	}
+
+	job.ContestID = contest.ID
+	job.UserID = user.ID
+	job.TeamID = nil
+	if contest.TeamMode() {
+		reg, err := s.db.ContestRegistration(ctx, contest.ID, user.ID)
+		if err != nil {
+			zap.S().Warn(err)
+			return -1, WrapError(err, "Couldn't get registration")
+		}
+		if reg != nil {
+			job.TeamID = reg.TeamID
+		}
+	}
+
+	id, err := s.db.CreatePrintJob(ctx, job)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't create print job")
+	}
+	return id, nil
+}
+
+// PrintJobs returns the contest's print jobs. If userID is set, only the user's jobs are returned
+func (s *BaseAPI) PrintJobs(ctx context.Context, contestID int, userID *int, pendingOnly bool) ([]*kilonova.PrintJob, *StatusError) {
+	jobs, err := s.db.PrintJobs(ctx, contestID, userID, pendingOnly)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get print jobs")
+	}
+	return jobs, nil
+}
+
+func (s *BaseAPI) PrintJob(ctx context.Context, contestID, id int) (*kilonova.PrintJob, *StatusError) {
+	job, err := s.db.PrintJob(ctx, contestID, id)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get print job")
+	}
+	if job == nil {
+		return nil, WrapError(ErrNotFound, "Print job not found")
+	}
+	return job, nil
+}
+
+// SetPrintJobPrinted marks the job as printed by the staff member, or as pending again if printed is false
+func (s *BaseAPI) SetPrintJobPrinted(ctx context.Context, contestID, id int, staff *kilonova.UserBrief, printed bool) *StatusError {
+	if _, err := s.PrintJob(ctx, contestID, id); err != nil {
+		return err
+	}
+	var printedBy *int
+	if printed {
+		printedBy = &staff.ID
+	}
+	if err := s.db.SetPrintJobPrinted(ctx, contestID, id, printedBy); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update print job")
+	}
+	return nil
+}
+
+// RenderPrintJob returns the printable HTML page of the job, with the source highlighted and a header identifying the contestant
+func (s *BaseAPI) RenderPrintJob(ctx context.Context, contest *kilonova.Contest, job *kilonova.PrintJob) ([]byte, *StatusError) {
+	user, err := s.UserBrief(ctx, job.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var team string
+	if job.TeamID != nil {
+		if t, err := s.ContestTeam(ctx, *job.TeamID); err == nil {
+			team = t.Name
+		}
+	}
+
+	code, err1 := highlightPrintJob(job)
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return nil, WrapError(err1, "Couldn't highlight source")
+	}
+
+	var buf bytes.Buffer
+	if err := printJobTempl.Execute(&buf, map[string]any{
+		"Job":      job,
+		"Contest":  contest.Name,
+		"User":     user.Name,
+		"Team":     team,
+		"Seat":     job.Seat,
+		"Location": job.Location,
+		"Code":     template.HTML(code),
+	}); err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't render print job")
+	}
+	return buf.Bytes(), nil
+}
+
+// PrintJobPDF renders the job as a PDF, ready to be sent to the printer
+func (s *BaseAPI) PrintJobPDF(ctx context.Context, contest *kilonova.Contest, job *kilonova.PrintJob) ([]byte, *StatusError) {
+	page, err := s.RenderPrintJob(ctx, contest, job)
+	if err != nil {
+		return nil, err
+	}
+	return s.RenderPDF(ctx, page)
+}
+
+// highlightPrintJob formats the source with inline styles, since the page is rendered without the site's stylesheets
+func highlightPrintJob(job *kilonova.PrintJob) (string, error) {
+	return SyntaxHighlight(job.Code, job.Language, job.Filename, chtml.WithClasses(false), chtml.WithLineNumbers(true))
+}
+
+// BalloonEvents returns the contest's first accepted submissions of every participant on every problem, in the order they were sent
+func (s *BaseAPI) BalloonEvents(ctx context.Context, contest *kilonova.Contest) ([]*kilonova.BalloonEvent, *StatusError) {
+	events, err := s.db.BalloonEvents(ctx, contest.ID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get balloons")
+	}
+	solved := make(map[int]bool)
+	for _, ev := range events {
+		if !solved[ev.ProblemID] {
+			ev.FirstSolve = true
+			solved[ev.ProblemID] = true
+		}
+	}
+	return events, nil
+}
+
+// SetBalloonDelivered marks the balloon for the submission as delivered by the staff member, or as not delivered if delivered is false
+func (s *BaseAPI) SetBalloonDelivered(ctx context.Context, contest *kilonova.Contest, submissionID int, staff *kilonova.UserBrief, delivered bool) *StatusError {
+	events, err := s.BalloonEvents(ctx, contest)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, ev := range events {
+		if ev.SubmissionID == submissionID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return WrapError(ErrNotFound, "Balloon not found")
+	}
+
+	var deliveredBy *int
+	if delivered {
+		deliveredBy = &staff.ID
+	}
+	if err := s.db.SetBalloonDelivered(ctx, contest.ID, submissionID, deliveredBy); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update balloon")
+	}
+	return nil
+}
@@ -2,14 +2,27 @@ package sudoapi
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/db"
 )
 
-func (s *BaseAPI) CreateContestQuestion(ctx context.Context, contest *kilonova.Contest, authorID int, text string) (int, *StatusError) {
+// CreateContestQuestion asks a question in a contest, optionally about one of its problems
+func (s *BaseAPI) CreateContestQuestion(ctx context.Context, contest *kilonova.Contest, authorID int, text string, problemID *int) (int, *StatusError) {
+	if problemID != nil {
+		settings, err := s.db.ContestProblemSetting(ctx, contest.ID, *problemID)
+		if err != nil {
+			return -1, WrapError(err, "Couldn't check contest problem")
+		}
+		if settings == nil {
+			return -1, Statusf(400, "Problem isn't in contest")
+		}
+	}
+
 	if contest.QuestionCooldown > 0 {
 		question, err := s.db.ContestQuestions(ctx, db.QuestionFilter{ContestID: &contest.ID, AuthorID: &authorID})
 		if err != nil {
@@ -22,18 +35,24 @@ func (s *BaseAPI) CreateContestQuestion(ctx context.Context, contest *kilonova.C
 		}
 	}
 
-	id, err := s.db.CreateContestQuestion(ctx, contest.ID, authorID, text)
+	id, err := s.db.CreateContestQuestion(ctx, contest.ID, authorID, text, problemID)
 	if err != nil {
 		return -1, WrapError(err, "Couldn't ask question")
 	}
+	s.publishEvent(&liveEvent{Type: EventQuestion, QuestionID: id})
 	return id, nil
 }
 
-func (s *BaseAPI) CreateContestAnnouncement(ctx context.Context, contestID int, text string) (int, *StatusError) {
-	id, err := s.db.CreateContestAnnouncement(ctx, contestID, text)
+// CreateContestAnnouncement makes an announcement visible to everyone in the contest.
+// If problemID is set, the announcement is a clarification about that problem
+func (s *BaseAPI) CreateContestAnnouncement(ctx context.Context, contestID int, text string, problemID *int) (int, *StatusError) {
+	id, err := s.db.CreateContestAnnouncement(ctx, contestID, text, problemID)
 	if err != nil {
 		return -1, WrapError(err, "Couldn't create announcement")
 	}
+	if announcement, err := s.db.ContestAnnouncement(ctx, id); err == nil && announcement != nil {
+		s.publishEvent(&liveEvent{Type: EventAnnouncement, Announcement: announcement})
+	}
 	return id, nil
 }
 
@@ -83,10 +102,94 @@ func (s *BaseAPI) ContestUserQuestions(ctx context.Context, contestID, userID in
 	return questions, nil
 }
 
-func (s *BaseAPI) AnswerContestQuestion(ctx context.Context, id int, text string) *StatusError {
-	if err := s.db.AnswerContestQuestion(ctx, id, text); err != nil {
+// ContestQuestionQueue returns the unanswered questions of the contest that the judge may answer:
+// the ones nobody claimed yet and the ones claimed by the judge
+func (s *BaseAPI) ContestQuestionQueue(ctx context.Context, contestID, judgeID int) ([]*kilonova.ContestQuestion, *StatusError) {
+	questions, err := s.db.ContestQuestions(ctx, db.QuestionFilter{ContestID: &contestID, Unanswered: true, AvailableTo: &judgeID})
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch questions")
+	}
+	slices.Reverse(questions) // Oldest questions first
+	return questions, nil
+}
+
+// AnswerContestQuestion answers a question, which must not be claimed by another judge.
+// If public is set, the question and its answer are also published as a clarification for everyone.
+// Updating the answer of a question that was answered publicly also updates its clarification
+func (s *BaseAPI) AnswerContestQuestion(ctx context.Context, question *kilonova.ContestQuestion, text string, judge *kilonova.UserBrief, public bool) *StatusError {
+	if question.Response == nil && question.ClaimedBy != nil && *question.ClaimedBy != judge.ID {
+		return Statusf(http.StatusConflict, "The question was claimed by another judge")
+	}
+
+	// The answer is saved first, so that clarifications are published only by the judge whose answer went through
+	ok, err := s.db.AnswerContestQuestion(ctx, question.ID, text, judge.ID, question.Response)
+	if err != nil {
 		return WrapError(err, "Couldn't answer question")
 	}
+	if !ok {
+		return Statusf(http.StatusConflict, "The question was answered or claimed by another judge in the meantime")
+	}
+
+	clarification := fmt.Sprintf("Q: %s\n\nA: %s", question.Text, text)
+	if question.AnnouncementID != nil {
+		if err := s.UpdateContestAnnouncement(ctx, *question.AnnouncementID, clarification); err != nil {
+			return err
+		}
+	} else if public {
+		id, err := s.CreateContestAnnouncement(ctx, question.ContestID, clarification, question.ProblemID)
+		if err != nil {
+			return err
+		}
+		if err := s.db.SetContestQuestionAnnouncement(ctx, question.ID, id); err != nil {
+			return WrapError(err, "Couldn't link clarification to question")
+		}
+	}
+
+	s.publishEvent(&liveEvent{Type: EventQuestion, QuestionID: question.ID})
+	s.notifyQuestionAnswer(ctx, question, text)
+	return nil
+}
+
+// ClaimContestQuestion marks the judge as the one answering the question, so other judges don't answer it at the same time
+func (s *BaseAPI) ClaimContestQuestion(ctx context.Context, question *kilonova.ContestQuestion, judge *kilonova.UserBrief) *StatusError {
+	if question.Response != nil {
+		return Statusf(400, "The question was already answered")
+	}
+	ok, err := s.db.ClaimContestQuestion(ctx, question.ID, judge.ID, false)
+	if err != nil {
+		return WrapError(err, "Couldn't claim question")
+	}
+	if !ok {
+		return Statusf(http.StatusConflict, "The question was claimed by another judge")
+	}
+	s.publishEvent(&liveEvent{Type: EventQuestion, QuestionID: question.ID})
+	return nil
+}
+
+// AssignContestQuestion hands an unanswered question over to another judge of the contest, even if it was already claimed
+func (s *BaseAPI) AssignContestQuestion(ctx context.Context, contest *kilonova.Contest, question *kilonova.ContestQuestion, judge *kilonova.UserBrief) *StatusError {
+	if question.Response != nil {
+		return Statusf(400, "The question was already answered")
+	}
+	if !s.IsContestEditor(judge, contest) {
+		return Statusf(400, "Questions can only be assigned to contest editors")
+	}
+	ok, err := s.db.ClaimContestQuestion(ctx, question.ID, judge.ID, true)
+	if err != nil {
+		return WrapError(err, "Couldn't assign question")
+	}
+	if !ok {
+		return Statusf(http.StatusConflict, "The question was answered in the meantime")
+	}
+	s.publishEvent(&liveEvent{Type: EventQuestion, QuestionID: question.ID})
+	return nil
+}
+
+func (s *BaseAPI) UnclaimContestQuestion(ctx context.Context, question *kilonova.ContestQuestion) *StatusError {
+	if err := s.db.UnclaimContestQuestion(ctx, question.ID); err != nil {
+		return WrapError(err, "Couldn't unclaim question")
+	}
+	s.publishEvent(&liveEvent{Type: EventQuestion, QuestionID: question.ID})
 	return nil
 }
 
@@ -0,0 +1,225 @@
+package sudoapi
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	RatingsEnabled = config.GenFlag("feature.ratings.enabled", true, "Compute user ratings once official contests end")
+	InitialRating  = config.GenFlag("feature.ratings.initial", 1500, "Rating assumed for users in their first rated contest")
+)
+
+type ratingParticipant struct {
+	userID int
+	// rank is the average of the places of the contestants tied with this one, so tied contestants share the same expectations
+	rank   float64
+	place  int
+	rating int
+	delta  int
+}
+
+// winProbability is the Elo probability that a contestant rated ra places above one rated rb
+func winProbability(ra, rb float64) float64 {
+	return 1 / (1 + math.Pow(10, (rb-ra)/400))
+}
+
+// computeRatingDeltas computes the rating changes using the Codeforces variant of Elo:
+// every contestant's performance is the rating at which their expected place would match the geometric mean of their
+// expected and actual places, and the change is half of the difference between that and their current rating.
+// The changes are then adjusted so they add up to about zero and so the top rated contestants can't inflate their rating.
+func computeRatingDeltas(parts []*ratingParticipant) {
+	n := len(parts)
+	if n == 0 {
+		return
+	}
+
+	// seed is the expected place of a contestant with the given rating, against everyone except the contestant itself
+	seed := func(rating float64, self int) float64 {
+		res := 1.0
+		for j, other := range parts {
+			if j != self {
+				res += winProbability(float64(other.rating), rating)
+			}
+		}
+		return res
+	}
+
+	for i, p := range parts {
+		mid := math.Sqrt(p.rank * seed(float64(p.rating), i))
+		lo, hi := 1.0, 8000.0
+		for hi-lo > 1 {
+			m := (lo + hi) / 2
+			if seed(m, i) < mid {
+				hi = m
+			} else {
+				lo = m
+			}
+		}
+		p.delta = int((lo - float64(p.rating)) / 2)
+	}
+
+	var sum int
+	for _, p := range parts {
+		sum += p.delta
+	}
+	inc := -sum/n - 1
+	for _, p := range parts {
+		p.delta += inc
+	}
+
+	sorted := slices.Clone(parts)
+	slices.SortFunc(sorted, func(a, b *ratingParticipant) int { return cmp.Compare(b.rating, a.rating) })
+	topCnt := min(n, int(4*math.Round(math.Sqrt(float64(n)))))
+	var topSum int
+	for _, p := range sorted[:topCnt] {
+		topSum += p.delta
+	}
+	inc = min(max(-topSum/topCnt, -10), 0)
+	for _, p := range parts {
+		p.delta += inc
+	}
+}
+
+// ComputeContestRatings (re)computes the rating changes of an ended official contest from its final leaderboard.
+// Only the contestants that submitted at least once are rated. In team contests, every member gets the team's place.
+// Recomputing an older contest doesn't update the rating changes of the contests that ended after it
+func (s *BaseAPI) ComputeContestRatings(ctx context.Context, contest *kilonova.Contest) *StatusError {
+	if contest.Type != kilonova.ContestTypeOfficial {
+		return Statusf(400, "Only official contests are rated")
+	}
+	if !contest.Ended() {
+		return Statusf(400, "Ratings can only be computed after the contest ended")
+	}
+	if contest.Pretests && contest.SystemTestStatus != kilonova.SystemTestFinished {
+		return Statusf(400, "Ratings can only be computed after system testing finished")
+	}
+
+	leaderboard, err := s.ContestLeaderboard(ctx, contest, nil, kilonova.UserFilter{})
+	if err != nil {
+		return err
+	}
+
+	entries := slices.DeleteFunc(slices.Clone(leaderboard.Entries), func(entry *kilonova.LeaderboardEntry) bool {
+		return !leaderboardParticipated(entry)
+	})
+
+	var parts []*ratingParticipant
+	for i := 0; i < len(entries); {
+		j := i
+		for j+1 < len(entries) && leaderboardTied(leaderboard, entries[i], entries[j+1]) {
+			j++
+		}
+		rank := float64(i+j+2) / 2
+		for _, entry := range entries[i : j+1] {
+			var users []*kilonova.UserBrief
+			if entry.Team != nil {
+				users = entry.Team.Members
+			} else if entry.User != nil {
+				users = []*kilonova.UserBrief{entry.User}
+			}
+			for _, user := range users {
+				parts = append(parts, &ratingParticipant{userID: user.ID, rank: rank, place: i + 1})
+			}
+		}
+		i = j + 1
+	}
+
+	userIDs := make([]int, 0, len(parts))
+	for _, p := range parts {
+		userIDs = append(userIDs, p.userID)
+	}
+	ratings, err1 := s.db.RatingsBeforeContest(ctx, contest, userIDs)
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return WrapError(err1, "Couldn't get previous ratings")
+	}
+	for _, p := range parts {
+		rating, ok := ratings[p.userID]
+		if !ok {
+			rating = InitialRating.Value()
+		}
+		p.rating = rating
+	}
+
+	computeRatingDeltas(parts)
+
+	changes := make([]*kilonova.RatingChange, 0, len(parts))
+	for _, p := range parts {
+		changes = append(changes, &kilonova.RatingChange{
+			ContestID: contest.ID,
+			UserID:    p.userID,
+			Rank:      p.place,
+			OldRating: p.rating,
+			NewRating: max(p.rating+p.delta, 0),
+		})
+	}
+	if err := s.db.SetContestRatingChanges(ctx, contest.ID, changes); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't save rating changes")
+	}
+
+	s.LogToDiscord(ctx, "Computed contest ratings", slog.Any("contest", contest), slog.Int("participant_count", len(changes)))
+	return nil
+}
+
+func (s *BaseAPI) ContestRatingChanges(ctx context.Context, contestID int) ([]*kilonova.RatingChange, *StatusError) {
+	changes, err := s.db.ContestRatingChanges(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get rating changes")
+	}
+	return changes, nil
+}
+
+func (s *BaseAPI) UserRatingHistory(ctx context.Context, userID int) ([]*kilonova.RatingChange, *StatusError) {
+	changes, err := s.db.UserRatingHistory(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get rating history")
+	}
+	return changes, nil
+}
+
+func (s *BaseAPI) rateContests(ctx context.Context) {
+	if !RatingsEnabled.Value() {
+		return
+	}
+	contests, err := s.db.ContestsAwaitingRating(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't get contests awaiting rating: ", err)
+		}
+		return
+	}
+	for _, contest := range contests {
+		if err := s.ComputeContestRatings(ctx, contest); err != nil {
+			zap.S().Warn("Couldn't compute contest ratings: ", err)
+		}
+	}
+}
+
+func (s *BaseAPI) ratingJob(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return nil
+		case <-t.C:
+			s.rateContests(ctx)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package sudoapi
+
+import (
+	"slices"
+	"testing"
+)
+
+type ratingDeltaTest struct {
+	Ratings []int
+	Ranks   []float64
+	Deltas  []int
+}
+
+var ratingDeltaExamples = map[string]ratingDeltaTest{
+	"empty":         {Ratings: []int{}, Ranks: []float64{}, Deltas: []int{}},
+	"single":        {Ratings: []int{1500}, Ranks: []float64{1}, Deltas: []int{-1}},
+	"equal ratings": {Ratings: []int{1500, 1500, 1500, 1500}, Ranks: []float64{1, 2, 3, 4}, Deltas: []int{111, 18, -39, -94}},
+	"tie":           {Ratings: []int{1500, 1500}, Ranks: []float64{1.5, 1.5}, Deltas: []int{-1, -1}},
+	"favorite wins": {Ratings: []int{1800, 1200}, Ranks: []float64{1, 2}, Deltas: []int{60, -62}},
+	"upset":         {Ratings: []int{1200, 1800}, Ranks: []float64{1, 2}, Deltas: []int{304, -306}},
+	"shared place":  {Ratings: []int{1600, 1400, 1400, 1500}, Ranks: []float64{1, 2.5, 2.5, 4}, Deltas: []int{79, 12, 12, -104}},
+}
+
+func TestComputeRatingDeltas(t *testing.T) {
+	for k, v := range ratingDeltaExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			parts := make([]*ratingParticipant, 0, len(v.Ratings))
+			for i := range v.Ratings {
+				parts = append(parts, &ratingParticipant{userID: i + 1, rating: v.Ratings[i], rank: v.Ranks[i]})
+			}
+			computeRatingDeltas(parts)
+
+			deltas := make([]int, 0, len(parts))
+			sum := 0
+			for _, p := range parts {
+				deltas = append(deltas, p.delta)
+				sum += p.delta
+			}
+			if !slices.Equal(deltas, v.Deltas) {
+				t.Fatalf("Invalid rating deltas, expected %v, got %v", v.Deltas, deltas)
+			}
+			// The changes should add up to about zero, so ratings don't inflate over time
+			if sum > 0 || sum < -len(parts)*2 {
+				t.Fatalf("Rating deltas add up to %d", sum)
+			}
+		})
+	}
+}
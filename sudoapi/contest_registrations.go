@@ -8,7 +8,9 @@ import (
 	"github.com/KiloProjects/kilonova"
 )
 
-func (s *BaseAPI) RegisterContestUser(ctx context.Context, contest *kilonova.Contest, userID int, invitationID *string, force bool) *StatusError {
+// RegisterContestUser registers the user in the contest, optionally under one of the contest's participant categories.
+// If the registration is done through an invitation that assigns a category, the invitation's category is used instead.
+func (s *BaseAPI) RegisterContestUser(ctx context.Context, contest *kilonova.Contest, userID int, invitationID *string, category *string, force bool) *StatusError {
 	_, err := s.ContestRegistration(ctx, contest.ID, userID)
 	if err != nil && !errors.Is(err, ErrNotFound) {
 		return WrapError(err, "User already registered")
@@ -18,7 +20,27 @@ func (s *BaseAPI) RegisterContestUser(ctx context.Context, contest *kilonova.Con
 		return Statusf(400, "Regular joining is disallowed")
 	}
 
-	if err := s.db.InsertContestRegistration(ctx, contest.ID, userID, invitationID); err != nil {
+	if invitationID != nil {
+		inv, err := s.ContestInvitation(ctx, *invitationID)
+		if err != nil {
+			return err
+		}
+		if inv.TeamID != nil {
+			return s.registerTeamMember(ctx, contest, userID, inv)
+		}
+		if inv.Category != nil {
+			category = inv.Category
+		}
+	}
+	if category != nil && !contest.HasCategory(*category) {
+		return Statusf(400, "Invalid participant category")
+	}
+	if contest.TeamMode() && !(force && invitationID == nil) {
+		// Only admins may (forcefully) register individual users in team contests
+		return Statusf(400, "This is a team contest. Create a team or join one using its invitation")
+	}
+
+	if err := s.db.InsertContestRegistration(ctx, contest.ID, userID, invitationID, category); err != nil {
 		return WrapError(err, "Couldn't register user for contest")
 	}
 	return nil
@@ -31,7 +53,7 @@ func (s *BaseAPI) StartContestRegistration(ctx context.Context, contest *kilonov
 
 	reg, err := s.ContestRegistration(ctx, contest.ID, userID)
 	if err != nil && !errors.Is(err, ErrNotFound) {
-		if err := s.RegisterContestUser(ctx, contest, userID, nil, false); err != nil {
+		if err := s.RegisterContestUser(ctx, contest, userID, nil, nil, false); err != nil {
 			return err
 		}
 	}
@@ -76,6 +98,20 @@ func (s *BaseAPI) ContestRegistration(ctx context.Context, contestID, userID int
 	return reg, nil
 }
 
+// UpdateContestRegistrationCategory changes the participant category of a registration. A nil category removes it
+func (s *BaseAPI) UpdateContestRegistrationCategory(ctx context.Context, contest *kilonova.Contest, userID int, category *string) *StatusError {
+	if category != nil && !contest.HasCategory(*category) {
+		return Statusf(400, "Invalid participant category")
+	}
+	if _, err := s.ContestRegistration(ctx, contest.ID, userID); err != nil {
+		return err
+	}
+	if err := s.db.UpdateContestRegistrationCategory(ctx, contest.ID, userID, category); err != nil {
+		return WrapError(err, "Couldn't update registration category")
+	}
+	return nil
+}
+
 func (s *BaseAPI) KickUserFromContest(ctx context.Context, contestID, userID int) *StatusError {
 	if err := s.db.DeleteContestRegistration(ctx, contestID, userID); err != nil {
 		return WrapError(err, "Couldn't kick contestant")
@@ -86,12 +122,15 @@ func (s *BaseAPI) KickUserFromContest(ctx context.Context, contestID, userID int
 	return nil
 }
 
-func (s *BaseAPI) CreateContestInvitation(ctx context.Context, contestID int, author *kilonova.UserBrief, maxUses *int) (string, *StatusError) {
+func (s *BaseAPI) CreateContestInvitation(ctx context.Context, contest *kilonova.Contest, author *kilonova.UserBrief, maxUses *int, category *string) (string, *StatusError) {
+	if category != nil && !contest.HasCategory(*category) {
+		return "", Statusf(400, "Invalid participant category")
+	}
 	var id *int
 	if author != nil {
 		id = &author.ID
 	}
-	invID, err := s.db.CreateContestInvitation(ctx, contestID, id, maxUses)
+	invID, err := s.db.CreateContestInvitation(ctx, contest.ID, id, maxUses, category)
 	if err != nil {
 		return "", WrapError(err, "Couldn't create invitation")
 	}
@@ -0,0 +1,168 @@
+package sudoapi
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"slices"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+)
+
+type resolverCell struct {
+	solved   bool
+	attempts int
+	time     float64
+}
+
+func leaderboardCell(entry *kilonova.LeaderboardEntry, problemID int) resolverCell {
+	score, ok := entry.ProblemScores[problemID]
+	cell := resolverCell{
+		solved:   ok && score.GreaterThanOrEqual(decimal.NewFromInt(100)),
+		attempts: entry.ProblemAttempts[problemID],
+	}
+	if cell.solved {
+		cell.time = entry.ProblemTimes[problemID]
+	}
+	return cell
+}
+
+type resolverEntry struct {
+	entry *kilonova.LeaderboardEntry
+
+	cells map[int]resolverCell
+	final map[int]resolverCell
+
+	numSolved int
+	penalty   int
+	lastSolve float64
+}
+
+func (e *resolverEntry) key() int {
+	if e.entry.Team != nil {
+		return e.entry.Team.ID
+	}
+	return e.entry.User.ID
+}
+
+// recompute updates the totals from the current cells, the same way contest_icpc_view does
+func (e *resolverEntry) recompute(penaltyTime int) {
+	e.numSolved, e.penalty, e.lastSolve = 0, 0, -1
+	for _, cell := range e.cells {
+		if !cell.solved {
+			continue
+		}
+		e.numSolved++
+		e.penalty += int(math.Floor(cell.time)) + cell.attempts*penaltyTime
+		e.lastSolve = max(e.lastSolve, cell.time)
+	}
+}
+
+func compareResolverEntries(a, b *resolverEntry) int {
+	if c := cmp.Compare(b.numSolved, a.numSolved); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.penalty, b.penalty); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.lastSolve, b.lastSolve); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.key(), b.key())
+}
+
+// ContestResolver computes the reveal sequence of an ended, frozen ICPC contest.
+// Like in the ICPC Resolver, the pending cells are revealed bottom-up: the lowest ranked entry with pending cells
+// gets its leftmost pending problem revealed, after which the standings are updated and the process repeats.
+func (s *BaseAPI) ContestResolver(ctx context.Context, contest *kilonova.Contest) (*kilonova.ContestResolver, *StatusError) {
+	if contest.LeaderboardStyle != kilonova.LeaderboardTypeICPC {
+		return nil, Statusf(400, "The resolver is only available for ICPC contests")
+	}
+	if contest.LeaderboardFreeze == nil || !contest.LeaderboardFreeze.Before(contest.EndTime) {
+		return nil, Statusf(400, "The contest leaderboard was never frozen")
+	}
+	if !contest.Ended() {
+		return nil, Statusf(400, "The contest must end before resolving the leaderboard")
+	}
+
+	frozen, err := s.ContestLeaderboard(ctx, contest, contest.LeaderboardFreeze, kilonova.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+	final, err := s.ContestLeaderboard(ctx, contest, nil, kilonova.UserFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	finalEntries := make(map[int]*kilonova.LeaderboardEntry)
+	for _, entry := range final.Entries {
+		re := &resolverEntry{entry: entry}
+		finalEntries[re.key()] = entry
+	}
+
+	standings := make([]*resolverEntry, 0, len(frozen.Entries))
+	for _, entry := range frozen.Entries {
+		re := &resolverEntry{entry: entry, cells: make(map[int]resolverCell), final: make(map[int]resolverCell)}
+		finalEntry, ok := finalEntries[re.key()]
+		for _, pb := range frozen.ProblemOrder {
+			re.cells[pb] = leaderboardCell(entry, pb)
+			if ok {
+				re.final[pb] = leaderboardCell(finalEntry, pb)
+			} else {
+				re.final[pb] = re.cells[pb]
+			}
+		}
+		re.recompute(contest.ICPCSubmissionPenalty)
+		standings = append(standings, re)
+	}
+	slices.SortStableFunc(standings, compareResolverEntries)
+
+	frozen.Entries = make([]*kilonova.LeaderboardEntry, 0, len(standings))
+	for _, re := range standings {
+		frozen.Entries = append(frozen.Entries, re.entry)
+	}
+
+	steps := []*kilonova.ResolverStep{}
+	for {
+		pos, problemID := -1, -1
+		for i := len(standings) - 1; i >= 0 && pos < 0; i-- {
+			for _, pb := range frozen.ProblemOrder {
+				if standings[i].cells[pb] != standings[i].final[pb] {
+					pos, problemID = i, pb
+					break
+				}
+			}
+		}
+		if pos < 0 {
+			break
+		}
+
+		re := standings[pos]
+		re.cells[problemID] = re.final[problemID]
+		re.recompute(contest.ICPCSubmissionPenalty)
+		slices.SortStableFunc(standings, compareResolverEntries)
+
+		step := &kilonova.ResolverStep{
+			ProblemID: problemID,
+			Solved:    re.cells[problemID].solved,
+			Attempts:  re.cells[problemID].attempts,
+			Time:      re.cells[problemID].time,
+
+			PrevRank: pos + 1,
+			NewRank:  slices.Index(standings, re) + 1,
+
+			NumSolved: re.numSolved,
+			Penalty:   re.penalty,
+		}
+		if re.entry.Team != nil {
+			step.TeamID = &re.entry.Team.ID
+		} else {
+			step.UserID = &re.entry.User.ID
+		}
+		steps = append(steps, step)
+	}
+
+	frozen.FreezeTime = contest.LeaderboardFreeze
+	return &kilonova.ContestResolver{Frozen: frozen, Steps: steps}, nil
+}
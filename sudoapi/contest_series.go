@@ -0,0 +1,240 @@
+package sudoapi
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+func (s *BaseAPI) CreateContestSeries(ctx context.Context, name string, author *kilonova.UserBrief) (int, *StatusError) {
+	if !author.IsProposer() {
+		return -1, Statusf(403, "Only proposers can create contest series")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return -1, Statusf(400, "Contest series name can't be empty")
+	}
+	id, err := s.db.CreateContestSeries(ctx, name, author.ID)
+	if err != nil {
+		zap.S().Warn(err)
+		return -1, WrapError(err, "Couldn't create contest series")
+	}
+	return id, nil
+}
+
+func (s *BaseAPI) ContestSeries(ctx context.Context, id int) (*kilonova.ContestSeries, *StatusError) {
+	series, err := s.db.ContestSeries(ctx, id)
+	if err != nil || series == nil {
+		return nil, WrapError(ErrNotFound, "Contest series not found")
+	}
+	return series, nil
+}
+
+func (s *BaseAPI) ContestSeriesList(ctx context.Context, filter kilonova.ContestSeriesFilter) ([]*kilonova.ContestSeries, *StatusError) {
+	series, err := s.db.ContestSeriesList(ctx, filter)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't fetch contest series")
+	}
+	return series, nil
+}
+
+func (s *BaseAPI) UpdateContestSeries(ctx context.Context, id int, upd kilonova.ContestSeriesUpdate) *StatusError {
+	if upd.Name != nil {
+		*upd.Name = strings.TrimSpace(*upd.Name)
+		if *upd.Name == "" {
+			return Statusf(400, "Contest series name can't be empty")
+		}
+	}
+	if upd.Aggregation != kilonova.SeriesAggregationNone && !upd.Aggregation.Valid() {
+		return Statusf(400, "Invalid aggregation rule")
+	}
+	if upd.BestK != nil && *upd.BestK < 0 {
+		return Statusf(400, "The number of counted rounds can't be negative")
+	}
+	if err := s.db.UpdateContestSeries(ctx, id, upd); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update contest series")
+	}
+	return nil
+}
+
+// UpdateSeriesRounds replaces the rounds of the series. Only contests the user can edit can be added as rounds
+func (s *BaseAPI) UpdateSeriesRounds(ctx context.Context, series *kilonova.ContestSeries, rounds []*kilonova.SeriesRound, user *kilonova.UserBrief) *StatusError {
+	seen := make(map[int]bool)
+	for _, round := range rounds {
+		if round == nil {
+			return Statusf(400, "Invalid round")
+		}
+		if seen[round.ContestID] {
+			return Statusf(400, "Contest #%d appears multiple times in the series", round.ContestID)
+		}
+		seen[round.ContestID] = true
+		if round.Weight.IsNegative() {
+			return Statusf(400, "Round weights can't be negative")
+		}
+		if slices.Contains(series.ContestIDs(), round.ContestID) {
+			continue
+		}
+		contest, err := s.Contest(ctx, round.ContestID)
+		if err != nil {
+			return err
+		}
+		if !s.IsContestEditor(user, contest) {
+			return Statusf(403, "You can only add contests you edit to the series")
+		}
+	}
+	if err := s.db.UpdateSeriesRounds(ctx, series.ID, rounds); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update series rounds")
+	}
+	return nil
+}
+
+func (s *BaseAPI) DeleteContestSeries(ctx context.Context, series *kilonova.ContestSeries) *StatusError {
+	if err := s.db.DeleteContestSeries(ctx, series.ID); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't delete contest series")
+	}
+	s.LogUserAction(ctx, "Removed contest series", slog.Any("series", series))
+	return nil
+}
+
+func (s *BaseAPI) IsSeriesEditor(user *kilonova.UserBrief, series *kilonova.ContestSeries) bool {
+	if !user.IsAuthed() || series == nil {
+		return false
+	}
+	return user.IsAdmin() || series.AuthorID == user.ID
+}
+
+func (s *BaseAPI) IsSeriesVisible(user *kilonova.UserBrief, series *kilonova.ContestSeries) bool {
+	if series == nil {
+		return false
+	}
+	return series.Visible || s.IsSeriesEditor(user, series)
+}
+
+// SeriesLeaderboard combines the leaderboards of the series' rounds, using the series' aggregation rule.
+// Rounds whose leaderboard the user can't see are left out of the standings, and frozen rounds use the frozen scores.
+// The names of the rounds the user can't see are hidden as well.
+// Regardless of their leaderboard style, the rounds are compared by the participants' total score on the classic leaderboard
+// (ICPC leaderboards don't have one, since they rank by solved problems and penalty). In team rounds, every member gets the team's score
+func (s *BaseAPI) SeriesLeaderboard(ctx context.Context, series *kilonova.ContestSeries, lookingUser *kilonova.UserBrief) (*kilonova.SeriesLeaderboard, *StatusError) {
+	leaderboard := &kilonova.SeriesLeaderboard{
+		Aggregation: series.Aggregation,
+		BestK:       series.BestK,
+		Rounds:      make([]*kilonova.SeriesLeaderboardRound, 0, len(series.Rounds)),
+		Entries:     []*kilonova.SeriesLeaderboardEntry{},
+	}
+
+	entries := make(map[int]*kilonova.SeriesLeaderboardEntry)
+	for _, round := range series.Rounds {
+		contest, err := s.Contest(ctx, round.ContestID)
+		if err != nil {
+			return nil, err
+		}
+		ldRound := &kilonova.SeriesLeaderboardRound{
+			ContestID: contest.ID,
+			Weight:    round.Weight,
+			Available: s.CanViewContestLeaderboard(lookingUser, contest),
+		}
+		if s.IsContestVisible(lookingUser, contest) {
+			ldRound.Name = contest.Name
+		}
+		leaderboard.Rounds = append(leaderboard.Rounds, ldRound)
+		if !ldRound.Available {
+			continue
+		}
+
+		classicRound := *contest
+		classicRound.LeaderboardStyle = kilonova.LeaderboardTypeClassic
+		roundLeaderboard, err := s.ContestLeaderboard(ctx, &classicRound, s.UserContestFreezeTime(lookingUser, contest, false), kilonova.UserFilter{})
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range roundLeaderboard.Entries {
+			var users []*kilonova.UserBrief
+			if entry.Team != nil {
+				users = entry.Team.Members
+			} else if entry.User != nil {
+				users = []*kilonova.UserBrief{entry.User}
+			}
+			for _, user := range users {
+				seriesEntry, ok := entries[user.ID]
+				if !ok {
+					seriesEntry = &kilonova.SeriesLeaderboardEntry{
+						User:        user,
+						RoundScores: make(map[int]decimal.Decimal),
+						Counted:     make(map[int]bool),
+					}
+					entries[user.ID] = seriesEntry
+					leaderboard.Entries = append(leaderboard.Entries, seriesEntry)
+				}
+				seriesEntry.RoundScores[contest.ID] = entry.TotalScore
+			}
+		}
+	}
+
+	for _, entry := range leaderboard.Entries {
+		aggregateSeriesEntry(series, entry)
+	}
+
+	slices.SortStableFunc(leaderboard.Entries, func(a, b *kilonova.SeriesLeaderboardEntry) int {
+		if c := b.Total.Cmp(a.Total); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.User.Name, b.User.Name)
+	})
+	for i, entry := range leaderboard.Entries {
+		entry.Rank = i + 1
+		if i > 0 && entry.Total.Equal(leaderboard.Entries[i-1].Total) {
+			entry.Rank = leaderboard.Entries[i-1].Rank
+		}
+	}
+
+	return leaderboard, nil
+}
+
+func aggregateSeriesEntry(series *kilonova.ContestSeries, entry *kilonova.SeriesLeaderboardEntry) {
+	entry.Total = decimal.Zero
+	switch series.Aggregation {
+	case kilonova.SeriesAggregationBestK:
+		contestIDs := make([]int, 0, len(entry.RoundScores))
+		for id := range entry.RoundScores {
+			contestIDs = append(contestIDs, id)
+		}
+		slices.SortFunc(contestIDs, func(a, b int) int {
+			if c := entry.RoundScores[b].Cmp(entry.RoundScores[a]); c != 0 {
+				return c
+			}
+			return cmp.Compare(a, b)
+		})
+		// A BestK of 0 counts all rounds
+		if series.BestK > 0 && len(contestIDs) > series.BestK {
+			contestIDs = contestIDs[:series.BestK]
+		}
+		for _, id := range contestIDs {
+			entry.Counted[id] = true
+			entry.Total = entry.Total.Add(entry.RoundScores[id])
+		}
+	case kilonova.SeriesAggregationWeighted:
+		for _, round := range series.Rounds {
+			score, ok := entry.RoundScores[round.ContestID]
+			if !ok {
+				continue
+			}
+			entry.Counted[round.ContestID] = true
+			entry.Total = entry.Total.Add(score.Mul(round.Weight))
+		}
+	default:
+		for id, score := range entry.RoundScores {
+			entry.Counted[id] = true
+			entry.Total = entry.Total.Add(score)
+		}
+	}
+}
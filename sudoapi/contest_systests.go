@@ -0,0 +1,84 @@
+package sudoapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+// StartSystemTest queues the contest's submissions accepted on pretests for evaluation on all tests
+func (s *BaseAPI) StartSystemTest(ctx context.Context, contest *kilonova.Contest) *StatusError {
+	if contest == nil || !contest.Pretests {
+		return Statusf(400, "Contest does not use pretests")
+	}
+	if !contest.Ended() {
+		return Statusf(400, "System testing can only start after the contest ended")
+	}
+	cnt, err := s.db.StartSystemTest(ctx, contest)
+	if err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't start system testing")
+	}
+	if cnt < 0 {
+		return Statusf(400, "System testing was already started")
+	}
+	s.LogToDiscord(ctx, "Started contest system testing", slog.Any("contest", contest), slog.Int("submission_count", cnt))
+	s.WakeGrader()
+	return nil
+}
+
+func (s *BaseAPI) systemTestContests(ctx context.Context) {
+	contests, err := s.db.ContestsAwaitingSystemTest(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't get contests awaiting system testing: ", err)
+		}
+		return
+	}
+	for _, contest := range contests {
+		if err := s.StartSystemTest(ctx, contest); err != nil {
+			zap.S().Warn("Couldn't start system testing: ", err)
+		}
+	}
+
+	ids, err := s.db.FinishSystemTests(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't finish system testing: ", err)
+		}
+		return
+	}
+	for _, id := range ids {
+		s.LogToDiscord(ctx, "Finished contest system testing", slog.Int("contest_id", id))
+	}
+}
+
+func (s *BaseAPI) systemTestJob(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return nil
+		case <-t.C:
+			s.systemTestContests(ctx)
+		}
+	}
+}
+
+// ContestPretestScores returns the leaderboard scores snapshotted when the contest's system testing started
+func (s *BaseAPI) ContestPretestScores(ctx context.Context, contestID int) ([]*kilonova.PretestScore, *StatusError) {
+	scores, err := s.db.ContestPretestScores(ctx, contestID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get pretest scores")
+	}
+	return scores, nil
+}
@@ -0,0 +1,112 @@
+package sudoapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/KiloProjects/kilonova"
+)
+
+const maxTeamNameLength = 64
+
+// CreateContestTeam creates a new team in the contest, registers the creator as its first member
+// and creates the invitation through which the others can join.
+func (s *BaseAPI) CreateContestTeam(ctx context.Context, contest *kilonova.Contest, creator *kilonova.UserBrief, name string) (*kilonova.ContestTeam, *StatusError) {
+	if !creator.IsAuthed() {
+		return nil, Statusf(400, "Invalid team creator")
+	}
+	if !contest.TeamMode() {
+		return nil, Statusf(400, "Contest is not a team contest")
+	}
+	if !s.CanJoinContest(contest) {
+		return nil, Statusf(400, "Regular joining is disallowed")
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTeamNameLength {
+		return nil, Statusf(400, "Team name must have between 1 and %d characters", maxTeamNameLength)
+	}
+	if _, err := s.ContestRegistration(ctx, contest.ID, creator.ID); !errors.Is(err, ErrNotFound) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, Statusf(400, "You are already registered in this contest")
+	}
+
+	teamID, err := s.db.CreateContestTeam(ctx, contest.ID, name, &creator.ID)
+	if err != nil {
+		return nil, Statusf(400, "Couldn't create team. Make sure the name is not already taken")
+	}
+	if err := s.db.InsertTeamRegistration(ctx, contest.ID, creator.ID, teamID, nil); err != nil {
+		if err := s.db.DeleteContestTeam(ctx, teamID); err != nil {
+			return nil, WrapError(err, "Couldn't clean up team")
+		}
+		return nil, WrapError(err, "Couldn't register team creator")
+	}
+	maxUses := contest.TeamSize - 1
+	if _, err := s.db.CreateTeamInvitation(ctx, contest.ID, teamID, &creator.ID, &maxUses); err != nil {
+		return nil, WrapError(err, "Couldn't create team invitation")
+	}
+
+	return s.ContestTeam(ctx, teamID)
+}
+
+func (s *BaseAPI) ContestTeam(ctx context.Context, id int) (*kilonova.ContestTeam, *StatusError) {
+	team, err := s.db.ContestTeam(ctx, id)
+	if err != nil || team == nil {
+		return nil, WrapError(ErrNotFound, "Team not found")
+	}
+	return team, nil
+}
+
+func (s *BaseAPI) ContestTeams(ctx context.Context, contestID int) ([]*kilonova.ContestTeam, *StatusError) {
+	teams, err := s.db.ContestTeams(ctx, contestID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get teams")
+	}
+	return teams, nil
+}
+
+// UserContestTeam returns the team the user is registered with in the contest
+func (s *BaseAPI) UserContestTeam(ctx context.Context, contestID, userID int) (*kilonova.ContestTeam, *StatusError) {
+	reg, err := s.ContestRegistration(ctx, contestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if reg.TeamID == nil {
+		return nil, WrapError(ErrNotFound, "User is not in a team")
+	}
+	return s.ContestTeam(ctx, *reg.TeamID)
+}
+
+func (s *BaseAPI) TeamInvitations(ctx context.Context, teamID int) ([]*kilonova.ContestInvitation, *StatusError) {
+	invitations, err := s.db.TeamInvitations(ctx, teamID)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get team invitations")
+	}
+	return invitations, nil
+}
+
+// registerTeamMember registers the user in the contest as a member of the team the invitation is bound to
+func (s *BaseAPI) registerTeamMember(ctx context.Context, contest *kilonova.Contest, userID int, inv *kilonova.ContestInvitation) *StatusError {
+	joined, err := s.db.JoinContestTeam(ctx, contest.ID, userID, *inv.TeamID, &inv.ID, contest.TeamSize)
+	if err != nil {
+		return WrapError(err, "Couldn't register user for contest")
+	}
+	if !joined {
+		return Statusf(400, "Team is full")
+	}
+	return nil
+}
+
+// sameContestTeam returns whether the user is in the team the submission was sent for
+func (s *BaseAPI) sameContestTeam(ctx context.Context, sub *kilonova.Submission, user *kilonova.UserBrief) bool {
+	if sub.TeamID == nil || sub.ContestID == nil || !user.IsAuthed() {
+		return false
+	}
+	reg, err := s.db.ContestRegistration(ctx, *sub.ContestID, user.ID)
+	if err != nil || reg == nil || reg.TeamID == nil {
+		return false
+	}
+	return *reg.TeamID == *sub.TeamID
+}
@@ -0,0 +1,125 @@
+package sudoapi
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+)
+
+// VirtualContestDuration returns the length of a virtual run of the contest.
+// USACO-style contests keep their per-user time, the others last as long as the original contest.
+func VirtualContestDuration(contest *kilonova.Contest) time.Duration {
+	if contest.PerUserTime > 0 {
+		return time.Duration(contest.PerUserTime) * time.Second
+	}
+	return contest.EndTime.Sub(contest.StartTime)
+}
+
+// CanStartVirtualParticipation returns whether the user can start a virtual run of the contest.
+// Only visible, individual contests that ended can be virtually participated in, and only by users that weren't involved in the original one.
+func (s *BaseAPI) CanStartVirtualParticipation(ctx context.Context, user *kilonova.UserBrief, contest *kilonova.Contest) bool {
+	if !user.IsAuthed() || contest == nil {
+		return false
+	}
+	if !contest.Ended() || !contest.Visible || contest.TeamMode() || s.IsContestTester(user, contest) {
+		return false
+	}
+	if contest.LeaderboardStyle != kilonova.LeaderboardTypeClassic && contest.LeaderboardStyle != kilonova.LeaderboardTypeICPC {
+		return false
+	}
+	_, err := s.ContestRegistration(ctx, contest.ID, user.ID)
+	return errors.Is(err, ErrNotFound)
+}
+
+// StartVirtualParticipation starts the user's personal run of the ended contest. Their clock starts now.
+func (s *BaseAPI) StartVirtualParticipation(ctx context.Context, contest *kilonova.Contest, user *kilonova.UserBrief) *StatusError {
+	if !user.IsAuthed() || contest == nil {
+		return Statusf(400, "Invalid virtual participation parameters")
+	}
+	if !contest.Ended() {
+		return Statusf(400, "Virtual participation is only available once the contest ended")
+	}
+	if !s.CanStartVirtualParticipation(ctx, user, contest) {
+		return Statusf(400, "You can't start a virtual participation in this contest. Note that only one participation per contest is allowed")
+	}
+
+	startTime := time.Now()
+	endTime := startTime.Add(VirtualContestDuration(contest))
+	if err := s.db.InsertVirtualRegistration(ctx, contest.ID, user.ID, startTime, endTime); err != nil {
+		return WrapError(err, "Couldn't start virtual participation")
+	}
+	return nil
+}
+
+// VirtualContestLeaderboard returns the ghost leaderboard of the contest: virtual participants are merged with the
+// original participants at equivalent elapsed time. For a virtual participant that is still running, the elapsed time is
+// the time since the start of their run, otherwise it's the whole contest.
+func (s *BaseAPI) VirtualContestLeaderboard(ctx context.Context, contest *kilonova.Contest, lookingUser *kilonova.UserBrief, filter kilonova.UserFilter) (*kilonova.ContestLeaderboard, *StatusError) {
+	if !contest.Ended() {
+		return nil, Statusf(400, "Virtual leaderboards are only available once the contest ended")
+	}
+	elapsed := VirtualContestDuration(contest)
+	if lookingUser != nil {
+		reg, err := s.ContestRegistration(ctx, contest.ID, lookingUser.ID)
+		if err == nil && reg.VirtualRunning() {
+			elapsed = time.Since(*reg.IndividualStartTime)
+		}
+	}
+	if freeze := s.UserContestFreezeTime(lookingUser, contest, false); freeze != nil && freeze.Sub(contest.StartTime) < elapsed {
+		elapsed = freeze.Sub(contest.StartTime)
+	}
+
+	officialCutoff := contest.StartTime.Add(elapsed)
+	leaderboard, err := s.ContestLeaderboard(ctx, contest, &officialCutoff, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	regs, err1 := s.db.VirtualRegistrations(ctx, contest.ID)
+	if err1 != nil {
+		return nil, WrapError(err1, "Couldn't get virtual participants")
+	}
+	for _, reg := range regs {
+		if reg.IndividualStartTime.After(time.Now()) {
+			continue
+		}
+		entry, err := s.db.ContestVirtualEntry(ctx, contest, reg, elapsed, &filter)
+		if err != nil {
+			return nil, WrapError(err, "Couldn't generate virtual leaderboard entry")
+		}
+		if entry != nil {
+			leaderboard.Entries = append(leaderboard.Entries, entry)
+		}
+	}
+
+	slices.SortStableFunc(leaderboard.Entries, func(a, b *kilonova.LeaderboardEntry) int {
+		if leaderboard.Type == kilonova.LeaderboardTypeICPC {
+			if c := cmp.Compare(b.NumSolved, a.NumSolved); c != 0 {
+				return c
+			}
+			if c := cmp.Compare(a.Penalty, b.Penalty); c != 0 {
+				return c
+			}
+		} else if c := b.TotalScore.Cmp(a.TotalScore); c != 0 {
+			return c
+		}
+		switch {
+		case a.LastTime == nil && b.LastTime == nil:
+			return 0
+		case a.LastTime == nil:
+			return 1
+		case b.LastTime == nil:
+			return -1
+		}
+		return a.LastTime.Compare(*b.LastTime)
+	})
+
+	leaderboard.FreezeTime = nil
+	leaderboard.Virtual = true
+	leaderboard.Elapsed = int(elapsed.Seconds())
+	return leaderboard, nil
+}
@@ -0,0 +1,340 @@
+package sudoapi
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	LiveEventsEnabled = config.GenFlag[bool]("feature.live_events.enabled", true, "Stream live leaderboard, submission and contest communication updates over Server-Sent Events")
+	MaxUserStreams    = config.GenFlag[int]("feature.live_events.max_user_streams", 8, "Maximum number of concurrent event streams a user may have open")
+	MaxIPStreams      = config.GenFlag[int]("feature.live_events.max_anonymous_ip_streams", 8, "Maximum number of concurrent event streams anonymous users may have open from the same IP address")
+	MaxEventStreams   = config.GenFlag[int]("feature.live_events.max_streams", 2000, "Maximum number of concurrent event streams on the whole platform")
+)
+
+// leaderboardBatchInterval is how often the leaderboard updates are sent.
+// Updates of the same contestant within the interval are merged, since busy contests finish many submissions per second
+const leaderboardBatchInterval = 2 * time.Second
+
+type EventType string
+
+const (
+	// EventSubmission is sent to the author of a submission whenever its status or score changes
+	EventSubmission EventType = "submission"
+	// EventSubTest is sent to the author of a submission whenever one of its tests is evaluated
+	EventSubTest EventType = "subtest"
+	// EventLeaderboard contains the updated leaderboard entry of a contestant, once one of their submissions finished evaluating
+	EventLeaderboard EventType = "leaderboard"
+	// EventAnnouncement is sent to everyone watching a contest whenever a new announcement is made
+	EventAnnouncement EventType = "announcement"
+	// EventQuestion is sent to the author of a contest question once it's answered,
+	// and to the contest's editors whenever a question is asked, claimed or answered
+	EventQuestion EventType = "question"
+)
+
+// Event is a live update, already filtered for the user of the subscription it was sent to
+type Event struct {
+	Type EventType `json:"type"`
+	Data any       `json:"data"`
+}
+
+// liveEvent is the raw update, as published by the rest of the API.
+// It is expanded by the dispatcher into an Event for every subscription that may see it
+type liveEvent struct {
+	Type EventType
+
+	SubmissionID int
+	SubTestID    int
+
+	Announcement *kilonova.ContestAnnouncement
+	QuestionID   int
+}
+
+// EventSubscription is an open event stream of an user (which may be anonymous), optionally also watching a contest.
+// Only the contest's ID is kept, since the contest's settings (and editors) may change while the stream is open
+type EventSubscription struct {
+	user      *kilonova.UserBrief
+	ip        *netip.Addr
+	contestID int
+
+	ch chan *Event
+}
+
+// Events returns the channel on which the subscription's events arrive.
+// Events are dropped if the subscriber doesn't keep up
+func (sub *EventSubscription) Events() <-chan *Event {
+	return sub.ch
+}
+
+func (sub *EventSubscription) send(ev *Event) {
+	select {
+	case sub.ch <- ev:
+	default:
+	}
+}
+
+func (sub *EventSubscription) userID() int {
+	if sub.user == nil {
+		return -1
+	}
+	return sub.user.ID
+}
+
+type eventHub struct {
+	mu   sync.RWMutex
+	subs map[*EventSubscription]struct{}
+}
+
+func (h *eventHub) empty() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs) == 0
+}
+
+func (h *eventHub) subscriptions() []*EventSubscription {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	subs := make([]*EventSubscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// SubscribeEvents opens a new event stream for the user. If contest is not nil, the contest's events are also streamed.
+// Anonymous streams are limited per IP address. The subscription must be closed using UnsubscribeEvents
+func (s *BaseAPI) SubscribeEvents(user *kilonova.UserBrief, contest *kilonova.Contest, ip *netip.Addr) (*EventSubscription, *StatusError) {
+	if !LiveEventsEnabled.Value() {
+		return nil, kilonova.ErrFeatureDisabled
+	}
+	if contest != nil && !s.IsContestVisible(user, contest) {
+		return nil, Statusf(403, "Contest is not visible")
+	}
+	if user == nil && ip == nil {
+		return nil, Statusf(400, "Couldn't determine IP address")
+	}
+
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	if MaxEventStreams.Value() > 0 && len(s.events.subs) >= MaxEventStreams.Value() {
+		return nil, Statusf(503, "Too many open event streams, try again later")
+	}
+	var cnt int
+	for sub := range s.events.subs {
+		if user != nil && sub.userID() == user.ID {
+			cnt++
+		} else if user == nil && sub.user == nil && *sub.ip == *ip {
+			cnt++
+		}
+	}
+	if user != nil && cnt >= MaxUserStreams.Value() {
+		return nil, Statusf(429, "Too many open event streams")
+	}
+	if user == nil && cnt >= MaxIPStreams.Value() {
+		return nil, Statusf(429, "Too many open event streams")
+	}
+
+	sub := &EventSubscription{user: user, ip: ip, ch: make(chan *Event, 20)}
+	if contest != nil {
+		sub.contestID = contest.ID
+	}
+	s.events.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (s *BaseAPI) UnsubscribeEvents(sub *EventSubscription) {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	delete(s.events.subs, sub)
+}
+
+// publishEvent queues the event for dispatching. It never blocks, so it's safe to call from the grader
+func (s *BaseAPI) publishEvent(ev *liveEvent) {
+	if s.events.empty() {
+		return
+	}
+	select {
+	case s.eventChan <- ev:
+	default:
+		zap.S().Debug("Event queue full, dropping live event")
+	}
+}
+
+func (s *BaseAPI) dispatchEvents(ctx context.Context) error {
+	// Pending leaderboard updates, by contest and user. The earliest submission time is kept,
+	// to know whether the update is visible in frozen standings
+	pending := make(map[int]map[int]time.Time)
+	flush := time.NewTicker(leaderboardBatchInterval)
+	defer flush.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return nil
+		case ev := <-s.eventChan:
+			if ev.Type == EventLeaderboard {
+				s.queueLeaderboardEvent(ctx, ev, pending)
+				continue
+			}
+			s.dispatchEvent(ctx, ev)
+		case <-flush.C:
+			for contestID, users := range pending {
+				s.dispatchLeaderboardEvent(ctx, contestID, users)
+			}
+			clear(pending)
+		}
+	}
+}
+
+func (s *BaseAPI) queueLeaderboardEvent(ctx context.Context, ev *liveEvent, pending map[int]map[int]time.Time) {
+	sub, err := s.db.Submission(ctx, ev.SubmissionID)
+	if err != nil || sub == nil || sub.ContestID == nil {
+		return
+	}
+	users, ok := pending[*sub.ContestID]
+	if !ok {
+		users = make(map[int]time.Time)
+		pending[*sub.ContestID] = users
+	}
+	if t, ok := users[sub.UserID]; !ok || sub.CreatedAt.Before(t) {
+		users[sub.UserID] = sub.CreatedAt
+	}
+}
+
+func (s *BaseAPI) dispatchEvent(ctx context.Context, ev *liveEvent) {
+	subs := s.events.subscriptions()
+	if len(subs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	switch ev.Type {
+	case EventSubmission, EventSubTest:
+		s.dispatchSubmissionEvent(ctx, ev, subs)
+	case EventAnnouncement:
+		for _, sub := range subs {
+			if sub.contestID == ev.Announcement.ContestID {
+				sub.send(&Event{Type: EventAnnouncement, Data: ev.Announcement})
+			}
+		}
+	case EventQuestion:
+		question, err := s.db.ContestQuestion(ctx, ev.QuestionID)
+		if err != nil || question == nil {
+			return
+		}
+		contest, err1 := s.Contest(ctx, question.ContestID)
+		if err1 != nil {
+			return
+		}
+		for _, sub := range subs {
+			if sub.userID() == question.AuthorID || (sub.contestID == contest.ID && s.IsContestEditor(sub.user, contest)) {
+				sub.send(&Event{Type: EventQuestion, Data: question})
+			}
+		}
+	default:
+		zap.S().Warnf("Unknown live event type %q", ev.Type)
+	}
+}
+
+// dispatchSubmissionEvent streams progress only to the author of the submission.
+// Subtests are not sent for submissions whose feedback is limited for the author
+func (s *BaseAPI) dispatchSubmissionEvent(ctx context.Context, ev *liveEvent, subs []*EventSubscription) {
+	var subtest *kilonova.SubTest
+	if ev.Type == EventSubTest {
+		stest, err := s.db.SubTest(ctx, ev.SubTestID)
+		if err != nil || stest == nil {
+			return
+		}
+		subtest = stest
+		ev.SubmissionID = stest.SubmissionID
+	}
+	rawSub, err := s.db.Submission(ctx, ev.SubmissionID)
+	if err != nil || rawSub == nil {
+		return
+	}
+
+	for _, esub := range subs {
+		if esub.user == nil || esub.user.ID != rawSub.UserID {
+			continue
+		}
+		sub, err := s.db.SubmissionLookingUser(ctx, rawSub.ID, esub.user)
+		if err != nil || sub == nil {
+			continue
+		}
+		problem, err1 := s.Problem(ctx, sub.ProblemID)
+		if err1 != nil {
+			continue
+		}
+		s.filterSubmission(ctx, sub, problem, esub.user)
+		if subtest != nil {
+			if !sub.FeedbackLimited() {
+				esub.send(&Event{Type: EventSubTest, Data: subtest})
+			}
+			continue
+		}
+		esub.send(&Event{Type: EventSubmission, Data: sub})
+	}
+}
+
+// dispatchLeaderboardEvent sends the updated entries of the given contestants (or their teams) to everyone watching the contest
+// that may view its leaderboard. Updates that happen after the leaderboard was frozen are withheld from those that see the frozen standings
+func (s *BaseAPI) dispatchLeaderboardEvent(ctx context.Context, contestID int, users map[int]time.Time) {
+	subs := s.events.subscriptions()
+	if len(subs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	contest, err1 := s.Contest(ctx, contestID)
+	if err1 != nil {
+		return
+	}
+
+	// Entries are cached by freeze time, since most subscribers see the same standings
+	type entryKey struct {
+		userID int
+		freeze time.Time
+	}
+	entries := make(map[entryKey]*kilonova.LeaderboardEntry)
+	for _, esub := range subs {
+		if esub.contestID != contest.ID || !s.CanViewContestLeaderboard(esub.user, contest) {
+			continue
+		}
+		freeze := s.UserContestFreezeTime(esub.user, contest, false)
+		for userID, createdAt := range users {
+			if freeze != nil && createdAt.After(*freeze) {
+				continue
+			}
+			key := entryKey{userID: userID}
+			if freeze != nil {
+				key.freeze = *freeze
+			}
+			entry, ok := entries[key]
+			if !ok {
+				leaderboard, err := s.ContestLeaderboard(ctx, contest, freeze, kilonova.UserFilter{ID: &userID})
+				if err != nil {
+					zap.S().Warn(err)
+					return
+				}
+				if len(leaderboard.Entries) > 0 {
+					entry = leaderboard.Entries[0]
+				}
+				entries[key] = entry
+			}
+			if entry != nil {
+				esub.send(&Event{Type: EventLeaderboard, Data: entry})
+			}
+		}
+	}
+}
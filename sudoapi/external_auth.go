@@ -0,0 +1,424 @@
+package sudoapi
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/db"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider is an external OpenID Connect provider users can sign in with.
+// SAML identity providers are not supported; Google Workspace and Microsoft Entra ID both offer OpenID Connect
+type OIDCProvider struct {
+	// ID is used in URLs and to identify linked accounts. It must not change once users have linked their accounts
+	ID string `json:"id"`
+	// Name is shown on the login button, such as "Google" or "Microsoft"
+	Name string `json:"name"`
+	// Issuer is the provider's issuer URL, such as https://accounts.google.com or https://login.microsoftonline.com/<tenant>/v2.0
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// AllowedDomains are the email domains for which accounts are created on first sign in.
+	// If empty, only users that linked the identity to an existing account can sign in
+	AllowedDomains []string `json:"allowed_domains"`
+	// TrustEmail treats emails as verified even if the provider doesn't send the email_verified claim
+	// (Microsoft Entra ID doesn't). Only enable it for providers that vouch for their users' addresses
+	TrustEmail bool `json:"trust_email"`
+}
+
+var (
+	OIDCProviders = config.GenFlag("integrations.oidc.providers", []OIDCProvider{}, "External OpenID Connect login providers (list of objects with id, name, issuer, client_id, client_secret, allowed_domains and trust_email)")
+
+	ErrUnknownProvider = Statusf(404, "Unknown login provider")
+)
+
+const externalLoginStateLifetime = 15 * time.Minute
+
+// oidcHTTPClient is used for all requests to login providers. Discovery runs while holding externalOIDCMu,
+// so a slow provider must not be able to block the other sign ins indefinitely
+var oidcHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// OIDCProviderByID returns the configured provider with the given ID, or nil if it doesn't exist
+func OIDCProviderByID(id string) *OIDCProvider {
+	for _, provider := range OIDCProviders.Value() {
+		if provider.ID == id {
+			return &provider
+		}
+	}
+	return nil
+}
+
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+
+	fetchedAt time.Time
+}
+
+// providerMetadata returns the provider's discovery document. It is cached for an hour
+func (s *BaseAPI) providerMetadata(ctx context.Context, provider *OIDCProvider) (*oidcProviderMetadata, *StatusError) {
+	s.externalOIDCMu.Lock()
+	defer s.externalOIDCMu.Unlock()
+	if meta, ok := s.externalOIDC[provider.Issuer]; ok && time.Since(meta.fetchedAt) < time.Hour {
+		return meta, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(provider.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, WrapError(err, "Invalid provider issuer")
+	}
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't reach login provider")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, Statusf(502, "Login provider returned %s", resp.Status)
+	}
+	var meta oidcProviderMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, WrapError(err, "Invalid login provider configuration")
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		return nil, Statusf(502, "Invalid login provider configuration")
+	}
+	// OpenID Connect Discovery, section 4.3: the document must be issued by the configured provider,
+	// since ID tokens are then checked against its issuer
+	if strings.TrimSuffix(meta.Issuer, "/") != strings.TrimSuffix(provider.Issuer, "/") {
+		return nil, Statusf(502, "Login provider's issuer doesn't match the configured one")
+	}
+	meta.fetchedAt = time.Now()
+
+	if s.externalOIDC == nil {
+		s.externalOIDC = make(map[string]*oidcProviderMetadata)
+	}
+	s.externalOIDC[provider.Issuer] = &meta
+	return &meta, nil
+}
+
+func (s *BaseAPI) providerConfig(ctx context.Context, provider *OIDCProvider) (*oauth2.Config, *oidcProviderMetadata, *StatusError) {
+	meta, err := s.providerMetadata(ctx, provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &oauth2.Config{
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  meta.AuthorizationEndpoint,
+			TokenURL: meta.TokenEndpoint,
+		},
+
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+
+		Scopes: []string{"openid", "email", "profile"},
+
+		RedirectURL: config.Common.HostPrefix + "/login/external/" + provider.ID + "/callback",
+	}, meta, nil
+}
+
+// ExternalLoginURL returns the provider's URL the user must be sent to. If linkUserID is set, the identity is linked
+// to that user after a successful sign in. back is the page the user returns to after signing in
+func (s *BaseAPI) ExternalLoginURL(ctx context.Context, providerID string, linkUserID *int, back string) (string, *StatusError) {
+	provider := OIDCProviderByID(providerID)
+	if provider == nil {
+		return "", ErrUnknownProvider
+	}
+	conf, _, err := s.providerConfig(ctx, provider)
+	if err != nil {
+		return "", err
+	}
+
+	state := &db.ExternalLoginState{
+		ID:           kilonova.RandomString(32),
+		Provider:     provider.ID,
+		UserID:       linkUserID,
+		Nonce:        kilonova.RandomString(32),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		Back:         back,
+	}
+	if err := s.db.CreateExternalLoginState(ctx, state); err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't initialize login request")
+	}
+	return conf.AuthCodeURL(state.ID, oauth2.S256ChallengeOption(state.CodeVerifier), oauth2.SetAuthURLParam("nonce", state.Nonce)), nil
+}
+
+// ExternalLoginResult is the outcome of a sign in through an external provider
+type ExternalLoginResult struct {
+	// SessionID is set when the user signed in. It is empty when an identity was linked to the user's account
+	SessionID string
+	UserID    int
+	Back      string
+}
+
+type externalIDClaims struct {
+	Issuer        string          `json:"iss"`
+	Subject       string          `json:"sub"`
+	Audience      json.RawMessage `json:"aud"`
+	Expiry        int64           `json:"exp"`
+	Nonce         string          `json:"nonce"`
+	Email         string          `json:"email"`
+	EmailVerified *bool           `json:"email_verified"`
+	Name          string          `json:"name"`
+}
+
+func (c *externalIDClaims) hasAudience(clientID string) bool {
+	var single string
+	if err := json.Unmarshal(c.Audience, &single); err == nil {
+		return single == clientID
+	}
+	var multiple []string
+	if err := json.Unmarshal(c.Audience, &multiple); err == nil {
+		return slices.Contains(multiple, clientID)
+	}
+	return false
+}
+
+// verifiedEmail returns the email of the account, if the provider says it was verified or is trusted to verify its users' emails
+func (c *externalIDClaims) verifiedEmail(provider *OIDCProvider) *string {
+	email := strings.ToLower(strings.TrimSpace(c.Email))
+	if email == "" {
+		return nil
+	}
+	if !provider.TrustEmail && (c.EmailVerified == nil || !*c.EmailVerified) {
+		return nil
+	}
+	return &email
+}
+
+// parseExternalIDToken checks the claims of the ID token. Its signature is not verified: the token was received
+// directly from the provider's token endpoint over TLS, which OpenID Connect Core (section 3.1.3.7) allows in place of signature checks
+func parseExternalIDToken(rawToken string, meta *oidcProviderMetadata, provider *OIDCProvider, nonce string) (*externalIDClaims, *StatusError) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, Statusf(400, "Invalid ID token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, Statusf(400, "Invalid ID token")
+	}
+	var claims externalIDClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, Statusf(400, "Invalid ID token")
+	}
+	if claims.Issuer != meta.Issuer || !claims.hasAudience(provider.ClientID) {
+		return nil, Statusf(400, "ID token was issued for someone else")
+	}
+	if time.Unix(claims.Expiry, 0).Before(time.Now()) {
+		return nil, Statusf(400, "ID token expired")
+	}
+	if claims.Nonce != nonce {
+		return nil, Statusf(400, "ID token doesn't match the login request")
+	}
+	if claims.Subject == "" {
+		return nil, Statusf(400, "Invalid ID token")
+	}
+	return &claims, nil
+}
+
+// HandleExternalLogin finishes the sign in through an external provider, after it redirected the user back with the authorization code.
+// Depending on the request, the identity is linked to the user's account, the user is signed in, or a new account is created for them
+func (s *BaseAPI) HandleExternalLogin(ctx context.Context, authedUser *kilonova.UserBrief, providerID, stateID, code string, ip *netip.Addr, userAgent *string) (*ExternalLoginResult, *StatusError) {
+	state, err := s.db.ConsumeExternalLoginState(ctx, stateID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get login request")
+	}
+	if state == nil || state.Provider != providerID || time.Since(state.CreatedAt) > externalLoginStateLifetime {
+		return nil, Statusf(400, "Login request expired, please try again")
+	}
+	provider := OIDCProviderByID(providerID)
+	if provider == nil {
+		return nil, ErrUnknownProvider
+	}
+	conf, meta, err1 := s.providerConfig(ctx, provider)
+	if err1 != nil {
+		return nil, err1
+	}
+
+	token, err := conf.Exchange(context.WithValue(ctx, oauth2.HTTPClient, oidcHTTPClient), code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return nil, WrapError(err, "Couldn't get token from login provider")
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	claims, err1 := parseExternalIDToken(rawIDToken, meta, provider, state.Nonce)
+	if err1 != nil {
+		return nil, err1
+	}
+	email := claims.verifiedEmail(provider)
+
+	identity, err := s.db.ExternalIdentity(ctx, provider.ID, claims.Subject)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get linked account")
+	}
+
+	if state.UserID != nil {
+		if !authedUser.IsAuthed() || authedUser.ID != *state.UserID {
+			return nil, Statusf(403, "The account can only be linked by the user that started linking it")
+		}
+		if identity != nil {
+			if identity.UserID != *state.UserID {
+				return nil, Statusf(400, "This %s account is already linked to another user", provider.Name)
+			}
+			return &ExternalLoginResult{UserID: *state.UserID, Back: state.Back}, nil
+		}
+		if err := s.db.CreateExternalIdentity(ctx, &kilonova.ExternalIdentity{
+			Provider: provider.ID, Subject: claims.Subject, UserID: *state.UserID, Email: email,
+		}); err != nil {
+			zap.S().Warn(err)
+			return nil, WrapError(err, "Couldn't link account")
+		}
+		s.LogVerbose(ctx, "User linked external identity", slog.Int("user_id", *state.UserID), slog.String("provider", provider.ID), slog.String("subject", claims.Subject))
+		return &ExternalLoginResult{UserID: *state.UserID, Back: state.Back}, nil
+	}
+
+	var userID int
+	if identity != nil {
+		userID = identity.UserID
+		if err := s.db.TouchExternalIdentity(ctx, provider.ID, claims.Subject, email); err != nil {
+			zap.S().Warn(err)
+		}
+	} else {
+		user, err := s.provisionExternalUser(ctx, provider, claims, email, ip, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		userID = user.ID
+	}
+
+	user, err1 := s.UserFull(ctx, userID)
+	if err1 != nil {
+		return nil, err1
+	}
+	if user.LockedLogin && !user.Admin {
+		return nil, Statusf(401, "Login for this account has been restricted by an administrator")
+	}
+	sid, err1 := s.CreateSession(ctx, user.ID)
+	if err1 != nil {
+		return nil, err1
+	}
+	return &ExternalLoginResult{SessionID: sid, UserID: user.ID, Back: state.Back}, nil
+}
+
+// provisionExternalUser creates an account for a user signing in for the first time, if their email domain is allowed
+func (s *BaseAPI) provisionExternalUser(ctx context.Context, provider *OIDCProvider, claims *externalIDClaims, email *string, ip *netip.Addr, userAgent *string) (*kilonova.UserFull, *StatusError) {
+	if email == nil {
+		return nil, Statusf(403, "Your %s account isn't linked to any user. Log in and link it from your profile first", provider.Name)
+	}
+	_, domain, _ := strings.Cut(*email, "@")
+	if !slices.ContainsFunc(provider.AllowedDomains, func(allowed string) bool { return strings.EqualFold(allowed, domain) }) {
+		return nil, Statusf(403, "Your %s account isn't linked to any user. Log in and link it from your profile first", provider.Name)
+	}
+	// Existing accounts are never linked automatically, since the email address may have been reused
+	exists, err := s.db.CountUsers(ctx, kilonova.UserFilter{Email: email})
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't check email")
+	}
+	if exists > 0 {
+		return nil, Statusf(400, "An account with this email address already exists. Log in and link your %s account from your profile", provider.Name)
+	}
+
+	uname, err1 := s.externalUsername(ctx, *email)
+	if err1 != nil {
+		return nil, err1
+	}
+	// Provisioned users sign in through the provider. They can set a password later through the password reset flow
+	id, err := s.createUser(ctx, uname, *email, kilonova.RandomString(40), config.Common.DefaultLang, kilonova.PreferredThemeDark, strings.TrimSpace(claims.Name), "", false)
+	if err != nil {
+		return nil, WrapError(err, "Couldn't create user")
+	}
+	verified := true
+	if err := s.updateUser(ctx, id, kilonova.UserFullUpdate{VerifiedEmail: &verified}); err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateExternalIdentity(ctx, &kilonova.ExternalIdentity{
+		Provider: provider.ID, Subject: claims.Subject, UserID: id, Email: email,
+	}); err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't link account")
+	}
+
+	if err := s.LogSignup(context.WithoutCancel(ctx), id, ip, userAgent); err != nil {
+		zap.S().Warn(err)
+	}
+	s.LogUserAction(ctx, "Provisioned user from external login", slog.Int("user_id", id), slog.String("username", uname), slog.String("provider", provider.ID))
+	return s.UserFull(ctx, id)
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// externalUsername picks an unused username based on the local part of the email address
+func (s *BaseAPI) externalUsername(ctx context.Context, email string) (string, *StatusError) {
+	local, _, _ := strings.Cut(email, "@")
+	base := usernameInvalidChars.ReplaceAllString(local, "")
+	if len(base) > 20 {
+		base = base[:20]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+	for i := 0; i < 100; i++ {
+		uname := base
+		if i > 0 {
+			uname = fmt.Sprintf("%s%d", base, i)
+		}
+		if s.CheckValidUsername(uname) != nil {
+			continue
+		}
+		count, err := s.db.CountUsers(ctx, kilonova.UserFilter{Name: &uname})
+		if err != nil {
+			zap.S().Warn(err)
+			return "", WrapError(err, "Couldn't check username")
+		}
+		if count == 0 {
+			return uname, nil
+		}
+	}
+	return "", Statusf(500, "Couldn't find an available username")
+}
+
+// ExternalIdentities returns the external accounts linked to the user
+func (s *BaseAPI) ExternalIdentities(ctx context.Context, userID int) ([]*kilonova.ExternalIdentity, *StatusError) {
+	identities, err := s.db.ExternalIdentities(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get linked accounts")
+	}
+	return identities, nil
+}
+
+func (s *BaseAPI) UnlinkExternalIdentity(ctx context.Context, userID int, providerID string) *StatusError {
+	removed, err := s.db.RemoveExternalIdentity(ctx, userID, providerID)
+	if err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't unlink account")
+	}
+	if !removed {
+		return WrapError(ErrNotFound, "Linked account not found")
+	}
+	s.LogVerbose(ctx, "User unlinked external identity", slog.Int("user_id", userID), slog.String("provider", providerID))
+	return nil
+}
+
+func (s *BaseAPI) cleanupExternalLoginStates(ctx context.Context) {
+	if err := s.db.RemoveStaleExternalLoginStates(ctx, time.Now().Add(-externalLoginStateLifetime)); err != nil && !errors.Is(err, context.Canceled) {
+		zap.S().Warn(err)
+	}
+}
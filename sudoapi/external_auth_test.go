@@ -0,0 +1,98 @@
+package sudoapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func testIDToken(t *testing.T, claims map[string]any) string {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+var idTokenExamples = map[string]struct {
+	Claims map[string]any
+	Error  bool
+}{
+	"valid":                         {Claims: map[string]any{}, Error: false},
+	"audience array":                {Claims: map[string]any{"aud": []string{"other-client", "kilonova"}}, Error: false},
+	"wrong audience":                {Claims: map[string]any{"aud": "other-client"}, Error: true},
+	"audience array without client": {Claims: map[string]any{"aud": []string{"other-client"}}, Error: true},
+	"wrong issuer":                  {Claims: map[string]any{"iss": "https://evil.example.com"}, Error: true},
+	"expired":                       {Claims: map[string]any{"exp": time.Now().Add(-time.Minute).Unix()}, Error: true},
+	"nonce mismatch":                {Claims: map[string]any{"nonce": "other-nonce"}, Error: true},
+	"missing nonce":                 {Claims: map[string]any{"nonce": ""}, Error: true},
+	"missing subject":               {Claims: map[string]any{"sub": ""}, Error: true},
+}
+
+func TestParseExternalIDToken(t *testing.T) {
+	meta := &oidcProviderMetadata{Issuer: "https://accounts.example.com"}
+	provider := &OIDCProvider{ID: "example", Issuer: "https://accounts.example.com", ClientID: "kilonova"}
+	for k, v := range idTokenExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			claims := map[string]any{
+				"iss":   "https://accounts.example.com",
+				"sub":   "12345",
+				"aud":   "kilonova",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": "nonce",
+			}
+			for key, val := range v.Claims {
+				claims[key] = val
+			}
+			parsed, err := parseExternalIDToken(testIDToken(t, claims), meta, provider, "nonce")
+			if err != nil && !v.Error {
+				t.Fatalf("Token should be accepted: %v", err)
+			}
+			if err == nil && v.Error {
+				t.Fatalf("Token should be rejected")
+			}
+			if err == nil && parsed.Subject != "12345" {
+				t.Fatalf("Invalid subject %q", parsed.Subject)
+			}
+		})
+	}
+}
+
+func TestParseMalformedIDToken(t *testing.T) {
+	meta := &oidcProviderMetadata{Issuer: "https://accounts.example.com"}
+	provider := &OIDCProvider{ID: "example", Issuer: "https://accounts.example.com", ClientID: "kilonova"}
+	for _, token := range []string{"", "a.b", "a.!!!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte("not json")) + ".c"} {
+		if _, err := parseExternalIDToken(token, meta, provider, "nonce"); err == nil {
+			t.Fatalf("Malformed token %q should be rejected", token)
+		}
+	}
+}
+
+func TestVerifiedEmail(t *testing.T) {
+	verified, unverified := true, false
+	trusted := &OIDCProvider{TrustEmail: true}
+	untrusted := &OIDCProvider{}
+
+	examples := map[string]struct {
+		Claims   externalIDClaims
+		Provider *OIDCProvider
+		Email    string
+	}{
+		"verified":              {Claims: externalIDClaims{Email: " Student@School.ro ", EmailVerified: &verified}, Provider: untrusted, Email: "student@school.ro"},
+		"unverified":            {Claims: externalIDClaims{Email: "student@school.ro", EmailVerified: &unverified}, Provider: untrusted},
+		"missing claim":         {Claims: externalIDClaims{Email: "student@school.ro"}, Provider: untrusted},
+		"missing claim trusted": {Claims: externalIDClaims{Email: "student@school.ro"}, Provider: trusted, Email: "student@school.ro"},
+		"no email":              {Claims: externalIDClaims{EmailVerified: &verified}, Provider: trusted},
+	}
+	for k, v := range examples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			email := v.Claims.verifiedEmail(v.Provider)
+			if (email == nil) != (v.Email == "") || (email != nil && *email != v.Email) {
+				t.Fatalf("Expected email %q, got %v", v.Email, email)
+			}
+		})
+	}
+}
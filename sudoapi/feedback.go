@@ -0,0 +1,112 @@
+package sudoapi
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/KiloProjects/kilonova"
+	"go.uber.org/zap"
+)
+
+// ContestFeedbackLevel returns the level of detail the user may see of their own submissions in the contest,
+// without taking any used feedback tokens into account
+func (s *BaseAPI) ContestFeedbackLevel(contest *kilonova.Contest, problem *kilonova.Problem, user *kilonova.UserBrief) kilonova.FeedbackLevel {
+	if contest == nil || !contest.FeedbackLevel.Valid() || contest.FeedbackLevel == kilonova.FeedbackLevelFull {
+		return kilonova.FeedbackLevelFull
+	}
+	// Full feedback is given once the contest is over
+	if contest.Ended() {
+		return kilonova.FeedbackLevelFull
+	}
+	if s.IsContestTester(user, contest) || (problem != nil && s.IsProblemEditor(user, problem)) {
+		return kilonova.FeedbackLevelFull
+	}
+	return contest.FeedbackLevel
+}
+
+func (s *BaseAPI) submissionFeedbackLevel(ctx context.Context, sub *kilonova.Submission, subProblem *kilonova.Problem, user *kilonova.UserBrief) kilonova.FeedbackLevel {
+	if sub.ContestID == nil {
+		return kilonova.FeedbackLevelFull
+	}
+	contest, err := s.Contest(ctx, *sub.ContestID)
+	if err != nil {
+		// Fail closed, since we can't know what the contest allows
+		zap.S().Warn("Couldn't get submission contest: ", err)
+		return kilonova.FeedbackLevelTotal
+	}
+	level := s.ContestFeedbackLevel(contest, subProblem, user)
+	if level == kilonova.FeedbackLevelFull {
+		return level
+	}
+	used, err1 := s.db.FeedbackTokenUsed(ctx, sub.ID)
+	if err1 != nil {
+		zap.S().Warn("Couldn't check feedback token: ", err1)
+		return level
+	}
+	if used {
+		return kilonova.FeedbackLevelFull
+	}
+	return level
+}
+
+// RemainingFeedbackTokens returns the number of feedback tokens the user can still use on the contest problem
+func (s *BaseAPI) RemainingFeedbackTokens(ctx context.Context, contest *kilonova.Contest, problemID int, user *kilonova.UserBrief) (int, *StatusError) {
+	if contest == nil || !user.IsAuthed() {
+		return 0, nil
+	}
+	used, err := s.db.UsedFeedbackTokens(ctx, user.ID, contest.ID, problemID)
+	if err != nil {
+		return -1, WrapError(err, "Couldn't get used feedback tokens")
+	}
+	return max(contest.FeedbackTokens-used, 0), nil
+}
+
+// UseFeedbackToken reveals the full evaluation of the submission to its author, consuming one of their tokens for the problem
+func (s *BaseAPI) UseFeedbackToken(ctx context.Context, sub *kilonova.Submission, user *kilonova.UserBrief) *StatusError {
+	if sub == nil || !user.IsAuthed() || sub.UserID != user.ID {
+		return Statusf(403, "You can only use feedback tokens on your own submissions")
+	}
+	if sub.ContestID == nil {
+		return Statusf(400, "Feedback tokens can only be used on contest submissions")
+	}
+	contest, err := s.Contest(ctx, *sub.ContestID)
+	if err != nil {
+		return err
+	}
+	problem, err := s.Problem(ctx, sub.ProblemID)
+	if err != nil {
+		return err
+	}
+	if s.ContestFeedbackLevel(contest, problem, user) == kilonova.FeedbackLevelFull {
+		return Statusf(400, "Full feedback is already available for this submission")
+	}
+	used, err1 := s.db.FeedbackTokenUsed(ctx, sub.ID)
+	if err1 != nil {
+		return WrapError(err1, "Couldn't check feedback token")
+	}
+	if used {
+		return Statusf(400, "A feedback token was already used on this submission")
+	}
+	ok, err1 := s.db.UseFeedbackToken(ctx, sub.ID, user.ID, contest.ID, problem.ID, contest.FeedbackTokens)
+	if err1 != nil {
+		return WrapError(err1, "Couldn't use feedback token")
+	}
+	if !ok {
+		return Statusf(400, "You have no feedback tokens left for this problem")
+	}
+	s.LogVerbose(ctx, "Used feedback token", slog.Int("submission_id", sub.ID), slog.Any("contest", contest))
+	return nil
+}
+
+// limitSubmissionFeedback strips the evaluation details that are hidden by the submission's feedback level
+func limitSubmissionFeedback(sub *kilonova.Submission) {
+	if !sub.FeedbackLimited() {
+		return
+	}
+	sub.MaxTime = 0
+	sub.MaxMemory = 0
+	// ICPC verdicts name the first failed test
+	if sub.ICPCVerdict != nil && *sub.ICPCVerdict != "test_verdict.accepted" && *sub.ICPCVerdict != "test_verdict.compile_error" {
+		sub.ICPCVerdict = nil
+	}
+}
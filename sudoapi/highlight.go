@@ -0,0 +1,39 @@
+package sudoapi
+
+import (
+	"bytes"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/chroma/v2"
+	chtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// SyntaxHighlight formats the source code as HTML, with the lexer picked from the language (or, failing that, the file name).
+// The options are applied after the defaults, so callers choose between CSS classes (for site pages) and inline styles (for standalone pages)
+func SyntaxHighlight(code string, lang string, filename string, opts ...chtml.Option) (string, error) {
+	if lang == "pascal" {
+		lang = "pas"
+	}
+	lm := lexers.Get(strings.TrimFunc(lang, unicode.IsDigit))
+	if lm == nil && filename != "" {
+		lm = lexers.Match(filename)
+	}
+	if lm == nil {
+		lm = lexers.Fallback
+	}
+	lm = chroma.Coalesce(lm)
+	it, err := lm.Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+
+	formatter := chtml.New(append([]chtml.Option{chtml.TabWidth(4)}, opts...)...)
+	var buf bytes.Buffer
+	if err := formatter.Format(&buf, styles.Get("github"), it); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
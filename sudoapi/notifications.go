@@ -0,0 +1,425 @@
+package sudoapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	NotificationsEnabled = config.GenFlag("feature.notifications.enabled", true, "Send opt-in contest email notifications (reminders, question answers and results)")
+	NotificationRate     = config.GenFlag("feature.notifications.mails_per_minute", 30, "Maximum number of notification emails sent per minute")
+)
+
+const (
+	// maxMailAttempts is the number of times sending a queued email is tried before giving up
+	maxMailAttempts = 5
+	// resultNotificationMaxAge is how long after a contest ends its result emails are still sent, in case the leaderboard is unfrozen late
+	resultNotificationMaxAge = 30 * 24 * time.Hour
+)
+
+// notificationTempls holds, for every language, the subject and body templates of every notification kind
+var notificationTempls = map[string]*template.Template{
+	"en": template.Must(template.New("en").Parse(`
+{{- define "footer"}}
+
+------
+You are receiving this email because you enabled contest notifications on Kilonova.
+You can change your preferences here: {{.SettingsURL}}
+{{end}}
+
+{{- define "reminder_subject"}}Reminder: {{.Contest}} starts soon{{end}}
+{{- define "reminder_body"}}Hey, {{.Name}}!
+
+The contest "{{.Contest}}", for which you are registered, starts on {{.StartTime}}.
+
+Contest page: {{.ContestURL}}
+{{template "footer" .}}{{end}}
+
+{{- define "answer_subject"}}Your question in {{.Contest}} was answered{{end}}
+{{- define "answer_body"}}Hey, {{.Name}}!
+
+Your question in the contest "{{.Contest}}" was answered.
+
+Q: {{.Question}}
+
+A: {{.Answer}}
+
+Contest page: {{.ContestURL}}
+{{template "footer" .}}{{end}}
+
+{{- define "results_subject"}}Results of {{.Contest}}{{end}}
+{{- define "results_body"}}Hey, {{.Name}}!
+
+The final standings of the contest "{{.Contest}}" are available.
+{{if .Team}}Your team, {{.Team}}, placed{{else}}You placed{{end}} {{.Place}} out of {{.Participants}}{{if .ICPC}}, solving {{.Solved}} problems with a penalty of {{.Penalty}}{{else}}, with a total score of {{.Total}} points{{end}}.
+{{range .Scores}}
+{{.Problem}}: {{.Score}}{{end}}
+
+Leaderboard: {{.ContestURL}}/leaderboard
+{{template "footer" .}}{{end}}
+`)),
+	"ro": template.Must(template.New("ro").Parse(`
+{{- define "footer"}}
+
+------
+Primești acest email deoarece ai activat notificările pentru concursuri pe Kilonova.
+Îți poți modifica preferințele aici: {{.SettingsURL}}
+{{end}}
+
+{{- define "reminder_subject"}}Reamintire: {{.Contest}} începe în curând{{end}}
+{{- define "reminder_body"}}Hey, {{.Name}}!
+
+Concursul „{{.Contest}}”, la care ești înregistrat, începe pe {{.StartTime}}.
+
+Pagina concursului: {{.ContestURL}}
+{{template "footer" .}}{{end}}
+
+{{- define "answer_subject"}}Ai primit un răspuns în {{.Contest}}{{end}}
+{{- define "answer_body"}}Hey, {{.Name}}!
+
+Întrebarea ta din concursul „{{.Contest}}” a primit un răspuns.
+
+Î: {{.Question}}
+
+R: {{.Answer}}
+
+Pagina concursului: {{.ContestURL}}
+{{template "footer" .}}{{end}}
+
+{{- define "results_subject"}}Rezultatele concursului {{.Contest}}{{end}}
+{{- define "results_body"}}Hey, {{.Name}}!
+
+Clasamentul final al concursului „{{.Contest}}” este disponibil.
+{{if .Team}}Echipa ta, {{.Team}}, s-a clasat{{else}}Te-ai clasat{{end}} pe locul {{.Place}} din {{.Participants}}{{if .ICPC}}, cu {{.Solved}} probleme rezolvate și o penalizare de {{.Penalty}}{{else}}, cu un punctaj total de {{.Total}} puncte{{end}}.
+{{range .Scores}}
+{{.Problem}}: {{.Score}}{{end}}
+
+Clasament: {{.ContestURL}}/leaderboard
+{{template "footer" .}}{{end}}
+`)),
+}
+
+type notificationScore struct {
+	Problem string
+	Score   string
+}
+
+type notificationData struct {
+	Name        string
+	SettingsURL string
+
+	Contest    string
+	ContestURL string
+
+	// For reminders
+	StartTime string
+
+	// For question answers
+	Question string
+	Answer   string
+
+	// For results
+	Team         string
+	Place        int
+	Participants int
+	ICPC         bool
+	Solved       int
+	Penalty      int
+	Total        string
+	Scores       []notificationScore
+}
+
+func (s *BaseAPI) NotificationPreferences(ctx context.Context, userID int) (*kilonova.NotificationPreferences, *StatusError) {
+	prefs, err := s.db.NotificationPreferences(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get notification preferences")
+	}
+	return prefs, nil
+}
+
+func (s *BaseAPI) UpdateNotificationPreferences(ctx context.Context, userID int, prefs *kilonova.NotificationPreferences) *StatusError {
+	if prefs.ReminderHours < 1 || prefs.ReminderHours > 7*24 {
+		return Statusf(400, "Reminders can be sent between 1 hour and 7 days before the contest")
+	}
+	if err := s.db.SetNotificationPreferences(ctx, userID, prefs); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update notification preferences")
+	}
+	return nil
+}
+
+// queueNotification renders the notification in the user's preferred language and adds it to the mail queue.
+// Notifications with the same non-empty dedupe key are only queued once
+func (s *BaseAPI) queueNotification(ctx context.Context, user *kilonova.UserFull, kind string, dedupeKey string, data *notificationData) *StatusError {
+	if !NotificationsEnabled.Value() || !s.MailerEnabled() {
+		return nil
+	}
+	if user == nil || !user.VerifiedEmail || user.Email == "" {
+		return nil
+	}
+
+	templ, ok := notificationTempls[user.PreferredLanguage]
+	if !ok {
+		templ = notificationTempls[config.Common.DefaultLang]
+		if templ == nil {
+			templ = notificationTempls["en"]
+		}
+	}
+	data.Name = user.Name
+	data.SettingsURL = config.Common.HostPrefix + "/settings/notifications"
+
+	var subject, body bytes.Buffer
+	if err := templ.ExecuteTemplate(&subject, kind+"_subject", data); err != nil {
+		zap.S().Error("Error rendering notification subject: ", err)
+		return Statusf(500, "Error rendering email")
+	}
+	if err := templ.ExecuteTemplate(&body, kind+"_body", data); err != nil {
+		zap.S().Error("Error rendering notification email: ", err)
+		return Statusf(500, "Error rendering email")
+	}
+
+	if err := s.db.EnqueueMail(ctx, user.ID, user.Email, subject.String(), body.String(), dedupeKey); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't queue email")
+	}
+	return nil
+}
+
+func contestURL(contest *kilonova.Contest) string {
+	return config.Common.HostPrefix + "/contests/" + strconv.Itoa(contest.ID)
+}
+
+// notifyQuestionAnswer queues the answer notification for the author of the question, if they opted into it
+func (s *BaseAPI) notifyQuestionAnswer(ctx context.Context, question *kilonova.ContestQuestion, answer string) {
+	prefs, err := s.NotificationPreferences(ctx, question.AuthorID)
+	if err != nil || !prefs.QuestionAnswers {
+		return
+	}
+	author, err := s.UserFull(ctx, question.AuthorID)
+	if err != nil {
+		return
+	}
+	contest, err := s.Contest(ctx, question.ContestID)
+	if err != nil {
+		return
+	}
+	// Answers can be edited, so every answer is sent
+	if err := s.queueNotification(ctx, author, "answer", "", &notificationData{
+		Contest:    contest.Name,
+		ContestURL: contestURL(contest),
+		Question:   question.Text,
+		Answer:     answer,
+	}); err != nil {
+		zap.S().Warn("Couldn't queue question answer notification: ", err)
+	}
+}
+
+func (s *BaseAPI) queueContestReminders(ctx context.Context) {
+	targets, err := s.db.ContestReminderTargets(ctx)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't get contest reminder targets: ", err)
+		}
+		return
+	}
+	contests := make(map[int]*kilonova.Contest)
+	for _, target := range targets {
+		contest, ok := contests[target.ContestID]
+		if !ok {
+			contest, err = s.Contest(ctx, target.ContestID)
+			if err != nil {
+				zap.S().Warn(err)
+				continue
+			}
+			contests[target.ContestID] = contest
+		}
+		user, err := s.UserFull(ctx, target.UserID)
+		if err != nil {
+			zap.S().Warn(err)
+			continue
+		}
+		if err := s.queueNotification(ctx, user, "reminder", fmt.Sprintf("reminder:%d:%d", contest.ID, user.ID), &notificationData{
+			Contest:    contest.Name,
+			ContestURL: contestURL(contest),
+			StartTime:  contest.StartTime.UTC().Format("2006-01-02 15:04 MST"),
+		}); err != nil {
+			zap.S().Warn("Couldn't queue contest reminder: ", err)
+		}
+	}
+}
+
+// queueContestResults queues the result summaries of the contests whose final standings became public.
+// Every participant (that is, every contestant with at least one submission) that opted into result notifications gets their rank and scores.
+// Emails are deduplicated, so it is safe to call again if queueing failed for some of the recipients
+func (s *BaseAPI) queueContestResults(ctx context.Context, contest *kilonova.Contest) *StatusError {
+	leaderboard, err := s.ContestLeaderboard(ctx, contest, nil, kilonova.UserFilter{})
+	if err != nil {
+		return err
+	}
+
+	type recipient struct {
+		entry *kilonova.LeaderboardEntry
+		place int
+	}
+	recipients := make(map[int]recipient)
+	var userIDs []int
+	participants, prevPlace := 0, 0
+	var prev *kilonova.LeaderboardEntry
+	for _, entry := range leaderboard.Entries {
+		if !leaderboardParticipated(entry) {
+			continue
+		}
+		participants++
+		place := participants
+		if prev != nil && leaderboardTied(leaderboard, prev, entry) {
+			place = prevPlace
+		}
+		prev, prevPlace = entry, place
+		var users []*kilonova.UserBrief
+		if entry.Team != nil {
+			users = entry.Team.Members
+		} else if entry.User != nil {
+			users = []*kilonova.UserBrief{entry.User}
+		}
+		for _, user := range users {
+			recipients[user.ID] = recipient{entry: entry, place: place}
+			userIDs = append(userIDs, user.ID)
+		}
+	}
+
+	optedIn, err1 := s.db.ContestResultRecipients(ctx, userIDs)
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return WrapError(err1, "Couldn't get notification recipients")
+	}
+	var failed int
+	for _, userID := range optedIn {
+		user, err := s.UserFull(ctx, userID)
+		if err != nil {
+			zap.S().Warn(err)
+			failed++
+			continue
+		}
+		rec := recipients[userID]
+		data := &notificationData{
+			Contest:      contest.Name,
+			ContestURL:   contestURL(contest),
+			Place:        rec.place,
+			Participants: participants,
+			ICPC:         leaderboard.Type == kilonova.LeaderboardTypeICPC,
+			Solved:       rec.entry.NumSolved,
+			Penalty:      rec.entry.Penalty,
+			Total:        rec.entry.TotalScore.String(),
+		}
+		if rec.entry.Team != nil {
+			data.Team = rec.entry.Team.Name
+		}
+		for _, pbID := range leaderboard.ProblemOrder {
+			name := leaderboard.ProblemNames[pbID]
+			if label, ok := leaderboard.ProblemLabels[pbID]; ok && label != "" {
+				name = label + ". " + name
+			}
+			score := "-"
+			if leaderboard.Type == kilonova.LeaderboardTypeICPC {
+				if _, ok := rec.entry.ProblemTimes[pbID]; ok {
+					score = "✓"
+				}
+			} else if sc, ok := rec.entry.ProblemScores[pbID]; ok && !sc.IsNegative() {
+				score = sc.String()
+			}
+			data.Scores = append(data.Scores, notificationScore{Problem: name, Score: score})
+		}
+		if err := s.queueNotification(ctx, user, "results", fmt.Sprintf("results:%d:%d", contest.ID, user.ID), data); err != nil {
+			zap.S().Warn("Couldn't queue contest results: ", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return Statusf(500, "Couldn't queue %d result notifications", failed)
+	}
+	return nil
+}
+
+func (s *BaseAPI) notifyContestResults(ctx context.Context) {
+	contests, err := s.db.ContestsAwaitingResultNotifications(ctx, resultNotificationMaxAge)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't get contests awaiting result notifications: ", err)
+		}
+		return
+	}
+	for _, contest := range contests {
+		// The contest is only marked once every email was queued, so failures are retried on the next run
+		if err := s.queueContestResults(ctx, contest); err != nil {
+			zap.S().Warn("Couldn't queue contest results: ", err)
+			continue
+		}
+		ok, err := s.db.MarkContestResultsNotified(ctx, contest.ID)
+		if err != nil {
+			zap.S().Warn(err)
+			continue
+		}
+		if ok {
+			s.LogToDiscord(ctx, "Queued contest result notifications", slog.Any("contest", contest))
+		}
+	}
+}
+
+// sendQueuedMails sends the oldest queued emails, at most NotificationRate at a time
+func (s *BaseAPI) sendQueuedMails(ctx context.Context) {
+	if !s.MailerEnabled() {
+		return
+	}
+	mails, err := s.db.PendingMails(ctx, maxMailAttempts, NotificationRate.Value())
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Couldn't get queued emails: ", err)
+		}
+		return
+	}
+	for _, mail := range mails {
+		if err := s.mailer.SendEmail(&kilonova.MailerMessage{To: mail.Email, Subject: mail.Subject, PlainContent: mail.Content}); err != nil {
+			zap.S().Warn("Couldn't send queued email: ", err)
+			if err := s.db.MarkMailFailed(ctx, mail.ID); err != nil {
+				zap.S().Warn(err)
+			}
+			continue
+		}
+		if err := s.db.MarkMailSent(ctx, mail.ID); err != nil {
+			zap.S().Warn(err)
+		}
+	}
+}
+
+// notificationJob queues the contest reminders and results, then sends a batch of queued emails.
+// It should run every minute, so that NotificationRate caps the number of emails sent per minute
+func (s *BaseAPI) notificationJob(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return nil
+		case <-t.C:
+			if NotificationsEnabled.Value() && s.MailerEnabled() {
+				s.queueContestReminders(ctx)
+				s.notifyContestResults(ctx)
+			}
+			s.sendQueuedMails(ctx)
+		}
+	}
+}
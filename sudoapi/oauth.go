@@ -0,0 +1,453 @@
+package sudoapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/db"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	OAuthEnabled    = config.GenFlag("feature.oauth.enabled", true, "Allow third-party apps to sign users in through OAuth2 / OpenID Connect")
+	MaxOAuthClients = config.GenFlag[int]("behavior.oauth.max_clients_per_user", 10, "Maximum number of OAuth apps a user can register")
+)
+
+const (
+	oauthCodeLifetime         = 10 * time.Minute
+	oauthAccessTokenLifetime  = 1 * time.Hour
+	oauthRefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// validateRedirectURI accepts HTTPS URLs, HTTP URLs on the loopback interface and
+// private-use schemes of native apps (RFC 8252), such as com.example.app:/callback
+func validateRedirectURI(uri string) *StatusError {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return Statusf(400, "Invalid redirect URI %q", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return Statusf(400, "Invalid redirect URI %q", uri)
+		}
+	case "http":
+		if host := u.Hostname(); host != "localhost" && host != "127.0.0.1" && host != "::1" {
+			return Statusf(400, "Redirect URIs must use HTTPS, unless they point to localhost")
+		}
+	default:
+		if !strings.Contains(u.Scheme, ".") {
+			return Statusf(400, "Custom redirect URI schemes must be reverse domain names, such as com.example.app")
+		}
+	}
+	return nil
+}
+
+func (s *BaseAPI) validateOAuthClient(client *kilonova.OAuthClient) *StatusError {
+	client.Name = strings.TrimSpace(client.Name)
+	client.Description = strings.TrimSpace(client.Description)
+	if client.Name == "" || len(client.Name) > 100 {
+		return Statusf(400, "Invalid app name")
+	}
+	if len(client.Description) > 1000 {
+		return Statusf(400, "App description is too long")
+	}
+	uris := make([]string, 0, len(client.RedirectURIs))
+	for _, uri := range client.RedirectURIs {
+		uri = strings.TrimSpace(uri)
+		if uri == "" {
+			continue
+		}
+		if err := validateRedirectURI(uri); err != nil {
+			return err
+		}
+		uris = append(uris, uri)
+	}
+	if len(uris) == 0 {
+		return Statusf(400, "Apps must have at least one redirect URI")
+	}
+	client.RedirectURIs = slices.Compact(uris)
+	return nil
+}
+
+// CreateOAuthClient registers a new app. Confidential apps get a secret, which is only returned here
+func (s *BaseAPI) CreateOAuthClient(ctx context.Context, owner *kilonova.UserBrief, client *kilonova.OAuthClient) (string, *StatusError) {
+	if !OAuthEnabled.Value() {
+		return "", kilonova.ErrFeatureDisabled
+	}
+	if err := s.validateOAuthClient(client); err != nil {
+		return "", err
+	}
+	clients, err := s.OAuthClients(ctx, owner.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(clients) >= MaxOAuthClients.Value() {
+		return "", Statusf(400, "You have registered too many apps")
+	}
+
+	client.ID = kilonova.RandomString(24)
+	client.OwnerID = owner.ID
+	var secret string
+	var secretHash *string
+	if client.Confidential {
+		secret = kilonova.RandomString(48)
+		hash := hashAPIToken(secret)
+		secretHash = &hash
+	}
+	if err := s.db.CreateOAuthClient(ctx, client, secretHash); err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't create app")
+	}
+
+	s.LogUserAction(ctx, "Registered OAuth app", slog.String("client_id", client.ID), slog.String("name", client.Name))
+	return secret, nil
+}
+
+func (s *BaseAPI) OAuthClient(ctx context.Context, id string) (*kilonova.OAuthClient, *StatusError) {
+	client, err := s.db.OAuthClient(ctx, id)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get app")
+	}
+	if client == nil {
+		return nil, WrapError(ErrNotFound, "App not found")
+	}
+	return client, nil
+}
+
+func (s *BaseAPI) OAuthClients(ctx context.Context, ownerID int) ([]*kilonova.OAuthClient, *StatusError) {
+	clients, err := s.db.OAuthClients(ctx, ownerID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get apps")
+	}
+	return clients, nil
+}
+
+func (s *BaseAPI) IsOAuthClientEditor(user *kilonova.UserBrief, client *kilonova.OAuthClient) bool {
+	if !user.IsAuthed() || client == nil {
+		return false
+	}
+	return user.IsAdmin() || user.ID == client.OwnerID
+}
+
+// UpdateOAuthClient updates the app's name, description and redirect URIs
+func (s *BaseAPI) UpdateOAuthClient(ctx context.Context, client *kilonova.OAuthClient) *StatusError {
+	if err := s.validateOAuthClient(client); err != nil {
+		return err
+	}
+	if err := s.db.UpdateOAuthClient(ctx, client); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't update app")
+	}
+	return nil
+}
+
+// ResetOAuthClientSecret generates a new secret for the app. Public apps become confidential
+func (s *BaseAPI) ResetOAuthClientSecret(ctx context.Context, client *kilonova.OAuthClient) (string, *StatusError) {
+	secret := kilonova.RandomString(48)
+	if err := s.db.SetOAuthClientSecret(ctx, client.ID, hashAPIToken(secret)); err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't reset app secret")
+	}
+	s.LogUserAction(ctx, "Reset OAuth app secret", slog.String("client_id", client.ID), slog.String("name", client.Name))
+	return secret, nil
+}
+
+// DeleteOAuthClient removes the app, along with all the tokens issued to it
+func (s *BaseAPI) DeleteOAuthClient(ctx context.Context, client *kilonova.OAuthClient) *StatusError {
+	if err := s.db.DeleteOAuthClient(ctx, client.ID); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't delete app")
+	}
+	s.LogUserAction(ctx, "Deleted OAuth app", slog.String("client_id", client.ID), slog.String("name", client.Name))
+	return nil
+}
+
+// OAuthAuthorizeClient returns the client of the authorization request, after checking the redirect URI is registered.
+// Until this passes, errors must be shown to the user, instead of being sent to the redirect URI
+func (s *BaseAPI) OAuthAuthorizeClient(ctx context.Context, req *kilonova.OAuthAuthorizeRequest) (*kilonova.OAuthClient, *StatusError) {
+	if !OAuthEnabled.Value() {
+		return nil, kilonova.ErrFeatureDisabled
+	}
+	client, err := s.OAuthClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, Statusf(400, "The redirect URI was not registered for this app")
+	}
+	return client, nil
+}
+
+// CheckOAuthAuthorizeRequest validates the rest of the authorization request. The errors can be sent back to the app
+func (s *BaseAPI) CheckOAuthAuthorizeRequest(client *kilonova.OAuthClient, req *kilonova.OAuthAuthorizeRequest) *StatusError {
+	if req.ResponseType != "code" {
+		return kilonova.OAuthError(kilonova.ErrOAuthUnsupportedResponseType, "Only the authorization code flow is supported")
+	}
+	for _, scope := range req.Scopes() {
+		if !slices.Contains(kilonova.OAuthScopes, scope) {
+			return kilonova.OAuthError(kilonova.ErrOAuthInvalidScope, "Unknown scope "+string(scope))
+		}
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return kilonova.OAuthError(kilonova.ErrOAuthInvalidRequest, "Only the S256 code challenge method is supported")
+	}
+	if !client.Confidential && req.CodeChallenge == "" {
+		return kilonova.OAuthError(kilonova.ErrOAuthInvalidRequest, "Public apps must use PKCE")
+	}
+	return nil
+}
+
+// OAuthRedirectURL adds the parameters to the query of the app's redirect URI
+func OAuthRedirectURL(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for key, vals := range params {
+		for _, val := range vals {
+			query.Add(key, val)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// AuthorizeOAuthRequest records the user's consent and returns the URL the user must be redirected to, holding the authorization code
+func (s *BaseAPI) AuthorizeOAuthRequest(ctx context.Context, user *kilonova.UserBrief, req *kilonova.OAuthAuthorizeRequest) (string, *StatusError) {
+	client, err := s.OAuthAuthorizeClient(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if err := s.CheckOAuthAuthorizeRequest(client, req); err != nil {
+		return "", err
+	}
+
+	scopes := req.Scopes()
+	slices.Sort(scopes)
+	code := kilonova.RandomString(40)
+	if err := s.db.CreateOAuthCode(ctx, hashAPIToken(code), &db.OAuthCode{
+		ExpiresAt:     time.Now().Add(oauthCodeLifetime),
+		ClientID:      client.ID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        slices.Compact(scopes),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+	}); err != nil {
+		zap.S().Warn(err)
+		return "", WrapError(err, "Couldn't create authorization code")
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return OAuthRedirectURL(req.RedirectURI, params), nil
+}
+
+// authenticateOAuthClient checks the client's credentials. Public clients don't have a secret
+func (s *BaseAPI) authenticateOAuthClient(ctx context.Context, clientID, secret string) (*kilonova.OAuthClient, *StatusError) {
+	client, err := s.db.OAuthClient(ctx, clientID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get app")
+	}
+	if client == nil {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidClient, "Unknown client")
+	}
+	if !client.Confidential {
+		return client, nil
+	}
+	hash, err := s.db.OAuthClientSecretHash(ctx, clientID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get app")
+	}
+	if hash == nil || subtle.ConstantTimeCompare([]byte(*hash), []byte(hashAPIToken(secret))) != 1 {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidClient, "Invalid client credentials")
+	}
+	return client, nil
+}
+
+// verifyCodeChallenge checks the PKCE code verifier against the S256 challenge of the authorization request
+func verifyCodeChallenge(challenge, verifier string) bool {
+	if verifier == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(sum[:])), []byte(challenge)) == 1
+}
+
+// ExchangeOAuthCode exchanges an authorization code for tokens
+func (s *BaseAPI) ExchangeOAuthCode(ctx context.Context, clientID, secret, code, redirectURI, codeVerifier string) (*kilonova.OAuthTokenResponse, *StatusError) {
+	client, err := s.authenticateOAuthClient(ctx, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	authCode, err1 := s.db.ConsumeOAuthCode(ctx, hashAPIToken(code))
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return nil, WrapError(err1, "Couldn't get authorization code")
+	}
+	if authCode == nil || authCode.ExpiresAt.Before(time.Now()) || authCode.ClientID != client.ID {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidGrant, "Invalid or expired authorization code")
+	}
+	if authCode.RedirectURI != redirectURI {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidGrant, "Redirect URI doesn't match the authorization request")
+	}
+	if authCode.CodeChallenge != "" && !verifyCodeChallenge(authCode.CodeChallenge, codeVerifier) {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidGrant, "Invalid code verifier")
+	}
+
+	return s.issueOAuthTokens(ctx, client, authCode.UserID, authCode.Scopes, authCode.Nonce)
+}
+
+// RefreshOAuthToken exchanges a refresh token for new tokens. The refresh token is rotated, the old one can't be used again
+func (s *BaseAPI) RefreshOAuthToken(ctx context.Context, clientID, secret, refreshToken string) (*kilonova.OAuthTokenResponse, *StatusError) {
+	client, err := s.authenticateOAuthClient(ctx, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	token, err1 := s.db.ConsumeOAuthRefreshToken(ctx, hashAPIToken(refreshToken))
+	if err1 != nil {
+		zap.S().Warn(err1)
+		return nil, WrapError(err1, "Couldn't get refresh token")
+	}
+	if token == nil || token.ExpiresAt.Before(time.Now()) || token.ClientID != client.ID {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidGrant, "Invalid or expired refresh token")
+	}
+
+	return s.issueOAuthTokens(ctx, client, token.UserID, token.Scopes, "")
+}
+
+func (s *BaseAPI) issueOAuthTokens(ctx context.Context, client *kilonova.OAuthClient, userID int, scopes []kilonova.APITokenScope, nonce string) (*kilonova.OAuthTokenResponse, *StatusError) {
+	user, err := s.UserFull(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.LockedLogin {
+		return nil, kilonova.OAuthError(kilonova.ErrOAuthInvalidGrant, "User can't log in")
+	}
+
+	accessToken := kilonova.OAuthTokenPrefix + kilonova.RandomString(40)
+	expiresAt := time.Now().Add(oauthAccessTokenLifetime)
+	if err := s.db.CreateAPIToken(ctx, &kilonova.APIToken{
+		UserID:    userID,
+		Name:      client.Name,
+		Scopes:    scopes,
+		ExpiresAt: &expiresAt,
+		ClientID:  &client.ID,
+	}, hashAPIToken(accessToken)); err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't create access token")
+	}
+
+	refreshToken := kilonova.RandomString(48)
+	if err := s.db.CreateOAuthRefreshToken(ctx, hashAPIToken(refreshToken), &db.OAuthRefreshToken{
+		ExpiresAt: time.Now().Add(oauthRefreshTokenLifetime),
+		ClientID:  client.ID,
+		UserID:    userID,
+		Scopes:    scopes,
+	}); err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't create refresh token")
+	}
+
+	scopeNames := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scopeNames = append(scopeNames, string(scope))
+	}
+	resp := &kilonova.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopeNames, " "),
+	}
+	if slices.Contains(scopes, kilonova.ScopeOpenID) {
+		resp.IDToken, err = s.oidcIDToken(ctx, client, user.Brief(), nonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// RevokeOAuthToken invalidates an access or refresh token, as defined in RFC 7009.
+// Unknown tokens are not an error, since the app only wants to make sure the token can't be used anymore
+func (s *BaseAPI) RevokeOAuthToken(ctx context.Context, clientID, secret, token string) *StatusError {
+	client, err := s.authenticateOAuthClient(ctx, clientID, secret)
+	if err != nil {
+		return err
+	}
+	if err := s.db.RevokeOAuthToken(ctx, client.ID, hashAPIToken(token)); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't revoke token")
+	}
+	return nil
+}
+
+// OAuthGrants returns the apps the user authorized
+func (s *BaseAPI) OAuthGrants(ctx context.Context, userID int) ([]*kilonova.OAuthGrant, *StatusError) {
+	tokens, err := s.db.OAuthGrants(ctx, userID)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get authorized apps")
+	}
+	grants := make([]*kilonova.OAuthGrant, 0, len(tokens))
+	for _, token := range tokens {
+		client, err := s.OAuthClient(ctx, token.ClientID)
+		if err != nil {
+			continue
+		}
+		grants = append(grants, &kilonova.OAuthGrant{
+			Client:    client,
+			Scopes:    token.Scopes,
+			GrantedAt: token.CreatedAt,
+		})
+	}
+	return grants, nil
+}
+
+// RevokeOAuthGrant removes the app's access to the user's account
+func (s *BaseAPI) RevokeOAuthGrant(ctx context.Context, userID int, clientID string) *StatusError {
+	if err := s.db.RevokeOAuthGrant(ctx, userID, clientID); err != nil {
+		zap.S().Warn(err)
+		return WrapError(err, "Couldn't revoke app access")
+	}
+	s.LogUserAction(ctx, "Revoked OAuth app access", slog.String("client_id", clientID), slog.Int("user_id", userID))
+	return nil
+}
+
+func (s *BaseAPI) oauthCleanupJob(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return nil
+		case <-t.C:
+			if err := s.db.RemoveExpiredOAuthTokens(ctx); err != nil {
+				zap.S().Warn(err)
+			}
+			s.cleanupExternalLoginStates(ctx)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sudoapi
+
+import "testing"
+
+var redirectURIExamples = map[string]struct {
+	URI   string
+	Error bool
+}{
+	"https":               {URI: "https://example.com/callback", Error: false},
+	"https with port":     {URI: "https://example.com:8443/callback?app=1", Error: false},
+	"localhost":           {URI: "http://localhost:8080/callback", Error: false},
+	"loopback ipv4":       {URI: "http://127.0.0.1:51234/callback", Error: false},
+	"loopback ipv6":       {URI: "http://[::1]:51234/callback", Error: false},
+	"custom scheme":       {URI: "com.example.app:/callback", Error: false},
+	"plain http":          {URI: "http://example.com/callback", Error: true},
+	"localhost lookalike": {URI: "http://localhost.example.com/callback", Error: true},
+	"missing host":        {URI: "https:///callback", Error: true},
+	"relative":            {URI: "/callback", Error: true},
+	"fragment":            {URI: "https://example.com/callback#token", Error: true},
+	"scheme without dot":  {URI: "myapp:/callback", Error: true},
+	"javascript":          {URI: "javascript:alert(1)", Error: true},
+	"empty":               {URI: "", Error: true},
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	for k, v := range redirectURIExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			err := validateRedirectURI(v.URI)
+			if err != nil && !v.Error {
+				t.Fatalf("Redirect URI %q should be accepted: %v", v.URI, err)
+			}
+			if err == nil && v.Error {
+				t.Fatalf("Redirect URI %q should be rejected", v.URI)
+			}
+		})
+	}
+}
+
+// The challenge is the example from RFC 7636, appendix B
+const exampleCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+var codeVerifierExamples = map[string]struct {
+	Verifier string
+	Valid    bool
+}{
+	"matching":        {Verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", Valid: true},
+	"mismatch":        {Verifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXl", Valid: false},
+	"empty":           {Verifier: "", Valid: false},
+	"challenge reuse": {Verifier: exampleCodeChallenge, Valid: false},
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	for k, v := range codeVerifierExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			if ok := verifyCodeChallenge(exampleCodeChallenge, v.Verifier); ok != v.Valid {
+				t.Fatalf("Expected %t for verifier %q, got %t", v.Valid, v.Verifier, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package sudoapi
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KiloProjects/kilonova"
+	"github.com/KiloProjects/kilonova/internal/config"
+	"go.uber.org/zap"
+)
+
+const oidcIDTokenLifetime = 1 * time.Hour
+
+type oidcSigningKey struct {
+	id  string
+	key *rsa.PrivateKey
+}
+
+// OIDCIssuer returns the issuer identifier of the OpenID Connect provider
+func OIDCIssuer() string {
+	return strings.TrimSuffix(config.Common.HostPrefix, "/")
+}
+
+// oidcSigningKey returns the key ID tokens are signed with. It is generated on first use and kept in the database,
+// so tokens remain valid across restarts and instances
+func (s *BaseAPI) oidcSigningKey(ctx context.Context) (*oidcSigningKey, *StatusError) {
+	s.oidcKeyMu.Lock()
+	defer s.oidcKeyMu.Unlock()
+	if s.oidcKey != nil {
+		return s.oidcKey, nil
+	}
+
+	dbKey, err := s.db.OAuthSigningKey(ctx)
+	if err != nil {
+		zap.S().Warn(err)
+		return nil, WrapError(err, "Couldn't get signing key")
+	}
+	if dbKey == nil {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, WrapError(err, "Couldn't generate signing key")
+		}
+		der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+		if err != nil {
+			return nil, WrapError(err, "Couldn't encode signing key")
+		}
+		// Another instance might have saved its key in the meantime, in which case that one is used
+		dbKey, err = s.db.InitOAuthSigningKey(ctx, kilonova.RandomString(16), string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+		if err != nil {
+			zap.S().Warn(err)
+			return nil, WrapError(err, "Couldn't save signing key")
+		}
+	}
+
+	block, _ := pem.Decode([]byte(dbKey.PrivateKey))
+	if block == nil {
+		return nil, Statusf(500, "Invalid signing key")
+	}
+	key, err1 := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err1 != nil {
+		return nil, WrapError(err1, "Invalid signing key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, Statusf(500, "Signing key is not an RSA key")
+	}
+	s.oidcKey = &oidcSigningKey{id: dbKey.ID, key: rsaKey}
+	return s.oidcKey, nil
+}
+
+// OIDCUserClaims returns the standard claims describing the user, shared by ID tokens and the userinfo endpoint
+func OIDCUserClaims(user *kilonova.UserBrief) map[string]any {
+	return map[string]any{
+		"sub":                strconv.Itoa(user.ID),
+		"preferred_username": user.Name,
+		"name":               user.AppropriateName(),
+		"profile":            OIDCIssuer() + "/profile/" + user.Name,
+	}
+}
+
+// oidcIDToken returns a signed RS256 JWT identifying the user to the app
+func (s *BaseAPI) oidcIDToken(ctx context.Context, client *kilonova.OAuthClient, user *kilonova.UserBrief, nonce string) (string, *StatusError) {
+	key, err := s.oidcSigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := OIDCUserClaims(user)
+	claims["iss"] = OIDCIssuer()
+	claims["aud"] = client.ID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(oidcIDTokenLifetime).Unix()
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	header, err1 := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": key.id})
+	if err1 != nil {
+		return "", WrapError(err1, "Couldn't encode ID token")
+	}
+	payload, err1 := json.Marshal(claims)
+	if err1 != nil {
+		return "", WrapError(err1, "Couldn't encode ID token")
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err1 := rsa.SignPKCS1v15(rand.Reader, key.key, crypto.SHA256, sum[:])
+	if err1 != nil {
+		return "", WrapError(err1, "Couldn't sign ID token")
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// OIDCKeySet returns the JSON Web Key Set apps can use to verify ID tokens
+func (s *BaseAPI) OIDCKeySet(ctx context.Context) (map[string]any, *StatusError) {
+	key, err := s.oidcSigningKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pub := key.key.PublicKey
+	return map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": key.id,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	}, nil
+}
+
+// OIDCDiscoveryDocument returns the OpenID provider metadata, served at /.well-known/openid-configuration
+func OIDCDiscoveryDocument() map[string]any {
+	issuer := OIDCIssuer()
+	scopes := make([]string, 0, len(kilonova.OAuthScopes))
+	for _, scope := range kilonova.OAuthScopes {
+		scopes = append(scopes, string(scope))
+	}
+	return map[string]any{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/oauth/userinfo",
+		"jwks_uri":                              issuer + "/oauth/jwks.json",
+		"revocation_endpoint":                   issuer + "/oauth/revoke",
+		"scopes_supported":                      scopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "preferred_username", "profile"},
+	}
+}
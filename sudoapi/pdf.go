@@ -0,0 +1,231 @@
+package sudoapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/KiloProjects/kilonova/internal/config"
+)
+
+var (
+	ChromiumPath = config.GenFlag("feature.pdf.chromium_path", "", "Path of the Chromium executable used to render certificates and print jobs as PDF. PDF rendering is disabled if empty")
+)
+
+const (
+	pdfRenderTimeout = 30 * time.Second
+	// pdfLoadTimeout is how long images in the page get to load before it is printed anyway
+	pdfLoadTimeout = 5 * time.Second
+)
+
+func (s *BaseAPI) PDFRenderingEnabled() bool {
+	return ChromiumPath.Value() != ""
+}
+
+// RenderPDF prints the HTML page to PDF using headless Chromium.
+// The page is passed through the DevTools pipe instead of a file, so it can't reference local files.
+// Since the pages may contain user content, scripts are disabled and all network requests fail
+func (s *BaseAPI) RenderPDF(ctx context.Context, page []byte) ([]byte, *StatusError) {
+	if !s.PDFRenderingEnabled() {
+		return nil, Statusf(503, "PDF rendering was not configured by admins")
+	}
+
+	profileDir, err := os.MkdirTemp("", "kn-pdf-*")
+	if err != nil {
+		return nil, WrapError(err, "Couldn't create temporary directory")
+	}
+	defer os.RemoveAll(profileDir)
+
+	ctx, cancel := context.WithTimeout(ctx, pdfRenderTimeout)
+	defer cancel()
+
+	// Chromium reads DevTools commands from fd 3 and writes the responses to fd 4
+	cmdR, cmdW, err := os.Pipe()
+	if err != nil {
+		return nil, WrapError(err, "Couldn't create pipe")
+	}
+	defer cmdW.Close()
+	respR, respW, err := os.Pipe()
+	if err != nil {
+		cmdR.Close()
+		return nil, WrapError(err, "Couldn't create pipe")
+	}
+	defer respR.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ChromiumPath.Value(),
+		"--headless", "--disable-gpu", "--no-first-run",
+		"--disable-background-networking", "--disable-extensions",
+		"--blink-settings=scriptEnabled=false",
+		"--host-rules=MAP * ~NOTFOUND",
+		// host-rules don't apply to IP addresses, so requests to them go through a proxy that doesn't exist
+		"--proxy-server=127.0.0.1:9", "--proxy-bypass-list=<-loopback>",
+		"--user-data-dir="+profileDir,
+		"--remote-debugging-pipe",
+	)
+	cmd.ExtraFiles = []*os.File{cmdR, respW}
+	cmd.Stderr = &stderr
+	err = cmd.Start()
+	// The child has its own copies of these ends
+	cmdR.Close()
+	respW.Close()
+	if err != nil {
+		return nil, WrapError(err, "Couldn't start Chromium")
+	}
+
+	conn := newCDPConn(cmdW, respR)
+	pdf, err := conn.printPage(ctx, page)
+	// Chromium might exit before answering, so the result doesn't matter
+	_ = conn.call(ctx, "", "Browser.close", nil, nil)
+	cmdW.Close()
+	_ = cmd.Wait()
+	if err != nil {
+		slog.Warn("Couldn't render PDF", slog.Any("err", err), slog.String("output", stderr.String()))
+		return nil, WrapError(err, "Couldn't render PDF")
+	}
+	return pdf, nil
+}
+
+type cdpError struct {
+	Message string `json:"message"`
+}
+
+type cdpMessage struct {
+	ID        int             `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    any             `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *cdpError       `json:"error,omitempty"`
+}
+
+// cdpConn is a minimal client for the Chrome DevTools Protocol, over the pipe transport (messages are separated by null bytes)
+type cdpConn struct {
+	w      io.Writer
+	lastID int
+
+	responses chan *cdpMessage
+	loaded    chan struct{}
+}
+
+func newCDPConn(w io.Writer, r io.Reader) *cdpConn {
+	// Only a handful of commands are sent, so the reader never blocks on responses, even if nobody waits for them anymore
+	c := &cdpConn{
+		w:         w,
+		responses: make(chan *cdpMessage, 16),
+		loaded:    make(chan struct{}, 1),
+	}
+	go c.read(r)
+	return c
+}
+
+func (c *cdpConn) read(r io.Reader) {
+	defer close(c.responses)
+	br := bufio.NewReader(r)
+	for {
+		data, err := br.ReadBytes(0)
+		if err != nil {
+			return
+		}
+		var msg cdpMessage
+		if err := json.Unmarshal(data[:len(data)-1], &msg); err != nil {
+			slog.Warn("Invalid DevTools message", slog.Any("err", err))
+			continue
+		}
+		switch {
+		case msg.ID > 0:
+			c.responses <- &msg
+		case msg.Method == "Page.loadEventFired":
+			select {
+			case c.loaded <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// call sends the command and waits for its response, which is decoded into result (if not nil)
+func (c *cdpConn) call(ctx context.Context, sessionID string, method string, params any, result any) error {
+	c.lastID++
+	id := c.lastID
+	data, err := json.Marshal(&cdpMessage{ID: id, SessionID: sessionID, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	if _, err := c.w.Write(append(data, 0)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-c.responses:
+			if !ok {
+				return errors.New("chromium closed the DevTools pipe")
+			}
+			if msg.ID != id {
+				continue
+			}
+			if msg.Error != nil {
+				return errors.New(method + ": " + msg.Error.Message)
+			}
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(msg.Result, result)
+		}
+	}
+}
+
+func (c *cdpConn) printPage(ctx context.Context, page []byte) ([]byte, error) {
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := c.call(ctx, "", "Target.createTarget", map[string]any{"url": "about:blank"}, &target); err != nil {
+		return nil, err
+	}
+	var session struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := c.call(ctx, "", "Target.attachToTarget", map[string]any{"targetId": target.TargetID, "flatten": true}, &session); err != nil {
+		return nil, err
+	}
+	var tree struct {
+		FrameTree struct {
+			Frame struct {
+				ID string `json:"id"`
+			} `json:"frame"`
+		} `json:"frameTree"`
+	}
+	if err := c.call(ctx, session.SessionID, "Page.getFrameTree", nil, &tree); err != nil {
+		return nil, err
+	}
+	if err := c.call(ctx, session.SessionID, "Page.enable", nil, nil); err != nil {
+		return nil, err
+	}
+	if err := c.call(ctx, session.SessionID, "Page.setDocumentContent", map[string]any{"frameId": tree.FrameTree.Frame.ID, "html": string(page)}, nil); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.loaded:
+	case <-time.After(pdfLoadTimeout):
+	}
+
+	var pdf struct {
+		// Data is base64 encoded, which encoding/json decodes by itself
+		Data []byte `json:"data"`
+	}
+	if err := c.call(ctx, session.SessionID, "Page.printToPDF", map[string]any{"printBackground": true, "preferCSSPageSize": true}, &pdf); err != nil {
+		return nil, err
+	}
+	return pdf.Data, nil
+}
@@ -31,6 +31,7 @@ func (s *BaseAPI) CreateSession(ctx context.Context, uid int) (string, *StatusEr
 		}
 		zap.S().Debugf("Removed %d old sessions", len(sessions))
 	}
+	s.enforceSingleSession(ctx, uid, sid)
 
 	return sid, nil
 }
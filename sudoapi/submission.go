@@ -296,6 +296,13 @@ func (s *BaseAPI) getSubmission(ctx context.Context, subid int, lookingUser *Use
 		return nil, WrapError(err1, "Couldn't fetch subtasks")
 	}
 
+	if rez.FeedbackLimited() {
+		rez.SubTests = []*kilonova.SubTest{}
+		if rez.FeedbackLevel == kilonova.FeedbackLevelTotal {
+			rez.SubTasks = []*kilonova.SubmissionSubTask{}
+		}
+	}
+
 	return rez, nil
 }
 
@@ -304,6 +311,10 @@ func (s *BaseAPI) UpdateSubmission(ctx context.Context, id int, status kilonova.
 		zap.S().Warn(err, id)
 		return WrapError(err, "Couldn't update submission")
 	}
+	s.publishEvent(&liveEvent{Type: EventSubmission, SubmissionID: id})
+	if status.Status == kilonova.StatusFinished {
+		s.publishEvent(&liveEvent{Type: EventLeaderboard, SubmissionID: id})
+	}
 	return nil
 }
 
@@ -317,10 +328,18 @@ func (s *BaseAPI) RemainingSubmissionCount(ctx context.Context, contest *kilonov
 	if err != nil {
 		return -1, WrapError(err, "Couldn't get submission count")
 	}
-	if contest.MaxSubs < 0 {
+	maxSubs := contest.MaxSubs
+	settings, err1 := s.ContestProblemSetting(ctx, contest.ID, problem.ID)
+	if err1 != nil {
+		return -1, err1
+	}
+	if settings.MaxSubs != nil {
+		maxSubs = *settings.MaxSubs
+	}
+	if maxSubs < 0 {
 		return 1, nil
 	}
-	return contest.MaxSubs - cnt, nil
+	return maxSubs - cnt, nil
 }
 
 func (s *BaseAPI) LastSubmissionTime(ctx context.Context, filter kilonova.SubmissionFilter) (*time.Time, *StatusError) {
@@ -335,6 +354,8 @@ var (
 	WaitingSubLimit    = config.GenFlag[int]("behavior.submissions.user_max_waiting", 5, "Maximum number of unfinished submissions in the eval queue (for a single user)")
 	TotalSubLimit      = config.GenFlag[int]("behavior.submissions.user_max_minute", 20, "Maximum number of submissions uploaded per minute (for a single user with verified email)")
 	UnverifiedSubLimit = config.GenFlag[int]("behavior.submissions.user_max_unverified", 5, "Maximum number of submissions uploaded per minute (for a single user with unverified email)")
+
+	InternalErrorRetries = config.GenFlag[int]("behavior.submissions.internal_error_retries", 3, "Number of times a submission is automatically reevaluated after an internal grader error")
 )
 
 // CreateSubmission produces a new submission and also creates the necessary subtests
@@ -386,12 +407,14 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 		}
 	}
 
+	var pretestOnly bool
+	var teamID *int
 	if contestID != nil {
 		contest, err := s.Contest(ctx, *contestID)
 		if err != nil || !s.IsContestVisible(author.Brief(), contest) {
 			return -1, Statusf(404, "Couldn't find contest")
 		}
-		if !s.CanSubmitInContest(author.Brief(), contest) {
+		if !s.CanSubmitInContest(ctx, author.Brief(), contest) {
 			return -1, Statusf(400, "Submitter cannot submit to contest")
 		}
 		pb, err := s.ContestProblem(ctx, contest, author.Brief(), problem.ID)
@@ -405,6 +428,32 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 		if cnt <= 0 {
 			return -1, Statusf(http.StatusTooManyRequests, "Max submission count for problem reached")
 		}
+		pbSettings, err := s.ContestProblemSetting(ctx, contest.ID, problem.ID)
+		if err != nil {
+			return -1, err
+		}
+		if len(pbSettings.Languages) > 0 && !slices.Contains(pbSettings.Languages, lang.InternalName) {
+			return -1, Statusf(400, "Language is not allowed in this contest")
+		}
+		if contest.Hacking {
+			locked, err := s.ContestProblemLocked(ctx, contest.ID, author.ID, problem.ID)
+			if err != nil {
+				return -1, err
+			}
+			if locked {
+				return -1, Statusf(400, "You locked this problem, so you cannot submit to it anymore")
+			}
+		}
+		pretestOnly = contest.Pretests && contest.Running()
+		if contest.TeamMode() {
+			reg, err := s.db.ContestRegistration(ctx, contest.ID, author.ID)
+			if err != nil {
+				return -1, WrapError(err, "Couldn't get contest registration")
+			}
+			if reg != nil {
+				teamID = reg.TeamID
+			}
+		}
 		if !s.IsContestTester(author.Brief(), contest) && contest.SubmissionCooldown > 0 {
 			t, err := s.LastSubmissionTime(ctx, kilonova.SubmissionFilter{
 				ContestID: &contest.ID,
@@ -440,7 +489,7 @@ func (s *BaseAPI) CreateSubmission(ctx context.Context, author *UserFull, proble
 	}
 
 	// Add submission
-	id, err := s.db.CreateSubmission(ctx, author.ID, problem, lang, string(code), contestID)
+	id, err := s.db.CreateSubmission(ctx, author.ID, problem, lang, string(code), contestID, teamID, pretestOnly)
 	if err != nil {
 		zap.S().Warn("Couldn't create submission:", err)
 		return -1, Statusf(500, "Couldn't create submission")
@@ -466,8 +515,10 @@ func (s *BaseAPI) DeleteSubmission(ctx context.Context, subID int) *StatusError
 }
 
 func (s *BaseAPI) ResetProblemSubmissions(ctx context.Context, problem *kilonova.Problem) *StatusError {
+	var noRetries int
 	if err := s.db.BulkUpdateSubmissions(ctx, kilonova.SubmissionFilter{ProblemID: &problem.ID}, kilonova.SubmissionUpdate{
-		Status: kilonova.StatusReevaling,
+		Status:          kilonova.StatusReevaling,
+		InternalRetries: &noRetries,
 	}); err != nil {
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't mark submissions for reevaluation")
@@ -480,6 +531,37 @@ func (s *BaseAPI) ResetProblemSubmissions(ctx context.Context, problem *kilonova
 	return nil
 }
 
+// RetrySubmission marks a submission that hit an internal grader error for reevaluation.
+// It returns false if the retry budget is exhausted, in which case the admins are notified and the submission should be finalized as is.
+func (s *BaseAPI) RetrySubmission(ctx context.Context, sub *kilonova.Submission) (bool, *StatusError) {
+	retries, ok := nextInternalRetry(sub.InternalRetries)
+	if !ok {
+		s.LogToDiscord(ctx, "Submission failed with internal error after exhausting all retries",
+			slog.Int("submission_id", sub.ID), slog.Int("retries", sub.InternalRetries),
+			slog.Int("problem_id", sub.ProblemID), slog.Int("user_id", sub.UserID),
+		)
+		return false, nil
+	}
+	if err := s.db.UpdateSubmission(ctx, sub.ID, kilonova.SubmissionUpdate{
+		Status:          kilonova.StatusReevaling,
+		InternalRetries: &retries,
+	}); err != nil {
+		zap.S().Warn(err)
+		return false, WrapError(err, "Couldn't mark submission for retry")
+	}
+	s.LogVerbose(ctx, "Retrying submission after internal error", slog.Int("submission_id", sub.ID), slog.Int("retry", retries))
+	return true, nil
+}
+
+// nextInternalRetry returns the retry counter for the next automatic reevaluation, or false if all retries were used.
+// The counter is kept when the submission is reset for the retry, and cleared only when it is reevaluated manually
+func nextInternalRetry(retries int) (int, bool) {
+	if retries >= InternalErrorRetries.Value() {
+		return retries, false
+	}
+	return retries + 1, true
+}
+
 func (s *BaseAPI) subVisibleRegardless(ctx context.Context, sub *kilonova.Submission, user *kilonova.UserBrief, subProblem *kilonova.Problem) bool {
 	if sub == nil {
 		return false
@@ -530,6 +612,16 @@ func (s *BaseAPI) isSubmissionVisible(ctx context.Context, sub *kilonova.Submiss
 		}
 	}
 
+	// During the hacking phase, contestants that locked the problem can see the accepted solutions
+	if s.CanHackSubmission(ctx, sub, user) {
+		return true
+	}
+
+	// Team members see each other's submissions
+	if s.sameContestTeam(ctx, sub, user) {
+		return true
+	}
+
 	return s.subVisibleRegardless(ctx, sub, user, subProblem)
 }
 
@@ -545,6 +637,8 @@ func (s *BaseAPI) filterSubmission(ctx context.Context, sub *kilonova.Submission
 	if !s.IsProblemEditor(user, subProblem) {
 		sub.CompileTime = nil
 	}
+	sub.FeedbackLevel = s.submissionFeedbackLevel(ctx, sub, subProblem, user)
+	limitSubmissionFeedback(sub)
 }
 
 func (s *BaseAPI) CreatePaste(ctx context.Context, sub *kilonova.Submission, user *kilonova.UserBrief) (string, *StatusError) {
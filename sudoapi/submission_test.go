@@ -0,0 +1,33 @@
+package sudoapi
+
+import "testing"
+
+func TestInternalRetriesGiveUp(t *testing.T) {
+	limit := InternalErrorRetries.Value()
+	retries, attempts := 0, 0
+	for {
+		next, ok := nextInternalRetry(retries)
+		if !ok {
+			break
+		}
+		if next != retries+1 {
+			t.Fatalf("Retry counter went from %d to %d", retries, next)
+		}
+		retries = next
+		attempts++
+		if attempts > limit {
+			t.Fatalf("Submission was retried more than %d times", limit)
+		}
+	}
+	if attempts != limit {
+		t.Fatalf("Expected %d retries, got %d", limit, attempts)
+	}
+}
+
+func TestInternalRetriesExhausted(t *testing.T) {
+	for _, retries := range []int{InternalErrorRetries.Value(), InternalErrorRetries.Value() + 1} {
+		if _, ok := nextInternalRetry(retries); ok {
+			t.Fatalf("Submission with %d retries shouldn't be retried again", retries)
+		}
+	}
+}
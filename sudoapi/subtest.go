@@ -32,6 +32,7 @@ func (s *BaseAPI) UpdateSubTest(ctx context.Context, id int, upd kilonova.SubTes
 		zap.S().Warn(err)
 		return WrapError(err, "Couldn't update subtest")
 	}
+	s.publishEvent(&liveEvent{Type: EventSubTest, SubTestID: id})
 	return nil
 }
 
@@ -12,11 +12,15 @@ type Test struct {
 	Score     decimal.Decimal `json:"score"`
 	ProblemID int             `db:"problem_id" json:"problem_id"`
 	VisibleID int             `db:"visible_id" json:"visible_id"`
+
+	// Pretest marks the test as one of those run during contests with pretests enabled
+	Pretest bool `json:"pretest"`
 }
 
 type TestUpdate struct {
 	Score     *decimal.Decimal `json:"score"`
 	VisibleID *int             `json:"visible_id"`
+	Pretest   *bool            `json:"pretest"`
 }
 
 type SubTask struct {
@@ -26,6 +26,9 @@ type UserBrief struct {
 	DisplayName string `json:"display_name"`
 
 	Generated bool `json:"generated"`
+
+	// Rating is computed from the user's results in official contests. It is nil while the user is unrated
+	Rating *int `json:"rating"`
 }
 
 func (u *UserBrief) LogValue() slog.Value {
@@ -102,6 +105,11 @@ type UserFilter struct {
 
 	// For filtering in leaderboards
 	Generated *bool `json:"generated"`
+	// Category requires ContestID to be set
+	Category *string `json:"category"`
+	// Unrated users are excluded by MinRating, but included by MaxRating
+	MinRating *int `json:"min_rating"`
+	MaxRating *int `json:"max_rating"`
 
 	// For session recognition
 	SessionID *string `json:"session_id"`
@@ -170,3 +178,13 @@ func CheckPwdHash(password, hash string) bool {
 	}
 	return true
 }
+
+// NotificationPreferences holds the email notifications the user opted into
+type NotificationPreferences struct {
+	ContestReminders bool `json:"contest_reminders" db:"contest_reminders"`
+	QuestionAnswers  bool `json:"question_answers" db:"question_answers"`
+	ContestResults   bool `json:"contest_results" db:"contest_results"`
+
+	// ReminderHours is how many hours before the start of a registered contest the reminder is sent
+	ReminderHours int `json:"reminder_hours" db:"reminder_hours"`
+}
@@ -3,8 +3,17 @@
 import { getCall, postCall } from "./client";
 import { apiToast } from "../toast";
 
-export async function registerForContest(contestID: number) {
-	const res = await postCall(`/contest/${contestID}/register`, {});
+export async function registerForContest(contestID: number, category?: string) {
+	const res = await postCall(`/contest/${contestID}/register`, { category });
+	if (res.status === "error") {
+		apiToast(res);
+		return;
+	}
+	window.location.reload();
+}
+
+export async function createContestTeam(contestID: number, name: string) {
+	const res = await postCall(`/contest/${contestID}/createTeam`, { name });
 	if (res.status === "error") {
 		apiToast(res);
 		return;
@@ -25,15 +34,49 @@ export async function startContestRegistration(contestID: number) {
 	}
 }
 
-export async function answerQuestion(q: Question, text: string) {
-	let res = await postCall(`/contest/${q.contest_id}/answerQuestion`, { questionID: q.id, text });
+export async function startVirtualParticipation(contestID: number) {
+	const res = await postCall(`/contest/${contestID}/startVirtual`, {});
+	if (res.status === "error") {
+		apiToast(res);
+		return;
+	}
+	if (window.location.pathname.startsWith(`/contests/${contestID}`)) {
+		window.location.reload();
+	} else {
+		window.location.assign(`/contests/${contestID}`);
+	}
+}
+
+export async function answerQuestion(q: Question, text: string, isPublic: boolean = false) {
+	let res = await postCall(`/contest/${q.contest_id}/answerQuestion`, { questionID: q.id, text, public: isPublic });
 	apiToast(res);
 	if (res.status === "success") {
 		reloadQuestions();
+		if (isPublic) {
+			reloadAnnouncements();
+		}
 		return;
 	}
 }
 
+export async function claimQuestion(q: Question) {
+	let res = await postCall(`/contest/${q.contest_id}/claimQuestion`, { questionID: q.id });
+	apiToast(res);
+	reloadQuestions();
+}
+
+export async function unclaimQuestion(q: Question) {
+	let res = await postCall(`/contest/${q.contest_id}/unclaimQuestion`, { questionID: q.id });
+	apiToast(res);
+	reloadQuestions();
+}
+
+export async function assignQuestion(q: Question, username: string) {
+	let res = await postCall(`/contest/${q.contest_id}/assignQuestion`, { questionID: q.id, username });
+	apiToast(res);
+	reloadQuestions();
+}
+
 export async function updateAnnouncement(ann: Announcement, text: string) {
 	let res = await postCall(`/contest/${ann.contest_id}/updateAnnouncement`, { id: ann.id, text });
 	apiToast(res);
@@ -68,6 +111,15 @@ export async function getAllQuestions(contestID: number): Promise<Question[]> {
 	return res.data;
 }
 
+// getQuestionQueue returns the unanswered questions the current judge can answer: the unclaimed ones and the ones claimed by them
+export async function getQuestionQueue(contestID: number): Promise<Question[]> {
+	let res = await getCall<Question[]>(`/contest/${contestID}/questionQueue`, {});
+	if (res.status === "error") {
+		throw new Error(res.data);
+	}
+	return res.data;
+}
+
 export async function getUserQuestions(contestID: number): Promise<Question[]> {
 	if (window.platform_info.user_id <= 0) {
 		// Skip if unauthed
@@ -125,3 +177,18 @@ export function stopReloadingQnA() {
 	console.warn("Disabling question/answer reloading");
 	clearInterval(x);
 }
+
+// streamContestEvents listens for the contest's live updates, sent by the server as they happen.
+// Returns false if the browser can't receive them, in which case polling should be used instead
+export function streamContestEvents(contestID: number): boolean {
+	if (typeof EventSource === "undefined") {
+		return false;
+	}
+	const source = new EventSource(`/api/contest/${contestID}/events`);
+	source.addEventListener("announcement", () => reloadAnnouncements());
+	source.addEventListener("question", () => reloadQuestions());
+	source.addEventListener("leaderboard", (e) => {
+		document.dispatchEvent(new CustomEvent("kn-leaderboard-update", { detail: JSON.parse(e.data) }));
+	});
+	return true;
+}
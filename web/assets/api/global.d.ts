@@ -131,6 +131,11 @@ declare global {
 		response?: string;
 		author_id: number;
 		contest_id: number;
+		problem_id: number | null;
+		claimed_by: number | null;
+		claimed_at: string | null;
+		answered_by: number | null;
+		announcement_id: number | null;
 	};
 
 	type Announcement = {
@@ -138,5 +143,6 @@ declare global {
 		created_at: string;
 		contest_id: number;
 		text: string;
+		problem_id: number | null;
 	};
 }
@@ -5,7 +5,18 @@ import { dayjs, getGradient } from "../util";
 import getText from "../translation";
 import { sprintf } from "sprintf-js";
 import { fromBase64 } from "js-base64";
-import { answerQuestion, getAllQuestions, getUserQuestions, getAnnouncements, updateAnnouncement, deleteAnnouncement } from "../api/contest";
+import {
+	answerQuestion,
+	getAllQuestions,
+	getUserQuestions,
+	getAnnouncements,
+	updateAnnouncement,
+	deleteAnnouncement,
+	claimQuestion,
+	unclaimQuestion,
+	assignQuestion,
+	getQuestionQueue,
+} from "../api/contest";
 import { apiToast, createToast } from "../toast";
 import { BigSpinner, Paginator } from "./common";
 import { getCall, postCall } from "../api/client";
@@ -60,8 +71,10 @@ export function ContestCountdown({ target_time, type }: { target_time: string; t
 type LeaderboardResponse = {
 	problem_ordering: number[];
 	problem_names: Record<number, string>;
+	problem_labels: Record<number, string> | null;
 	entries: {
-		user: UserBrief;
+		user: UserBrief | null;
+		team?: { id: number; name: string; members: UserBrief[] };
 		scores: Record<number, number>;
 		total: number;
 
@@ -71,24 +84,59 @@ type LeaderboardResponse = {
 		freeze_time: string | null;
 		last_times: Record<number, number>;
 		attempts: Record<number, number>; // TODO: check if will still be null once finished
+
+		hack_score: number;
+		successful_hacks: number;
+		unsuccessful_hacks: number;
+
+		virtual: boolean;
+		category: string | null;
 	}[];
 
 	advanced_filter: boolean;
 
 	freeze_time?: string;
 	type: "classic" | "acm-icpc";
+	hacking: boolean;
+	system_testing: boolean;
+
+	virtual: boolean;
+	elapsed: number;
+
+	category: string | null;
 };
 
-export function ContestLeaderboard({ contestID, editor }: { contestID: number; editor: boolean }) {
+// entryKey uniquely identifies a leaderboard entry, which may be either a user or a team
+function entryKey(entry: LeaderboardResponse["entries"][number]): string {
+	return entry.team ? `t${entry.team.id}` : `u${entry.user!.id}`;
+}
+
+export function ContestLeaderboard({
+	contestID,
+	editor,
+	ended,
+	virtualRun,
+	categories,
+}: {
+	contestID: number;
+	editor: boolean;
+	ended: boolean;
+	virtualRun: boolean;
+	categories: string[];
+}) {
 	let [loading, setLoading] = useState(true);
-	let [problems, setProblems] = useState<{ id: number; name: string }[]>([]);
+	let [problems, setProblems] = useState<{ id: number; name: string; label?: string }[]>([]);
 	let [leaderboard, setLeaderboard] = useState<LeaderboardResponse | null>(null);
 	let [lastUpdated, setLastUpdated] = useState<string | null>(null);
 
 	let [generated, setGenerated] = useState<boolean | null>(null);
+	let [virtual, setVirtual] = useState<boolean>(virtualRun);
+	let [category, setCategory] = useState<string>("");
+	let [minRating, setMinRating] = useState<string>("");
+	let [maxRating, setMaxRating] = useState<string>("");
 
 	const firstSolves = useMemo(() => {
-		let firstSolves: Record<number, { minTime: number; userID: number }> = {};
+		let firstSolves: Record<number, { minTime: number; key: string }> = {};
 		if (leaderboard == null || typeof leaderboard.entries === "undefined") {
 			return {};
 		}
@@ -99,7 +147,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 					continue;
 				}
 				if (typeof firstSolves[problemID] == "undefined" || firstSolves[problemID].minTime > times[1]) {
-					firstSolves[problemID] = { minTime: times[1], userID: entry.user.id };
+					firstSolves[problemID] = { minTime: times[1], key: entryKey(entry) };
 				}
 			}
 		}
@@ -112,6 +160,10 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 		setLoading(true);
 		const res = await getCall<LeaderboardResponse>(`/contest/${contestID}/leaderboard`, {
 			generated_acc: generated == null ? undefined : generated,
+			virtual: virtual ? true : undefined,
+			category: category.length > 0 ? category : undefined,
+			min_rating: minRating.length > 0 ? minRating : undefined,
+			max_rating: maxRating.length > 0 ? maxRating : undefined,
 		});
 		if (res.status === "error") {
 			apiToast(res);
@@ -130,13 +182,48 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 		);
 		console.log(res.data);
 		setLeaderboard(res.data);
-		setProblems(res.data.problem_ordering.map((val) => ({ id: val, name: res.data.problem_names[val] })));
+		setProblems(res.data.problem_ordering.map((val) => ({ id: val, name: res.data.problem_names[val], label: res.data.problem_labels?.[val] })));
 		setLoading(false);
 	}
 
 	useEffect(() => {
 		loadLeaderboard().catch(console.error);
-	}, [contestID, generated]);
+	}, [contestID, generated, virtual, category, minRating, maxRating]);
+
+	useEffect(() => {
+		// Live updates only carry the official standings, so they are ignored while filtering or looking at the ghost leaderboard
+		if (virtual || generated != null || category.length > 0 || minRating.length > 0 || maxRating.length > 0) {
+			return;
+		}
+		function onLeaderboardUpdate(e: CustomEvent) {
+			const entry: LeaderboardResponse["entries"][number] = e.detail;
+			setLeaderboard((ld) => {
+				if (ld == null) {
+					return ld;
+				}
+				const entries = ld.entries.filter((val) => entryKey(val) != entryKey(entry));
+				entries.push(entry);
+				entries.sort((a, b) => {
+					if (ld.type == "acm-icpc") {
+						if (a.num_solved != b.num_solved) return b.num_solved - a.num_solved;
+						if (a.penalty != b.penalty) return a.penalty - b.penalty;
+					} else if (a.total != b.total) {
+						return b.total - a.total;
+					}
+					if (a.last_time == null || b.last_time == null) {
+						return a.last_time == null ? (b.last_time == null ? 0 : 1) : -1;
+					}
+					return dayjs(a.last_time).diff(b.last_time);
+				});
+				return { ...ld, entries };
+			});
+			if (entry.last_time != null) {
+				setLastUpdated((last) => (last == null || dayjs(last).isBefore(entry.last_time) ? entry.last_time : last));
+			}
+		}
+		document.addEventListener("kn-leaderboard-update", onLeaderboardUpdate);
+		return () => document.removeEventListener("kn-leaderboard-update", onLeaderboardUpdate);
+	}, [virtual, generated, category, minRating, maxRating]);
 
 	if (loading || leaderboard == null) {
 		return (
@@ -154,7 +241,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 			<button class="btn btn-blue mb-2" onClick={() => loadLeaderboard()}>
 				{getText("reload")}
 			</button>
-			{(generated != null || (leaderboard.advanced_filter && leaderboard.entries.filter((entry) => entry.user.generated).length > 0)) && (
+			{(generated != null || (leaderboard.advanced_filter && leaderboard.entries.filter((entry) => entry.user?.generated).length > 0)) && (
 				<label class="block mb-2">
 					<span class="form-label">{getText("participants.label")}:</span>
 					<select
@@ -170,7 +257,49 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 					</select>
 				</label>
 			)}
+			{categories.length > 0 && (
+				<label class="block mb-2">
+					<span class="form-label">{getText("participant_category")}:</span>
+					<select class="form-select" value={category} onChange={(e) => setCategory(e.currentTarget.value)}>
+						<option value="">{getText("participants.all")}</option>
+						{categories.map((cat) => (
+							<option value={cat} key={cat}>
+								{cat}
+							</option>
+						))}
+					</select>
+				</label>
+			)}
+			<div class="block mb-2">
+				<span class="form-label">{getText("rating")}:</span>
+				<input
+					type="number"
+					class="form-input mx-2 w-24"
+					placeholder={getText("min_rating")}
+					value={minRating}
+					onChange={(e) => setMinRating(e.currentTarget.value)}
+				/>
+				-
+				<input
+					type="number"
+					class="form-input mx-2 w-24"
+					placeholder={getText("max_rating")}
+					value={maxRating}
+					onChange={(e) => setMaxRating(e.currentTarget.value)}
+				/>
+			</div>
+			{ended && (
+				<label class="block mb-2">
+					<input type="checkbox" class="form-checkbox" checked={virtual} onChange={(e) => setVirtual(e.currentTarget.checked)} />
+					<span class="form-label ml-2">{getText("virtual_leaderboard")}</span>
+				</label>
+			)}
 			<div class="mb-2">
+				{leaderboard.virtual && (
+					<p>
+						{getText("virtual_leaderboard_elapsed")}: {formatDuration(leaderboard.elapsed, true, true)}
+					</p>
+				)}
 				<p>
 					{getText("last_updated_at")}: {lastUpdated ? dayjs(lastUpdated).format("DD/MM/YYYY HH:mm") : "-"}
 				</p>
@@ -179,6 +308,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 						{getText("freeze_time")}: {dayjs(leaderboard.freeze_time).format("DD/MM/YYYY HH:mm")}
 					</p>
 				)}
+				{leaderboard.system_testing && <p class="text-muted">{getText("contest.system_testing")}</p>}
 			</div>
 			<table class="kn-table table-fixed">
 				<thead>
@@ -201,9 +331,16 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 						)}
 						{problems.map((pb) => (
 							<th class="kn-table-cell" style={{ wordBreak: "break-all" }} scope="col" key={pb.id}>
-								<a href={`/contests/${contestID}/problems/${pb.id}`}>{pb.name}</a>
+								<a href={`/contests/${contestID}/problems/${pb.id}`} title={pb.label ? pb.name : undefined}>
+									{pb.label ?? pb.name}
+								</a>
 							</th>
 						))}
+						{leaderboard.type == "classic" && leaderboard.hacking && (
+							<th class="kn-table-cell w-1/12" style={{ wordBreak: "break-all" }} scope="col">
+								{getText("hacks")}
+							</th>
+						)}
 						{leaderboard.type == "classic" && (
 							<th class="kn-table-cell w-1/6" style={{ wordBreak: "break-all" }} scope="col">
 								{getText("total")}
@@ -213,12 +350,28 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 				</thead>
 				<tbody>
 					{leaderboard.entries.map((entry, idx) => (
-						<tr class="kn-table-row" key={entry.user.id}>
+						<tr class="kn-table-row" key={entryKey(entry)}>
 							<td class="kn-table-cell">{idx + 1}.</td>
 							<td class="kn-table-cell">
-								<a href={`/profile/${entry.user.name}`}>
-									{entry.user.display_name.length > 0 ? `${entry.user.display_name} (${entry.user.name})` : entry.user.name}
-								</a>
+								{entry.team ? (
+									<>
+										<span class="font-semibold">{entry.team.name}</span>
+										<span class="block text-sm text-muted">
+											{entry.team.members.map((member, idx) => (
+												<>
+													{idx > 0 && ", "}
+													<a href={`/profile/${member.name}`}>{member.name}</a>
+												</>
+											))}
+										</span>
+									</>
+								) : (
+									<a href={`/profile/${entry.user!.name}`}>
+										{entry.user!.display_name.length > 0 ? `${entry.user!.display_name} (${entry.user!.name})` : entry.user!.name}
+									</a>
+								)}
+								{entry.virtual && <span class="badge-lite text-sm ml-1">{getText("virtual_participant")}</span>}
+								{categories.length > 0 && entry.category && category.length == 0 && <span class="badge-lite text-sm ml-1">{entry.category}</span>}
 							</td>
 							{leaderboard?.type == "acm-icpc" && (
 								<>
@@ -229,10 +382,10 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 							{problems.map((pb) =>
 								leaderboard?.type == "classic" ? (
 									<td
-										class={"kn-table-cell" + (editor ? " cursor-pointer" : "")}
+										class={"kn-table-cell" + (editor && entry.user ? " cursor-pointer" : "")}
 										scope="col"
-										key={entry.user.name + pb.id + (editor ? "-e" : "-ne")}
-										onClick={() => editor && buildScoreBreakdownModal(pb.id, contestID, entry.user.id)}
+										key={entryKey(entry) + pb.id + (editor ? "-e" : "-ne")}
+										onClick={() => editor && entry.user && buildScoreBreakdownModal(pb.id, contestID, entry.user.id)}
 									>
 										{pb.id in entry.scores && entry.scores[pb.id] >= 0 ? entry.scores[pb.id] : "-"}
 									</td>
@@ -242,7 +395,7 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 										style={{
 											color: entry.scores[pb.id] >= 100 ? "black" : undefined,
 											backgroundColor: (() => {
-												if (typeof firstSolves[pb.id] !== "undefined" && firstSolves[pb.id].userID == entry.user.id) {
+												if (typeof firstSolves[pb.id] !== "undefined" && firstSolves[pb.id].key == entryKey(entry)) {
 													return "#51a300";
 												}
 												return getGradient(entry.scores[pb.id] >= 100 ? 1 : 0, 1);
@@ -266,13 +419,18 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 									<td class="kn-table-cell">-</td>
 								)
 							)}
+							{leaderboard?.type == "classic" && leaderboard.hacking && (
+								<td class="kn-table-cell" title={`+${entry.successful_hacks} / -${entry.unsuccessful_hacks}`}>
+									{entry.hack_score}
+								</td>
+							)}
 							{leaderboard?.type == "classic" && <td class="kn-table-cell">{entry.total}</td>}
 						</tr>
 					))}
 					{leaderboard.entries.length == 0 && (
 						<tr class="kn-table-row">
 							{/* TODO: Update here if header changes */}
-							<td class="kn-table-cell" colSpan={2 + (leaderboard.type === "acm-icpc" ? 2 : 1) + problems.length}>
+							<td class="kn-table-cell" colSpan={2 + (leaderboard.type === "acm-icpc" ? 2 : leaderboard.hacking ? 2 : 1) + problems.length}>
 								<h1>{getText("no_users")}</h1>
 							</td>
 						</tr>
@@ -284,11 +442,202 @@ export function ContestLeaderboard({ contestID, editor }: { contestID: number; e
 	);
 }
 
+type ResolverStep = {
+	user_id?: number;
+	team_id?: number;
+	problem_id: number;
+	solved: boolean;
+	attempts: number;
+	time: number;
+	prev_rank: number;
+	new_rank: number;
+	num_solved: number;
+	penalty: number;
+};
+
+type ResolverRow = {
+	key: string;
+	entry: LeaderboardResponse["entries"][number];
+	num_solved: number;
+	penalty: number;
+	cells: Record<number, { solved: boolean; attempts: number; time: number; pending: boolean }>;
+};
+
+function resolverStepKey(step: ResolverStep): string {
+	return typeof step.team_id !== "undefined" ? `t${step.team_id}` : `u${step.user_id}`;
+}
+
+function ContestResolver({ contestID }: { contestID: number }) {
+	let [loading, setLoading] = useState(true);
+	let [problems, setProblems] = useState<{ id: number; name: string; label?: string }[]>([]);
+	let [rows, setRows] = useState<ResolverRow[]>([]);
+	let [steps, setSteps] = useState<ResolverStep[]>([]);
+	let [stepIdx, setStepIdx] = useState(0);
+	let [highlight, setHighlight] = useState<string | null>(null);
+
+	async function load() {
+		setLoading(true);
+		const res = await getCall<{ frozen: LeaderboardResponse; steps: ResolverStep[] }>(`/contest/${contestID}/resolver`, {});
+		if (res.status === "error") {
+			apiToast(res);
+			return;
+		}
+		const frozen = res.data.frozen;
+		const pending = new Set(res.data.steps.map((step) => `${resolverStepKey(step)}-${step.problem_id}`));
+		setProblems(frozen.problem_ordering.map((val) => ({ id: val, name: frozen.problem_names[val], label: frozen.problem_labels?.[val] })));
+		setRows(
+			frozen.entries.map((entry) => {
+				const key = entryKey(entry);
+				let cells: ResolverRow["cells"] = {};
+				for (let pb of frozen.problem_ordering) {
+					cells[pb] = {
+						solved: entry.scores[pb] >= 100,
+						attempts: entry.attempts[pb] ?? 0,
+						time: entry.last_times[pb] ?? 0,
+						pending: pending.has(`${key}-${pb}`),
+					};
+				}
+				return { key, entry, num_solved: entry.num_solved, penalty: entry.penalty, cells };
+			})
+		);
+		setSteps(res.data.steps);
+		setStepIdx(0);
+		setHighlight(null);
+		setLoading(false);
+	}
+
+	function nextStep() {
+		if (stepIdx >= steps.length) {
+			return;
+		}
+		const step = steps[stepIdx];
+		const key = resolverStepKey(step);
+		setRows((rows) => {
+			const newRows = rows.filter((row) => row.key != key);
+			const row = rows.find((row) => row.key == key);
+			if (typeof row === "undefined") {
+				return rows;
+			}
+			const newRow: ResolverRow = {
+				...row,
+				num_solved: step.num_solved,
+				penalty: step.penalty,
+				cells: { ...row.cells, [step.problem_id]: { solved: step.solved, attempts: step.attempts, time: step.time, pending: false } },
+			};
+			newRows.splice(step.new_rank - 1, 0, newRow);
+			return newRows;
+		});
+		setHighlight(key);
+		setStepIdx(stepIdx + 1);
+	}
+
+	useEffect(() => {
+		load().catch(console.error);
+	}, [contestID]);
+
+	useEffect(() => {
+		function onKey(e: KeyboardEvent) {
+			if (e.key === " " || e.key === "ArrowRight") {
+				e.preventDefault();
+				nextStep();
+			}
+		}
+		document.addEventListener("keydown", onKey);
+		return () => document.removeEventListener("keydown", onKey);
+	}, [steps, stepIdx]);
+
+	if (loading) {
+		return <BigSpinner />;
+	}
+
+	return (
+		<>
+			<div class="mb-2">
+				<button class="btn btn-blue mr-2" onClick={() => nextStep()} disabled={stepIdx >= steps.length}>
+					{getText("resolver_next")}
+				</button>
+				<button class="btn mr-2" onClick={() => load()}>
+					{getText("reload")}
+				</button>
+				<span>{sprintf(getText("resolver_progress"), stepIdx, steps.length)}</span>
+			</div>
+			<table class="kn-table table-fixed">
+				<thead>
+					<tr>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("position")}
+						</th>
+						<th class="kn-table-cell w-1/5" scope="col">
+							{getText("name")}
+						</th>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("icpc_num_solved")}
+						</th>
+						<th class="kn-table-cell w-1/12" scope="col">
+							{getText("penalty")}
+						</th>
+						{problems.map((pb) => (
+							<th class="kn-table-cell" style={{ wordBreak: "break-all" }} scope="col" key={pb.id} title={pb.label ? pb.name : undefined}>
+								{pb.label ?? pb.name}
+							</th>
+						))}
+					</tr>
+				</thead>
+				<tbody>
+					{rows.map((row, idx) => (
+						<tr class={"kn-table-row" + (row.key == highlight ? " font-bold" : "")} key={row.key}>
+							<td class="kn-table-cell">{idx + 1}.</td>
+							<td class="kn-table-cell">{row.entry.team ? row.entry.team.name : row.entry.user!.display_name || row.entry.user!.name}</td>
+							<td class="kn-table-cell">{row.num_solved}</td>
+							<td class="kn-table-cell">{row.penalty}</td>
+							{problems.map((pb) => {
+								const cell = row.cells[pb.id];
+								if (cell.pending) {
+									return (
+										<td class="kn-table-cell" style={{ backgroundColor: "#facc15", color: "black" }} key={pb.id}>
+											<span class="block font-bold text-lg">?</span>
+										</td>
+									);
+								}
+								if (!cell.solved && cell.attempts == 0) {
+									return (
+										<td class="kn-table-cell" key={pb.id}>
+											-
+										</td>
+									);
+								}
+								return (
+									<td class="kn-table-cell" style={{ color: cell.solved ? "black" : undefined, backgroundColor: getGradient(cell.solved ? 1 : 0, 1) }} key={pb.id}>
+										<span class="block font-bold text-lg">
+											{cell.solved ? "+" : "-"} {cell.attempts > 0 && cell.attempts}
+										</span>
+										{cell.solved && <span class="block">{formatDuration(Math.floor(cell.time) * 60, true, true)}</span>}
+									</td>
+								);
+							})}
+						</tr>
+					))}
+				</tbody>
+			</table>
+		</>
+	);
+}
+
 function formatJSONTime(t: string, format_key: string): string {
 	return dayjs(t).format(getText(format_key));
 }
 
-export function AnnouncementView({ ann, canEditAnnouncement }: { ann: Announcement; canEditAnnouncement: boolean }) {
+// ProblemTitles maps the IDs of the contest problems to the titles they are shown with
+type ProblemTitles = Record<number, string>;
+
+function ProblemBadge({ problemID, problems }: { problemID: number | null; problems: ProblemTitles }) {
+	if (problemID == null) {
+		return <></>;
+	}
+	return <span class="badge mb-1">{problems[problemID] ?? `#${problemID}`}</span>;
+}
+
+export function AnnouncementView({ ann, canEditAnnouncement, problems }: { ann: Announcement; canEditAnnouncement: boolean; problems: ProblemTitles }) {
 	let [text, setText] = useState(ann.text);
 	let [expandAnnouncement, setExpandAnnouncement] = useState<boolean>(false);
 
@@ -316,6 +665,7 @@ export function AnnouncementView({ ann, canEditAnnouncement }: { ann: Announceme
 
 	return (
 		<div class="segment-panel">
+			<ProblemBadge problemID={ann.problem_id} problems={problems} />
 			<pre class="mt-2 mb-1">{text}</pre>
 			<p class="text-sm">{formatJSONTime(ann.created_at, "contest_timestamp_posted_format")}</p>
 			{canEditAnnouncement && (
@@ -333,13 +683,28 @@ export function AnnouncementView({ ann, canEditAnnouncement }: { ann: Announceme
 	);
 }
 
-export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question; canEditAnswer: boolean; userLoadable: boolean }) {
+const CANNED_RESPONSES = ["yes", "no", "no_comment", "read_statement", "invalid_question"];
+
+export function QuestionView({
+	q,
+	canEditAnswer,
+	userLoadable,
+	problems,
+}: {
+	q: Question;
+	canEditAnswer: boolean;
+	userLoadable: boolean;
+	problems: ProblemTitles;
+}) {
 	let [response, setResponse] = useState<string>(q.response ?? "");
+	let [answerPublicly, setAnswerPublicly] = useState<boolean>(false);
 	let [expandAnswer, setExpandAnswer] = useState<boolean>(false);
 	let [user, setUser] = useState<UserBrief | null>(null);
+	let [claimer, setClaimer] = useState<UserBrief | null>(null);
+	let [assignee, setAssignee] = useState<string>("");
 
 	async function doAnswer() {
-		await answerQuestion(q, response);
+		await answerQuestion(q, response, answerPublicly);
 		setExpandAnswer(false);
 	}
 
@@ -352,6 +717,86 @@ export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question;
 		}
 	}, [q, userLoadable]);
 
+	useEffect(() => {
+		if (canEditAnswer && q.claimed_by != null && q.claimed_by != window.platform_info.user_id) {
+			defaultClient
+				.getUser(q.claimed_by)
+				.then((d) => setClaimer(d))
+				.catch(console.error);
+		} else {
+			setClaimer(null);
+		}
+	}, [q, canEditAnswer]);
+
+	const claimedByMe = q.claimed_by != null && q.claimed_by == window.platform_info.user_id;
+	const claimedByOther = q.claimed_by != null && !claimedByMe;
+
+	let claimComponent = <></>;
+	if (canEditAnswer && q.response == null) {
+		claimComponent = (
+			<div class="my-2">
+				{claimedByMe && (
+					<>
+						<span class="badge badge-green mr-2">{getText("question_claimed_by_you")}</span>
+						<button class="btn mr-2" onClick={() => unclaimQuestion(q)}>
+							{getText("unclaim_question")}
+						</button>
+					</>
+				)}
+				{claimedByOther && (
+					<>
+						<span class="badge badge-red mr-2">
+							{getText("question_claimed_by")} {claimer == null ? getText("loading") : claimer.name}
+						</span>
+						<button class="btn mr-2" onClick={() => unclaimQuestion(q)}>
+							{getText("unclaim_question")}
+						</button>
+					</>
+				)}
+				{q.claimed_by == null && (
+					<button class="btn btn-blue mr-2" onClick={() => claimQuestion(q)}>
+						{getText("claim_question")}
+					</button>
+				)}
+				<span class="inline-block">
+					<input
+						type="text"
+						class="form-input mr-2"
+						placeholder={getText("username")}
+						value={assignee}
+						onInput={(e) => setAssignee(e.currentTarget.value)}
+					/>
+					<button class="btn" onClick={() => assignQuestion(q, assignee)} disabled={assignee.length == 0}>
+						{getText("assign_question")}
+					</button>
+				</span>
+			</div>
+		);
+	}
+
+	const answerForm = (
+		<>
+			<div class="my-2">
+				{CANNED_RESPONSES.map((key) => (
+					<button class="btn mr-2 mb-1" key={key} onClick={() => setResponse(getText(`canned_response.${key}`))}>
+						{getText(`canned_response.${key}`)}
+					</button>
+				))}
+			</div>
+			<label class="block my-2">
+				<textarea class="form-textarea" value={response} onInput={(e) => setResponse(e.currentTarget.value)} />
+			</label>
+			{q.announcement_id == null ? (
+				<label class="block my-2">
+					<input type="checkbox" class="form-checkbox" checked={answerPublicly} onChange={(e) => setAnswerPublicly(e.currentTarget.checked)} />{" "}
+					<span class="form-label">{getText("answer_publicly")}</span>
+				</label>
+			) : (
+				<p class="text-sm text-muted my-2">{getText("answered_publicly_explainer")}</p>
+			)}
+		</>
+	);
+
 	let responseComponent = <></>;
 	if (q.response != null && !canEditAnswer) {
 		// View answer
@@ -373,17 +818,15 @@ export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question;
 							[{getText("hide")}]
 						</a>
 					</h3>
-					<label class="block my-2">
-						<textarea class="form-textarea" value={response} onInput={(e) => setResponse(e.currentTarget.value)} />
-					</label>
-					<button class="btn btn-blue" onClick={doAnswer}>
+					{answerForm}
+					<button class="btn btn-blue" onClick={doAnswer} disabled={claimedByOther}>
 						{getText("button.answer")}
 					</button>
 				</>
 			);
 		} else {
 			responseComponent = (
-				<button class="btn btn-blue mt-2" onClick={() => setExpandAnswer(!expandAnswer)}>
+				<button class="btn btn-blue mt-2" onClick={() => setExpandAnswer(!expandAnswer)} disabled={claimedByOther}>
 					{getText("button.respond")}
 				</button>
 			);
@@ -409,9 +852,7 @@ export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question;
 								[{getText("button.cancel")}]
 							</a>
 						</h3>
-						<label class="block my-2">
-							<textarea class="form-textarea" value={response} onInput={(e) => setResponse(e.currentTarget.value)} />
-						</label>
+						{answerForm}
 						<button class="btn btn-blue" onClick={doAnswer}>
 							{getText("button.update")}
 						</button>
@@ -426,6 +867,8 @@ export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question;
 
 	return (
 		<div class="segment-panel">
+			<ProblemBadge problemID={q.problem_id} problems={problems} />
+			{q.announcement_id != null && <span class="badge badge-green mb-1 ml-1">{getText("answered_publicly")}</span>}
 			<pre class="mt-2 mb-1">{q.text}</pre>
 			<p class="text-sm">{formatJSONTime(q.asked_at, "contest_timestamp_asked_format")}</p>
 			{userLoadable && (
@@ -433,13 +876,16 @@ export function QuestionView({ q, canEditAnswer, userLoadable }: { q: Question;
 					{getText("author")}: {user == null ? getText("loading") : <a href={`/profile/${user.name}`}>{user.name}</a>}
 				</p>
 			)}
+			{claimComponent}
 			{responseComponent}
 		</div>
 	);
 }
 
-export function QuestionManager({ initialQuestions, contestID }: { initialQuestions: Question[]; contestID: number }) {
+export function QuestionManager({ initialQuestions, contestID, problems }: { initialQuestions: Question[]; contestID: number; problems: ProblemTitles }) {
 	let [questions, setQuestions] = useState(initialQuestions);
+	let [queue, setQueue] = useState<Question[] | null>(null);
+	let [showQueue, setShowQueue] = useState<boolean>(false);
 
 	const answeredQuestions = useMemo(
 		() =>
@@ -459,6 +905,7 @@ export function QuestionManager({ initialQuestions, contestID }: { initialQuesti
 	async function onQuestionReload() {
 		const qs = await getAllQuestions(contestID);
 		setQuestions(qs);
+		setQueue(await getQuestionQueue(contestID));
 	}
 
 	useEffect(() => {
@@ -466,18 +913,34 @@ export function QuestionManager({ initialQuestions, contestID }: { initialQuesti
 		return () => document.removeEventListener("kn-contest-question-reload", onQuestionReload);
 	}, []);
 
+	useEffect(() => {
+		if (showQueue && queue == null) {
+			getQuestionQueue(contestID).then(setQueue).catch(console.error);
+		}
+	}, [showQueue]);
+
+	const shownUnanswered = showQueue ? queue ?? [] : unansweredQuestions;
+
 	return (
 		<div>
 			{questions.length == 0 && <p>{getText("noQuestions")}</p>}
-			{unansweredQuestions.length > 0 && <h3>{getText("unanswered_questions")}:</h3>}
-			{unansweredQuestions.map((q) => (
-				<QuestionView q={q} canEditAnswer={true} userLoadable={true} key={q.id} />
+			{unansweredQuestions.length > 0 && (
+				<>
+					<h3>{getText("unanswered_questions")}:</h3>
+					<label class="block my-2">
+						<input type="checkbox" class="form-checkbox" checked={showQueue} onChange={(e) => setShowQueue(e.currentTarget.checked)} />{" "}
+						<span class="form-label">{getText("question_queue_only")}</span>
+					</label>
+				</>
+			)}
+			{shownUnanswered.map((q) => (
+				<QuestionView q={q} canEditAnswer={true} userLoadable={true} problems={problems} key={q.id} />
 			))}
 			{answeredQuestions.length > 0 && (
 				<details>
 					<summary>{getText("answered_questions")}</summary>
 					{answeredQuestions.map((q) => (
-						<QuestionView q={q} canEditAnswer={true} userLoadable={true} key={q.id} />
+						<QuestionView q={q} canEditAnswer={true} userLoadable={true} problems={problems} key={q.id} />
 					))}
 				</details>
 			)}
@@ -485,7 +948,7 @@ export function QuestionManager({ initialQuestions, contestID }: { initialQuesti
 	);
 }
 
-export function QuestionList({ initialQuestions, contestID }: { initialQuestions: Question[]; contestID: number }) {
+export function QuestionList({ initialQuestions, contestID, problems }: { initialQuestions: Question[]; contestID: number; problems: ProblemTitles }) {
 	let [questions, setQuestions] = useState(initialQuestions);
 
 	const answeredQuestions = useMemo(
@@ -525,7 +988,7 @@ export function QuestionList({ initialQuestions, contestID }: { initialQuestions
 				<div class="segment-panel">
 					<h2>{getText("unanswered_questions")}</h2>
 					{unansweredQuestions.map((q) => (
-						<QuestionView q={q} canEditAnswer={false} userLoadable={false} key={q.id} />
+						<QuestionView q={q} canEditAnswer={false} userLoadable={false} problems={problems} key={q.id} />
 					))}
 				</div>
 			)}
@@ -533,7 +996,7 @@ export function QuestionList({ initialQuestions, contestID }: { initialQuestions
 				<div class="segment-panel">
 					<h2>{getText("answered_questions")}</h2>
 					{answeredQuestions.map((q) => (
-						<QuestionView q={q} canEditAnswer={false} userLoadable={false} key={q.id} />
+						<QuestionView q={q} canEditAnswer={false} userLoadable={false} problems={problems} key={q.id} />
 					))}
 				</div>
 			)}
@@ -541,7 +1004,17 @@ export function QuestionList({ initialQuestions, contestID }: { initialQuestions
 	);
 }
 
-function AnnouncementList({ initialAnnouncements, contestID, canEdit }: { initialAnnouncements: Announcement[]; contestID: number; canEdit: boolean }) {
+function AnnouncementList({
+	initialAnnouncements,
+	contestID,
+	canEdit,
+	problems,
+}: {
+	initialAnnouncements: Announcement[];
+	contestID: number;
+	canEdit: boolean;
+	problems: ProblemTitles;
+}) {
 	let [announcements, setAnnouncements] = useState(initialAnnouncements);
 
 	async function onAnnouncementReload() {
@@ -559,7 +1032,7 @@ function AnnouncementList({ initialAnnouncements, contestID, canEdit }: { initia
 			<h2>{getText("announcements")}</h2>
 			{announcements.length == 0 && <p>{getText("noAnnouncements")}</p>}
 			{announcements.map((ann) => (
-				<AnnouncementView ann={ann} canEditAnnouncement={canEdit} key={ann.id} />
+				<AnnouncementView ann={ann} canEditAnnouncement={canEdit} problems={problems} key={ann.id} />
 			))}
 		</>
 	);
@@ -624,6 +1097,7 @@ type ContestRegistration = {
 	user_id: number;
 	individual_start?: string;
 	individual_end?: string;
+	category: string | null;
 };
 
 type ContestRegRez = {
@@ -631,12 +1105,13 @@ type ContestRegRez = {
 	registration: ContestRegistration;
 };
 
-function ContestRegistrations(params: { contestid: string; usacomode: string }) {
+function ContestRegistrations(params: { contestid: string; usacomode: string; categories: string }) {
 	const contestID = parseInt(params.contestid);
 	if (isNaN(contestID)) {
 		throw new Error("Invalid contest ID");
 	}
 	const usacoMode = params.usacomode == "true";
+	const categories: string[] = params.categories ? JSON.parse(fromBase64(params.categories)) ?? [] : [];
 
 	let [users, setUsers] = useState<ContestRegRez[]>([]);
 	let [page, setPage] = useState<number>(1);
@@ -704,6 +1179,11 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 									{getText("started_at")}
 								</th>
 							)}
+							{categories.length > 0 && (
+								<th class="kn-table-cell" scope="col">
+									{getText("participant_category")}
+								</th>
+							)}
 							<th class="kn-table-cell" scope="col">
 								{getText("action")}
 							</th>
@@ -732,6 +1212,29 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 										)}
 									</td>
 								)}
+								{categories.length > 0 && (
+									<td class="kn-table-cell">
+										<select
+											class="form-select"
+											value={user.registration.category ?? ""}
+											onChange={async (e) => {
+												let res = await postCall(`/contest/${contestID}/update/registrationCategory`, {
+													name: user.user.name,
+													category: e.currentTarget.value,
+												});
+												apiToast(res);
+												await poll();
+											}}
+										>
+											<option value="">-</option>
+											{categories.map((cat) => (
+												<option value={cat} key={cat}>
+													{cat}
+												</option>
+											))}
+										</select>
+									</td>
+								)}
 								<td class="kn-table-cell">
 									<button
 										class="btn btn-red"
@@ -758,31 +1261,38 @@ function ContestRegistrations(params: { contestid: string; usacomode: string })
 	);
 }
 
-function AnnouncementListDOM({ encoded, contestid, canedit }: { encoded: string; contestid: string; canedit: string }) {
+function decodeProblemTitles(problems?: string): ProblemTitles {
+	if (typeof problems === "undefined" || problems.length == 0) {
+		return {};
+	}
+	return JSON.parse(fromBase64(problems));
+}
+
+function AnnouncementListDOM({ encoded, contestid, canedit, problems }: { encoded: string; contestid: string; canedit: string; problems?: string }) {
 	const q: Announcement[] = JSON.parse(fromBase64(encoded));
 	const contestID = parseInt(contestid);
 	if (isNaN(contestID)) {
 		throw new Error("Invalid contest ID");
 	}
-	return <AnnouncementList initialAnnouncements={q} canEdit={canedit == "true"} contestID={contestID} />;
+	return <AnnouncementList initialAnnouncements={q} canEdit={canedit == "true"} contestID={contestID} problems={decodeProblemTitles(problems)} />;
 }
 
-function QuestionListDOM({ encoded, contestid }: { encoded: string; contestid: string }) {
+function QuestionListDOM({ encoded, contestid, problems }: { encoded: string; contestid: string; problems?: string }) {
 	const q: Question[] = JSON.parse(fromBase64(encoded));
 	const contestID = parseInt(contestid);
 	if (isNaN(contestID)) {
 		throw new Error("Invalid contest ID");
 	}
-	return <QuestionList initialQuestions={q} contestID={contestID} />;
+	return <QuestionList initialQuestions={q} contestID={contestID} problems={decodeProblemTitles(problems)} />;
 }
 
-function QuestionManagerDOM({ encoded, contestid }: { encoded: string; contestid: string }) {
+function QuestionManagerDOM({ encoded, contestid, problems }: { encoded: string; contestid: string; problems?: string }) {
 	const q: Question[] = JSON.parse(fromBase64(encoded));
 	const contestID = parseInt(contestid);
 	if (isNaN(contestID)) {
 		throw new Error("Invalid contest ID");
 	}
-	return <QuestionManager initialQuestions={q} contestID={contestID} />;
+	return <QuestionManager initialQuestions={q} contestID={contestID} problems={decodeProblemTitles(problems)} />;
 }
 
 function CommunicationAnnouncerDOM({ contestid, contesteditor }: { contestid: string; contesteditor: string }) {
@@ -793,18 +1303,42 @@ function CommunicationAnnouncerDOM({ contestid, contesteditor }: { contestid: st
 	return <CommunicationAnnouncer contestID={contestID} contestEditor={contesteditor == "true"} />;
 }
 
-function ContestLeaderboardDOM({ contestid, editor }: { contestid: string; editor: string }) {
+function ContestResolverDOM({ contestid }: { contestid: string }) {
+	const contestID = parseInt(contestid);
+	if (isNaN(contestID)) {
+		throw new Error("Invalid contest ID");
+	}
+	return <ContestResolver contestID={contestID} />;
+}
+
+function ContestLeaderboardDOM({
+	contestid,
+	editor,
+	ended,
+	virtualrun,
+	categories,
+}: {
+	contestid: string;
+	editor: string;
+	ended: string;
+	virtualrun: string;
+	categories: string;
+}) {
 	const contestID = parseInt(contestid);
 	if (isNaN(contestID)) {
 		throw new Error("Invalid contest ID");
 	}
-	return <ContestLeaderboard contestID={contestID} editor={editor === "true"} />;
+	const cats: string[] = categories ? JSON.parse(fromBase64(categories)) ?? [] : [];
+	return (
+		<ContestLeaderboard contestID={contestID} editor={editor === "true"} ended={ended === "true"} virtualRun={virtualrun === "true"} categories={cats} />
+	);
 }
 
-register(QuestionManagerDOM, "kn-question-mgr", ["encoded", "contestid"]);
-register(QuestionListDOM, "kn-questions", ["encoded", "contestid"]);
-register(AnnouncementListDOM, "kn-announcements", ["encoded", "contestid", "canedit"]);
+register(QuestionManagerDOM, "kn-question-mgr", ["encoded", "contestid", "problems"]);
+register(QuestionListDOM, "kn-questions", ["encoded", "contestid", "problems"]);
+register(AnnouncementListDOM, "kn-announcements", ["encoded", "contestid", "canedit", "problems"]);
 register(ContestCountdown, "kn-contest-countdown", ["target_time", "type"]);
 register(CommunicationAnnouncerDOM, "kn-comm-announcer", ["contestid", "contesteditor"]);
-register(ContestLeaderboardDOM, "kn-leaderboard", ["contestid", "editor"]);
-register(ContestRegistrations, "kn-contest-registrations", ["contestid", "usacomode"]);
+register(ContestLeaderboardDOM, "kn-leaderboard", ["contestid", "editor", "ended", "virtualrun", "categories"]);
+register(ContestRegistrations, "kn-contest-registrations", ["contestid", "usacomode", "categories"]);
+register(ContestResolverDOM, "kn-contest-resolver", ["contestid"]);
@@ -15,3 +15,4 @@ export * from "./problem_search";
 export * from "./tags";
 export * from "./modal";
 export * from "./glossary";
+export * from "./rating_chart";
@@ -0,0 +1,75 @@
+import { h, Fragment } from "preact";
+import register from "preact-custom-element";
+import { fromBase64 } from "js-base64";
+import getText from "../translation";
+import { dayjs } from "../util";
+
+type RatingChange = {
+	contest_id: number;
+	rank: number;
+	old_rating: number;
+	new_rating: number;
+	contest_name: string;
+	contest_end_time: string;
+};
+
+const CHART_WIDTH = 800;
+const CHART_HEIGHT = 250;
+const CHART_PADDING = 40;
+
+export function RatingChart({ history }: { history: RatingChange[] }) {
+	if (history.length == 0) {
+		return <p>{getText("unrated")}</p>;
+	}
+	const ratings = [history[0].old_rating, ...history.map((change) => change.new_rating)];
+	// Round the bounds to the nearest 100 so the grid lines are meaningful
+	const minRating = Math.floor((Math.min(...ratings) - 50) / 100) * 100;
+	const maxRating = Math.ceil((Math.max(...ratings) + 50) / 100) * 100;
+
+	const x = (idx: number) => CHART_PADDING + (history.length == 1 ? (CHART_WIDTH - 2 * CHART_PADDING) / 2 : (idx * (CHART_WIDTH - 2 * CHART_PADDING)) / (history.length - 1));
+	const y = (rating: number) => CHART_HEIGHT - CHART_PADDING - ((rating - minRating) * (CHART_HEIGHT - 2 * CHART_PADDING)) / (maxRating - minRating);
+
+	const gridLines: number[] = [];
+	const step = Math.max(100, Math.ceil((maxRating - minRating) / 500) * 100);
+	for (let rating = minRating; rating <= maxRating; rating += step) {
+		gridLines.push(rating);
+	}
+
+	return (
+		<>
+			<svg class="w-full" viewBox={`0 0 ${CHART_WIDTH} ${CHART_HEIGHT}`}>
+				{gridLines.map((rating) => (
+					<g key={rating}>
+						<line x1={CHART_PADDING} x2={CHART_WIDTH - CHART_PADDING} y1={y(rating)} y2={y(rating)} stroke="currentColor" stroke-opacity="0.15" />
+						<text x={CHART_PADDING - 5} y={y(rating) + 4} text-anchor="end" font-size="12" fill="currentColor">
+							{rating}
+						</text>
+					</g>
+				))}
+				<polyline
+					fill="none"
+					stroke="#1c64f2"
+					stroke-width="2"
+					points={history.map((change, idx) => `${x(idx)},${y(change.new_rating)}`).join(" ")}
+				/>
+				{history.map((change, idx) => (
+					<a href={`/contests/${change.contest_id}/leaderboard`} key={change.contest_id}>
+						<circle cx={x(idx)} cy={y(change.new_rating)} r="4" fill="#1c64f2">
+							<title>
+								{`${change.contest_name} (${dayjs(change.contest_end_time).format("DD/MM/YYYY")})\n${getText("position")}: ${change.rank}\n${getText(
+									"rating"
+								)}: ${change.new_rating} (${change.new_rating >= change.old_rating ? "+" : ""}${change.new_rating - change.old_rating})`}
+							</title>
+						</circle>
+					</a>
+				))}
+			</svg>
+		</>
+	);
+}
+
+function RatingChartDOM({ enc }: { enc: string }) {
+	return <RatingChart history={JSON.parse(fromBase64(enc))} />;
+}
+
+register(RatingChartDOM, "kn-rating-chart", ["enc"]);
@@ -508,6 +508,7 @@ export class SubmissionManager extends Component<{ id: number; initialData: Full
 	poll_mu: boolean;
 	finished: boolean;
 	poller: number | null;
+	events: EventSource | null;
 	constructor(props) {
 		super();
 		this.poll_mu = false;
@@ -517,6 +518,7 @@ export class SubmissionManager extends Component<{ id: number; initialData: Full
 		};
 
 		this.poller = null;
+		this.events = null;
 	}
 
 	async componentDidMount() {
@@ -524,15 +526,35 @@ export class SubmissionManager extends Component<{ id: number; initialData: Full
 			await this.poll();
 		}
 		if (!this.finished) {
+			let interval = 2000;
+			if (typeof EventSource !== "undefined" && window.platform_info.user_id > 0) {
+				// Progress is pushed by the server, polling is just a fallback
+				this.events = new EventSource("/api/events");
+				const onEvent = async (e: MessageEvent) => {
+					const data = JSON.parse(e.data);
+					if ((data.submission_id ?? data.id) == this.props.id) {
+						await this.poll();
+					}
+				};
+				this.events.addEventListener("submission", onEvent);
+				this.events.addEventListener("subtest", onEvent);
+				interval = 10000;
+			}
 			console.info("Started poller");
 			this.poller = setInterval(async () => {
 				document.dispatchEvent(new CustomEvent("kn-poll"));
 				await this.poll();
-			}, 2000);
+			}, interval);
 		}
 	}
 
+	componentWillUnmount() {
+		this.stopPoller();
+	}
+
 	stopPoller() {
+		this.events?.close();
+		this.events = null;
 		if (this.poller == null) {
 			return;
 		}
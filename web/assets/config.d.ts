@@ -6,6 +6,7 @@ interface CustomEventMap {
 	"kn-upload-update": CustomEvent;
 	"kn-contest-question-reload": CustomEvent;
 	"kn-contest-announcement-reload": CustomEvent;
+	"kn-leaderboard-update": CustomEvent;
 }
 
 export declare global {
@@ -103,9 +103,13 @@ export function navigateBack() {
 		window.location.assign("/");
 		return;
 	}
-	locURL.pathname = val;
-	locURL.search = "";
-	window.location.assign(locURL);
+	// The destination may hold a query string (such as OAuth authorization requests), but it must stay on this site
+	let dest = new URL(val, locURL.origin);
+	if (dest.origin != locURL.origin) {
+		window.location.assign("/");
+		return;
+	}
+	window.location.assign(dest);
 }
 
 document.addEventListener("DOMContentLoaded", () => {
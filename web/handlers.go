@@ -92,6 +92,80 @@ func (rt *Web) discordLink() http.HandlerFunc {
 	}
 }
 
+// externalStateCookie binds the external login request to the browser that started it,
+// so users can't be tricked into finishing a sign in started by someone else
+const externalStateCookie = "kn-external-login"
+
+// safeBackPath returns the local page the user should return to, ignoring URLs pointing to other sites.
+// Browsers treat backslashes like slashes, so "/\example.com" is rejected just like "//example.com"
+func safeBackPath(back string) string {
+	u, err := url.Parse(back)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(back, "//") || strings.ContainsRune(back, '\\') {
+		return "/"
+	}
+	return u.RequestURI()
+}
+
+func (rt *Web) redirectToExternalLogin(w http.ResponseWriter, r *http.Request, linkUserID *int, back string) {
+	loginURL, err := rt.base.ExternalLoginURL(r.Context(), chi.URLParam(r, "provider"), linkUserID, back)
+	if err != nil {
+		rt.statusPage(w, r, err.Code, err.Text)
+		return
+	}
+	// The state is the only query parameter of the callback that identifies the request
+	u, _ := url.Parse(loginURL)
+	http.SetCookie(w, &http.Cookie{
+		Name:     externalStateCookie,
+		Value:    u.Query().Get("state"),
+		Path:     "/login/external",
+		MaxAge:   int((15 * time.Minute).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, loginURL, http.StatusTemporaryRedirect)
+}
+
+func (rt *Web) externalLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rt.redirectToExternalLogin(w, r, nil, safeBackPath(r.FormValue("back")))
+	}
+}
+
+func (rt *Web) externalLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rt.redirectToExternalLogin(w, r, &util.UserBrief(r).ID, "/profile/linked")
+	}
+}
+
+func (rt *Web) externalLoginCallback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if errCode := r.FormValue("error"); errCode != "" {
+			rt.statusPage(w, r, 400, cmp.Or(r.FormValue("error_description"), errCode))
+			return
+		}
+		state := r.FormValue("state")
+		if c, err := r.Cookie(externalStateCookie); err != nil || c.Value != state {
+			rt.statusPage(w, r, 400, "Login request expired, please try again")
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: externalStateCookie, Value: "", Path: "/login/external", MaxAge: -1})
+
+		ip, ua := rt.base.GetRequestInfo(r)
+		res, err := rt.base.HandleExternalLogin(r.Context(), util.UserBrief(r), chi.URLParam(r, "provider"), state, r.FormValue("code"), ip, &ua)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Text)
+			return
+		}
+		if res.SessionID != "" {
+			// Mirrors the cookies set by the frontend after logging in with a password
+			expires := time.Now().AddDate(0, 0, 29)
+			http.SetCookie(w, &http.Cookie{Name: "kn-sessionid", Value: res.SessionID, Path: "/", Expires: expires, SameSite: http.SameSiteLaxMode})
+			http.SetCookie(w, &http.Cookie{Name: "kn-session-check-date", Value: strconv.FormatInt(time.Now().AddDate(0, 0, 10).UnixMilli(), 10), Path: "/", Expires: expires, SameSite: http.SameSiteLaxMode})
+		}
+		http.Redirect(w, r, safeBackPath(res.Back), http.StatusTemporaryRedirect)
+	}
+}
+
 func (rt *Web) index() http.HandlerFunc {
 	templ := rt.parse(nil, "index.html", "modals/pblist.html", "modals/pbs.html", "modals/contest_brief.html", "modals/login.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -553,7 +627,11 @@ func (rt *Web) debugPage() http.HandlerFunc {
 func (rt *Web) submission() http.HandlerFunc {
 	templ := rt.parse(nil, "submission.html")
 	return func(w http.ResponseWriter, r *http.Request) {
-		rt.runTempl(w, r, templ, &SubParams{util.Submission(r)})
+		sub := util.Submission(r)
+		rt.runTempl(w, r, templ, &SubParams{
+			Submission: sub,
+			CanHack:    rt.base.CanHackSubmission(r.Context(), &sub.Submission, util.UserBrief(r)),
+		})
 	}
 }
 
@@ -688,21 +766,10 @@ func (rt *Web) problem() http.HandlerFunc {
 			atts = newAtts
 		}
 
-		langs := eval.Langs
-		if evalSettings, err := rt.base.ProblemSettings(r.Context(), util.Problem(r).ID); err != nil {
-			if !errors.Is(err, context.Canceled) {
-				zap.S().Warn("Error getting problem settings:", err, util.Problem(r).ID)
-			}
+		langs, err1 := rt.submitLanguages(r)
+		if err1 != nil {
 			rt.statusPage(w, r, 500, "Couldn't get problem settings")
 			return
-		} else if len(evalSettings.LanguageWhitelist) > 0 {
-			newLangs := make(map[string]eval.Language)
-			for name, lang := range langs {
-				if slices.Contains(evalSettings.LanguageWhitelist, name) {
-					newLangs[name] = lang
-				}
-			}
-			langs = newLangs
 		}
 
 		var tags = []*kilonova.Tag{}
@@ -760,6 +827,39 @@ func (rt *Web) problem() http.HandlerFunc {
 	}
 }
 
+// submitLanguages returns the languages that can be used to submit to the current problem.
+// Inside a contest, the contest's language restrictions also apply
+func (rt *Web) submitLanguages(r *http.Request) (map[string]eval.Language, error) {
+	evalSettings, err := rt.base.ProblemSettings(r.Context(), util.Problem(r).ID)
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			zap.S().Warn("Error getting problem settings:", err, util.Problem(r).ID)
+		}
+		return nil, err
+	}
+	whitelist := evalSettings.LanguageWhitelist
+	if util.Contest(r) != nil {
+		if pbSettings, err := rt.base.ContestProblemSetting(r.Context(), util.Contest(r).ID, util.Problem(r).ID); err == nil && len(pbSettings.Languages) > 0 {
+			whitelist = slices.DeleteFunc(slices.Clone(pbSettings.Languages), func(name string) bool {
+				return len(whitelist) > 0 && !slices.Contains(whitelist, name)
+			})
+			if len(whitelist) == 0 {
+				return map[string]eval.Language{}, nil
+			}
+		}
+	}
+	if len(whitelist) == 0 {
+		return eval.Langs, nil
+	}
+	langs := make(map[string]eval.Language)
+	for name, lang := range eval.Langs {
+		if slices.Contains(whitelist, name) {
+			langs[name] = lang
+		}
+	}
+	return langs, nil
+}
+
 func (rt *Web) problemSubmissions() http.HandlerFunc {
 	templ := rt.parse(nil, "problem/pb_submissions.html", "problem/topbar.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -774,21 +874,10 @@ func (rt *Web) problemSubmissions() http.HandlerFunc {
 func (rt *Web) problemSubmit() http.HandlerFunc {
 	templ := rt.parse(nil, "problem/pb_submit.html", "problem/topbar.html", "modals/contest_sidebar.html", "modals/pb_submit_form.html")
 	return func(w http.ResponseWriter, r *http.Request) {
-		langs := eval.Langs
-		if evalSettings, err := rt.base.ProblemSettings(r.Context(), util.Problem(r).ID); err != nil {
-			if !errors.Is(err, context.Canceled) {
-				zap.S().Warn("Error getting problem settings:", err, util.Problem(r).ID)
-			}
+		langs, err := rt.submitLanguages(r)
+		if err != nil {
 			rt.statusPage(w, r, 500, "Couldn't get problem settings")
 			return
-		} else if len(evalSettings.LanguageWhitelist) > 0 {
-			newLangs := make(map[string]eval.Language)
-			for name, lang := range langs {
-				if slices.Contains(evalSettings.LanguageWhitelist, name) {
-					newLangs[name] = lang
-				}
-			}
-			langs = newLangs
 		}
 
 		rt.runTempl(w, r, templ, &ProblemTopbarParams{
@@ -1160,10 +1249,21 @@ func (rt *Web) createContest() http.HandlerFunc {
 func (rt *Web) contest() http.HandlerFunc {
 	templ := rt.parse(nil, "contest/view.html", "problem/topbar.html", "modals/pbs.html", "modals/contest_sidebar.html")
 	return func(w http.ResponseWriter, r *http.Request) {
+		var published bool
+		if util.Contest(r).Ended() && util.UserBrief(r).IsAuthed() && rt.base.PDFRenderingEnabled() {
+			settings, err := rt.base.CertificateSettings(r.Context(), util.Contest(r).ID)
+			if err == nil && settings.Published {
+				_, err := rt.base.UserContestCertificate(r.Context(), util.Contest(r).ID, util.UserBrief(r).ID)
+				published = err == nil
+			}
+		}
+
 		rt.runTempl(w, r, templ, &ContestParams{
 			Topbar: rt.problemTopbar(r, "contest_general", -1),
 
 			Contest: util.Contest(r),
+
+			CertificatesPublished: published,
 		})
 	}
 }
@@ -1183,6 +1283,11 @@ func (rt *Web) contestEdit() http.HandlerFunc {
 			mossSubs = []*kilonova.MOSSSubmission{}
 		}
 
+		pbSettings, err := rt.base.ContestProblemSettings(r.Context(), util.Contest(r).ID)
+		if err != nil {
+			pbSettings = []*kilonova.ContestProblemSettings{}
+		}
+
 		rt.runTempl(w, r, templ, &ContestParams{
 			Topbar: rt.problemTopbar(r, "contest_edit", -1),
 
@@ -1190,6 +1295,7 @@ func (rt *Web) contestEdit() http.HandlerFunc {
 
 			ContestInvitations: invitations,
 			MOSSResults:        mossSubs,
+			ProblemSettings:    pbSettings,
 		})
 	}
 }
@@ -1258,6 +1364,193 @@ func (rt *Web) contestRegistrations() http.HandlerFunc {
 	}
 }
 
+func (rt *Web) contestResolver() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/resolver.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		rt.runTempl(w, r, templ, &ContestParams{
+			Topbar: rt.problemTopbar(r, "contest_resolver", -1),
+
+			Contest: util.Contest(r),
+		})
+	}
+}
+
+func (rt *Web) contestCertificates() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/certificates.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		settings, err := rt.base.CertificateSettings(r.Context(), util.Contest(r).ID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+		certs, err := rt.base.ContestCertificates(r.Context(), util.Contest(r).ID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+
+		rt.runTempl(w, r, templ, &ContestParams{
+			Topbar: rt.problemTopbar(r, "contest_certificates", -1),
+
+			Contest: util.Contest(r),
+
+			CertificateSettings:   settings,
+			CertificatePDFEnabled: rt.base.PDFRenderingEnabled(),
+			CertificatesIssued:    len(certs),
+		})
+	}
+}
+
+func (rt *Web) certificate() http.HandlerFunc {
+	templ := rt.parse(nil, "certificate.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		cert, err := rt.base.Certificate(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		contest, err := rt.base.Contest(r.Context(), cert.ContestID)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		user, err := rt.base.UserBrief(r.Context(), cert.UserID)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+
+		rt.runTempl(w, r, templ, &CertificateParams{
+			Certificate: cert,
+			Contest:     contest,
+			User:        user,
+		})
+	}
+}
+
+func (rt *Web) contestPrint() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/print.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		contest := util.Contest(r)
+		jobs, err := rt.base.PrintJobs(r.Context(), contest.ID, &util.UserBrief(r).ID, false)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+
+		rt.runTempl(w, r, templ, &OnSiteParams{
+			Topbar: rt.problemTopbar(r, "contest_print", -1),
+
+			Contest:   contest,
+			PrintJobs: jobs,
+		})
+	}
+}
+
+func (rt *Web) contestPrintQueue() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/print_queue.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		contest := util.Contest(r)
+		showPrinted := r.FormValue("all") == "true"
+		jobs, err := rt.base.PrintJobs(r.Context(), contest.ID, nil, !showPrinted)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		userTeams := make(map[int]*int)
+		for _, job := range jobs {
+			userTeams[job.UserID] = job.TeamID
+		}
+		params, err := rt.onSiteParams(r, "contest_print_queue", userTeams)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		params.PrintJobs = jobs
+		params.ShowPrinted = showPrinted
+
+		rt.runTempl(w, r, templ, params)
+	}
+}
+
+func (rt *Web) contestBalloons() http.HandlerFunc {
+	templ := rt.parse(nil, "contest/balloons.html", "problem/topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		balloons, err := rt.base.BalloonEvents(r.Context(), util.Contest(r))
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		userTeams := make(map[int]*int)
+		for _, ev := range balloons {
+			userTeams[ev.UserID] = ev.TeamID
+		}
+		params, err := rt.onSiteParams(r, "contest_balloons", userTeams)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		params.Balloons = balloons
+
+		rt.runTempl(w, r, templ, params)
+	}
+}
+
+// onSiteParams loads the names of the users, teams and problems shown on the staff pages of on-site contests.
+// userTeams maps the IDs of the users shown on the page to their team IDs
+func (rt *Web) onSiteParams(r *http.Request, page string, userTeams map[int]*int) (*OnSiteParams, *kilonova.StatusError) {
+	contest := util.Contest(r)
+	params := &OnSiteParams{
+		Topbar: rt.problemTopbar(r, page, -1),
+
+		Contest: contest,
+
+		Users:     make(map[int]*kilonova.UserBrief),
+		UserTeams: make(map[int]*kilonova.ContestTeam),
+		Problems:  make(map[int]*kilonova.ScoredProblem),
+
+		PDFEnabled: rt.base.PDFRenderingEnabled(),
+	}
+
+	if len(userTeams) > 0 {
+		userIDs := make([]int, 0, len(userTeams))
+		for id := range userTeams {
+			userIDs = append(userIDs, id)
+		}
+		users, err := rt.base.UsersBrief(r.Context(), kilonova.UserFilter{IDs: userIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			params.Users[user.ID] = user
+		}
+	}
+	if contest.TeamMode() {
+		teams, err := rt.base.ContestTeams(r.Context(), contest.ID)
+		if err != nil {
+			return nil, err
+		}
+		for userID, teamID := range userTeams {
+			if teamID == nil {
+				continue
+			}
+			for _, team := range teams {
+				if team.ID == *teamID {
+					params.UserTeams[userID] = team
+				}
+			}
+		}
+	}
+	pbs, err := rt.base.ContestProblems(r.Context(), contest, util.UserBrief(r))
+	if err != nil {
+		return nil, err
+	}
+	for _, pb := range pbs {
+		params.Problems[pb.ID] = pb
+	}
+	return params, nil
+}
+
 func (rt *Web) contestLeaderboard() http.HandlerFunc {
 	templ := rt.parse(nil, "contest/leaderboard.html", "problem/topbar.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1275,6 +1568,47 @@ func (rt *Web) contestLeaderboard() http.HandlerFunc {
 	}
 }
 
+func (rt *Web) contestSeriesIndex() http.HandlerFunc {
+	templ := rt.parse(nil, "series/index.html", "contest/index_topbar.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		series, err := rt.base.ContestSeriesList(r.Context(), kilonova.ContestSeriesFilter{Look: true, LookingUser: util.UserBrief(r)})
+		if err != nil {
+			rt.statusPage(w, r, 500, "N-am putut obține seriile de concursuri")
+			return
+		}
+		rt.runTempl(w, r, templ, &ContestSeriesIndexParams{
+			Series: series,
+			Page:   "series",
+		})
+	}
+}
+
+func (rt *Web) contestSeries() http.HandlerFunc {
+	templ := rt.parse(nil, "series/view.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		leaderboard, err := rt.base.SeriesLeaderboard(r.Context(), util.ContestSeries(r), util.UserBrief(r))
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Error())
+			return
+		}
+		rt.runTempl(w, r, templ, &ContestSeriesParams{
+			Series:      util.ContestSeries(r),
+			Leaderboard: leaderboard,
+		})
+	}
+}
+
+func (rt *Web) contestSeriesEdit() http.HandlerFunc {
+	templ := rt.parse(nil, "series/edit.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rt.base.IsSeriesEditor(util.UserBrief(r), util.ContestSeries(r)) {
+			rt.statusPage(w, r, 403, "Nu poți edita această serie de concursuri!")
+			return
+		}
+		rt.runTempl(w, r, templ, &ContestSeriesParams{Series: util.ContestSeries(r)})
+	}
+}
+
 func (rt *Web) graderInfo() http.HandlerFunc {
 	templ := rt.parse(nil, "admin/grader.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1375,8 +1709,21 @@ func (rt *Web) profilePage(w http.ResponseWriter, r *http.Request, templ *templa
 		changeHistory = []*kilonova.UsernameChange{}
 	}
 
+	ratingHistory, err := rt.base.UserRatingHistory(r.Context(), user.ID)
+	if err != nil {
+		ratingHistory = []*kilonova.RatingChange{}
+	}
+
+	var apiTokens []*kilonova.APIToken
+	if authed := util.UserBrief(r); authed != nil && authed.ID == user.ID {
+		apiTokens, err = rt.base.APITokens(r.Context(), user.ID)
+		if err != nil {
+			apiTokens = []*kilonova.APIToken{}
+		}
+	}
+
 	rt.runTempl(w, r, templ, &ProfileParams{
-		user, solvedPbs, solvedCnt, attemptedPbs, attemptedCnt, changeHistory,
+		user, solvedPbs, solvedCnt, attemptedPbs, attemptedCnt, changeHistory, ratingHistory, apiTokens,
 	})
 }
 
@@ -1410,9 +1757,26 @@ func (rt *Web) linkStatusPage(w http.ResponseWriter, r *http.Request, templ *tem
 		slog.Warn("Could not get Discord identity", slog.Any("user", user), slog.Any("err", err))
 		dUser = nil
 	}
+	identities, err := rt.base.ExternalIdentities(r.Context(), user.ID)
+	if err != nil {
+		slog.Warn("Could not get external identities", slog.Any("user", user), slog.Any("err", err))
+		identities = nil
+	}
+	var accounts []*ExternalProvider
+	for _, provider := range sudoapi.OIDCProviders.Value() {
+		account := &ExternalProvider{ID: provider.ID, Name: provider.Name}
+		for _, identity := range identities {
+			if identity.Provider == provider.ID {
+				account.Identity = identity
+			}
+		}
+		accounts = append(accounts, account)
+	}
 	rt.runTempl(w, r, templ, &DiscordLinkParams{
 		ContentUser: user,
 		DiscordUser: dUser,
+
+		ExternalAccounts: accounts,
 	})
 }
 
@@ -1439,6 +1803,7 @@ func (rt *Web) linkStatus() http.HandlerFunc {
 		// Only admins and that specific user can view their sessions
 		if !(util.UserBrief(r).IsAdmin() || util.UserBrief(r).ID == user.ID) {
 			rt.statusPage(w, r, 403, "")
+			return
 		}
 
 		rt.linkStatusPage(w, r, templ, user)
@@ -1466,6 +1831,122 @@ func (rt *Web) selfSessions() http.HandlerFunc {
 	}
 }
 
+var oauthScopeTexts = map[kilonova.APITokenScope]string{
+	kilonova.ScopeOpenID:             "oauth.scope_openid",
+	kilonova.ScopeProfile:            "oauth.scope_profile",
+	kilonova.ScopeReadSubmissions:    "api_tokens.scope_read_submissions",
+	kilonova.ScopeSubmit:             "api_tokens.scope_submit",
+	kilonova.ScopeReadProblems:       "api_tokens.scope_read_problems",
+	kilonova.ScopeManageProblems:     "api_tokens.scope_manage_problems",
+	kilonova.ScopeContestParticipate: "api_tokens.scope_contest_participate",
+	kilonova.ScopeContestAdmin:       "api_tokens.scope_contest_admin",
+	kilonova.ScopeContestFeed:        "api_tokens.scope_contest_feed",
+}
+
+func (rt *Web) oauthAuthorize() http.HandlerFunc {
+	templ := rt.parse(nil, "oauth/consent.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !util.UserBrief(r).IsAuthed() {
+			// Unlike mustBeAuthed, the query must be kept, since it holds the authorization request
+			http.Redirect(w, r, "/login?back="+url.QueryEscape(r.URL.RequestURI()), http.StatusTemporaryRedirect)
+			return
+		}
+
+		q := r.URL.Query()
+		req := &kilonova.OAuthAuthorizeRequest{
+			ClientID:            q.Get("client_id"),
+			RedirectURI:         q.Get("redirect_uri"),
+			ResponseType:        q.Get("response_type"),
+			Scope:               q.Get("scope"),
+			State:               q.Get("state"),
+			Nonce:               q.Get("nonce"),
+			CodeChallenge:       q.Get("code_challenge"),
+			CodeChallengeMethod: q.Get("code_challenge_method"),
+		}
+		client, err := rt.base.OAuthAuthorizeClient(r.Context(), req)
+		if err != nil {
+			rt.statusPage(w, r, err.Code, err.Text)
+			return
+		}
+
+		// From here on, the redirect URI is trusted, so errors are sent back to the app
+		redirectParams := func(code, description string) url.Values {
+			params := url.Values{"error": {code}}
+			if description != "" {
+				params.Set("error_description", description)
+			}
+			if req.State != "" {
+				params.Set("state", req.State)
+			}
+			return params
+		}
+		if err := rt.base.CheckOAuthAuthorizeRequest(client, req); err != nil {
+			http.Redirect(w, r, sudoapi.OAuthRedirectURL(req.RedirectURI, redirectParams(kilonova.OAuthErrorCode(err), err.Text)), http.StatusFound)
+			return
+		}
+
+		owner, err := rt.base.UserBrief(r.Context(), client.OwnerID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+		var scopeTexts []string
+		for _, scope := range req.Scopes() {
+			if text, ok := oauthScopeTexts[scope]; ok && !slices.Contains(scopeTexts, text) {
+				scopeTexts = append(scopeTexts, text)
+			}
+		}
+
+		// The consent page must not be framed by other sites, so users can't be tricked into authorizing apps
+		w.Header().Set("X-Frame-Options", "DENY")
+		rt.runTempl(w, r, templ, &OAuthConsentParams{
+			Client:     client,
+			Owner:      owner,
+			Request:    req,
+			ScopeTexts: scopeTexts,
+			DenyURL:    sudoapi.OAuthRedirectURL(req.RedirectURI, redirectParams("access_denied", "")),
+		})
+	}
+}
+
+func (rt *Web) oauthApps() http.HandlerFunc {
+	templ := rt.parse(nil, "oauth/apps.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		clients, err := rt.base.OAuthClients(r.Context(), util.UserBrief(r).ID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+		grants, err := rt.base.OAuthGrants(r.Context(), util.UserBrief(r).ID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+
+		rt.runTempl(w, r, templ, &OAuthAppsParams{
+			Clients: clients,
+			Grants:  grants,
+			Enabled: sudoapi.OAuthEnabled.Value(),
+		})
+	}
+}
+
+func (rt *Web) notificationSettings() http.HandlerFunc {
+	templ := rt.parse(nil, "notification_settings.html")
+	return func(w http.ResponseWriter, r *http.Request) {
+		prefs, err := rt.base.NotificationPreferences(r.Context(), util.UserBrief(r).ID)
+		if err != nil {
+			rt.statusPage(w, r, 500, err.Error())
+			return
+		}
+
+		rt.runTempl(w, r, templ, &NotificationSettingsParams{
+			Preferences:   prefs,
+			MailerEnabled: rt.base.MailerEnabled() && sudoapi.NotificationsEnabled.Value(),
+		})
+	}
+}
+
 func (rt *Web) userSessions() http.HandlerFunc {
 	templ := rt.parse(nil, "auth/sessions.html")
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1837,6 +2318,12 @@ func (rt *Web) runTempl(w io.Writer, r *http.Request, templ *template.Template,
 		"isContestEditor": func(c *kilonova.Contest) bool {
 			return rt.base.IsContestEditor(authedUser, c)
 		},
+		"isContestTester": func(c *kilonova.Contest) bool {
+			return rt.base.IsContestTester(authedUser, c)
+		},
+		"isSeriesEditor": func(s *kilonova.ContestSeries) bool {
+			return rt.base.IsSeriesEditor(authedUser, s)
+		},
 		"genContestProblemsParams": func(pbs []*kilonova.ScoredProblem, contest *kilonova.Contest) *ProblemListingParams {
 			return &ProblemListingParams{pbs, rt.base.IsContestEditor(authedUser, contest) || contest.Ended(), true, contest.ID, -1}
 		},
@@ -1866,6 +2353,25 @@ func (rt *Web) runTempl(w io.Writer, r *http.Request, templ *template.Template,
 			}
 			return reg
 		},
+		"canStartVirtual": func(c *kilonova.Contest) bool {
+			return rt.base.CanStartVirtualParticipation(r.Context(), authedUser, c)
+		},
+		"canSubmitInContest": func(user *kilonova.UserBrief, c *kilonova.Contest) bool {
+			return rt.base.CanSubmitInContest(r.Context(), user, c)
+		},
+		"contestTeam": func(c *kilonova.Contest) *kilonova.ContestTeam {
+			if authedUser == nil || c == nil || !c.TeamMode() {
+				return nil
+			}
+			team, err := rt.base.UserContestTeam(r.Context(), c.ID, authedUser.ID)
+			if err != nil {
+				if !errors.Is(err, kilonova.ErrNotFound) && !errors.Is(err, context.Canceled) {
+					zap.S().Warn(err)
+				}
+				return nil
+			}
+			return team
+		},
 		"problemFullyVisible": func() bool {
 			return rt.base.IsProblemFullyVisible(util.UserBrief(r), util.Problem(r))
 		},
@@ -0,0 +1,30 @@
+package web
+
+import "testing"
+
+var backPathExamples = map[string]struct {
+	Back     string
+	Expected string
+}{
+	"page":              {Back: "/problems/1", Expected: "/problems/1"},
+	"query":             {Back: "/contests/2/leaderboard?frozen=true", Expected: "/contests/2/leaderboard?frozen=true"},
+	"empty":             {Back: "", Expected: "/"},
+	"relative":          {Back: "profile", Expected: "/"},
+	"protocol relative": {Back: "//evil.com", Expected: "/"},
+	"backslash":         {Back: `/\evil.com`, Expected: "/"},
+	"double backslash":  {Back: `\\evil.com`, Expected: "/"},
+	"absolute":          {Back: "https://evil.com/login", Expected: "/"},
+	"javascript":        {Back: "javascript:alert(1)", Expected: "/"},
+	"control character": {Back: "/\t/evil.com", Expected: "/"},
+}
+
+func TestSafeBackPath(t *testing.T) {
+	for k, v := range backPathExamples {
+		v := v
+		t.Run(k, func(t *testing.T) {
+			if got := safeBackPath(v.Back); got != v.Expected {
+				t.Fatalf("Expected %q for %q, got %q", v.Expected, v.Back, got)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/internal/util"
+	"github.com/KiloProjects/kilonova/sudoapi"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"golang.org/x/text/language"
@@ -88,6 +89,23 @@ func (rt *Web) ValidateListID(next http.Handler) http.Handler {
 	})
 }
 
+// ValidateSeriesID puts the contest series in the router context, if it is visible to the logged in user
+func (rt *Web) ValidateSeriesID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seriesID, err := strconv.Atoi(trimNonDigits(chi.URLParam(r, "seriesID")))
+		if err != nil {
+			rt.statusPage(w, r, http.StatusBadRequest, "ID invalid")
+			return
+		}
+		series, err1 := rt.base.ContestSeries(r.Context(), seriesID)
+		if err1 != nil || !rt.base.IsSeriesVisible(util.UserBrief(r), series) {
+			rt.statusPage(w, r, 404, "Seria de concursuri nu a fost găsită")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.SeriesKey, series)))
+	})
+}
+
 // ValidateTagID makes sure the tag ID is a valid uint
 func (rt *Web) ValidateTagID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -118,6 +136,10 @@ func (rt *Web) ValidateProblemVisible(next http.Handler) http.Handler {
 			rt.statusPage(w, r, 403, "Nu ai voie să accesezi problema!")
 			return
 		}
+		if !rt.base.LockdownAllowsProblem(r.Context(), util.UserBrief(r), util.Problem(r)) {
+			rt.statusPage(w, r, 403, "În timpul concursului poți accesa doar problemele concursului!")
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -140,6 +162,10 @@ func (rt *Web) ValidateBlogPostVisible(next http.Handler) http.Handler {
 			rt.statusPage(w, r, 403, "Nu ai voie să accesezi articolul!")
 			return
 		}
+		if rt.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+			rt.statusPage(w, r, 403, "Articolele nu sunt disponibile în timpul concursului!")
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
@@ -157,6 +183,10 @@ func (rt *Web) ValidateContestID(next http.Handler) http.Handler {
 			rt.statusPage(w, r, 404, "Concursul nu a fost găsit")
 			return
 		}
+		if err := rt.base.EnforceContestSession(r.Context(), contest, util.UserBrief(r), rt.base.GetSessCookie(r)); err != nil {
+			rt.statusPage(w, r, err.Code, err.Text)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), util.ContestKey, contest)))
 	})
 }
@@ -207,6 +237,10 @@ func (rt *Web) ValidatePasteID(next http.Handler) http.Handler {
 			rt.statusPage(w, r, 404, "Feature has been disabled by administrator.")
 			return
 		}
+		if rt.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+			rt.statusPage(w, r, 403, "Paste-urile nu sunt disponibile în timpul concursului!")
+			return
+		}
 		paste, err1 := rt.base.SubmissionPaste(r.Context(), chi.URLParam(r, "id"))
 		if err1 != nil {
 			rt.statusPage(w, r, 400, "Paste-ul nu există")
@@ -257,6 +291,17 @@ func (rt *Web) mustBeProposer(next http.Handler) http.Handler {
 	})
 }
 
+// mustNotBeLockedDown hides pages unrelated to the contest from the participants of a contest with lockdown
+func (rt *Web) mustNotBeLockedDown(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt.base.IsLockedDown(r.Context(), util.UserBrief(r)) {
+			rt.statusPage(w, r, 403, "Pagina nu este disponibilă în timpul concursului!")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (rt *Web) mustBeAdmin(next http.Handler) http.Handler {
 	return rt.mustBeAuthed(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !util.UserBrief(r).IsAdmin() {
@@ -307,8 +352,29 @@ func (rt *Web) mustBeContestEditor(next http.Handler) http.Handler {
 	})
 }
 
+func (rt *Web) mustBeContestTester(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rt.base.IsContestTester(util.UserBrief(r), util.Contest(r)) {
+			rt.statusPage(w, r, 401, "Trebuie să fii un tester al concursului")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (rt *Web) mustBeOnSite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !util.Contest(r).OnSite {
+			rt.statusPage(w, r, 404, "")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (rt *Web) initSession(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(sudoapi.WithLockdownCache(r.Context()))
 		user, err := rt.base.SessionUser(r.Context(), rt.base.GetSessCookie(r), r)
 		if err != nil || user == nil {
 			next.ServeHTTP(w, r)
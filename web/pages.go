@@ -130,6 +130,25 @@ type ContestParams struct {
 
 	ContestInvitations []*kilonova.ContestInvitation
 	MOSSResults        []*kilonova.MOSSSubmission
+	ProblemSettings    []*kilonova.ContestProblemSettings
+
+	CertificateSettings *kilonova.CertificateSettings
+	// CertificatesPublished is set on the contest page if the user was issued a certificate they can download
+	CertificatesPublished bool
+	CertificatePDFEnabled bool
+	CertificatesIssued    int
+}
+
+type ContestSeriesIndexParams struct {
+	Series []*kilonova.ContestSeries
+
+	// Page is used by the contest index topbar
+	Page string
+}
+
+type ContestSeriesParams struct {
+	Series      *kilonova.ContestSeries
+	Leaderboard *kilonova.SeriesLeaderboard
 }
 
 type ContestInviteParams struct {
@@ -311,12 +330,27 @@ type ProfileParams struct {
 	AttemptedCount    int
 
 	ChangeHistory []*kilonova.UsernameChange
+
+	RatingHistory []*kilonova.RatingChange
+
+	// APITokens is only set when users view their own profile
+	APITokens []*kilonova.APIToken
 }
 
 type DiscordLinkParams struct {
 	ContentUser *kilonova.UserFull
 
 	DiscordUser *discordgo.User
+
+	ExternalAccounts []*ExternalProvider
+}
+
+// ExternalProvider is a login provider shown on the login and linked accounts pages.
+// Identity is set if the user linked an account of the provider
+type ExternalProvider struct {
+	ID       string
+	Name     string
+	Identity *kilonova.ExternalIdentity
 }
 
 type SessionsParams struct {
@@ -326,6 +360,52 @@ type SessionsParams struct {
 	NumPages    int
 }
 
+type NotificationSettingsParams struct {
+	Preferences *kilonova.NotificationPreferences
+	// MailerEnabled is false if emails can't be sent on this instance, so the preferences have no effect
+	MailerEnabled bool
+}
+
+type OAuthConsentParams struct {
+	Client  *kilonova.OAuthClient
+	Owner   *kilonova.UserBrief
+	Request *kilonova.OAuthAuthorizeRequest
+	// ScopeTexts are the translation keys describing the requested scopes
+	ScopeTexts []string
+	DenyURL    string
+}
+
+type OAuthAppsParams struct {
+	Clients []*kilonova.OAuthClient
+	Grants  []*kilonova.OAuthGrant
+	Enabled bool
+}
+
+// OnSiteParams is used by the print queue and balloon board pages of on-site contests
+type OnSiteParams struct {
+	Topbar *ProblemTopbar
+
+	Contest *kilonova.Contest
+
+	PrintJobs []*kilonova.PrintJob
+	Balloons  []*kilonova.BalloonEvent
+	// ShowPrinted is set when the print queue also lists the already printed jobs
+	ShowPrinted bool
+
+	Users map[int]*kilonova.UserBrief
+	// UserTeams maps the users shown on the page to the team they participated with
+	UserTeams map[int]*kilonova.ContestTeam
+	Problems  map[int]*kilonova.ScoredProblem
+
+	PDFEnabled bool
+}
+
+type CertificateParams struct {
+	Certificate *kilonova.Certificate
+	Contest     *kilonova.Contest
+	User        *kilonova.UserBrief
+}
+
 type AuditLogParams struct {
 	Logs     []*kilonova.AuditLog
 	Page     int
@@ -362,6 +442,9 @@ type PasswordResetParams struct {
 
 type SubParams struct {
 	Submission *kilonova.FullSubmission
+
+	// CanHack is true if the user can challenge the submission during the contest's hacking phase
+	CanHack bool
 }
 
 type PasteParams struct {
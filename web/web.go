@@ -3,7 +3,6 @@
 package web
 
 import (
-	"bytes"
 	"context"
 	"embed"
 	"encoding/base64"
@@ -21,16 +20,12 @@ import (
 	"strconv"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/KiloProjects/kilonova"
 	"github.com/KiloProjects/kilonova/eval"
 	"github.com/KiloProjects/kilonova/internal/config"
 	"github.com/KiloProjects/kilonova/sudoapi"
-	"github.com/alecthomas/chroma/v2"
 	chtml "github.com/alecthomas/chroma/v2/formatters/html"
-	"github.com/alecthomas/chroma/v2/lexers"
-	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/benbjohnson/hashfs"
 	"github.com/bwmarrin/discordgo"
 	"github.com/davecgh/go-spew/spew"
@@ -148,6 +143,8 @@ func (rt *Web) Handler() http.Handler {
 		})
 
 		r.With(rt.mustBeVisitor).Get("/login", rt.justRender("auth/login.html", "modals/login.html"))
+		r.With(rt.mustBeVisitor).Get("/login/external/{provider}", rt.externalLogin())
+		r.Get("/login/external/{provider}/callback", rt.externalLoginCallback())
 		r.With(rt.mustBeVisitor).Get("/signup", rt.justRender("auth/signup.html"))
 		r.With(rt.mustBeVisitor).Get("/forgot_pwd", rt.justRender("auth/forgot_pwd_send.html"))
 
@@ -160,6 +157,7 @@ func (rt *Web) Handler() http.Handler {
 
 		r.Get("/", rt.index())
 		r.With(rt.mustBeAuthed).Get("/link", rt.discordLink())
+		r.With(rt.mustBeAuthed).Get("/link/{provider}", rt.externalLink())
 		r.With(rt.mustBeAuthed).Get("/profile", rt.selfProfile())
 		r.With(rt.mustBeAuthed).Get("/profile/linked", rt.selfLinkStatus())
 		r.With(rt.mustBeAuthed).Get("/profile/sessions", rt.selfSessions())
@@ -167,22 +165,26 @@ func (rt *Web) Handler() http.Handler {
 		r.With(rt.mustBeAuthed).Get("/profile/{user}/linked", rt.linkStatus())
 		r.With(rt.mustBeAuthed).Get("/profile/{user}/sessions", rt.userSessions())
 		r.With(rt.mustBeAuthed).Get("/settings", rt.justRender("settings.html"))
+		r.With(rt.mustBeAuthed).Get("/settings/notifications", rt.notificationSettings())
+		r.With(rt.mustBeAuthed).Get("/settings/apps", rt.oauthApps())
+		r.Get("/oauth/authorize", rt.oauthAuthorize())
 		r.Get("/donate", rt.donationPage())
 		r.Get("/grader", rt.graderInfo())
 
 		r.Route("/problems", func(r chi.Router) {
-			r.Get("/", rt.problems())
+			r.With(rt.mustNotBeLockedDown).Get("/", rt.problems())
 			r.Route("/{pbid}", rt.problemRouter)
 		})
 
 		r.Route("/posts", func(r chi.Router) {
-			r.Get("/", rt.blogPosts())
+			r.With(rt.mustNotBeLockedDown).Get("/", rt.blogPosts())
 			r.Route("/{postslug}", rt.blogPostRouter)
 		})
 		// not /posts/create since there could be a post that is slugged "create"
 		r.With(rt.mustBeProposer).Get("/createPost", rt.justRender("blogpost/create.html"))
 
 		r.Route("/tags", func(r chi.Router) {
+			r.Use(rt.mustNotBeLockedDown)
 			r.Get("/", rt.tags())
 			r.With(rt.ValidateTagID).Get("/{tagid}", rt.tag())
 		})
@@ -205,11 +207,31 @@ func (rt *Web) Handler() http.Handler {
 					r.Use(rt.mustBeContestEditor)
 					r.Get("/edit", rt.contestEdit())
 					r.Get("/registrations", rt.contestRegistrations())
+					r.Get("/resolver", rt.contestResolver())
+					r.Get("/certificates", rt.contestCertificates())
+				})
+				r.With(rt.mustBeAuthed, rt.mustBeOnSite).Get("/print", rt.contestPrint())
+				r.Route("/staff", func(r chi.Router) {
+					r.Use(rt.mustBeContestTester)
+					r.Use(rt.mustBeOnSite)
+					r.Get("/print", rt.contestPrintQueue())
+					r.Get("/balloons", rt.contestBalloons())
 				})
 				r.Route("/problems/{pbid}", rt.problemRouter)
 			})
 		})
 
+		r.Get("/certificates/{id}", rt.certificate())
+
+		r.Route("/series", func(r chi.Router) {
+			r.Get("/", rt.contestSeriesIndex())
+			r.Route("/{seriesID}", func(r chi.Router) {
+				r.Use(rt.ValidateSeriesID)
+				r.Get("/", rt.contestSeries())
+				r.With(rt.mustBeAuthed).Get("/edit", rt.contestSeriesEdit())
+			})
+		})
+
 		r.Route("/submissions", func(r chi.Router) {
 			r.Get("/", rt.submissions())
 			r.With(rt.ValidateSubmissionID).Get("/{id}", rt.submission())
@@ -218,6 +240,7 @@ func (rt *Web) Handler() http.Handler {
 		r.With(rt.ValidatePasteID).Get("/pastes/{id}", rt.paste())
 
 		r.Route("/problem_lists", func(r chi.Router) {
+			r.Use(rt.mustNotBeLockedDown)
 			r.Get("/", rt.pbListIndex())
 			r.Get("/progress", rt.pbListProgressIndex())
 			r.With(rt.ValidateListID).Get("/{id}/progress", rt.pbListProgressView())
@@ -294,6 +317,13 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			}
 			return pbs
 		},
+		"teamInvitations": func(team *kilonova.ContestTeam) []*kilonova.ContestInvitation {
+			invs, err := base.TeamInvitations(context.Background(), team.ID)
+			if err != nil {
+				return []*kilonova.ContestInvitation{}
+			}
+			return invs
+		},
 		"problemFromList": func(pbs []*kilonova.ScoredProblem, id int) *kilonova.ScoredProblem {
 			for _, pb := range pbs {
 				if pb.ID == id {
@@ -312,7 +342,7 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			}
 			actualContests := make([]*kilonova.Contest, 0, len(contests))
 			for _, contest := range contests {
-				if base.CanSubmitInContest(user, contest) {
+				if base.CanSubmitInContest(context.Background(), user, contest) {
 					actualContests = append(actualContests, contest)
 				}
 			}
@@ -394,6 +424,9 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			return reg.IndividualStartTime != nil && reg.IndividualEndTime.Before(time.Now())
 		},
 		"remainingContestTime": func(c *kilonova.Contest, reg *kilonova.ContestRegistration) time.Time {
+			if reg.VirtualRunning() {
+				return *reg.IndividualEndTime
+			}
 			if c.PerUserTime == 0 || reg == nil || reg.IndividualStartTime == nil {
 				return c.EndTime
 			}
@@ -402,6 +435,21 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			}
 			return c.EndTime
 		},
+		"contestProblemTitles": func(user *kilonova.UserBrief, c *kilonova.Contest) map[int]string {
+			titles := make(map[int]string)
+			pbs, err := base.ContestProblems(context.Background(), c, user)
+			if err != nil {
+				return titles
+			}
+			for _, pb := range pbs {
+				if pb.ContestLabel != "" {
+					titles[pb.ID] = pb.ContestLabel + ". " + pb.Name
+				} else {
+					titles[pb.ID] = pb.Name
+				}
+			}
+			return titles
+		},
 		"allContestQuestions": func(c *kilonova.Contest) []*kilonova.ContestQuestion {
 			questions, err := base.ContestQuestions(context.Background(), c.ID)
 			if err != nil {
@@ -471,24 +519,7 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			return time.Now().Format("15:04:05")
 		},
 		"syntaxHighlight": func(code []byte, lang string) (string, error) {
-			fmt := chtml.New(chtml.WithClasses(true), chtml.TabWidth(4))
-			if lang == "pascal" {
-				lang = "pas"
-			}
-			lm := lexers.Get(strings.TrimFunc(lang, unicode.IsDigit))
-			if lm == nil {
-				lm = lexers.Fallback
-			}
-			lm = chroma.Coalesce(lm)
-			var buf bytes.Buffer
-			it, err := lm.Tokenise(nil, string(code))
-			if err != nil {
-				return "", err
-			}
-			if err := fmt.Format(&buf, styles.Get("github"), it); err != nil {
-				return "", err
-			}
-			return buf.String(), nil
+			return sudoapi.SyntaxHighlight(string(code), lang, "", chtml.WithClasses(true))
 		},
 		"submissionEditor": func(user *kilonova.UserBrief, sub *kilonova.Submission) bool {
 			return base.IsSubmissionEditor(sub, user)
@@ -640,6 +671,13 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			}
 			return val
 		},
+		"externalLoginProviders": func() []*ExternalProvider {
+			var providers []*ExternalProvider
+			for _, provider := range sudoapi.OIDCProviders.Value() {
+				providers = append(providers, &ExternalProvider{ID: provider.ID, Name: provider.Name})
+			}
+			return providers
+		},
 		"stringFlag": func(name string) string {
 			val, ok := config.GetFlagVal[string](name)
 			if !ok {
@@ -747,10 +785,9 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			return code
 		},
 
-		"httpstatus":         http.StatusText,
-		"dump":               spew.Sdump,
-		"canJoinContest":     base.CanJoinContest,
-		"canSubmitInContest": base.CanSubmitInContest,
+		"httpstatus":     http.StatusText,
+		"dump":           spew.Sdump,
+		"canJoinContest": base.CanJoinContest,
 		"contestDuration": func(c *kilonova.Contest) string {
 			d := c.EndTime.Sub(c.StartTime).Round(time.Minute)
 			return d.String()
@@ -811,6 +848,14 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			zap.S().Error("Uninitialized `isContestEditor`")
 			return false
 		},
+		"isContestTester": func(c *kilonova.Contest) bool {
+			zap.S().Error("Uninitialized `isContestTester`")
+			return false
+		},
+		"isSeriesEditor": func(s *kilonova.ContestSeries) bool {
+			zap.S().Error("Uninitialized `isSeriesEditor`")
+			return false
+		},
 		"contestLeaderboardVisible": func(c *kilonova.Contest) bool {
 			zap.S().Error("Uninitialized `contestLeaderboardVisible`")
 			return false
@@ -835,6 +880,18 @@ func NewWeb(base *sudoapi.BaseAPI) *Web {
 			zap.S().Error("Uninitialized `contestRegistration`")
 			return nil
 		},
+		"canStartVirtual": func(c *kilonova.Contest) bool {
+			zap.S().Error("Uninitialized `canStartVirtual`")
+			return false
+		},
+		"canSubmitInContest": func(user *kilonova.UserBrief, c *kilonova.Contest) bool {
+			zap.S().Error("Uninitialized `canSubmitInContest`")
+			return false
+		},
+		"contestTeam": func(c *kilonova.Contest) *kilonova.ContestTeam {
+			zap.S().Error("Uninitialized `contestTeam`")
+			return nil
+		},
 		"problemFullyVisible": func() bool {
 			zap.S().Error("Uninitialized `problemFullyVisible`")
 			return false